@@ -8,6 +8,9 @@ import (
 	"github.com/dzungtran/echo-rest-api/modules/core"
 	coreTemplates "github.com/dzungtran/echo-rest-api/modules/core/handlers/templates"
 	"github.com/dzungtran/echo-rest-api/modules/projects"
+	"github.com/dzungtran/echo-rest-api/pkg/authz"
+	"github.com/dzungtran/echo-rest-api/pkg/hook"
+	"github.com/dzungtran/echo-rest-api/pkg/hook-subscriber/subscribers"
 	"github.com/dzungtran/echo-rest-api/pkg/logger"
 	"github.com/dzungtran/echo-rest-api/pkg/middlewares"
 	sqlTools "github.com/dzungtran/echo-rest-api/pkg/sql-tools"
@@ -32,6 +35,32 @@ func BuildDIContainer(
 		return sqlTools.NewSqlxTransaction(mdbi)
 	})
 
+	container.Provide(func() hook.HookerInterface {
+		return hook.CreateHooker()
+	})
+
+	// the in process policies, they decide while the remote policy decision point is unavailable
+	container.Provide(authz.NewOPAAuthorizer)
+
+	// the same instance decides the requests and is configured as the PolicyAdmin
+	container.Provide(func(embedded *authz.OPAAuthorizer) (authz.Authorizer, authz.PolicyAdmin, error) {
+		if conf.PolicyRemoteUrl == "" {
+			return embedded, embedded, nil
+		}
+		remote, err := authz.NewRemoteAuthorizer(authz.RemoteAuthorizerOptions{
+			Url:      conf.PolicyRemoteUrl,
+			Timeout:  conf.PolicyRemoteTimeout,
+			Retries:  conf.PolicyRemoteRetries,
+			Backoff:  conf.PolicyRemoteBackoff,
+			Fallback: conf.PolicyRemoteFallback,
+			Embedded: embedded,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return remote, remote, nil
+	})
+
 	return container
 }
 
@@ -57,6 +86,20 @@ func RegisterModules(e *echo.Echo, container *dig.Container) error {
 		}
 	}
 
+	err = container.Invoke(func(appConf *config.AppConfig) error {
+		return middlewares.RegisterAuthenticators(container, appConf)
+	})
+	if err != nil {
+		logger.Log().Errorf("RegisterAuthenticators error: %v", err)
+		return err
+	}
+
+	err = container.Invoke(registerSubscribers)
+	if err != nil {
+		logger.Log().Errorf("RegisterSubscribers error: %v", err)
+		return err
+	}
+
 	err = container.Provide(middlewares.NewMiddlewareManager)
 	if err != nil {
 		logger.Log().Errorf("RegisterHandlers error: %v", err)
@@ -74,6 +117,13 @@ func RegisterModules(e *echo.Echo, container *dig.Container) error {
 	return err
 }
 
+func registerSubscribers(hooker hook.HookerInterface, principalCache *middlewares.PrincipalCache) {
+	principalCacheSubscriber := subscribers.NewPrincipalCacheSubscriber(principalCache)
+	hooker.AddScopedSubscriber(hook.UserScope, principalCacheSubscriber)
+	hooker.AddScopedSubscriber(hook.UserOrgScope, principalCacheSubscriber)
+	hooker.AddScopedSubscriber(hook.PlatformRoleScope, principalCacheSubscriber)
+}
+
 func GetCoreTemplates() fs.FS {
 	return coreTemplates.CoreTemplates
 }
@@ -14,6 +14,7 @@ import (
 	_ "github.com/dzungtran/echo-rest-api/docs"
 	"github.com/dzungtran/echo-rest-api/infrastructure/datastore"
 	"github.com/dzungtran/echo-rest-api/migrations"
+	"github.com/dzungtran/echo-rest-api/pkg/authz"
 	"github.com/dzungtran/echo-rest-api/pkg/logger"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -45,7 +46,12 @@ func (t *TemplateRenderer) Render(w io.Writer, name string, data interface{}, c
 // @securityDefinitions.apikey  XUserEmailAuth
 // @in                          header
 // @name                        X-User-Email
-// @description					This method just enabled for local development
+// @description					This method just enabled for local development with AUTH_PROVIDER=dev-header
+
+// @securityDefinitions.apikey  XApiKey
+// @in                          header
+// @name                        X-Api-Key
+// @description					Shared key for 3rd-party web hooks
 
 // @securityDefinitions.apikey  XFirebaseBearer
 // @in                          header
@@ -53,7 +59,10 @@ func (t *TemplateRenderer) Render(w io.Writer, name string, data interface{}, c
 // @description					Enter the token with the `Bearer ` prefix, e.g. `Bearer jwt_token_string`.
 func main() {
 	// init app config
-	conf, _ := config.InitAppConfig()
+	conf, err := config.InitAppConfig()
+	if err != nil {
+		logger.Log().Fatal(err)
+	}
 
 	logger.InitWithOptions(logger.WithConfigLevel(conf.LogLevel))
 	if logger.Log() != nil {
@@ -74,6 +83,10 @@ func main() {
 	e.Use(middleware.RequestID())
 	e.HideBanner = true
 	e.Validator = conf.Validator
+	e.IPExtractor = config.GetIPExtractor(conf)
+	if len(conf.TrustedProxies) == 0 {
+		logger.Log().Infow("TRUSTED_PROXIES is not set, the client IP is the address of the connection and X-Forwarded-For is ignored")
+	}
 
 	// Setup infra
 	mDBInstance := datastore.NewMasterDbInstance(conf.DatabaseURL)
@@ -96,19 +109,52 @@ func main() {
 		conf,
 	)
 
+	// Replace the embedded policies, reloaded when they change
+	if conf.PolicyPath != "" {
+		policyCtx, stopPolicies := context.WithCancel(context.Background())
+		defer stopPolicies()
+		err = container.Invoke(func(embedded *authz.OPAAuthorizer) {
+			embedded.WatchPolicies(policyCtx, conf.PolicyPath, conf.PolicyReloadInterval)
+		})
+		if err != nil {
+			logger.Log().Fatal(err)
+		}
+	}
+
 	// Routes
 	e.GET("/", func(c echo.Context) error {
 		return c.JSON(http.StatusOK, map[string]interface{}{
 			"message": "Hello there!",
 		})
-	})
+	}).Name = "public:index"
 
-	err := di.RegisterModules(e, container)
+	err = di.RegisterModules(e, container)
 	if err != nil {
 		e.Logger.Fatal(err)
 	}
 
-	e.GET("/docs/*", echoSwagger.WrapHandler)
+	e.GET("/docs/*", echoSwagger.WrapHandler).Name = "public:docs"
+
+	// Check the registered routes instead of routes.json, which can be stale
+	var drift *authz.ACLDrift
+	err = container.Invoke(func(policyAdmin authz.PolicyAdmin) (err error) {
+		drift, err = policyAdmin.SetEndpointsACL(context.Background(), e.Routes())
+		return
+	})
+	if err != nil {
+		logger.Log().Fatalf("cannot compile policies with the routes: %v", err)
+	}
+	if !drift.Empty() {
+		logger.Log().Warnw("routes and roles of the policies drift",
+			"unnamed_routes", drift.UnnamedRoutes,
+			"ungranted_permissions", drift.UngrantedPermissions,
+			"unknown_role_permissions", drift.UnknownRolePermissions,
+			"remote_acl_mismatches", drift.RemoteACLMismatches,
+		)
+		if conf.PolicyStrictACL {
+			logger.Log().Fatal("POLICY_STRICT_ACL is set, refusing to start")
+		}
+	}
 
 	// Start server
 	go func() {
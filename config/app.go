@@ -3,9 +3,15 @@ package config
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	firebase "firebase.google.com/go/v4"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
 	"github.com/go-playground/validator/v10"
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
@@ -29,11 +35,76 @@ type AppConfig struct {
 	AutoMigrate bool   `json:"auto_migrate"`
 	LogLevel    string `json:"log_level"`
 
-	AuthProvider        string `json:"auth_provider"`
-	FirebaseCreds       string `json:"firebase_creds"`
-	FirebaseAuthCreds   string `json:"firebase_auth_creds"`
-	KratosWebhookApiKey string `json:"kratos_webhook_api_key"`
-	KratosApiEndpoint   string `json:"kratos_api_endpoint"`
+	AuthProvider        string   `json:"auth_provider"`
+	AuthProviders       []string `json:"auth_providers"` // ordered authenticators parsed from AuthProvider
+	FirebaseCreds       string   `json:"firebase_creds"`
+	FirebaseAuthCreds   string   `json:"firebase_auth_creds"`
+	KratosWebhookApiKey string   `json:"kratos_webhook_api_key"`
+	KratosApiEndpoint   string   `json:"kratos_api_endpoint"`
+	DevHeaderAutoCreate bool     `json:"dev_header_auto_create"`
+
+	OidcIssuer         string        `json:"oidc_issuer"`
+	OidcAudience       string        `json:"oidc_audience"`
+	OidcJwksURL        string        `json:"oidc_jwks_url"`
+	OidcJwksFile       string        `json:"oidc_jwks_file"`
+	OidcJwksCacheTTL   time.Duration `json:"oidc_jwks_cache_ttl"`
+	OidcClaimCode      string        `json:"oidc_claim_code"`
+	OidcClaimEmail     string        `json:"oidc_claim_email"`
+	OidcClaimFirstName string        `json:"oidc_claim_first_name"`
+	OidcClaimLastName  string        `json:"oidc_claim_last_name"`
+
+	ServiceAccountTokenSecret string        `json:"-"`
+	ServiceAccountTokenTTL    time.Duration `json:"service_account_token_ttl"`
+
+	LocalAuthIssuer          string        `json:"local_auth_issuer"`
+	LocalAuthSigningKeysFile string        `json:"local_auth_signing_keys_file"`
+	LocalAuthSigningKeyId    string        `json:"local_auth_signing_key_id"`
+	LocalAuthAccessTokenTTL  time.Duration `json:"local_auth_access_token_ttl"`
+	LocalAuthRefreshTokenTTL time.Duration `json:"local_auth_refresh_token_ttl"`
+
+	// SmtpHost is the server sending the emails, they are only logged when it is empty
+	SmtpHost     string        `json:"smtp_host"`
+	SmtpPort     int           `json:"smtp_port"`
+	SmtpUsername string        `json:"smtp_username"`
+	SmtpPassword string        `json:"-"`
+	SmtpFrom     string        `json:"smtp_from"`
+	SmtpTimeout  time.Duration `json:"smtp_timeout"`
+
+	PrincipalCacheTTL  time.Duration `json:"principal_cache_ttl"`
+	PrincipalCacheSize int           `json:"principal_cache_size"`
+
+	MfaIssuer    string        `json:"mfa_issuer"`
+	MfaStepUpTTL time.Duration `json:"mfa_step_up_ttl"`
+
+	ImpersonationTTL         time.Duration `json:"impersonation_ttl"`
+	ImpersonationAllowWrites bool          `json:"impersonation_allow_writes"`
+
+	// PlatformBootstrapSuperAdmin is the email of the user made super admin on start, while there is none
+	PlatformBootstrapSuperAdmin string `json:"platform_bootstrap_super_admin"`
+
+	// TrustedProxies are the CIDRs X-Forwarded-For is read from, the peer address is the client IP otherwise
+	TrustedProxies []string `json:"trusted_proxies"`
+
+	// PolicyPath is a directory or an OPA bundle tarball replacing the embedded policies
+	PolicyPath           string        `json:"policy_path"`
+	PolicyReloadInterval time.Duration `json:"policy_reload_interval"`
+	// PolicyStrictACL refuses to start while the routes and the roles of the policies drift
+	PolicyStrictACL bool `json:"policy_strict_acl"`
+
+	// PolicyRemoteUrl is an OPA server deciding instead of the embedded policies, e.g. http://opa:8181
+	PolicyRemoteUrl     string        `json:"policy_remote_url"`
+	PolicyRemoteTimeout time.Duration `json:"policy_remote_timeout"`
+	PolicyRemoteRetries int           `json:"policy_remote_retries"`
+	// PolicyRemoteBackoff is the longest wait before the first retry, doubled on every retry
+	PolicyRemoteBackoff time.Duration `json:"policy_remote_backoff"`
+	// PolicyRemoteFallback is closed or embedded, what decides while the OPA server is unavailable
+	PolicyRemoteFallback string `json:"policy_remote_fallback"`
+
+	// DecisionLogSink is log, db or file, the decisions are not recorded when empty
+	DecisionLogSink         string   `json:"decision_log_sink"`
+	DecisionLogFile         string   `json:"decision_log_file"`
+	DecisionLogSampleRate   float64  `json:"decision_log_sample_rate"`
+	DecisionLogRedactFields []string `json:"decision_log_redact_fields"`
 }
 
 type AppValidator struct {
@@ -50,7 +121,7 @@ func (cv *AppValidator) Validate(i interface{}) error {
 func InitAppConfig() (*AppConfig, error) {
 	var fbApp *firebase.App
 	var err error
-	currentEnv := "development"
+	currentEnv := constants.EnvironmentDevelopment
 
 	// load env file if exists
 	_, err = os.Stat(".env")
@@ -78,6 +149,24 @@ func InitAppConfig() (*AppConfig, error) {
 		}
 	}
 
+	authProviders := parseAuthProviders(os.Getenv("AUTH_PROVIDER"))
+	if currentEnv == constants.EnvironmentProduction && utils.IsSliceContains(authProviders, constants.AuthProviderDevHeader) {
+		return nil, fmt.Errorf("error initializing app: %s auth provider is not allowed in production", constants.AuthProviderDevHeader)
+	}
+
+	// without them any token signed by a key of the JWKS is accepted, including tokens minted for other clients
+	if utils.IsSliceContains(authProviders, constants.AuthProviderOidc) &&
+		(os.Getenv("OIDC_ISSUER") == "" || os.Getenv("OIDC_AUDIENCE") == "") {
+		return nil, fmt.Errorf("error initializing app: OIDC_ISSUER and OIDC_AUDIENCE are required by the %s auth provider", constants.AuthProviderOidc)
+	}
+
+	trustedProxies := splitEnvList(os.Getenv("TRUSTED_PROXIES"))
+	for _, cidr := range trustedProxies {
+		if _, _, err = net.ParseCIDR(cidr); err != nil {
+			return nil, fmt.Errorf("error initializing app: invalid TRUSTED_PROXIES: %v", err)
+		}
+	}
+
 	return &AppConfig{
 		Environment: currentEnv,
 		AppPort:     appPort,
@@ -92,10 +181,125 @@ func InitAppConfig() (*AppConfig, error) {
 		LogLevel:    os.Getenv("LOG_LEVEL"),
 
 		AuthProvider:        os.Getenv("AUTH_PROVIDER"),
+		AuthProviders:       authProviders,
 		KratosWebhookApiKey: os.Getenv("KRATOS_WEBHOOK_API_KEY"),
 		KratosApiEndpoint:   os.Getenv("KRATOS_API_ENDPOINT"),
+		DevHeaderAutoCreate: os.Getenv("DEV_HEADER_AUTO_CREATE") == "true",
 		FirebaseApp:         fbApp,
 		FirebaseCreds:       os.Getenv("FIREBASE_CREDENTIALS"),
 		FirebaseAuthCreds:   os.Getenv("FIREBASE_AUTH_CREDENTIALS"),
+
+		OidcIssuer:         os.Getenv("OIDC_ISSUER"),
+		OidcAudience:       os.Getenv("OIDC_AUDIENCE"),
+		OidcJwksURL:        os.Getenv("OIDC_JWKS_URL"),
+		OidcJwksFile:       os.Getenv("OIDC_JWKS_FILE"),
+		OidcJwksCacheTTL:   getEnvDuration("OIDC_JWKS_CACHE_TTL", time.Hour),
+		OidcClaimCode:      getEnvWithDefault("OIDC_CLAIM_CODE", "sub"),
+		OidcClaimEmail:     getEnvWithDefault("OIDC_CLAIM_EMAIL", "email"),
+		OidcClaimFirstName: getEnvWithDefault("OIDC_CLAIM_FIRST_NAME", "given_name"),
+		OidcClaimLastName:  getEnvWithDefault("OIDC_CLAIM_LAST_NAME", "family_name"),
+
+		ServiceAccountTokenSecret: os.Getenv("SERVICE_ACCOUNT_TOKEN_SECRET"),
+		ServiceAccountTokenTTL:    getEnvDuration("SERVICE_ACCOUNT_TOKEN_TTL", 15*time.Minute),
+
+		LocalAuthIssuer:          getEnvWithDefault("LOCAL_AUTH_ISSUER", "urn:echo-rest-api:local"),
+		LocalAuthSigningKeysFile: os.Getenv("LOCAL_AUTH_SIGNING_KEYS_FILE"),
+		LocalAuthSigningKeyId:    os.Getenv("LOCAL_AUTH_SIGNING_KEY_ID"),
+		LocalAuthAccessTokenTTL:  getEnvDuration("LOCAL_AUTH_ACCESS_TOKEN_TTL", 15*time.Minute),
+		LocalAuthRefreshTokenTTL: getEnvDuration("LOCAL_AUTH_REFRESH_TOKEN_TTL", 30*24*time.Hour),
+
+		SmtpHost:     os.Getenv("SMTP_HOST"),
+		SmtpPort:     getEnvInt("SMTP_PORT", 587),
+		SmtpUsername: os.Getenv("SMTP_USERNAME"),
+		SmtpPassword: os.Getenv("SMTP_PASSWORD"),
+		SmtpFrom:     os.Getenv("SMTP_FROM"),
+		SmtpTimeout:  getEnvDuration("SMTP_TIMEOUT", 10*time.Second),
+
+		PrincipalCacheTTL:  getEnvDuration("PRINCIPAL_CACHE_TTL", 30*time.Second),
+		PrincipalCacheSize: getEnvInt("PRINCIPAL_CACHE_SIZE", 10000),
+
+		MfaIssuer:    getEnvWithDefault("MFA_ISSUER", "echo-rest-api"),
+		MfaStepUpTTL: getEnvDuration("MFA_STEP_UP_TTL", 12*time.Hour),
+
+		ImpersonationTTL:         getEnvDuration("IMPERSONATION_TTL", time.Hour),
+		ImpersonationAllowWrites: os.Getenv("IMPERSONATION_ALLOW_WRITES") == "true",
+
+		PlatformBootstrapSuperAdmin: os.Getenv("PLATFORM_BOOTSTRAP_SUPER_ADMIN"),
+
+		TrustedProxies: trustedProxies,
+
+		PolicyPath:           os.Getenv("POLICY_PATH"),
+		PolicyReloadInterval: getEnvDuration("POLICY_RELOAD_INTERVAL", 10*time.Second),
+		PolicyStrictACL:      os.Getenv("POLICY_STRICT_ACL") == "true",
+
+		PolicyRemoteUrl:      os.Getenv("POLICY_REMOTE_URL"),
+		PolicyRemoteTimeout:  getEnvDuration("POLICY_REMOTE_TIMEOUT", 500*time.Millisecond),
+		PolicyRemoteRetries:  getEnvInt("POLICY_REMOTE_RETRIES", 1),
+		PolicyRemoteBackoff:  getEnvDuration("POLICY_REMOTE_BACKOFF", 50*time.Millisecond),
+		PolicyRemoteFallback: getEnvWithDefault("POLICY_REMOTE_FALLBACK", "closed"),
+
+		DecisionLogSink:         os.Getenv("DECISION_LOG_SINK"),
+		DecisionLogFile:         os.Getenv("DECISION_LOG_FILE"),
+		DecisionLogSampleRate:   getEnvFloat("DECISION_LOG_SAMPLE_RATE", 1),
+		DecisionLogRedactFields: splitEnvList(os.Getenv("DECISION_LOG_REDACT_FIELDS")),
 	}, nil
 }
+
+// splitEnvList splits a comma separated list, empty items are skipped
+func splitEnvList(val string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(val, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseAuthProviders splits a comma separated provider list, firebase is the default provider
+func parseAuthProviders(val string) []string {
+	providers := make([]string, 0)
+	for _, p := range strings.Split(val, ",") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			providers = append(providers, p)
+		}
+	}
+
+	if len(providers) == 0 {
+		providers = append(providers, constants.AuthProviderFirebase)
+	}
+	return providers
+}
+
+func getEnvWithDefault(key, defaultVal string) string {
+	if val, ok := os.LookupEnv(key); ok {
+		return val
+	}
+	return defaultVal
+}
+
+func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return defaultVal
+	}
+	return d
+}
+
+func getEnvFloat(key string, defaultVal float64) float64 {
+	f, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultVal
+	}
+	return f
+}
+
+func getEnvInt(key string, defaultVal int) int {
+	i, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultVal
+	}
+	return i
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"net"
 	"time"
 
+	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
@@ -12,3 +14,24 @@ func GetEchoLogConfig(appConf *AppConfig) middleware.LoggerConfig {
 	// echoLogConf.Format = fmt.Sprintln(`{"level":"info","source":"echo","id":"${id}","mt":"${method}","uri":"${uri}","st":${status},"e":"${error}","lc":"${latency_human}","ts":"${time_custom}"}`)
 	return echoLogConf
 }
+
+// GetIPExtractor reads the client IP from X-Forwarded-For behind the trusted proxies only,
+// the headers of a request not coming through them are ignored. Without TRUSTED_PROXIES the address
+// of the connection is used, deployments behind a proxy have to set them (a breaking change, see README)
+func GetIPExtractor(appConf *AppConfig) echo.IPExtractor {
+	if len(appConf.TrustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+
+	opts := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, cidr := range appConf.TrustedProxies {
+		if _, ipNet, err := net.ParseCIDR(cidr); err == nil {
+			opts = append(opts, echo.TrustIPRange(ipNet))
+		}
+	}
+	return echo.ExtractIPFromXFFHeader(opts...)
+}
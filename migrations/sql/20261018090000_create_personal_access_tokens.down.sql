@@ -0,0 +1,4 @@
+ALTER TABLE IF EXISTS ONLY personal_access_tokens DROP CONSTRAINT IF EXISTS personal_access_tokens_user_id_fkey;
+DROP INDEX IF EXISTS personal_access_tokens_user_id_idx;
+DROP INDEX IF EXISTS personal_access_tokens_token_hash_key;
+DROP TABLE IF EXISTS personal_access_tokens;
@@ -0,0 +1,24 @@
+CREATE TABLE personal_access_tokens (
+    id serial NOT NULL,
+    user_id integer NOT NULL,
+    name character varying(100) NOT NULL,
+    token_hash character varying(64) NOT NULL,
+    token_prefix character varying(20) NOT NULL,
+    permissions text[] DEFAULT '{}'::text[] NOT NULL,
+    org_ids integer[] DEFAULT '{}'::integer[] NOT NULL,
+    expires_at timestamp without time zone,
+    last_used_at timestamp without time zone,
+    revoked_at timestamp without time zone,
+    created_at timestamp without time zone,
+    updated_at timestamp without time zone
+);
+
+ALTER TABLE ONLY personal_access_tokens
+    ADD CONSTRAINT personal_access_tokens_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX personal_access_tokens_token_hash_key ON personal_access_tokens USING btree (token_hash);
+
+CREATE INDEX personal_access_tokens_user_id_idx ON personal_access_tokens USING btree (user_id);
+
+ALTER TABLE ONLY personal_access_tokens
+    ADD CONSTRAINT personal_access_tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
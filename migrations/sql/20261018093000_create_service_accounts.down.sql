@@ -0,0 +1,4 @@
+ALTER TABLE IF EXISTS ONLY service_accounts DROP CONSTRAINT IF EXISTS service_accounts_org_id_fkey;
+DROP INDEX IF EXISTS service_accounts_org_id_idx;
+DROP INDEX IF EXISTS service_accounts_client_id_key;
+DROP TABLE IF EXISTS service_accounts;
@@ -0,0 +1,22 @@
+CREATE TABLE service_accounts (
+    id serial NOT NULL,
+    org_id integer NOT NULL,
+    name character varying(100) NOT NULL,
+    client_id character varying(50) NOT NULL,
+    client_secret_hash character varying(64) NOT NULL,
+    role character varying(50) NOT NULL,
+    status character varying(30) DEFAULT 'active'::character varying NOT NULL,
+    last_used_at timestamp without time zone,
+    created_at timestamp without time zone,
+    updated_at timestamp without time zone
+);
+
+ALTER TABLE ONLY service_accounts
+    ADD CONSTRAINT service_accounts_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX service_accounts_client_id_key ON service_accounts USING btree (client_id);
+
+CREATE INDEX service_accounts_org_id_idx ON service_accounts USING btree (org_id);
+
+ALTER TABLE ONLY service_accounts
+    ADD CONSTRAINT service_accounts_org_id_fkey FOREIGN KEY (org_id) REFERENCES orgs(id) ON DELETE CASCADE;
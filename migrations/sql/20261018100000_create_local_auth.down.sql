@@ -0,0 +1,11 @@
+ALTER TABLE IF EXISTS ONLY refresh_tokens DROP CONSTRAINT IF EXISTS refresh_tokens_user_id_fkey;
+DROP INDEX IF EXISTS refresh_tokens_family_id_idx;
+DROP INDEX IF EXISTS refresh_tokens_token_hash_key;
+DROP TABLE IF EXISTS refresh_tokens;
+
+ALTER TABLE IF EXISTS ONLY local_auth_tokens DROP CONSTRAINT IF EXISTS local_auth_tokens_user_id_fkey;
+DROP INDEX IF EXISTS local_auth_tokens_token_hash_key;
+DROP TABLE IF EXISTS local_auth_tokens;
+
+ALTER TABLE IF EXISTS ONLY local_credentials DROP CONSTRAINT IF EXISTS local_credentials_user_id_fkey;
+DROP TABLE IF EXISTS local_credentials;
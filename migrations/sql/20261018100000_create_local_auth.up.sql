@@ -0,0 +1,54 @@
+CREATE TABLE local_credentials (
+    user_id integer NOT NULL,
+    password_hash character varying(100) NOT NULL,
+    email_verified_at timestamp without time zone,
+    created_at timestamp without time zone,
+    updated_at timestamp without time zone
+);
+
+ALTER TABLE ONLY local_credentials
+    ADD CONSTRAINT local_credentials_pkey PRIMARY KEY (user_id);
+
+ALTER TABLE ONLY local_credentials
+    ADD CONSTRAINT local_credentials_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE TABLE local_auth_tokens (
+    id serial NOT NULL,
+    user_id integer NOT NULL,
+    purpose character varying(30) NOT NULL,
+    token_hash character varying(64) NOT NULL,
+    expires_at timestamp without time zone NOT NULL,
+    used_at timestamp without time zone,
+    created_at timestamp without time zone,
+    updated_at timestamp without time zone
+);
+
+ALTER TABLE ONLY local_auth_tokens
+    ADD CONSTRAINT local_auth_tokens_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX local_auth_tokens_token_hash_key ON local_auth_tokens USING btree (token_hash);
+
+ALTER TABLE ONLY local_auth_tokens
+    ADD CONSTRAINT local_auth_tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE TABLE refresh_tokens (
+    id serial NOT NULL,
+    user_id integer NOT NULL,
+    family_id character varying(50) NOT NULL,
+    token_hash character varying(64) NOT NULL,
+    expires_at timestamp without time zone NOT NULL,
+    rotated_at timestamp without time zone,
+    revoked_at timestamp without time zone,
+    created_at timestamp without time zone,
+    updated_at timestamp without time zone
+);
+
+ALTER TABLE ONLY refresh_tokens
+    ADD CONSTRAINT refresh_tokens_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX refresh_tokens_token_hash_key ON refresh_tokens USING btree (token_hash);
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens USING btree (family_id);
+
+ALTER TABLE ONLY refresh_tokens
+    ADD CONSTRAINT refresh_tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
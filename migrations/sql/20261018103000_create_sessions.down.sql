@@ -0,0 +1,13 @@
+DELETE FROM refresh_tokens;
+
+ALTER TABLE IF EXISTS ONLY refresh_tokens DROP CONSTRAINT IF EXISTS refresh_tokens_session_id_fkey;
+DROP INDEX IF EXISTS refresh_tokens_session_id_idx;
+ALTER TABLE IF EXISTS ONLY refresh_tokens DROP COLUMN IF EXISTS session_id;
+ALTER TABLE IF EXISTS ONLY refresh_tokens ADD COLUMN family_id character varying(50) NOT NULL;
+ALTER TABLE IF EXISTS ONLY refresh_tokens ADD COLUMN revoked_at timestamp without time zone;
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens USING btree (family_id);
+
+ALTER TABLE IF EXISTS ONLY sessions DROP CONSTRAINT IF EXISTS sessions_user_id_fkey;
+DROP INDEX IF EXISTS sessions_user_id_idx;
+DROP TABLE IF EXISTS sessions;
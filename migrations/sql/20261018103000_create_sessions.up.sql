@@ -0,0 +1,34 @@
+CREATE TABLE sessions (
+    id serial NOT NULL,
+    user_id integer NOT NULL,
+    auth_method character varying(30) NOT NULL,
+    device character varying(100) DEFAULT ''::character varying NOT NULL,
+    ip_address character varying(45) DEFAULT ''::character varying NOT NULL,
+    user_agent character varying(255) DEFAULT ''::character varying NOT NULL,
+    last_seen_at timestamp without time zone NOT NULL,
+    expires_at timestamp without time zone NOT NULL,
+    revoked_at timestamp without time zone,
+    created_at timestamp without time zone,
+    updated_at timestamp without time zone
+);
+
+ALTER TABLE ONLY sessions
+    ADD CONSTRAINT sessions_pkey PRIMARY KEY (id);
+
+CREATE INDEX sessions_user_id_idx ON sessions USING btree (user_id);
+
+ALTER TABLE ONLY sessions
+    ADD CONSTRAINT sessions_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+-- Refresh token families are replaced by sessions, tokens issued before cannot be attached to a session
+DELETE FROM refresh_tokens;
+
+DROP INDEX IF EXISTS refresh_tokens_family_id_idx;
+ALTER TABLE ONLY refresh_tokens DROP COLUMN family_id;
+ALTER TABLE ONLY refresh_tokens DROP COLUMN revoked_at;
+ALTER TABLE ONLY refresh_tokens ADD COLUMN session_id integer NOT NULL;
+
+CREATE INDEX refresh_tokens_session_id_idx ON refresh_tokens USING btree (session_id);
+
+ALTER TABLE ONLY refresh_tokens
+    ADD CONSTRAINT refresh_tokens_session_id_fkey FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE;
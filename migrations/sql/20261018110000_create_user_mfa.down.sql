@@ -0,0 +1,10 @@
+ALTER TABLE IF EXISTS ONLY orgs DROP COLUMN IF EXISTS require_mfa;
+
+ALTER TABLE IF EXISTS ONLY sessions DROP COLUMN IF EXISTS mfa_verified_at;
+
+ALTER TABLE IF EXISTS ONLY mfa_recovery_codes DROP CONSTRAINT IF EXISTS mfa_recovery_codes_user_id_fkey;
+DROP INDEX IF EXISTS mfa_recovery_codes_user_id_code_hash_key;
+DROP TABLE IF EXISTS mfa_recovery_codes;
+
+ALTER TABLE IF EXISTS ONLY user_mfa DROP CONSTRAINT IF EXISTS user_mfa_user_id_fkey;
+DROP TABLE IF EXISTS user_mfa;
@@ -0,0 +1,35 @@
+CREATE TABLE user_mfa (
+    user_id integer NOT NULL,
+    secret character varying(64) NOT NULL,
+    last_used_step bigint DEFAULT 0 NOT NULL,
+    confirmed_at timestamp without time zone,
+    created_at timestamp without time zone,
+    updated_at timestamp without time zone
+);
+
+ALTER TABLE ONLY user_mfa
+    ADD CONSTRAINT user_mfa_pkey PRIMARY KEY (user_id);
+
+ALTER TABLE ONLY user_mfa
+    ADD CONSTRAINT user_mfa_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE TABLE mfa_recovery_codes (
+    id serial NOT NULL,
+    user_id integer NOT NULL,
+    code_hash character varying(64) NOT NULL,
+    used_at timestamp without time zone,
+    created_at timestamp without time zone,
+    updated_at timestamp without time zone
+);
+
+ALTER TABLE ONLY mfa_recovery_codes
+    ADD CONSTRAINT mfa_recovery_codes_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX mfa_recovery_codes_user_id_code_hash_key ON mfa_recovery_codes USING btree (user_id, code_hash);
+
+ALTER TABLE ONLY mfa_recovery_codes
+    ADD CONSTRAINT mfa_recovery_codes_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE ONLY sessions ADD COLUMN mfa_verified_at timestamp without time zone;
+
+ALTER TABLE ONLY orgs ADD COLUMN require_mfa boolean DEFAULT false NOT NULL;
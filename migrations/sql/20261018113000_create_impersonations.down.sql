@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS impersonation_audit_logs_target_user_id_idx;
+DROP INDEX IF EXISTS impersonation_audit_logs_actor_user_id_idx;
+DROP TABLE IF EXISTS impersonation_audit_logs;
+
+ALTER TABLE IF EXISTS ONLY impersonations DROP CONSTRAINT IF EXISTS impersonations_target_user_id_fkey;
+ALTER TABLE IF EXISTS ONLY impersonations DROP CONSTRAINT IF EXISTS impersonations_actor_user_id_fkey;
+DROP INDEX IF EXISTS impersonations_actor_user_id_idx;
+DROP TABLE IF EXISTS impersonations;
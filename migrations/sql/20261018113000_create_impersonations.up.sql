@@ -0,0 +1,44 @@
+CREATE TABLE impersonations (
+    id serial NOT NULL,
+    actor_user_id integer NOT NULL,
+    target_user_id integer NOT NULL,
+    reason character varying(255) NOT NULL,
+    allow_writes boolean DEFAULT false NOT NULL,
+    expires_at timestamp without time zone NOT NULL,
+    ended_at timestamp without time zone,
+    created_at timestamp without time zone,
+    updated_at timestamp without time zone
+);
+
+ALTER TABLE ONLY impersonations
+    ADD CONSTRAINT impersonations_pkey PRIMARY KEY (id);
+
+CREATE INDEX impersonations_actor_user_id_idx ON impersonations USING btree (actor_user_id);
+
+ALTER TABLE ONLY impersonations
+    ADD CONSTRAINT impersonations_actor_user_id_fkey FOREIGN KEY (actor_user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE ONLY impersonations
+    ADD CONSTRAINT impersonations_target_user_id_fkey FOREIGN KEY (target_user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+-- Audit logs outlive the users, no foreign keys
+CREATE TABLE impersonation_audit_logs (
+    id bigserial NOT NULL,
+    impersonation_id integer,
+    actor_user_id integer NOT NULL,
+    target_user_id integer NOT NULL,
+    method character varying(10) NOT NULL,
+    endpoint character varying(255) NOT NULL,
+    uri character varying(2048) NOT NULL,
+    status integer NOT NULL,
+    ip_address character varying(45) DEFAULT ''::character varying NOT NULL,
+    request_id character varying(100) DEFAULT ''::character varying NOT NULL,
+    created_at timestamp without time zone
+);
+
+ALTER TABLE ONLY impersonation_audit_logs
+    ADD CONSTRAINT impersonation_audit_logs_pkey PRIMARY KEY (id);
+
+CREATE INDEX impersonation_audit_logs_actor_user_id_idx ON impersonation_audit_logs USING btree (actor_user_id);
+
+CREATE INDEX impersonation_audit_logs_target_user_id_idx ON impersonation_audit_logs USING btree (target_user_id);
@@ -0,0 +1,4 @@
+ALTER TABLE IF EXISTS ONLY platform_roles DROP CONSTRAINT IF EXISTS platform_roles_granted_by_fkey;
+ALTER TABLE IF EXISTS ONLY platform_roles DROP CONSTRAINT IF EXISTS platform_roles_user_id_fkey;
+DROP INDEX IF EXISTS platform_roles_role_idx;
+DROP TABLE IF EXISTS platform_roles;
@@ -0,0 +1,21 @@
+CREATE TABLE platform_roles (
+    id serial NOT NULL,
+    user_id integer NOT NULL,
+    role character varying(50) NOT NULL,
+    granted_by integer,
+    created_at timestamp without time zone
+);
+
+ALTER TABLE ONLY platform_roles
+    ADD CONSTRAINT platform_roles_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY platform_roles
+    ADD CONSTRAINT platform_roles_user_id_role_key UNIQUE (user_id, role);
+
+CREATE INDEX platform_roles_role_idx ON platform_roles USING btree (role);
+
+ALTER TABLE ONLY platform_roles
+    ADD CONSTRAINT platform_roles_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE ONLY platform_roles
+    ADD CONSTRAINT platform_roles_granted_by_fkey FOREIGN KEY (granted_by) REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE IF EXISTS ONLY org_roles DROP CONSTRAINT IF EXISTS org_roles_created_by_fkey;
+ALTER TABLE IF EXISTS ONLY org_roles DROP CONSTRAINT IF EXISTS org_roles_org_id_fkey;
+DROP TABLE IF EXISTS org_roles;
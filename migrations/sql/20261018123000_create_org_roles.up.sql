@@ -0,0 +1,23 @@
+CREATE TABLE org_roles (
+    id serial NOT NULL,
+    org_id integer NOT NULL,
+    name character varying(50) NOT NULL,
+    description character varying(255) DEFAULT ''::character varying NOT NULL,
+    permissions text[] DEFAULT '{}'::text[] NOT NULL,
+    parent character varying(50) DEFAULT ''::character varying NOT NULL,
+    created_by integer,
+    created_at timestamp without time zone,
+    updated_at timestamp without time zone
+);
+
+ALTER TABLE ONLY org_roles
+    ADD CONSTRAINT org_roles_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY org_roles
+    ADD CONSTRAINT org_roles_org_id_name_key UNIQUE (org_id, name);
+
+ALTER TABLE ONLY org_roles
+    ADD CONSTRAINT org_roles_org_id_fkey FOREIGN KEY (org_id) REFERENCES orgs(id) ON DELETE CASCADE;
+
+ALTER TABLE ONLY org_roles
+    ADD CONSTRAINT org_roles_created_by_fkey FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS authz_decisions_request_id_idx;
+DROP INDEX IF EXISTS authz_decisions_user_id_created_at_idx;
+DROP TABLE IF EXISTS authz_decisions;
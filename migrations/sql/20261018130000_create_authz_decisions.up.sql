@@ -0,0 +1,22 @@
+CREATE TABLE authz_decisions (
+    id bigserial NOT NULL,
+    request_id character varying(100) DEFAULT ''::character varying NOT NULL,
+    user_id integer DEFAULT 0 NOT NULL,
+    org_id integer,
+    endpoint character varying(255) DEFAULT ''::character varying NOT NULL,
+    method character varying(10) DEFAULT ''::character varying NOT NULL,
+    permission character varying(100) DEFAULT ''::character varying NOT NULL,
+    allowed boolean NOT NULL,
+    deny_messages text[] DEFAULT '{}'::text[] NOT NULL,
+    error text DEFAULT ''::text NOT NULL,
+    latency_us bigint DEFAULT 0 NOT NULL,
+    policy_revision character varying(100) DEFAULT ''::character varying NOT NULL,
+    created_at timestamp without time zone
+);
+
+ALTER TABLE ONLY authz_decisions
+    ADD CONSTRAINT authz_decisions_pkey PRIMARY KEY (id);
+
+CREATE INDEX authz_decisions_user_id_created_at_idx ON authz_decisions USING btree (user_id, created_at);
+
+CREATE INDEX authz_decisions_request_id_idx ON authz_decisions USING btree (request_id);
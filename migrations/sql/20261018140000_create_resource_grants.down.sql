@@ -0,0 +1,5 @@
+ALTER TABLE IF EXISTS ONLY resource_grants DROP CONSTRAINT IF EXISTS resource_grants_created_by_fkey;
+ALTER TABLE IF EXISTS ONLY resource_grants DROP CONSTRAINT IF EXISTS resource_grants_user_id_fkey;
+ALTER TABLE IF EXISTS ONLY resource_grants DROP CONSTRAINT IF EXISTS resource_grants_org_id_fkey;
+DROP INDEX IF EXISTS resource_grants_resource_type_resource_id_idx;
+DROP TABLE IF EXISTS resource_grants;
@@ -0,0 +1,28 @@
+CREATE TABLE resource_grants (
+    id serial NOT NULL,
+    org_id integer NOT NULL,
+    user_id integer NOT NULL,
+    permission character varying(100) NOT NULL,
+    resource_type character varying(50) NOT NULL,
+    resource_id integer NOT NULL,
+    created_by integer,
+    created_at timestamp without time zone,
+    updated_at timestamp without time zone
+);
+
+ALTER TABLE ONLY resource_grants
+    ADD CONSTRAINT resource_grants_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY resource_grants
+    ADD CONSTRAINT resource_grants_user_id_permission_resource_key UNIQUE (user_id, permission, resource_type, resource_id);
+
+CREATE INDEX resource_grants_resource_type_resource_id_idx ON resource_grants USING btree (resource_type, resource_id);
+
+ALTER TABLE ONLY resource_grants
+    ADD CONSTRAINT resource_grants_org_id_fkey FOREIGN KEY (org_id) REFERENCES orgs(id) ON DELETE CASCADE;
+
+ALTER TABLE ONLY resource_grants
+    ADD CONSTRAINT resource_grants_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE ONLY resource_grants
+    ADD CONSTRAINT resource_grants_created_by_fkey FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE IF EXISTS ONLY orgs DROP COLUMN IF EXISTS access_hours_end;
+ALTER TABLE IF EXISTS ONLY orgs DROP COLUMN IF EXISTS access_hours_start;
+ALTER TABLE IF EXISTS ONLY orgs DROP COLUMN IF EXISTS ip_allowlist;
+ALTER TABLE IF EXISTS ONLY orgs DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,4 @@
+ALTER TABLE ONLY orgs ADD COLUMN timezone text DEFAULT 'UTC' NOT NULL;
+ALTER TABLE ONLY orgs ADD COLUMN ip_allowlist text[] DEFAULT '{}' NOT NULL;
+ALTER TABLE ONLY orgs ADD COLUMN access_hours_start text DEFAULT '' NOT NULL;
+ALTER TABLE ONLY orgs ADD COLUMN access_hours_end text DEFAULT '' NOT NULL;
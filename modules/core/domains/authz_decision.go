@@ -0,0 +1,28 @@
+package domains
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// AuthzDecision is the record of a policy evaluation, see pkg/authz/decision_log.go
+type AuthzDecision struct {
+	Id        int64  `json:"id" db:"id"`
+	RequestId string `json:"request_id" db:"request_id"`
+	UserId    int64  `json:"user_id" db:"user_id"`
+	// OrgId is the org the policies evaluated the request for, nil when none
+	OrgId    *int64 `json:"org_id" db:"org_id"`
+	Endpoint string `json:"endpoint" db:"endpoint" example:"/admin/orgs/:orgId"`
+	Method   string `json:"method" db:"method" example:"PUT"`
+	// Permission named by endpoints_acl for the endpoint and method
+	Permission   string         `json:"permission" db:"permission" example:"update:org"`
+	Allowed      bool           `json:"allowed" db:"allowed"`
+	DenyMessages pq.StringArray `json:"deny_messages" db:"deny_messages" swaggertype:"array,string"`
+	// Error is set when the policies could not be evaluated
+	Error string `json:"error,omitempty" db:"error"`
+	// LatencyUs is the evaluation time in microseconds
+	LatencyUs      int64     `json:"latency_us" db:"latency_us"`
+	PolicyRevision string    `json:"policy_revision" db:"policy_revision"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
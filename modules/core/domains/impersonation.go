@@ -0,0 +1,39 @@
+package domains
+
+import "time"
+
+// Impersonation lets a super admin use the API as another user until it ends or expires,
+// requests of the actor are served as the target meanwhile
+type Impersonation struct {
+	Id           int64  `json:"id" db:"id"`
+	ActorUserId  int64  `json:"actor_user_id" db:"actor_user_id"`
+	TargetUserId int64  `json:"target_user_id" db:"target_user_id"`
+	Reason       string `json:"reason" db:"reason"`
+	// AllowWrites lets mutating calls through when IMPERSONATION_ALLOW_WRITES is enabled
+	AllowWrites bool       `json:"allow_writes" db:"allow_writes"`
+	ExpiresAt   time.Time  `json:"expires_at" db:"expires_at"`
+	EndedAt     *time.Time `json:"ended_at" db:"ended_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// IsActive tells whether the impersonation is neither ended nor expired
+func (i Impersonation) IsActive(now time.Time) bool {
+	return i.EndedAt == nil && now.Before(i.ExpiresAt)
+}
+
+// ImpersonationAuditLog is a request served while impersonating, blocked ones included
+type ImpersonationAuditLog struct {
+	Id int64 `json:"id" db:"id"`
+	// ImpersonationId is nil for requests impersonating with the X-Impersonate-User header
+	ImpersonationId *int64    `json:"impersonation_id" db:"impersonation_id"`
+	ActorUserId     int64     `json:"actor_user_id" db:"actor_user_id"`
+	TargetUserId    int64     `json:"target_user_id" db:"target_user_id"`
+	Method          string    `json:"method" db:"method"`
+	Endpoint        string    `json:"endpoint" db:"endpoint"`
+	Uri             string    `json:"uri" db:"uri"`
+	Status          int       `json:"status" db:"status"`
+	IpAddress       string    `json:"ip_address" db:"ip_address"`
+	RequestId       string    `json:"request_id" db:"request_id"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
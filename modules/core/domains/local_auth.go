@@ -0,0 +1,48 @@
+package domains
+
+import "time"
+
+type LocalAuthTokenPurpose string
+
+const (
+	LocalAuthTokenPurposeVerifyEmail   LocalAuthTokenPurpose = "verify_email"
+	LocalAuthTokenPurposeResetPassword LocalAuthTokenPurpose = "reset_password"
+)
+
+// LocalCredential is the password of a user signed up with the built-in identity provider
+type LocalCredential struct {
+	UserId          int64      `json:"user_id" db:"user_id"`
+	PasswordHash    string     `json:"-" db:"password_hash"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at" db:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// LocalAuthToken is a single use token sent by email, only its hash is stored
+type LocalAuthToken struct {
+	Id        int64                 `json:"id" db:"id"`
+	UserId    int64                 `json:"user_id" db:"user_id"`
+	Purpose   LocalAuthTokenPurpose `json:"purpose" db:"purpose"`
+	TokenHash string                `json:"-" db:"token_hash"`
+	ExpiresAt time.Time             `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time            `json:"used_at" db:"used_at"`
+	CreatedAt time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at" db:"updated_at"`
+}
+
+// IsUsable tells whether the token can still be redeemed
+func (t LocalAuthToken) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// RefreshToken is rotated on every use, the session is revoked when a rotated token is presented again
+type RefreshToken struct {
+	Id        int64      `json:"id" db:"id"`
+	UserId    int64      `json:"user_id" db:"user_id"`
+	SessionId int64      `json:"session_id" db:"session_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	RotatedAt *time.Time `json:"rotated_at" db:"rotated_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,29 @@
+package domains
+
+import "time"
+
+// UserMfa is the TOTP second factor of a user, it is pending until confirmed with a first code
+type UserMfa struct {
+	UserId int64  `json:"user_id" db:"user_id"`
+	Secret string `json:"-" db:"secret"`
+	// LastUsedStep is the time step of the last accepted code, a code cannot be used twice
+	LastUsedStep int64      `json:"-" db:"last_used_step"`
+	ConfirmedAt  *time.Time `json:"confirmed_at" db:"confirmed_at"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// IsEnabled tells whether the second factor has been confirmed
+func (m UserMfa) IsEnabled() bool {
+	return m.ConfirmedAt != nil
+}
+
+// MfaRecoveryCode is a single use code replacing a TOTP code when the device is lost, only its hash is stored
+type MfaRecoveryCode struct {
+	Id        int64      `json:"id" db:"id"`
+	UserId    int64      `json:"user_id" db:"user_id"`
+	CodeHash  string     `json:"-" db:"code_hash"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
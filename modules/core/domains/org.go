@@ -7,6 +7,7 @@ import (
 
 	"github.com/dzungtran/echo-rest-api/pkg/cue"
 	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/lib/pq"
 )
 
 type Org struct {
@@ -16,6 +17,16 @@ type Org struct {
 	Description string `json:"description" db:"description"`
 	Domain      string `json:"domain" db:"domain"`
 	Logo        string `json:"logo" db:"logo"`
+	// RequireMfa denies access to the org until the session of the member is verified with a second factor
+	RequireMfa bool `json:"require_mfa" db:"require_mfa"`
+	// Timezone is the IANA name the access hours and input.request.org_clock of the policies are in
+	Timezone string `json:"timezone" db:"timezone" example:"Asia/Ho_Chi_Minh"`
+	// IpAllowlist are the CIDRs members may reach the org from, any address when empty
+	IpAllowlist pq.StringArray `json:"ip_allowlist" db:"ip_allowlist" swaggertype:"array,string" example:"203.0.113.0/24"`
+	// AccessHoursStart and AccessHoursEnd are HH:MM bounds members may reach the org within, any time when empty.
+	// The end is excluded and may be before the start for overnight hours
+	AccessHoursStart string `json:"access_hours_start" db:"access_hours_start" example:"08:00"`
+	AccessHoursEnd   string `json:"access_hours_end" db:"access_hours_end" example:"18:00"`
 
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
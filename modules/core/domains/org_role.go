@@ -0,0 +1,24 @@
+package domains
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// OrgCustomRole domain info
+// @Description Role defined by an org on top of the built-in roles, members hold it by name in users_orgs.role
+type OrgCustomRole struct {
+	Id          int64  `json:"id" db:"id" example:"1"`
+	OrgId       int64  `json:"org_id" db:"org_id" example:"1"`
+	Name        string `json:"name" db:"name" example:"auditor"`
+	Description string `json:"description" db:"description" example:"Reads projects and service accounts"`
+	// Permissions granted on top of the ones inherited from Parent
+	Permissions pq.StringArray `json:"permissions" db:"permissions" swaggertype:"array,string" example:"list:service_account"`
+	// Parent is a built-in or custom role of the org whose permissions are inherited, empty for none
+	Parent string `json:"parent" db:"parent" example:"viewer"`
+	// CreatedBy is nil once the creator is deleted
+	CreatedBy *int64    `json:"created_by" db:"created_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,63 @@
+package domains
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// PersonalAccessTokenPrefix is prepended to every personal access token
+	PersonalAccessTokenPrefix = "pat_"
+)
+
+// PersonalAccessToken domain info
+// @Description Long-lived token of a user, only its hash is stored
+type PersonalAccessToken struct {
+	Id     int64  `json:"id" db:"id" example:"1"`
+	UserId int64  `json:"user_id" db:"user_id" example:"1"`
+	Name   string `json:"name" db:"name" example:"CI pipeline"`
+	// SHA-256 of the token
+	TokenHash string `json:"-" db:"token_hash"`
+	// First characters of the token, helps users to recognize it
+	TokenPrefix string `json:"token_prefix" db:"token_prefix" example:"pat_3q2Xb"`
+	// Permissions granted to the token, empty means all permissions of the user
+	Permissions pq.StringArray `json:"permissions" db:"permissions" swaggertype:"array,string" example:"read:project"`
+	// Orgs the token is limited to, empty means all orgs of the user
+	OrgIds pq.Int64Array `json:"org_ids" db:"org_ids" swaggertype:"array,integer" example:"1"`
+
+	ExpiresAt  *time.Time `json:"expires_at" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// IsActive tells whether the token can still be used
+func (t PersonalAccessToken) IsActive(now time.Time) bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiresAt == nil || now.Before(*t.ExpiresAt)
+}
+
+// Restrict limits the org roles of the owner to the orgs of the token. Platform roles apply to every org,
+// a token limited to orgs does not keep them
+func (t PersonalAccessToken) Restrict(u *UserWithRoles) *UserWithRoles {
+	if len(t.OrgIds) == 0 {
+		return u
+	}
+
+	restricted := &UserWithRoles{
+		User:          u.User,
+		Kind:          u.Kind,
+		OrgRole:       map[int64]string{},
+		PlatformRoles: []PlatformRole{},
+	}
+	for _, orgId := range t.OrgIds {
+		if role, ok := u.OrgRole[orgId]; ok {
+			restricted.OrgRole[orgId] = role
+		}
+	}
+	return restricted
+}
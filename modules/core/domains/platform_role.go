@@ -0,0 +1,24 @@
+package domains
+
+import "time"
+
+type PlatformRole string
+
+const (
+	// PlatformRoleSuperAdmin is allowed everything, in every org
+	PlatformRoleSuperAdmin PlatformRole = "super_admin"
+	// PlatformRoleSupport reads users, orgs and projects to help customers
+	PlatformRoleSupport PlatformRole = "support"
+	// PlatformRoleBillingAdmin reads orgs
+	PlatformRoleBillingAdmin PlatformRole = "billing_admin"
+)
+
+// UserPlatformRole grants a role over the whole platform, the permissions of each role are in pkg/authz/data.json
+type UserPlatformRole struct {
+	Id     int64        `json:"id" db:"id"`
+	UserId int64        `json:"user_id" db:"user_id"`
+	Role   PlatformRole `json:"role" db:"role" example:"support" enums:"super_admin,support,billing_admin"`
+	// GrantedBy is nil for the role seeded by PLATFORM_BOOTSTRAP_SUPER_ADMIN
+	GrantedBy *int64    `json:"granted_by" db:"granted_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
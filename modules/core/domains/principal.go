@@ -0,0 +1,60 @@
+package domains
+
+import "time"
+
+type PrincipalKind string
+
+const (
+	PrincipalKindUser           PrincipalKind = "user"
+	PrincipalKindServiceAccount PrincipalKind = "service_account"
+	PrincipalKindApiKey         PrincipalKind = "api_key"
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Kind PrincipalKind `json:"kind"`
+	// Subject identifies the caller for the auth method, e.g. the token subject or the key id
+	Subject string `json:"subject"`
+	// AuthMethod is the name of the authenticator which accepted the request
+	AuthMethod string `json:"auth_method"`
+	// User carries the org roles of the caller, it is what the policies are evaluated against
+	User *UserWithRoles `json:"user"`
+	// Permissions restricts the caller to a subset of its role permissions, nil means no restriction
+	Permissions []string `json:"permissions,omitempty"`
+	// SessionId is the server-side session of the token, zero when the auth method has none
+	SessionId int64 `json:"session_id,omitempty"`
+	// MfaVerifiedAt is the last time a second factor was verified for the session, nil when never.
+	// Impersonated requests have no session and carry the verification of the actor
+	MfaVerifiedAt *time.Time `json:"mfa_verified_at,omitempty"`
+	// Actor is the super admin behind an impersonated request, User is then the impersonated user
+	Actor *UserWithRoles `json:"actor,omitempty"`
+	// ImpersonationId is the impersonation session of the request, zero for header based impersonation
+	ImpersonationId int64 `json:"impersonation_id,omitempty"`
+}
+
+// IsImpersonated tells whether the request is served as another user than its caller
+func (p Principal) IsImpersonated() bool {
+	return p.Actor != nil
+}
+
+// IsMfaVerified tells whether a second factor was verified within ttl. Service accounts belong to their org
+// and have no second factor, they are always verified
+func (p *Principal) IsMfaVerified(ttl time.Duration, now time.Time) bool {
+	if p == nil {
+		return false
+	}
+
+	if p.Kind == PrincipalKindServiceAccount {
+		return true
+	}
+
+	return p.MfaVerifiedAt != nil && now.Sub(*p.MfaVerifiedAt) <= ttl
+}
+
+// GetRoles returns the role of the principal per org id
+func (p Principal) GetRoles() map[int64]string {
+	if p.User == nil {
+		return map[int64]string{}
+	}
+	return p.User.OrgRole
+}
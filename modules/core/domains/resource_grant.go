@@ -0,0 +1,28 @@
+package domains
+
+import (
+	"time"
+)
+
+type ResourceType string
+
+const (
+	ResourceTypeOrg     ResourceType = "org"
+	ResourceTypeProject ResourceType = "project"
+)
+
+// ResourceGrant domain info
+// @Description Permission granted to a user on a single resource, without an org role
+type ResourceGrant struct {
+	Id int64 `json:"id" db:"id" example:"1"`
+	// OrgId is the org which owns the resource
+	OrgId        int64        `json:"org_id" db:"org_id" example:"1"`
+	UserId       int64        `json:"user_id" db:"user_id" example:"8"`
+	Permission   string       `json:"permission" db:"permission" example:"update:project"`
+	ResourceType ResourceType `json:"resource_type" db:"resource_type" example:"project"`
+	ResourceId   int64        `json:"resource_id" db:"resource_id" example:"42"`
+	// CreatedBy is nil once the creator is deleted
+	CreatedBy *int64    `json:"created_by" db:"created_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
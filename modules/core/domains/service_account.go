@@ -0,0 +1,48 @@
+package domains
+
+import "time"
+
+type ServiceAccountStatus string
+
+const (
+	ServiceAccountStatusActive   ServiceAccountStatus = "active"
+	ServiceAccountStatusDisabled ServiceAccountStatus = "disabled"
+)
+
+// ServiceAccount domain info
+// @Description Non-human member of an org, authenticated with a client id and secret
+type ServiceAccount struct {
+	Id       int64  `json:"id" db:"id" example:"1"`
+	OrgId    int64  `json:"org_id" db:"org_id" example:"1"`
+	Name     string `json:"name" db:"name" example:"Billing exporter"`
+	ClientId string `json:"client_id" db:"client_id" example:"sa_Ai3B9xxxxxxxxxxxxxxx"`
+	// SHA-256 of the client secret
+	ClientSecretHash string               `json:"-" db:"client_secret_hash"`
+	Role             UserOrgRole          `json:"role" db:"role" example:"viewer" enums:"manager,editor,viewer"`
+	Status           ServiceAccountStatus `json:"status" db:"status" example:"active" enums:"active,disabled"`
+
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// ToUserWithRoles builds the identity the policies are evaluated against,
+// a service account only holds its role in its own org
+func (sa ServiceAccount) ToUserWithRoles() *UserWithRoles {
+	status := UserStatusActive
+	if sa.Status != ServiceAccountStatusActive {
+		status = UserStatusDeactivated
+	}
+
+	return &UserWithRoles{
+		User: User{
+			Code:      sa.ClientId,
+			FirstName: sa.Name,
+			Status:    status,
+		},
+		Kind: PrincipalKindServiceAccount,
+		OrgRole: map[int64]string{
+			sa.OrgId: string(sa.Role),
+		},
+	}
+}
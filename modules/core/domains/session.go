@@ -0,0 +1,36 @@
+package domains
+
+import "time"
+
+// Session is a sign-in of a user on a device, the refresh tokens and access tokens issued for it
+// stop working as soon as it is revoked
+type Session struct {
+	Id         int64      `json:"id" db:"id"`
+	UserId     int64      `json:"user_id" db:"user_id"`
+	AuthMethod string     `json:"auth_method" db:"auth_method"`
+	Device     string     `json:"device" db:"device"`
+	IpAddress  string     `json:"ip_address" db:"ip_address"`
+	UserAgent  string     `json:"user_agent" db:"user_agent"`
+	LastSeenAt time.Time  `json:"last_seen_at" db:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at" db:"revoked_at"`
+	// MfaVerifiedAt is the last time a second factor was verified for the session
+	MfaVerifiedAt *time.Time `json:"mfa_verified_at" db:"mfa_verified_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+
+	// Current flags the session of the request listing the sessions
+	Current bool `json:"current" db:"-"`
+}
+
+// SessionClient describes where a session is used from
+type SessionClient struct {
+	Device    string
+	IpAddress string
+	UserAgent string
+}
+
+// IsActive tells whether the session is neither revoked nor expired
+func (s Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
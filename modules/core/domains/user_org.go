@@ -26,7 +26,20 @@ type UserOrg struct {
 
 type UserWithRoles struct {
 	User
+	// Kind tells the policies whether the caller is a human or a service account
+	Kind    PrincipalKind    `json:"kind" example:"user" enums:"user,service_account,api_key"`
 	OrgRole map[int64]string `json:"org_role"`
+	// PlatformRoles apply to every org, see UserPlatformRole
+	PlatformRoles []PlatformRole `json:"platform_roles"`
+}
+
+func (u UserWithRoles) HasPlatformRole(role PlatformRole) bool {
+	for _, r := range u.PlatformRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
 }
 
 func (u UserWithRoles) GetOrgIds() []int64 {
@@ -1,5 +1,11 @@
 package dto
 
+import (
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+)
+
 // CreateOrgReq represent create org request body
 type CreateOrgReq struct {
 	Name        string `json:"name"`
@@ -16,6 +22,13 @@ type UpdateOrgReq struct {
 	Description string `json:"description"`
 	Domain      string `json:"domain"`
 	Logo        string `json:"logo"`
+	// RequireMfa is left unchanged when omitted
+	RequireMfa *bool `json:"require_mfa,omitempty"`
+	// The access settings are left unchanged when omitted, see domains.Org
+	Timezone         *string   `json:"timezone,omitempty" example:"Asia/Ho_Chi_Minh"`
+	IpAllowlist      *[]string `json:"ip_allowlist,omitempty" example:"203.0.113.0/24"`
+	AccessHoursStart *string   `json:"access_hours_start,omitempty" example:"08:00"`
+	AccessHoursEnd   *string   `json:"access_hours_end,omitempty" example:"18:00"`
 }
 
 type SearchOrgsReq struct {
@@ -49,3 +62,190 @@ type SearchUsersReq struct {
 	Limit int64 `json:"limit" query:"limit"`
 	Page  int64 `json:"page" query:"page"`
 }
+
+// CreatePersonalAccessTokenReq represent create personal access token request body
+type CreatePersonalAccessTokenReq struct {
+	Name string `json:"name" example:"CI pipeline"`
+	// Permissions granted to the token, empty means all permissions of the user
+	Permissions []string `json:"permissions" example:"read:project"`
+	// Orgs the token is limited to, empty means all orgs of the user
+	OrgIds    []int64    `json:"org_ids" example:"1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateServiceAccountReq represent create service account request body
+type CreateServiceAccountReq struct {
+	OrgId int64  `json:"-" param:"orgId"`
+	Name  string `json:"name" example:"Billing exporter"`
+	Role  string `json:"role" example:"viewer" enums:"manager,editor,viewer"`
+}
+
+// UpdateServiceAccountReq represent update service account request body
+type UpdateServiceAccountReq struct {
+	OrgId            int64  `json:"-" param:"orgId"`
+	ServiceAccountId int64  `json:"-" param:"serviceAccountId"`
+	Name             string `json:"name" example:"Billing exporter"`
+	Role             string `json:"role" example:"viewer" enums:"manager,editor,viewer"`
+	Status           string `json:"status" example:"active" enums:"active,disabled"`
+}
+
+// ServiceAccountTokenReq represent the client credentials exchanged for an access token
+type ServiceAccountTokenReq struct {
+	ClientId     string `json:"client_id" form:"client_id"`
+	ClientSecret string `json:"client_secret" form:"client_secret"`
+}
+
+// LocalSignupReq represent the signup request body of the built-in identity provider
+type LocalSignupReq struct {
+	Email     string `json:"email" example:"jane@example.com"`
+	Password  string `json:"password" example:"correct horse battery staple"`
+	FirstName string `json:"first_name" example:"Jane"`
+	LastName  string `json:"last_name" example:"Doe"`
+}
+
+// LocalLoginReq represent the login request body of the built-in identity provider
+type LocalLoginReq struct {
+	Email    string `json:"email" example:"jane@example.com"`
+	Password string `json:"password" example:"correct horse battery staple"`
+	// Device names the session in the session list
+	Device string `json:"device" example:"Jane's laptop"`
+}
+
+// LocalRefreshReq represent a refresh token exchanged for new tokens, also used to log out
+type LocalRefreshReq struct {
+	RefreshToken string `json:"refresh_token" example:"rt_3q2Xb..."`
+}
+
+// LocalVerifyEmailReq represent the token sent by the verification email
+type LocalVerifyEmailReq struct {
+	Token string `json:"token" query:"token"`
+}
+
+// LocalEmailReq represent a request only carrying an email, e.g. forgot password
+type LocalEmailReq struct {
+	Email string `json:"email" example:"jane@example.com"`
+}
+
+// LocalResetPasswordReq represent the reset password request body
+type LocalResetPasswordReq struct {
+	Token    string `json:"token"`
+	Password string `json:"password" example:"correct horse battery staple"`
+}
+
+// MfaCodeReq represent a TOTP code of the authenticator app, or a recovery code when the device is lost
+type MfaCodeReq struct {
+	Code         string `json:"code" example:"123456"`
+	RecoveryCode string `json:"recovery_code" example:"k7d2m-q9xfa"`
+}
+
+// StartImpersonationReq represent the start impersonation request body
+type StartImpersonationReq struct {
+	UserId int64  `json:"-" param:"userId"`
+	Reason string `json:"reason" example:"Ticket #1234, dashboard does not load"`
+	// AllowWrites lets mutating calls through, only honored when IMPERSONATION_ALLOW_WRITES is enabled
+	AllowWrites bool `json:"allow_writes"`
+}
+
+type SearchImpersonationAuditLogsReq struct {
+	ActorId  int64 `query:"actor_id"`
+	TargetId int64 `query:"target_id"`
+	Limit    int64 `query:"limit"`
+	Page     int64 `query:"page"`
+}
+
+// GrantPlatformRoleReq represent the grant platform role request body
+type GrantPlatformRoleReq struct {
+	UserId int64  `json:"-" param:"userId"`
+	Role   string `json:"role" example:"support" enums:"super_admin,support,billing_admin"`
+}
+
+// CreateOrgRoleReq represent create custom org role request body
+type CreateOrgRoleReq struct {
+	OrgId       int64  `json:"-" param:"orgId"`
+	Name        string `json:"name" example:"auditor"`
+	Description string `json:"description" example:"Reads projects and service accounts"`
+	// Permissions granted on top of the parent ones, named like the routes
+	Permissions []string `json:"permissions" example:"list:service_account"`
+	// Parent is a built-in or custom role whose permissions are inherited
+	Parent string `json:"parent" example:"viewer"`
+}
+
+// UpdateOrgRoleReq represent update custom org role request body, a role cannot be renamed
+type UpdateOrgRoleReq struct {
+	OrgId       int64    `json:"-" param:"orgId"`
+	RoleId      int64    `json:"-" param:"roleId"`
+	Description string   `json:"description" example:"Reads projects and service accounts"`
+	Permissions []string `json:"permissions" example:"list:service_account"`
+	Parent      string   `json:"parent" example:"viewer"`
+}
+
+// UpdateMemberRoleReq represent the role assigned to a member of an org
+type UpdateMemberRoleReq struct {
+	OrgId  int64  `json:"-" param:"orgId"`
+	UserId int64  `json:"-" param:"userId"`
+	Role   string `json:"role" example:"auditor"`
+}
+
+type SearchAuthzDecisionsReq struct {
+	UserId    int64  `query:"user_id"`
+	OrgId     int64  `query:"org_id"`
+	Allowed   *bool  `query:"allowed"`
+	RequestId string `query:"request_id"`
+	// Since is a RFC 3339 time
+	Since *time.Time `query:"since"`
+	Limit int64      `query:"limit"`
+	Page  int64      `query:"page"`
+}
+
+// CheckAuthzReq represent a request evaluated by the policies without being served
+type CheckAuthzReq struct {
+	// UserId of the user to check, or the inline User
+	UserId int64                  `json:"user_id,omitempty" example:"1"`
+	User   *domains.UserWithRoles `json:"user,omitempty"`
+	Method string                 `json:"method" example:"PUT"`
+	// Endpoint is the route path, e.g. /admin/orgs/:orgId
+	Endpoint  string                 `json:"endpoint" example:"/admin/orgs/:orgId"`
+	OrgId     int64                  `json:"org_id,omitempty" example:"1"`
+	ProjectId int64                  `json:"project_id,omitempty" example:"1"`
+	Payload   map[string]interface{} `json:"payload,omitempty"`
+	// TokenPermissions scope the user like a personal access token
+	TokenPermissions []string `json:"token_permissions,omitempty"`
+	// Trace returns the full evaluation trace
+	Trace bool `json:"trace,omitempty"`
+}
+
+// GetPermissionsReq represent the org the permissions of the current user are evaluated in
+type GetPermissionsReq struct {
+	OrgId int64 `query:"org_id"`
+}
+
+// PermissionCheck represent a request the current user may make, with the org or project it targets
+type PermissionCheck struct {
+	Method string `json:"method" example:"PUT"`
+	// Endpoint is the route path, e.g. /admin/orgs/:orgId
+	Endpoint  string `json:"endpoint" example:"/admin/orgs/:orgId"`
+	OrgId     int64  `json:"org_id,omitempty" example:"1"`
+	ProjectId int64  `json:"project_id,omitempty"`
+}
+
+// CheckPermissionsReq represent a batch of requests checked for the current user
+type CheckPermissionsReq struct {
+	Checks []PermissionCheck `json:"checks"`
+}
+
+// SearchResourceGrantsReq represent the filters of the grants of an org
+type SearchResourceGrantsReq struct {
+	OrgId        int64  `param:"orgId"`
+	UserId       int64  `query:"user_id"`
+	ResourceType string `query:"resource_type"`
+	ResourceId   int64  `query:"resource_id"`
+}
+
+// CreateResourceGrantReq represent a permission granted to a user on a resource of the org
+type CreateResourceGrantReq struct {
+	OrgId        int64  `json:"-" param:"orgId"`
+	UserId       int64  `json:"user_id" example:"8"`
+	Permission   string `json:"permission" example:"update:project"`
+	ResourceType string `json:"resource_type" example:"project"`
+	ResourceId   int64  `json:"resource_id" example:"42"`
+}
@@ -1 +1,64 @@
 package dto
+
+import (
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+)
+
+// PersonalAccessTokenCreatedResp represent a created personal access token, the token is only returned once
+type PersonalAccessTokenCreatedResp struct {
+	domains.PersonalAccessToken
+	Token string `json:"token" example:"pat_3q2Xb..."`
+}
+
+// ServiceAccountCreatedResp represent a service account with its client secret, the secret is only returned once
+type ServiceAccountCreatedResp struct {
+	domains.ServiceAccount
+	ClientSecret string `json:"client_secret" example:"Zm9vYmFy..."`
+}
+
+// ServiceAccountTokenResp represent a short-lived access token of a service account
+type ServiceAccountTokenResp struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type" example:"Bearer"`
+	ExpiresIn   int64  `json:"expires_in" example:"900"`
+}
+
+// LocalAuthTokenResp represent the tokens issued by the built-in identity provider
+type LocalAuthTokenResp struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int64  `json:"expires_in" example:"900"`
+}
+
+// MfaStatusResp represent the second factor of the current user
+type MfaStatusResp struct {
+	Enabled                bool       `json:"enabled"`
+	ConfirmedAt            *time.Time `json:"confirmed_at"`
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+}
+
+// MfaEnrollmentResp represent the secret to add to the authenticator app, it is only returned once
+type MfaEnrollmentResp struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	// URI is rendered as QR code by the frontend
+	URI string `json:"uri" example:"otpauth://totp/echo-rest-api:jane@example.com?secret=JBSWY3DPEHPK3PXP&issuer=echo-rest-api"`
+}
+
+// MfaRecoveryCodesResp represent the recovery codes of the current user, they are only returned once
+type MfaRecoveryCodesResp struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// PermissionCheckResp represent whether the current user may make a request of CheckPermissionsReq
+type PermissionCheckResp struct {
+	PermissionCheck
+	Allowed      bool     `json:"allowed"`
+	DenyMessages []string `json:"deny_messages"`
+	// Error is set when the org or project of the check is not found
+	Error string `json:"error,omitempty"`
+	// Code is mfa_required when the org of the check requires a second factor the caller has not verified
+	Code string `json:"code,omitempty" example:"mfa_required"`
+}
@@ -21,8 +21,8 @@ func NewAuthHandler(g *echo.Group, middManager *middlewares.MiddlewareManager, u
 	}
 
 	apiV1 := g.Group("auth")
-	apiV1.GET("/login", handler.LoginForm)
-	apiV1.GET("/success", handler.LoginSuccess)
+	apiV1.GET("/login", handler.LoginForm).Name = "public:login_form"
+	apiV1.GET("/success", handler.LoginSuccess).Name = "public:login_success"
 }
 
 // LoginForm godoc
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/dzungtran/echo-rest-api/pkg/middlewares"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/dzungtran/echo-rest-api/pkg/wrapper"
+	"github.com/labstack/echo/v4"
+)
+
+type AuthzDecisionHandler struct {
+	AuthzDecisionUC usecases.AuthzDecisionUsecase
+	UserResolver    *middlewares.UserResolver
+}
+
+// NewAuthzDecisionHandler will initialize the authorization decision log and explain endpoints, super admin only
+func NewAuthzDecisionHandler(
+	g *echo.Group,
+	middManager *middlewares.MiddlewareManager,
+	authzDecisionUsecase usecases.AuthzDecisionUsecase,
+	userResolver *middlewares.UserResolver,
+) {
+	handler := &AuthzDecisionHandler{
+		AuthzDecisionUC: authzDecisionUsecase,
+		UserResolver:    userResolver,
+	}
+
+	apiV1 := g.Group("admin/authz", middManager.Auth(), middManager.CheckPolicies())
+	apiV1.GET("/decisions", wrapper.Wrap(handler.Fetch)).Name = "list:authz_decision"
+	apiV1.POST("/check", wrapper.Wrap(handler.Check)).Name = "check:authz"
+}
+
+// GetListAuthzDecisions godoc
+// @Summary      Get authorization decisions
+// @Description  Get the recorded decisions of the policies, newest first. They are read from the database with the db sink,
+// @Description  from the last ones kept in memory otherwise
+// @Tags         policies
+// @Accept       json
+// @Produce      json
+// @Param        user_id     query     int     false  "Filter by user ID"
+// @Param        org_id      query     int     false  "Filter by org ID"
+// @Param        allowed     query     bool    false  "Filter by outcome"
+// @Param        request_id  query     string  false  "Filter by request ID"
+// @Param        since       query     string  false  "Only decisions made after this RFC 3339 time"
+// @Param        limit   query     int  false  "Number of records should be returned"
+// @Param        page    query     int  false  "Page"
+// @Success      200  {object}  wrapper.SuccessResponse{data=[]domains.AuthzDecision}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/authz/decisions [get]
+func (h *AuthzDecisionHandler) Fetch(c echo.Context) wrapper.Response {
+	var req dto.SearchAuthzDecisionsReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	decisions, count, err := h.AuthzDecisionUC.Fetch(c.Request().Context(), req)
+	if err != nil {
+		logger.Log().Errorw("error while fetch authz decisions", "error", err)
+		return wrapper.Response{
+			Status: http.StatusInternalServerError,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	return wrapper.Response{
+		Data:         decisions,
+		Total:        count,
+		IncludeTotal: true,
+	}
+}
+
+// CheckAuthz godoc
+// @Summary      Explain an authorization decision
+// @Description  Evaluate a request for a user like the policies middlewares do, without serving it nor recording the decision.
+// @Description  It returns the decision, the permission of the route, the role of the user in the org and the rules which contributed
+// @Tags         policies
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.CheckAuthzReq  true  "Request to evaluate"
+// @Success      200  {object}  wrapper.SuccessResponse{data=authz.Explanation}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      404  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/authz/check [post]
+func (h *AuthzDecisionHandler) Check(c echo.Context) wrapper.Response {
+	var req dto.CheckAuthzReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	if err := h.AuthzDecisionUC.ValidateCheck(req); err != nil {
+		logger.Log().Debugw("invalid check authz request", "error", err)
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, "invalid payload"),
+		}
+	}
+
+	ctx := c.Request().Context()
+	user := req.User
+	if user == nil {
+		var err error
+		if user, err = h.UserResolver.FetchUserByID(ctx, req.UserId); err != nil {
+			return authzCheckError(err)
+		}
+	} else if user.OrgRole == nil {
+		user.OrgRole = map[int64]string{}
+	}
+
+	explanation, err := h.AuthzDecisionUC.Explain(ctx, user, req)
+	if err != nil {
+		return authzCheckError(err)
+	}
+	return wrapper.Response{Data: explanation}
+}
+
+func authzCheckError(err error) wrapper.Response {
+	if errors.Is(err, constants.ErrNotFound) {
+		return wrapper.Response{
+			Status: http.StatusNotFound,
+			Error:  utils.NewNotFoundError(),
+		}
+	}
+
+	logger.Log().Errorw("error while check authz", "error", err)
+	return wrapper.Response{
+		Status: http.StatusInternalServerError,
+		Error:  utils.NewError(err, ""),
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/kratos"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/dzungtran/echo-rest-api/pkg/middlewares"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+type HookHandler struct {
+	UserUC usecases.UserUsecase
+}
+
+// NewHookHandler will initialize the endpoints called by 3rd-parties
+func NewHookHandler(g *echo.Group, middManager *middlewares.MiddlewareManager, userUsecase usecases.UserUsecase) {
+	handler := &HookHandler{
+		UserUC: userUsecase,
+	}
+
+	apiKratos := g.Group("hooks/kratos", middManager.KratosWebhookAuth())
+	apiKratos.POST("/identity", handler.KratosIdentity).Name = "public:kratos_identity"
+}
+
+// KratosIdentity godoc
+// @Summary      Sync Kratos identity
+// @Description  Called by Kratos after registration and after settings change, creates or updates the local user
+// @Tags         hooks
+// @Accept       json
+// @Produce      json
+// @Param        body  body      kratos.WebhookPayload  true  "Kratos identity"
+// @Success      204
+// @Failure      400  {object}  kratos.WebhookErrorResponse
+// @Failure      401  {object}  kratos.WebhookErrorResponse
+// @Failure      500  {object}  kratos.WebhookErrorResponse
+// @Security     XApiKey
+// @Router       /hooks/kratos/identity [post]
+func (h *HookHandler) KratosIdentity(c echo.Context) error {
+	var req kratos.WebhookPayload
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, kratos.NewWebhookError("#", kratos.MessageIdValidationGeneric, "invalid payload"))
+	}
+
+	if req.Identity.Id == "" {
+		return c.JSON(http.StatusBadRequest, kratos.NewWebhookError("#/identity/id", kratos.MessageIdValidationGeneric, "identity id is required"))
+	}
+
+	u := req.Identity.ToUser()
+	_, err := h.UserUC.UpsertByCode(c.Request().Context(), dto.CreateUserReq{
+		Code:      u.Code,
+		Email:     u.Email,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+	})
+	if err != nil {
+		if utils.IsCueError(err) {
+			logger.Log().Debugw("invalid kratos identity", "error", err)
+			return c.JSON(http.StatusBadRequest, kratos.NewWebhookError("#/traits", kratos.MessageIdValidationGeneric, err.Error()))
+		}
+
+		if errors.Is(err, constants.ErrDuplicated) {
+			return c.JSON(http.StatusBadRequest, kratos.NewWebhookError("#/traits/email", kratos.MessageIdValidationGeneric, "email is already in use"))
+		}
+
+		logger.Log().Errorw("error while sync kratos identity", "identity_id", u.Code, "error", err)
+		return c.JSON(http.StatusInternalServerError, kratos.NewWebhookError("#", kratos.MessageIdValidationGeneric, "internal server error"))
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
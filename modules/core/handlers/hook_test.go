@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/cue"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeUserUsecase struct {
+	usecases.UserUsecase
+	upsertErr error
+	upserted  *dto.CreateUserReq
+}
+
+func (u *fakeUserUsecase) UpsertByCode(ctx context.Context, req dto.CreateUserReq) (*domains.User, error) {
+	u.upserted = &req
+	if u.upsertErr != nil {
+		return nil, u.upsertErr
+	}
+	return &domains.User{Id: 1, Code: req.Code}, nil
+}
+
+func TestKratosIdentity(t *testing.T) {
+	identity := `{"identity": {"id": "9f0c6a56-2d3c-4d4a-9d1b-2b1f0a7d0c11", "traits": {"email": "ann@example.com", "name": {"first": "Ann", "last": "Lee"}}}}`
+	cueErr := utils.CueValidateObject("CreateUserRequest", cue.CueDefinitionForUser, dto.CreateUserReq{Code: "short"})
+
+	tcs := []struct {
+		name           string
+		body           string
+		upsertErr      error
+		expectedStatus int
+		expectedPtr    string
+	}{
+		{"should sync the identity", identity, nil, http.StatusNoContent, ""},
+		{"should reject invalid json", `{`, nil, http.StatusBadRequest, "#"},
+		{"should reject identity without id", `{"identity": {}}`, nil, http.StatusBadRequest, "#/identity/id"},
+		{"should reject invalid traits", identity, cueErr, http.StatusBadRequest, "#/traits"},
+		{"should reject email of another user", identity, constants.ErrDuplicated, http.StatusBadRequest, "#/traits/email"},
+		{"should fail on other errors", identity, errors.New("db down"), http.StatusInternalServerError, "#"},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			uc := &fakeUserUsecase{upsertErr: tc.upsertErr}
+			h := &HookHandler{UserUC: uc}
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/hooks/kratos/identity", strings.NewReader(tc.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			assert.Nil(t, h.KratosIdentity(e.NewContext(req, rec)))
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			if tc.expectedPtr != "" {
+				assert.Contains(t, rec.Body.String(), `"instance_ptr":"`+tc.expectedPtr+`"`)
+			}
+			if tc.expectedStatus == http.StatusNoContent {
+				assert.Equal(t, "9f0c6a56-2d3c-4d4a-9d1b-2b1f0a7d0c11", uc.upserted.Code)
+				assert.Equal(t, "ann@example.com", uc.upserted.Email)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/dzungtran/echo-rest-api/pkg/middlewares"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/dzungtran/echo-rest-api/pkg/wrapper"
+	"github.com/labstack/echo/v4"
+)
+
+type ImpersonationHandler struct {
+	ImpersonationUC usecases.ImpersonationUsecase
+}
+
+// NewImpersonationHandler will initialize the impersonation endpoints, super admin only
+func NewImpersonationHandler(g *echo.Group, middManager *middlewares.MiddlewareManager, impersonationUsecase usecases.ImpersonationUsecase) {
+	handler := &ImpersonationHandler{
+		ImpersonationUC: impersonationUsecase,
+	}
+
+	apiUser := g.Group("admin/users/:userId/impersonation",
+		middManager.Auth(),
+		middlewares.RequireResourceIdInParam("userId"),
+		middManager.CheckPolicies(),
+	)
+	apiUser.POST("", wrapper.Wrap(handler.Start)).Name = "impersonate:user"
+
+	apiV1 := g.Group("admin/impersonation", middManager.Auth(), middManager.CheckPolicies())
+	apiV1.GET("", wrapper.Wrap(handler.GetActive)).Name = "read:impersonation"
+	apiV1.DELETE("", wrapper.Wrap(handler.Stop)).Name = "stop:impersonation"
+	apiV1.GET("/logs", wrapper.Wrap(handler.FetchAuditLogs)).Name = "list:impersonation_log"
+}
+
+// StartImpersonation godoc
+// @Summary      Start impersonating a user
+// @Description  Act as the user on the next requests until stopped or IMPERSONATION_TTL, mutating calls are blocked unless allowed
+// @Tags         impersonation
+// @Accept       json
+// @Produce      json
+// @Param        userId   path      int  true  "User ID"
+// @Param        body  body      dto.StartImpersonationReq  true  "Impersonation reason"
+// @Success      201  {object}  wrapper.SuccessResponse{data=domains.Impersonation}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      404  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/users/{userId}/impersonation [post]
+func (h *ImpersonationHandler) Start(c echo.Context) wrapper.Response {
+	var req dto.StartImpersonationReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	user, _ := contexts.GetUserFromContext(c)
+	impersonation, err := h.ImpersonationUC.Start(c.Request().Context(), user.Id, req)
+	if err != nil {
+		if utils.IsCueError(err) {
+			logger.Log().Debugw("invalid start impersonation request", "error", err)
+			return wrapper.Response{
+				Status: http.StatusBadRequest,
+				Error:  utils.NewError(err, "invalid payload"),
+			}
+		}
+		return impersonationError(err, "start impersonation")
+	}
+
+	logger.Log().Infow("impersonation started", "actor_id", user.Id,
+		"target_id", impersonation.TargetUserId, "reason", impersonation.Reason)
+	return wrapper.Response{Status: http.StatusCreated, Data: impersonation}
+}
+
+// GetActiveImpersonation godoc
+// @Summary      Get the running impersonation
+// @Description  Get the running impersonation of the current super admin
+// @Tags         impersonation
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  wrapper.SuccessResponse{data=domains.Impersonation}
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      404  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/impersonation [get]
+func (h *ImpersonationHandler) GetActive(c echo.Context) wrapper.Response {
+	user, _ := contexts.GetUserFromContext(c)
+	impersonation, err := h.ImpersonationUC.GetActive(c.Request().Context(), user.Id)
+	if err != nil {
+		return impersonationError(err, "get impersonation")
+	}
+	return wrapper.Response{Data: impersonation}
+}
+
+// StopImpersonation godoc
+// @Summary      Stop impersonating
+// @Description  End the running impersonation of the current super admin
+// @Tags         impersonation
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  wrapper.SuccessResponse{}
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/impersonation [delete]
+func (h *ImpersonationHandler) Stop(c echo.Context) wrapper.Response {
+	user, _ := contexts.GetUserFromContext(c)
+	if err := h.ImpersonationUC.Stop(c.Request().Context(), user.Id); err != nil {
+		return impersonationError(err, "stop impersonation")
+	}
+	return wrapper.Response{}
+}
+
+// GetListImpersonationAuditLogs godoc
+// @Summary      Get impersonation audit logs
+// @Description  Get the requests served while impersonating, newest first
+// @Tags         impersonation
+// @Accept       json
+// @Produce      json
+// @Param        actor_id    query     int  false  "Filter by super admin ID"
+// @Param        target_id   query     int  false  "Filter by impersonated user ID"
+// @Param        limit   query     int  false  "Number of records should be returned"
+// @Param        page    query     int  false  "Page"
+// @Success      200  {object}  wrapper.SuccessResponse{data=[]domains.ImpersonationAuditLog}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/impersonation/logs [get]
+func (h *ImpersonationHandler) FetchAuditLogs(c echo.Context) wrapper.Response {
+	var req dto.SearchImpersonationAuditLogsReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	logs, count, err := h.ImpersonationUC.FetchAuditLogs(c.Request().Context(), req)
+	if err != nil {
+		return impersonationError(err, "fetch impersonation audit logs")
+	}
+
+	return wrapper.Response{
+		Data:         logs,
+		Total:        count,
+		IncludeTotal: true,
+	}
+}
+
+func impersonationError(err error, action string) wrapper.Response {
+	switch {
+	case errors.Is(err, usecases.ErrImpersonationNotAllowed):
+		return wrapper.Response{
+			Status: http.StatusForbidden,
+			Error:  utils.NewError(err, ""),
+		}
+	case errors.Is(err, constants.ErrNotFound):
+		return wrapper.Response{
+			Status: http.StatusNotFound,
+			Error:  utils.NewNotFoundError(),
+		}
+	}
+
+	logger.Log().Errorw("error while "+action, "error", err)
+	return wrapper.Response{
+		Status: http.StatusInternalServerError,
+		Error:  utils.NewError(err, ""),
+	}
+}
@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/dzungtran/echo-rest-api/pkg/wrapper"
+	"github.com/labstack/echo/v4"
+)
+
+type LocalAuthHandler struct {
+	LocalAuthUC usecases.LocalAuthUsecase
+}
+
+// NewLocalAuthHandler will initialize the endpoints of the built-in identity provider
+func NewLocalAuthHandler(g *echo.Group, localAuthUsecase usecases.LocalAuthUsecase) {
+	handler := &LocalAuthHandler{
+		LocalAuthUC: localAuthUsecase,
+	}
+
+	g.GET(".well-known/jwks.json", handler.Jwks).Name = "public:jwks"
+
+	apiAuth := g.Group("auth")
+	apiAuth.POST("/signup", wrapper.Wrap(handler.Signup)).Name = "public:signup"
+	apiAuth.GET("/verify-email", wrapper.Wrap(handler.VerifyEmail)).Name = "public:verify_email"
+	apiAuth.POST("/verify-email", wrapper.Wrap(handler.VerifyEmail)).Name = "public:verify_email"
+	apiAuth.POST("/verify-email/resend", wrapper.Wrap(handler.ResendVerification)).Name = "public:resend_verification"
+	apiAuth.POST("/login", wrapper.Wrap(handler.Login)).Name = "public:login"
+	apiAuth.POST("/refresh", wrapper.Wrap(handler.Refresh)).Name = "public:refresh"
+	apiAuth.POST("/logout", wrapper.Wrap(handler.Logout)).Name = "public:logout"
+	apiAuth.POST("/password/forgot", wrapper.Wrap(handler.ForgotPassword)).Name = "public:forgot_password"
+	apiAuth.POST("/password/reset", wrapper.Wrap(handler.ResetPassword)).Name = "public:reset_password"
+}
+
+// Jwks godoc
+// @Summary      Signing keys
+// @Description  Public keys of the access tokens issued by the built-in identity provider
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}
+// @Router       /.well-known/jwks.json [get]
+func (h *LocalAuthHandler) Jwks(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, h.LocalAuthUC.PublicKeySet())
+}
+
+// LocalSignup godoc
+// @Summary      Sign up
+// @Description  Create an account with email and password, a verification link is sent by email
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.LocalSignupReq  true  "Signup request"
+// @Success      201  {object}  wrapper.SuccessResponse{data=domains.User}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      409  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Router       /auth/signup [post]
+func (h *LocalAuthHandler) Signup(c echo.Context) wrapper.Response {
+	var req dto.LocalSignupReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	user, err := h.LocalAuthUC.Signup(c.Request().Context(), req)
+	if err != nil {
+		if utils.IsCueError(err) {
+			logger.Log().Debugw("invalid signup request", "error", err)
+			return wrapper.Response{
+				Status: http.StatusBadRequest,
+				Error:  utils.NewError(err, "invalid payload"),
+			}
+		}
+
+		if errors.Is(err, constants.ErrDuplicated) {
+			return wrapper.Response{
+				Status: http.StatusConflict,
+				Error:  utils.NewError(err, "email is already registered"),
+			}
+		}
+
+		logger.Log().Errorw("error while sign up", "error", err)
+		return wrapper.Response{
+			Status: http.StatusInternalServerError,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	return wrapper.Response{Data: user, Status: http.StatusCreated}
+}
+
+// LocalVerifyEmail godoc
+// @Summary      Verify email
+// @Description  Redeem the token sent by the verification email
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        token  query     string  false  "Verification token"
+// @Param        body   body      dto.LocalVerifyEmailReq  false  "Verification token"
+// @Success      200  {object}  wrapper.SuccessResponse
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Router       /auth/verify-email [post]
+func (h *LocalAuthHandler) VerifyEmail(c echo.Context) wrapper.Response {
+	var req dto.LocalVerifyEmailReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	if err := h.LocalAuthUC.VerifyEmail(c.Request().Context(), req.Token); err != nil {
+		return localAuthActionError(err, "verify email")
+	}
+	return wrapper.Response{}
+}
+
+// LocalResendVerification godoc
+// @Summary      Resend verification email
+// @Description  Send a new verification link, the response does not tell whether the email is registered
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.LocalEmailReq  true  "Email"
+// @Success      200  {object}  wrapper.SuccessResponse
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Router       /auth/verify-email/resend [post]
+func (h *LocalAuthHandler) ResendVerification(c echo.Context) wrapper.Response {
+	var req dto.LocalEmailReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	if err := h.LocalAuthUC.ResendVerification(c.Request().Context(), req.Email); err != nil {
+		logger.Log().Errorw("error while resend verification email", "error", err)
+		return wrapper.Response{
+			Status: http.StatusInternalServerError,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+	return wrapper.Response{}
+}
+
+// LocalLogin godoc
+// @Summary      Log in
+// @Description  Exchange email and password for an access token and a refresh token
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.LocalLoginReq  true  "Credentials"
+// @Success      200  {object}  wrapper.SuccessResponse{data=dto.LocalAuthTokenResp}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Router       /auth/login [post]
+func (h *LocalAuthHandler) Login(c echo.Context) wrapper.Response {
+	var req dto.LocalLoginReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	resp, err := h.LocalAuthUC.Login(c.Request().Context(), req, sessionClientOf(c))
+	if err != nil {
+		if errors.Is(err, usecases.ErrEmailNotVerified) {
+			return wrapper.Response{
+				Status: http.StatusForbidden,
+				Error:  utils.NewError(err, ""),
+			}
+		}
+		return localAuthTokenError(err, "log in")
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return wrapper.Response{Data: resp}
+}
+
+// LocalRefresh godoc
+// @Summary      Refresh tokens
+// @Description  Exchange a refresh token for new tokens, the refresh token can only be used once
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.LocalRefreshReq  true  "Refresh token"
+// @Success      200  {object}  wrapper.SuccessResponse{data=dto.LocalAuthTokenResp}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Router       /auth/refresh [post]
+func (h *LocalAuthHandler) Refresh(c echo.Context) wrapper.Response {
+	var req dto.LocalRefreshReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	resp, err := h.LocalAuthUC.Refresh(c.Request().Context(), req.RefreshToken, sessionClientOf(c))
+	if err != nil {
+		return localAuthTokenError(err, "refresh tokens")
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return wrapper.Response{Data: resp}
+}
+
+// LocalLogout godoc
+// @Summary      Log out
+// @Description  Revoke the session of the refresh token
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.LocalRefreshReq  true  "Refresh token"
+// @Success      200  {object}  wrapper.SuccessResponse
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Router       /auth/logout [post]
+func (h *LocalAuthHandler) Logout(c echo.Context) wrapper.Response {
+	var req dto.LocalRefreshReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	if err := h.LocalAuthUC.Logout(c.Request().Context(), req.RefreshToken); err != nil {
+		return localAuthTokenError(err, "log out")
+	}
+	return wrapper.Response{}
+}
+
+// LocalForgotPassword godoc
+// @Summary      Forgot password
+// @Description  Send a reset password token by email, the response does not tell whether the email is registered
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.LocalEmailReq  true  "Email"
+// @Success      200  {object}  wrapper.SuccessResponse
+// @Failure      400  {object}  wrapper.FailResponse
+// @Router       /auth/password/forgot [post]
+func (h *LocalAuthHandler) ForgotPassword(c echo.Context) wrapper.Response {
+	var req dto.LocalEmailReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	if err := h.LocalAuthUC.ForgotPassword(c.Request().Context(), req.Email); err != nil {
+		logger.Log().Errorw("error while send reset password email", "error", err)
+	}
+	return wrapper.Response{}
+}
+
+// LocalResetPassword godoc
+// @Summary      Reset password
+// @Description  Set a new password with the token sent by email, all sessions of the user are revoked
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.LocalResetPasswordReq  true  "Reset password request"
+// @Success      200  {object}  wrapper.SuccessResponse
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Router       /auth/password/reset [post]
+func (h *LocalAuthHandler) ResetPassword(c echo.Context) wrapper.Response {
+	var req dto.LocalResetPasswordReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	if err := h.LocalAuthUC.ResetPassword(c.Request().Context(), req); err != nil {
+		if utils.IsCueError(err) {
+			logger.Log().Debugw("invalid reset password request", "error", err)
+			return wrapper.Response{
+				Status: http.StatusBadRequest,
+				Error:  utils.NewError(err, "invalid payload"),
+			}
+		}
+		return localAuthActionError(err, "reset password")
+	}
+	return wrapper.Response{}
+}
+
+func localAuthActionError(err error, action string) wrapper.Response {
+	if errors.Is(err, usecases.ErrInvalidLocalAuthToken) {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	logger.Log().Errorw("error while "+action, "error", err)
+	return wrapper.Response{
+		Status: http.StatusInternalServerError,
+		Error:  utils.NewError(err, ""),
+	}
+}
+
+func localAuthTokenError(err error, action string) wrapper.Response {
+	if errors.Is(err, constants.ErrUnauthorized) {
+		return wrapper.Response{
+			Status: http.StatusUnauthorized,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	logger.Log().Errorw("error while "+action, "error", err)
+	return wrapper.Response{
+		Status: http.StatusInternalServerError,
+		Error:  utils.NewError(err, ""),
+	}
+}
+
+func sessionClientOf(c echo.Context) domains.SessionClient {
+	return domains.SessionClient{
+		IpAddress: c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+	}
+}
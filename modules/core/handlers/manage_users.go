@@ -38,7 +38,7 @@ func (h *UserHandler) GetByID(c echo.Context) wrapper.Response {
 		}
 	}
 
-	denyMsg, err := authz.CheckPoliciesContext(c, authz.WithInputExtraData("user_info", &domains.User{
+	denyMsg, err := authz.CheckContext(h.Authorizer, c, authz.WithInputExtraData("user_info", &domains.User{
 		Id: int64(id),
 	}))
 	if err != nil {
@@ -46,6 +46,9 @@ func (h *UserHandler) GetByID(c echo.Context) wrapper.Response {
 		if len(denyMsg) > 0 {
 			msg = denyMsg[0]
 		}
+		if errors.Is(err, authz.ErrRemotePolicyUnavailable) {
+			msg = authz.RemotePolicyUnavailableMessage
+		}
 		return wrapper.Response{
 			Status: http.StatusForbidden,
 			Error:  utils.NewError(err, msg),
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/dzungtran/echo-rest-api/pkg/middlewares"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/dzungtran/echo-rest-api/pkg/wrapper"
+	"github.com/labstack/echo/v4"
+)
+
+type MfaHandler struct {
+	MfaUC usecases.MfaUsecase
+}
+
+// NewMfaHandler will initialize the second factor endpoints of the current user
+func NewMfaHandler(g *echo.Group, middManager *middlewares.MiddlewareManager, mfaUsecase usecases.MfaUsecase) {
+	handler := &MfaHandler{
+		MfaUC: mfaUsecase,
+	}
+
+	apiV1 := g.Group("me/mfa", middManager.Auth(), middManager.CheckPolicies())
+	apiV1.GET("", wrapper.Wrap(handler.GetStatus)).Name = "read:mfa"
+	apiV1.POST("", wrapper.Wrap(handler.Enroll)).Name = "create:mfa"
+	apiV1.DELETE("", wrapper.Wrap(handler.Disable)).Name = "delete:mfa"
+	apiV1.POST("/confirm", wrapper.Wrap(handler.Confirm)).Name = "confirm:mfa"
+	apiV1.POST("/verify", wrapper.Wrap(handler.StepUp)).Name = "verify:mfa"
+	apiV1.POST("/recovery-codes", wrapper.Wrap(handler.RegenerateRecoveryCodes)).Name = "update:mfa"
+}
+
+// GetMfaStatus godoc
+// @Summary      Get mfa status
+// @Description  Tell whether the current user has a confirmed second factor and how many recovery codes are left
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  wrapper.SuccessResponse{data=dto.MfaStatusResp}
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /me/mfa [get]
+func (h *MfaHandler) GetStatus(c echo.Context) wrapper.Response {
+	user, _ := contexts.GetUserFromContext(c)
+	status, err := h.MfaUC.GetStatus(c.Request().Context(), user.Id)
+	if err != nil {
+		return mfaError(err, "get mfa status")
+	}
+	return wrapper.Response{Data: status}
+}
+
+// EnrollMfa godoc
+// @Summary      Enroll a second factor
+// @Description  Generate a TOTP secret for the authenticator app, it is enabled once confirmed with a first code
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  wrapper.SuccessResponse{data=dto.MfaEnrollmentResp}
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      409  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /me/mfa [post]
+func (h *MfaHandler) Enroll(c echo.Context) wrapper.Response {
+	user, _ := contexts.GetUserFromContext(c)
+	enrollment, err := h.MfaUC.Enroll(c.Request().Context(), &user.User)
+	if err != nil {
+		return mfaError(err, "enroll mfa")
+	}
+	return wrapper.Response{Data: enrollment, Status: http.StatusCreated}
+}
+
+// ConfirmMfa godoc
+// @Summary      Confirm a second factor
+// @Description  Enable the enrolled secret with a first code, the recovery codes are only returned once
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.MfaCodeReq  true  "TOTP code"
+// @Success      200  {object}  wrapper.SuccessResponse{data=dto.MfaRecoveryCodesResp}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      409  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /me/mfa/confirm [post]
+func (h *MfaHandler) Confirm(c echo.Context) wrapper.Response {
+	var req dto.MfaCodeReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	principal, _ := contexts.GetPrincipalFromContext(c)
+	codes, err := h.MfaUC.Confirm(c.Request().Context(), principal.User.Id, principal.SessionId, req.Code)
+	if err != nil {
+		return mfaError(err, "confirm mfa")
+	}
+	return wrapper.Response{Data: codes}
+}
+
+// VerifyMfa godoc
+// @Summary      Step up with a second factor
+// @Description  Verify a TOTP or recovery code and mark the current session as verified, required by orgs with require_mfa
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.MfaCodeReq  true  "TOTP or recovery code"
+// @Success      200  {object}  wrapper.SuccessResponse
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /me/mfa/verify [post]
+func (h *MfaHandler) StepUp(c echo.Context) wrapper.Response {
+	var req dto.MfaCodeReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	principal, _ := contexts.GetPrincipalFromContext(c)
+	if err := h.MfaUC.StepUp(c.Request().Context(), principal.User.Id, principal.SessionId, req); err != nil {
+		return mfaError(err, "verify mfa")
+	}
+	return wrapper.Response{}
+}
+
+// RegenerateMfaRecoveryCodes godoc
+// @Summary      Regenerate recovery codes
+// @Description  Replace all recovery codes of the current user, the new codes are only returned once
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.MfaCodeReq  true  "TOTP or recovery code"
+// @Success      200  {object}  wrapper.SuccessResponse{data=dto.MfaRecoveryCodesResp}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /me/mfa/recovery-codes [post]
+func (h *MfaHandler) RegenerateRecoveryCodes(c echo.Context) wrapper.Response {
+	var req dto.MfaCodeReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	user, _ := contexts.GetUserFromContext(c)
+	codes, err := h.MfaUC.RegenerateRecoveryCodes(c.Request().Context(), user.Id, req)
+	if err != nil {
+		return mfaError(err, "regenerate mfa recovery codes")
+	}
+	return wrapper.Response{Data: codes}
+}
+
+// DisableMfa godoc
+// @Summary      Disable the second factor
+// @Description  Remove the second factor and the recovery codes of the current user
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.MfaCodeReq  true  "TOTP or recovery code"
+// @Success      200  {object}  wrapper.SuccessResponse
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /me/mfa [delete]
+func (h *MfaHandler) Disable(c echo.Context) wrapper.Response {
+	var req dto.MfaCodeReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	user, _ := contexts.GetUserFromContext(c)
+	if err := h.MfaUC.Disable(c.Request().Context(), user.Id, req); err != nil {
+		return mfaError(err, "disable mfa")
+	}
+	return wrapper.Response{}
+}
+
+func mfaError(err error, action string) wrapper.Response {
+	switch {
+	case errors.Is(err, usecases.ErrMfaAlreadyEnabled):
+		return wrapper.Response{
+			Status: http.StatusConflict,
+			Error:  utils.NewError(err, ""),
+		}
+	case errors.Is(err, usecases.ErrInvalidMfaCode),
+		errors.Is(err, usecases.ErrMfaNotEnabled),
+		errors.Is(err, usecases.ErrMfaNotEnrolled),
+		errors.Is(err, usecases.ErrMfaSessionRequired):
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	logger.Log().Errorw("error while "+action, "error", err)
+	return wrapper.Response{
+		Status: http.StatusInternalServerError,
+		Error:  utils.NewError(err, ""),
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -171,7 +172,8 @@ func (h *OrgHandler) Fetch(c echo.Context) wrapper.Response {
 
 // UpdateOrgInfo godoc
 // @Summary      Update org info
-// @Description  Update organization by ID
+// @Description  Update organization by ID. The timezone, IP allowlist and access hours restrict access of the members,
+// @Description  super admins are not restricted. require_mfa is refused when the local auth provider is not enabled
 // @Tags         orgs
 // @Accept       json
 // @Produce      json
@@ -206,6 +208,13 @@ func (h *OrgHandler) Update(c echo.Context) wrapper.Response {
 			}
 		}
 
+		if errors.Is(err, usecases.ErrInvalidOrgAccessSettings) {
+			return wrapper.Response{
+				Status: http.StatusBadRequest,
+				Error:  utils.NewError(err, err.Error()),
+			}
+		}
+
 		if err == sql.ErrNoRows {
 			return wrapper.Response{
 				Status: http.StatusNotFound,
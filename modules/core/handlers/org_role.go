@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/dzungtran/echo-rest-api/pkg/middlewares"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/dzungtran/echo-rest-api/pkg/wrapper"
+	"github.com/labstack/echo/v4"
+)
+
+type OrgRoleHandler struct {
+	OrgRoleUC usecases.OrgRoleUsecase
+}
+
+// NewOrgRoleHandler will initialize the custom org roles and member role endpoints
+func NewOrgRoleHandler(g *echo.Group, middManager *middlewares.MiddlewareManager, orgRoleUsecase usecases.OrgRoleUsecase) {
+	handler := &OrgRoleHandler{
+		OrgRoleUC: orgRoleUsecase,
+	}
+
+	apiV1 := g.Group("admin/orgs/:orgId/roles",
+		middManager.Auth(),
+		middlewares.RequireResourceIdInParam("orgId"),
+		middManager.CheckPoliciesWithOrg(),
+	)
+	apiV1.GET("", wrapper.Wrap(handler.Fetch)).Name = "list:org_role"
+	apiV1.POST("", wrapper.Wrap(handler.Create)).Name = "create:org_role"
+
+	apiV1Resource := apiV1.Group("/:roleId", middlewares.RequireResourceIdInParam("roleId"))
+	apiV1Resource.PUT("", wrapper.Wrap(handler.Update)).Name = "update:org_role"
+	apiV1Resource.DELETE("", wrapper.Wrap(handler.Delete)).Name = "delete:org_role"
+
+	apiV1Members := g.Group("admin/orgs/:orgId/members/:userId",
+		middManager.Auth(),
+		middlewares.RequireResourceIdInParam("orgId"),
+		middlewares.RequireResourceIdInParam("userId"),
+		middManager.CheckPoliciesWithOrg(),
+	)
+	apiV1Members.PUT("", wrapper.Wrap(handler.AssignMember)).Name = "update:member"
+}
+
+// GetListOrgRoles godoc
+// @Summary      Get list custom roles of an org
+// @Description  Get the roles the org defines on top of owner, manager, editor, viewer and guest
+// @Tags         org-roles
+// @Accept       json
+// @Produce      json
+// @Param        orgId   path      int  true  "Org ID"
+// @Success      200  {object}  wrapper.SuccessResponse{data=[]domains.OrgCustomRole}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/orgs/{orgId}/roles [get]
+func (h *OrgRoleHandler) Fetch(c echo.Context) wrapper.Response {
+	roles, err := h.OrgRoleUC.Fetch(c.Request().Context(), utils.GetResourceIdFromParam(c, "orgId"))
+	if err != nil {
+		return orgRoleError(err, "fetch org roles")
+	}
+	return wrapper.Response{Data: roles}
+}
+
+// CreateOrgRole godoc
+// @Summary      Create a custom role
+// @Description  Create a role with permissions taken from the routes and an optional parent role,
+// @Description  it cannot grant a permission the caller does not hold
+// @Tags         org-roles
+// @Accept       json
+// @Produce      json
+// @Param        orgId   path      int  true  "Org ID"
+// @Param        body    body      dto.CreateOrgRoleReq  true  "Custom role"
+// @Success      201  {object}  wrapper.SuccessResponse{data=domains.OrgCustomRole}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      409  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/orgs/{orgId}/roles [post]
+func (h *OrgRoleHandler) Create(c echo.Context) wrapper.Response {
+	var req dto.CreateOrgRoleReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	principal, _ := contexts.GetPrincipalFromContext(c)
+	role, err := h.OrgRoleUC.Create(c.Request().Context(), principal, req)
+	if err != nil {
+		if utils.IsCueError(err) {
+			logger.Log().Debugw("invalid create org role request", "error", err)
+			return wrapper.Response{
+				Status: http.StatusBadRequest,
+				Error:  utils.NewError(err, "invalid payload"),
+			}
+		}
+		return orgRoleError(err, "create org role")
+	}
+
+	return wrapper.Response{Status: http.StatusCreated, Data: role}
+}
+
+// UpdateOrgRole godoc
+// @Summary      Update a custom role
+// @Description  Update the description, permissions and parent of a role, it cannot be renamed
+// @Tags         org-roles
+// @Accept       json
+// @Produce      json
+// @Param        orgId   path      int  true  "Org ID"
+// @Param        roleId  path      int  true  "Role ID"
+// @Param        body    body      dto.UpdateOrgRoleReq  true  "Custom role"
+// @Success      200  {object}  wrapper.SuccessResponse{data=domains.OrgCustomRole}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      404  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/orgs/{orgId}/roles/{roleId} [put]
+func (h *OrgRoleHandler) Update(c echo.Context) wrapper.Response {
+	var req dto.UpdateOrgRoleReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	principal, _ := contexts.GetPrincipalFromContext(c)
+	role, err := h.OrgRoleUC.Update(c.Request().Context(), principal, req)
+	if err != nil {
+		if utils.IsCueError(err) {
+			logger.Log().Debugw("invalid update org role request", "error", err)
+			return wrapper.Response{
+				Status: http.StatusBadRequest,
+				Error:  utils.NewError(err, "invalid payload"),
+			}
+		}
+		return orgRoleError(err, "update org role")
+	}
+
+	return wrapper.Response{Data: role}
+}
+
+// DeleteOrgRole godoc
+// @Summary      Delete a custom role
+// @Description  Delete a role no member holds and no other role inherits from
+// @Tags         org-roles
+// @Accept       json
+// @Produce      json
+// @Param        orgId   path      int  true  "Org ID"
+// @Param        roleId  path      int  true  "Role ID"
+// @Success      200  {object}  wrapper.SuccessResponse{}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      404  {object}  wrapper.FailResponse
+// @Failure      409  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/orgs/{orgId}/roles/{roleId} [delete]
+func (h *OrgRoleHandler) Delete(c echo.Context) wrapper.Response {
+	err := h.OrgRoleUC.Delete(c.Request().Context(),
+		utils.GetResourceIdFromParam(c, "orgId"),
+		utils.GetResourceIdFromParam(c, "roleId"),
+	)
+	if err != nil {
+		return orgRoleError(err, "delete org role")
+	}
+	return wrapper.Response{}
+}
+
+// UpdateMemberRole godoc
+// @Summary      Change the role of a member
+// @Description  Assign a built-in or custom role to a member, it cannot grant a permission the caller does not hold
+// @Tags         org-roles
+// @Accept       json
+// @Produce      json
+// @Param        orgId   path      int  true  "Org ID"
+// @Param        userId  path      int  true  "User ID"
+// @Param        body    body      dto.UpdateMemberRoleReq  true  "Role"
+// @Success      200  {object}  wrapper.SuccessResponse{data=domains.UserOrg}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      404  {object}  wrapper.FailResponse
+// @Failure      409  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/orgs/{orgId}/members/{userId} [put]
+func (h *OrgRoleHandler) AssignMember(c echo.Context) wrapper.Response {
+	var req dto.UpdateMemberRoleReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	principal, _ := contexts.GetPrincipalFromContext(c)
+	member, err := h.OrgRoleUC.AssignMember(c.Request().Context(), principal, req)
+	if err != nil {
+		if utils.IsCueError(err) {
+			logger.Log().Debugw("invalid update member role request", "error", err)
+			return wrapper.Response{
+				Status: http.StatusBadRequest,
+				Error:  utils.NewError(err, "invalid payload"),
+			}
+		}
+		return orgRoleError(err, "update member role")
+	}
+
+	logger.Log().Infow("member role changed", "org_id", member.OrgId, "user_id", member.UserId, "role", member.Role)
+	return wrapper.Response{Data: member}
+}
+
+func orgRoleError(err error, action string) wrapper.Response {
+	switch {
+	case errors.Is(err, constants.ErrNotFound):
+		return wrapper.Response{
+			Status: http.StatusNotFound,
+			Error:  utils.NewNotFoundError(),
+		}
+	case errors.Is(err, usecases.ErrUnknownPermission),
+		errors.Is(err, usecases.ErrUnknownRole),
+		errors.Is(err, usecases.ErrBuiltinRoleName),
+		errors.Is(err, usecases.ErrRoleCycle):
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	case errors.Is(err, usecases.ErrRoleEscalation):
+		return wrapper.Response{
+			Status: http.StatusForbidden,
+			Error:  utils.NewError(err, ""),
+		}
+	case errors.Is(err, constants.ErrDuplicated),
+		errors.Is(err, usecases.ErrRoleInUse),
+		errors.Is(err, usecases.ErrLastOrgOwner):
+		return wrapper.Response{
+			Status: http.StatusConflict,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	logger.Log().Errorw("error while "+action, "error", err)
+	return wrapper.Response{
+		Status: http.StatusInternalServerError,
+		Error:  utils.NewError(err, ""),
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/authz"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/dzungtran/echo-rest-api/pkg/middlewares"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/dzungtran/echo-rest-api/pkg/wrapper"
+	"github.com/labstack/echo/v4"
+)
+
+type PermissionHandler struct {
+	PermissionUC usecases.PermissionUsecase
+}
+
+// NewPermissionHandler will initialize the effective permissions endpoints of the current user
+func NewPermissionHandler(g *echo.Group, middManager *middlewares.MiddlewareManager, permissionUsecase usecases.PermissionUsecase) {
+	handler := &PermissionHandler{
+		PermissionUC: permissionUsecase,
+	}
+
+	apiV1 := g.Group("me/permissions", middManager.Auth(), middManager.CheckPolicies())
+	apiV1.GET("", wrapper.Wrap(handler.Fetch)).Name = "list:permission"
+	apiV1.POST("/check", wrapper.Wrap(handler.Check)).Name = "check:permission"
+}
+
+// GetMyPermissions godoc
+// @Summary      Get permissions of current user
+// @Description  Get the role of the current user in the org and the permissions it holds, inherited, platform and
+// @Description  resource ones included. Requests can still be denied by deny rules, check them with POST /me/permissions/check
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        org_id  query     int  false  "Org ID, only the platform permissions are returned without it"
+// @Success      200  {object}  wrapper.SuccessResponse{data=authz.EffectivePermissions}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      404  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /me/permissions [get]
+func (h *PermissionHandler) Fetch(c echo.Context) wrapper.Response {
+	var req dto.GetPermissionsReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	principal, _ := contexts.GetPrincipalFromContext(c)
+	perms, err := h.PermissionUC.Fetch(c.Request().Context(), principal, req.OrgId)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return wrapper.Response{
+				Status: http.StatusNotFound,
+				Error:  utils.NewNotFoundError(),
+			}
+		}
+
+		logger.Log().Errorw("error while fetch permissions", "error", err)
+		return wrapper.Response{
+			Status: http.StatusInternalServerError,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+	return wrapper.Response{Data: perms}
+}
+
+// CheckMyPermissions godoc
+// @Summary      Check requests for current user
+// @Description  Tell whether the current user may make each request of the batch, up to 100 per call
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.CheckPermissionsReq  true  "Requests to check"
+// @Success      200  {object}  wrapper.SuccessResponse{data=[]dto.PermissionCheckResp}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /me/permissions/check [post]
+func (h *PermissionHandler) Check(c echo.Context) wrapper.Response {
+	var req dto.CheckPermissionsReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	principal, _ := contexts.GetPrincipalFromContext(c)
+	results, err := h.PermissionUC.Check(c.Request().Context(), principal, authz.RequestAttributesFromContext(c), req)
+	if err != nil {
+		if utils.IsCueError(err) {
+			logger.Log().Debugw("invalid check permissions request", "error", err)
+			return wrapper.Response{
+				Status: http.StatusBadRequest,
+				Error:  utils.NewError(err, "invalid payload"),
+			}
+		}
+
+		logger.Log().Errorw("error while check permissions", "error", err)
+		return wrapper.Response{
+			Status: http.StatusInternalServerError,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+	return wrapper.Response{Data: results}
+}
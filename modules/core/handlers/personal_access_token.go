@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/dzungtran/echo-rest-api/pkg/middlewares"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/dzungtran/echo-rest-api/pkg/wrapper"
+	"github.com/labstack/echo/v4"
+)
+
+type PersonalAccessTokenHandler struct {
+	TokenUC usecases.PersonalAccessTokenUsecase
+}
+
+// NewPersonalAccessTokenHandler will initialize the personal access token endpoints of the current user
+func NewPersonalAccessTokenHandler(g *echo.Group, middManager *middlewares.MiddlewareManager, tokenUsecase usecases.PersonalAccessTokenUsecase) {
+	handler := &PersonalAccessTokenHandler{
+		TokenUC: tokenUsecase,
+	}
+
+	apiV1 := g.Group("me/tokens", middManager.Auth(), middManager.CheckPolicies())
+	apiV1.GET("", wrapper.Wrap(handler.Fetch)).Name = "list:token"
+	apiV1.POST("", wrapper.Wrap(handler.Create)).Name = "create:token"
+	apiV1.DELETE("/:tokenId", wrapper.Wrap(handler.Revoke), middlewares.RequireResourceIdInParam("tokenId")).Name = "delete:token"
+}
+
+// CreatePersonalAccessToken godoc
+// @Summary      Create a personal access token
+// @Description  Create a long-lived token for the current user, the token is only returned once
+// @Tags         tokens
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.CreatePersonalAccessTokenReq  true  "Token creation request"
+// @Success      201  {object}  wrapper.SuccessResponse{data=dto.PersonalAccessTokenCreatedResp}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /me/tokens [post]
+func (h *PersonalAccessTokenHandler) Create(c echo.Context) wrapper.Response {
+	ctx := c.Request().Context()
+	var req dto.CreatePersonalAccessTokenReq
+
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	user, _ := contexts.GetUserFromContext(c)
+	token, err := h.TokenUC.Create(ctx, user.Id, req)
+	if err != nil {
+		if utils.IsCueError(err) {
+			logger.Log().Debugw("invalid create token request", "error", err)
+			return wrapper.Response{
+				Status: http.StatusBadRequest,
+				Error:  utils.NewError(err, "invalid payload"),
+			}
+		}
+
+		if errors.Is(err, usecases.ErrUnknownPermission) ||
+			errors.Is(err, usecases.ErrOrgNotJoined) ||
+			errors.Is(err, usecases.ErrExpiresAtInThePast) {
+			return wrapper.Response{
+				Status: http.StatusBadRequest,
+				Error:  utils.NewError(err, ""),
+			}
+		}
+
+		logger.Log().Errorw("error while create personal access token", "error", err)
+		return wrapper.Response{
+			Status: http.StatusInternalServerError,
+			Error:  utils.NewError(err, "internal server error"),
+		}
+	}
+
+	return wrapper.Response{Status: http.StatusCreated, Data: token}
+}
+
+// GetListPersonalAccessTokens godoc
+// @Summary      Get list personal access tokens
+// @Description  Get the active personal access tokens of the current user
+// @Tags         tokens
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  wrapper.SuccessResponse{data=[]domains.PersonalAccessToken}
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /me/tokens [get]
+func (h *PersonalAccessTokenHandler) Fetch(c echo.Context) wrapper.Response {
+	ctx := c.Request().Context()
+
+	user, _ := contexts.GetUserFromContext(c)
+	tokens, count, err := h.TokenUC.Fetch(ctx, user.Id)
+	if err != nil {
+		return wrapper.Response{
+			Error:  err,
+			Status: http.StatusInternalServerError,
+		}
+	}
+
+	return wrapper.Response{
+		Data:         tokens,
+		Total:        count,
+		IncludeTotal: true,
+	}
+}
+
+// RevokePersonalAccessToken godoc
+// @Summary      Revoke a personal access token
+// @Description  Revoke a personal access token of the current user
+// @Tags         tokens
+// @Accept       json
+// @Produce      json
+// @Param        tokenId   path      int  true  "Token ID"
+// @Success      200  {object}  wrapper.SuccessResponse{}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      404  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /me/tokens/{tokenId} [delete]
+func (h *PersonalAccessTokenHandler) Revoke(c echo.Context) wrapper.Response {
+	ctx := c.Request().Context()
+
+	user, _ := contexts.GetUserFromContext(c)
+	err := h.TokenUC.Revoke(ctx, user.Id, utils.GetResourceIdFromParam(c, "tokenId"))
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return wrapper.Response{
+				Status: http.StatusNotFound,
+				Error:  utils.NewNotFoundError(),
+			}
+		}
+		return wrapper.Response{
+			Status: http.StatusInternalServerError,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	return wrapper.Response{}
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/dzungtran/echo-rest-api/pkg/middlewares"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/dzungtran/echo-rest-api/pkg/wrapper"
+	"github.com/labstack/echo/v4"
+)
+
+type PlatformRoleHandler struct {
+	PlatformRoleUC usecases.PlatformRoleUsecase
+}
+
+// NewPlatformRoleHandler will initialize the platform role endpoints, super admin only
+func NewPlatformRoleHandler(g *echo.Group, middManager *middlewares.MiddlewareManager, platformRoleUsecase usecases.PlatformRoleUsecase) {
+	handler := &PlatformRoleHandler{
+		PlatformRoleUC: platformRoleUsecase,
+	}
+
+	apiV1 := g.Group("admin/users/:userId/platform-roles",
+		middManager.Auth(),
+		middlewares.RequireResourceIdInParam("userId"),
+		middManager.CheckPolicies(),
+	)
+	apiV1.GET("", wrapper.Wrap(handler.Fetch)).Name = "list:platform_role"
+	apiV1.POST("", wrapper.Wrap(handler.Grant)).Name = "grant:platform_role"
+	apiV1.DELETE("/:role", wrapper.Wrap(handler.Revoke)).Name = "revoke:platform_role"
+}
+
+// GetListPlatformRoles godoc
+// @Summary      Get platform roles of a user
+// @Description  Get the roles granted to a user over the whole platform
+// @Tags         platform-roles
+// @Accept       json
+// @Produce      json
+// @Param        userId   path      int  true  "User ID"
+// @Success      200  {object}  wrapper.SuccessResponse{data=[]domains.UserPlatformRole}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/users/{userId}/platform-roles [get]
+func (h *PlatformRoleHandler) Fetch(c echo.Context) wrapper.Response {
+	roles, err := h.PlatformRoleUC.FetchByUserId(c.Request().Context(), utils.GetResourceIdFromParam(c, "userId"))
+	if err != nil {
+		return platformRoleError(err, "fetch platform roles")
+	}
+	return wrapper.Response{Data: roles}
+}
+
+// GrantPlatformRole godoc
+// @Summary      Grant a platform role
+// @Description  Grant super_admin, support or billing_admin to a user
+// @Tags         platform-roles
+// @Accept       json
+// @Produce      json
+// @Param        userId   path      int  true  "User ID"
+// @Param        body  body      dto.GrantPlatformRoleReq  true  "Platform role"
+// @Success      201  {object}  wrapper.SuccessResponse{data=domains.UserPlatformRole}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      404  {object}  wrapper.FailResponse
+// @Failure      409  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/users/{userId}/platform-roles [post]
+func (h *PlatformRoleHandler) Grant(c echo.Context) wrapper.Response {
+	var req dto.GrantPlatformRoleReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	user, _ := contexts.GetUserFromContext(c)
+	role, err := h.PlatformRoleUC.Grant(c.Request().Context(), user.Id, req)
+	if err != nil {
+		if utils.IsCueError(err) {
+			logger.Log().Debugw("invalid grant platform role request", "error", err)
+			return wrapper.Response{
+				Status: http.StatusBadRequest,
+				Error:  utils.NewError(err, "invalid payload"),
+			}
+		}
+		return platformRoleError(err, "grant platform role")
+	}
+
+	logger.Log().Infow("platform role granted", "user_id", role.UserId, "role", role.Role, "granted_by", user.Id)
+	return wrapper.Response{Status: http.StatusCreated, Data: role}
+}
+
+// RevokePlatformRole godoc
+// @Summary      Revoke a platform role
+// @Description  Revoke a platform role of a user, the last super admin cannot be revoked
+// @Tags         platform-roles
+// @Accept       json
+// @Produce      json
+// @Param        userId   path      int  true  "User ID"
+// @Param        role     path      string  true  "Platform role"
+// @Success      200  {object}  wrapper.SuccessResponse{}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      404  {object}  wrapper.FailResponse
+// @Failure      409  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/users/{userId}/platform-roles/{role} [delete]
+func (h *PlatformRoleHandler) Revoke(c echo.Context) wrapper.Response {
+	userId := utils.GetResourceIdFromParam(c, "userId")
+	role := domains.PlatformRole(c.Param("role"))
+	if err := h.PlatformRoleUC.Revoke(c.Request().Context(), userId, role); err != nil {
+		return platformRoleError(err, "revoke platform role")
+	}
+
+	user, _ := contexts.GetUserFromContext(c)
+	logger.Log().Infow("platform role revoked", "user_id", userId, "role", role, "revoked_by", user.Id)
+	return wrapper.Response{}
+}
+
+func platformRoleError(err error, action string) wrapper.Response {
+	switch {
+	case errors.Is(err, constants.ErrNotFound):
+		return wrapper.Response{
+			Status: http.StatusNotFound,
+			Error:  utils.NewNotFoundError(),
+		}
+	case errors.Is(err, constants.ErrDuplicated),
+		errors.Is(err, usecases.ErrLastSuperAdmin):
+		return wrapper.Response{
+			Status: http.StatusConflict,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	logger.Log().Errorw("error while "+action, "error", err)
+	return wrapper.Response{
+		Status: http.StatusInternalServerError,
+		Error:  utils.NewError(err, ""),
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"github.com/dzungtran/echo-rest-api/pkg/authz"
+	"github.com/dzungtran/echo-rest-api/pkg/middlewares"
+	"github.com/dzungtran/echo-rest-api/pkg/wrapper"
+	"github.com/labstack/echo/v4"
+)
+
+type PolicyHandler struct {
+	PolicyAdmin authz.PolicyAdmin
+}
+
+// NewPolicyHandler will initialize the policy endpoints, super admin only
+func NewPolicyHandler(g *echo.Group, middManager *middlewares.MiddlewareManager, policyAdmin authz.PolicyAdmin) {
+	handler := &PolicyHandler{
+		PolicyAdmin: policyAdmin,
+	}
+
+	apiV1 := g.Group("admin/policies", middManager.Auth(), middManager.CheckPolicies())
+	apiV1.GET("", wrapper.Wrap(handler.GetStatus)).Name = "read:policy"
+}
+
+// GetPolicyStatus godoc
+// @Summary      Get the active policies
+// @Description  Get the revision of the policies deciding the requests, and the error of the last rejected reload
+// @Tags         policies
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  wrapper.SuccessResponse{data=authz.PolicyStatus}
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/policies [get]
+func (h *PolicyHandler) GetStatus(c echo.Context) wrapper.Response {
+	return wrapper.Response{Data: h.PolicyAdmin.PolicyStatus()}
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/dzungtran/echo-rest-api/pkg/middlewares"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/dzungtran/echo-rest-api/pkg/wrapper"
+	"github.com/labstack/echo/v4"
+)
+
+type ResourceGrantHandler struct {
+	ResourceGrantUC usecases.ResourceGrantUsecase
+}
+
+// NewResourceGrantHandler will initialize the resource grants endpoints of an org
+func NewResourceGrantHandler(g *echo.Group, middManager *middlewares.MiddlewareManager, resourceGrantUsecase usecases.ResourceGrantUsecase) {
+	handler := &ResourceGrantHandler{
+		ResourceGrantUC: resourceGrantUsecase,
+	}
+
+	apiV1 := g.Group("admin/orgs/:orgId/grants",
+		middManager.Auth(),
+		middlewares.RequireResourceIdInParam("orgId"),
+		middManager.CheckPoliciesWithOrg(),
+	)
+	apiV1.GET("", wrapper.Wrap(handler.Fetch)).Name = "list:resource_grant"
+	apiV1.POST("", wrapper.Wrap(handler.Create)).Name = "create:resource_grant"
+	apiV1.DELETE("/:grantId", wrapper.Wrap(handler.Delete), middlewares.RequireResourceIdInParam("grantId")).Name = "delete:resource_grant"
+}
+
+// GetListResourceGrants godoc
+// @Summary      Get list resource grants of an org
+// @Description  Get the permissions granted to users on single resources of the org
+// @Tags         resource-grants
+// @Accept       json
+// @Produce      json
+// @Param        orgId          path      int     true   "Org ID"
+// @Param        user_id        query     int     false  "Filter by user ID"
+// @Param        resource_type  query     string  false  "Filter by resource type, org or project"
+// @Param        resource_id    query     int     false  "Filter by resource ID"
+// @Success      200  {object}  wrapper.SuccessResponse{data=[]domains.ResourceGrant}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/orgs/{orgId}/grants [get]
+func (h *ResourceGrantHandler) Fetch(c echo.Context) wrapper.Response {
+	var req dto.SearchResourceGrantsReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	grants, err := h.ResourceGrantUC.Fetch(c.Request().Context(), req)
+	if err != nil {
+		return resourceGrantError(err, "fetch resource grants")
+	}
+	return wrapper.Response{Data: grants}
+}
+
+// CreateResourceGrant godoc
+// @Summary      Grant a permission on a resource
+// @Description  Let a user, member of the org or not, use a permission on the org or one of its projects.
+// @Description  It cannot grant a permission the caller does not hold
+// @Tags         resource-grants
+// @Accept       json
+// @Produce      json
+// @Param        orgId   path      int  true  "Org ID"
+// @Param        body    body      dto.CreateResourceGrantReq  true  "Grant"
+// @Success      201  {object}  wrapper.SuccessResponse{data=domains.ResourceGrant}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      404  {object}  wrapper.FailResponse
+// @Failure      409  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/orgs/{orgId}/grants [post]
+func (h *ResourceGrantHandler) Create(c echo.Context) wrapper.Response {
+	var req dto.CreateResourceGrantReq
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	principal, _ := contexts.GetPrincipalFromContext(c)
+	grant, err := h.ResourceGrantUC.Create(c.Request().Context(), principal, req)
+	if err != nil {
+		if utils.IsCueError(err) {
+			logger.Log().Debugw("invalid create resource grant request", "error", err)
+			return wrapper.Response{
+				Status: http.StatusBadRequest,
+				Error:  utils.NewError(err, "invalid payload"),
+			}
+		}
+		return resourceGrantError(err, "create resource grant")
+	}
+
+	logger.Log().Infow("resource permission granted", "org_id", grant.OrgId, "user_id", grant.UserId,
+		"permission", grant.Permission, "resource_type", grant.ResourceType, "resource_id", grant.ResourceId)
+	return wrapper.Response{Status: http.StatusCreated, Data: grant}
+}
+
+// DeleteResourceGrant godoc
+// @Summary      Revoke a resource grant
+// @Description  Revoke a permission granted on a resource of the org
+// @Tags         resource-grants
+// @Accept       json
+// @Produce      json
+// @Param        orgId    path      int  true  "Org ID"
+// @Param        grantId  path      int  true  "Grant ID"
+// @Success      200  {object}  wrapper.SuccessResponse{}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      404  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/orgs/{orgId}/grants/{grantId} [delete]
+func (h *ResourceGrantHandler) Delete(c echo.Context) wrapper.Response {
+	err := h.ResourceGrantUC.Delete(c.Request().Context(),
+		utils.GetResourceIdFromParam(c, "orgId"),
+		utils.GetResourceIdFromParam(c, "grantId"),
+	)
+	if err != nil {
+		return resourceGrantError(err, "delete resource grant")
+	}
+	return wrapper.Response{}
+}
+
+func resourceGrantError(err error, action string) wrapper.Response {
+	switch {
+	case errors.Is(err, constants.ErrNotFound):
+		return wrapper.Response{
+			Status: http.StatusNotFound,
+			Error:  utils.NewNotFoundError(),
+		}
+	case errors.Is(err, usecases.ErrUnknownPermission),
+		errors.Is(err, usecases.ErrGrantResource):
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	case errors.Is(err, usecases.ErrGrantEscalation):
+		return wrapper.Response{
+			Status: http.StatusForbidden,
+			Error:  utils.NewError(err, ""),
+		}
+	case errors.Is(err, constants.ErrDuplicated):
+		return wrapper.Response{
+			Status: http.StatusConflict,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	logger.Log().Errorw("error while "+action, "error", err)
+	return wrapper.Response{
+		Status: http.StatusInternalServerError,
+		Error:  utils.NewError(err, ""),
+	}
+}
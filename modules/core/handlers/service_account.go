@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/dzungtran/echo-rest-api/pkg/middlewares"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/dzungtran/echo-rest-api/pkg/wrapper"
+	"github.com/labstack/echo/v4"
+)
+
+type ServiceAccountHandler struct {
+	ServiceAccountUC usecases.ServiceAccountUsecase
+}
+
+// NewServiceAccountHandler will initialize the service account resources endpoint
+func NewServiceAccountHandler(g *echo.Group, middManager *middlewares.MiddlewareManager, saUsecase usecases.ServiceAccountUsecase) {
+	handler := &ServiceAccountHandler{
+		ServiceAccountUC: saUsecase,
+	}
+
+	apiAuth := g.Group("auth")
+	apiAuth.POST("/service-accounts/token", handler.IssueToken).Name = "public:service_account_token"
+
+	apiV1 := g.Group("admin/orgs/:orgId/service-accounts",
+		middManager.Auth(),
+		middlewares.RequireResourceIdInParam("orgId"),
+		middManager.CheckPoliciesWithOrg(),
+	)
+	apiV1.GET("", wrapper.Wrap(handler.Fetch)).Name = "list:service_account"
+	apiV1.POST("", wrapper.Wrap(handler.Create)).Name = "create:service_account"
+
+	apiV1Resource := apiV1.Group("/:serviceAccountId", middlewares.RequireResourceIdInParam("serviceAccountId"))
+	apiV1Resource.PUT("", wrapper.Wrap(handler.Update)).Name = "update:service_account"
+	apiV1Resource.DELETE("", wrapper.Wrap(handler.Delete)).Name = "delete:service_account"
+	apiV1Resource.POST("/secret", wrapper.Wrap(handler.RotateSecret)).Name = "update:service_account"
+}
+
+// IssueServiceAccountToken godoc
+// @Summary      Issue a service account token
+// @Description  Exchange the client id and secret of a service account for a short-lived access token
+// @Tags         service-accounts
+// @Accept       json,x-www-form-urlencoded
+// @Produce      json
+// @Param        body  body      dto.ServiceAccountTokenReq  true  "Client credentials"
+// @Success      200  {object}  dto.ServiceAccountTokenResp
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /auth/service-accounts/token [post]
+func (h *ServiceAccountHandler) IssueToken(c echo.Context) error {
+	var req dto.ServiceAccountTokenReq
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid_request",
+		})
+	}
+
+	resp, err := h.ServiceAccountUC.IssueToken(c.Request().Context(), req)
+	if err != nil {
+		if errors.Is(err, constants.ErrUnauthorized) {
+			return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+				"error": "invalid_client",
+			})
+		}
+
+		logger.Log().Errorw("error while issue service account token", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "server_error",
+		})
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, resp)
+}
+
+// CreateServiceAccount godoc
+// @Summary      Create a service account
+// @Description  Create a service account in the org, the client secret is only returned once
+// @Tags         service-accounts
+// @Accept       json
+// @Produce      json
+// @Param        orgId   path      int  true  "Org ID"
+// @Param        body    body      dto.CreateServiceAccountReq  true  "Service account creation request"
+// @Success      201  {object}  wrapper.SuccessResponse{data=dto.ServiceAccountCreatedResp}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/orgs/{orgId}/service-accounts [post]
+func (h *ServiceAccountHandler) Create(c echo.Context) wrapper.Response {
+	ctx := c.Request().Context()
+	var req dto.CreateServiceAccountReq
+
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	sa, err := h.ServiceAccountUC.Create(ctx, req)
+	if err != nil {
+		if utils.IsCueError(err) {
+			logger.Log().Debugw("invalid create service account request", "error", err)
+			return wrapper.Response{
+				Status: http.StatusBadRequest,
+				Error:  utils.NewError(err, "invalid payload"),
+			}
+		}
+
+		logger.Log().Errorw("error while create service account", "error", err)
+		return wrapper.Response{
+			Status: http.StatusInternalServerError,
+			Error:  utils.NewError(err, "internal server error"),
+		}
+	}
+
+	return wrapper.Response{Status: http.StatusCreated, Data: sa}
+}
+
+// GetListServiceAccounts godoc
+// @Summary      Get list service accounts
+// @Description  Get the service accounts of the org
+// @Tags         service-accounts
+// @Accept       json
+// @Produce      json
+// @Param        orgId   path      int  true  "Org ID"
+// @Success      200  {object}  wrapper.SuccessResponse{data=[]domains.ServiceAccount}
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      404  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/orgs/{orgId}/service-accounts [get]
+func (h *ServiceAccountHandler) Fetch(c echo.Context) wrapper.Response {
+	ctx := c.Request().Context()
+
+	sas, count, err := h.ServiceAccountUC.Fetch(ctx, utils.GetResourceIdFromParam(c, "orgId"))
+	if err != nil {
+		return wrapper.Response{
+			Error:  err,
+			Status: http.StatusInternalServerError,
+		}
+	}
+
+	return wrapper.Response{
+		Data:         sas,
+		Total:        count,
+		IncludeTotal: true,
+	}
+}
+
+// UpdateServiceAccount godoc
+// @Summary      Update a service account
+// @Description  Update name, role or status of a service account
+// @Tags         service-accounts
+// @Accept       json
+// @Produce      json
+// @Param        orgId              path      int  true  "Org ID"
+// @Param        serviceAccountId   path      int  true  "Service account ID"
+// @Param        body               body      dto.UpdateServiceAccountReq  true  "Service account update request"
+// @Success      200  {object}  wrapper.SuccessResponse{data=domains.ServiceAccount}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      404  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/orgs/{orgId}/service-accounts/{serviceAccountId} [put]
+func (h *ServiceAccountHandler) Update(c echo.Context) wrapper.Response {
+	ctx := c.Request().Context()
+	var req dto.UpdateServiceAccountReq
+
+	if err := c.Bind(&req); err != nil {
+		return wrapper.Response{
+			Status: http.StatusUnprocessableEntity,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	sa, err := h.ServiceAccountUC.Update(ctx, req)
+	if err != nil {
+		return h.errorResponse(err, "update")
+	}
+
+	return wrapper.Response{Data: sa}
+}
+
+// DeleteServiceAccount godoc
+// @Summary      Delete a service account
+// @Description  Delete a service account, its tokens are rejected right away
+// @Tags         service-accounts
+// @Accept       json
+// @Produce      json
+// @Param        orgId              path      int  true  "Org ID"
+// @Param        serviceAccountId   path      int  true  "Service account ID"
+// @Success      200  {object}  wrapper.SuccessResponse{}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      404  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/orgs/{orgId}/service-accounts/{serviceAccountId} [delete]
+func (h *ServiceAccountHandler) Delete(c echo.Context) wrapper.Response {
+	ctx := c.Request().Context()
+
+	err := h.ServiceAccountUC.Delete(ctx,
+		utils.GetResourceIdFromParam(c, "orgId"),
+		utils.GetResourceIdFromParam(c, "serviceAccountId"),
+	)
+	if err != nil {
+		return h.errorResponse(err, "delete")
+	}
+
+	return wrapper.Response{}
+}
+
+// RotateServiceAccountSecret godoc
+// @Summary      Rotate a service account secret
+// @Description  Generate a new client secret, the previous one stops working right away
+// @Tags         service-accounts
+// @Accept       json
+// @Produce      json
+// @Param        orgId              path      int  true  "Org ID"
+// @Param        serviceAccountId   path      int  true  "Service account ID"
+// @Success      200  {object}  wrapper.SuccessResponse{data=dto.ServiceAccountCreatedResp}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      404  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/orgs/{orgId}/service-accounts/{serviceAccountId}/secret [post]
+func (h *ServiceAccountHandler) RotateSecret(c echo.Context) wrapper.Response {
+	ctx := c.Request().Context()
+
+	sa, err := h.ServiceAccountUC.RotateSecret(ctx,
+		utils.GetResourceIdFromParam(c, "orgId"),
+		utils.GetResourceIdFromParam(c, "serviceAccountId"),
+	)
+	if err != nil {
+		return h.errorResponse(err, "rotate secret of")
+	}
+
+	return wrapper.Response{Data: sa}
+}
+
+func (h *ServiceAccountHandler) errorResponse(err error, action string) wrapper.Response {
+	if utils.IsCueError(err) {
+		logger.Log().Debugw("invalid "+action+" service account request", "error", err)
+		return wrapper.Response{
+			Status: http.StatusBadRequest,
+			Error:  utils.NewError(err, "invalid payload"),
+		}
+	}
+
+	if errors.Is(err, constants.ErrNotFound) {
+		return wrapper.Response{
+			Status: http.StatusNotFound,
+			Error:  utils.NewNotFoundError(),
+		}
+	}
+
+	logger.Log().Errorw("error while "+action+" service account", "error", err)
+	return wrapper.Response{
+		Status: http.StatusInternalServerError,
+		Error:  utils.NewError(err, "internal server error"),
+	}
+}
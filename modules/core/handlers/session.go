@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/dzungtran/echo-rest-api/pkg/middlewares"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/dzungtran/echo-rest-api/pkg/wrapper"
+	"github.com/labstack/echo/v4"
+)
+
+type SessionHandler struct {
+	SessionUC usecases.SessionUsecase
+}
+
+// NewSessionHandler will initialize the session endpoints of the current user and the super admin ones
+func NewSessionHandler(g *echo.Group, middManager *middlewares.MiddlewareManager, sessionUsecase usecases.SessionUsecase) {
+	handler := &SessionHandler{
+		SessionUC: sessionUsecase,
+	}
+
+	apiMe := g.Group("me/sessions", middManager.Auth(), middManager.CheckPolicies())
+	apiMe.GET("", wrapper.Wrap(handler.Fetch)).Name = "list:session"
+	apiMe.DELETE("/:sessionId", wrapper.Wrap(handler.Revoke), middlewares.RequireResourceIdInParam("sessionId")).Name = "delete:session"
+
+	apiAdmin := g.Group("admin/users/:userId/sessions",
+		middManager.Auth(),
+		middlewares.RequireResourceIdInParam("userId"),
+		middManager.CheckPolicies(),
+	)
+	apiAdmin.DELETE("", wrapper.Wrap(handler.RevokeAll)).Name = "revoke:session"
+}
+
+// GetListSessions godoc
+// @Summary      Get list sessions
+// @Description  Get the active sessions of the current user, the session of the request is flagged as current
+// @Tags         sessions
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  wrapper.SuccessResponse{data=[]domains.Session}
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /me/sessions [get]
+func (h *SessionHandler) Fetch(c echo.Context) wrapper.Response {
+	ctx := c.Request().Context()
+
+	principal, _ := contexts.GetPrincipalFromContext(c)
+	sessions, count, err := h.SessionUC.Fetch(ctx, principal.User.Id)
+	if err != nil {
+		return wrapper.Response{
+			Error:  err,
+			Status: http.StatusInternalServerError,
+		}
+	}
+
+	for _, s := range sessions {
+		s.Current = principal.SessionId == s.Id
+	}
+
+	return wrapper.Response{
+		Data:         sessions,
+		Total:        count,
+		IncludeTotal: true,
+	}
+}
+
+// RevokeSession godoc
+// @Summary      Revoke a session
+// @Description  Sign the current user out of a session, its tokens are rejected right away
+// @Tags         sessions
+// @Accept       json
+// @Produce      json
+// @Param        sessionId   path      int  true  "Session ID"
+// @Success      200  {object}  wrapper.SuccessResponse{}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      404  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /me/sessions/{sessionId} [delete]
+func (h *SessionHandler) Revoke(c echo.Context) wrapper.Response {
+	ctx := c.Request().Context()
+
+	user, _ := contexts.GetUserFromContext(c)
+	err := h.SessionUC.Revoke(ctx, user.Id, utils.GetResourceIdFromParam(c, "sessionId"))
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return wrapper.Response{
+				Status: http.StatusNotFound,
+				Error:  utils.NewNotFoundError(),
+			}
+		}
+		return wrapper.Response{
+			Status: http.StatusInternalServerError,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	return wrapper.Response{}
+}
+
+// RevokeAllSessions godoc
+// @Summary      Revoke all sessions of a user
+// @Description  Sign a user out everywhere, super admin only
+// @Tags         sessions
+// @Accept       json
+// @Produce      json
+// @Param        userId   path      int  true  "User ID"
+// @Success      200  {object}  wrapper.SuccessResponse{}
+// @Failure      400  {object}  wrapper.FailResponse
+// @Failure      401  {object}  wrapper.FailResponse
+// @Failure      403  {object}  wrapper.FailResponse
+// @Failure      500  {object}  wrapper.FailResponse
+// @Security     XFirebaseBearer
+// @Router       /admin/users/{userId}/sessions [delete]
+func (h *SessionHandler) RevokeAll(c echo.Context) wrapper.Response {
+	ctx := c.Request().Context()
+
+	userId := utils.GetResourceIdFromParam(c, "userId")
+	if err := h.SessionUC.RevokeAll(ctx, userId); err != nil {
+		logger.Log().Errorw("error while revoke sessions", "user_id", userId, "error", err)
+		return wrapper.Response{
+			Status: http.StatusInternalServerError,
+			Error:  utils.NewError(err, ""),
+		}
+	}
+
+	return wrapper.Response{}
+}
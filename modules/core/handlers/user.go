@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/authz"
 	"github.com/dzungtran/echo-rest-api/pkg/contexts"
 	"github.com/dzungtran/echo-rest-api/pkg/middlewares"
 	"github.com/dzungtran/echo-rest-api/pkg/wrapper"
@@ -9,13 +10,15 @@ import (
 )
 
 type UserHandler struct {
-	UserUC usecases.UserUsecase
+	UserUC     usecases.UserUsecase
+	Authorizer authz.Authorizer
 }
 
 // NewUserHandler will initialize the user resources endpoint
-func NewUserHandler(g *echo.Group, middManager *middlewares.MiddlewareManager, userUsecase usecases.UserUsecase) {
+func NewUserHandler(g *echo.Group, middManager *middlewares.MiddlewareManager, userUsecase usecases.UserUsecase, authorizer authz.Authorizer) {
 	handler := &UserHandler{
-		UserUC: userUsecase,
+		UserUC:     userUsecase,
+		Authorizer: authorizer,
 	}
 
 	apiMeV1 := g.Group("me", middManager.Auth())
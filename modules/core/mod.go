@@ -1,11 +1,17 @@
 package core
 
 import (
+	"context"
+
 	"github.com/dzungtran/echo-rest-api/config"
 	"github.com/dzungtran/echo-rest-api/modules/core/handlers"
 	"github.com/dzungtran/echo-rest-api/modules/core/repositories"
 	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/authz"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/mailer"
 	"github.com/dzungtran/echo-rest-api/pkg/middlewares"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/dig"
 )
@@ -18,24 +24,105 @@ func (coreModule) RegisterRepositories(container *dig.Container) error {
 	container.Provide(repositories.NewPgsqlUserRepository)
 	container.Provide(repositories.NewPgsqlOrgRepository)
 	container.Provide(repositories.NewPgsqlUserOrgRepository)
+	container.Provide(repositories.NewPgsqlPersonalAccessTokenRepository)
+	container.Provide(repositories.NewPgsqlServiceAccountRepository)
+	container.Provide(repositories.NewPgsqlLocalAuthRepository)
+	container.Provide(repositories.NewPgsqlSessionRepository)
+	container.Provide(repositories.NewPgsqlMfaRepository)
+	container.Provide(repositories.NewPgsqlImpersonationRepository)
+	container.Provide(repositories.NewPgsqlPlatformRoleRepository)
+	container.Provide(repositories.NewPgsqlOrgRoleRepository)
+	container.Provide(repositories.NewPgsqlAuthzDecisionRepository)
+	container.Provide(repositories.NewPgsqlResourceGrantRepository)
 	return nil
 }
 
 func (coreModule) RegisterUseCases(container *dig.Container) error {
 	container.Provide(usecases.NewUserUsecase)
 	container.Provide(usecases.NewOrgUsecase)
+	container.Provide(usecases.NewPersonalAccessTokenUsecase)
+	container.Provide(usecases.NewServiceAccountUsecase)
+	container.Provide(usecases.NewSessionUsecase)
+	container.Provide(usecases.NewMfaUsecase)
+	container.Provide(usecases.NewImpersonationUsecase)
+	container.Provide(usecases.NewPlatformRoleUsecase)
+	container.Provide(usecases.NewOrgRoleUsecase)
+	container.Provide(usecases.NewAuthzDecisionUsecase)
+	container.Provide(usecases.NewPermissionUsecase)
+	container.Provide(usecases.NewResourceGrantUsecase)
+	container.Provide(usecases.NewLocalAuthUsecase)
+	container.Provide(mailer.NewMailer)
 	return nil
 }
 
 func (coreModule) RegisterHandlers(g *echo.Group, container *dig.Container) error {
-	return container.Invoke(func(
+	err := container.Invoke(func(
 		appConf *config.AppConfig,
 		middManager *middlewares.MiddlewareManager,
 		userUsecase usecases.UserUsecase,
 		orgUsecase usecases.OrgUsecase,
+		tokenUsecase usecases.PersonalAccessTokenUsecase,
+		saUsecase usecases.ServiceAccountUsecase,
+		sessionUsecase usecases.SessionUsecase,
+		mfaUsecase usecases.MfaUsecase,
+		impersonationUsecase usecases.ImpersonationUsecase,
+		platformRoleUsecase usecases.PlatformRoleUsecase,
+		orgRoleUsecase usecases.OrgRoleUsecase,
+		orgRoleRepo repositories.OrgRoleRepository,
+		authzDecisionUsecase usecases.AuthzDecisionUsecase,
+		userResolver *middlewares.UserResolver,
+		permissionUsecase usecases.PermissionUsecase,
+		resourceGrantUsecase usecases.ResourceGrantUsecase,
+		authorizer authz.Authorizer,
+		policyAdmin authz.PolicyAdmin,
 	) {
 		handlers.NewOrgHandler(g, middManager, orgUsecase)
-		handlers.NewUserHandler(g, middManager, userUsecase)
+		handlers.NewUserHandler(g, middManager, userUsecase, authorizer)
 		handlers.NewAuthHandler(g, middManager, userUsecase, appConf)
+		handlers.NewHookHandler(g, middManager, userUsecase)
+		handlers.NewPersonalAccessTokenHandler(g, middManager, tokenUsecase)
+		handlers.NewServiceAccountHandler(g, middManager, saUsecase)
+		handlers.NewSessionHandler(g, middManager, sessionUsecase)
+		handlers.NewMfaHandler(g, middManager, mfaUsecase)
+		handlers.NewImpersonationHandler(g, middManager, impersonationUsecase)
+		handlers.NewPlatformRoleHandler(g, middManager, platformRoleUsecase)
+		handlers.NewPolicyHandler(g, middManager, policyAdmin)
+		handlers.NewOrgRoleHandler(g, middManager, orgRoleUsecase)
+		handlers.NewAuthzDecisionHandler(g, middManager, authzDecisionUsecase, userResolver)
+		handlers.NewPermissionHandler(g, middManager, permissionUsecase)
+		handlers.NewResourceGrantHandler(g, middManager, resourceGrantUsecase)
+
+		// the decisions merge the custom roles of the org into roles_chart
+		policyAdmin.SetOrgRoleStore(orgRoleRepo)
+	})
+	if err != nil {
+		return err
+	}
+
+	// record the decisions of the policies, DECISION_LOG_SINK is empty by default
+	err = container.Invoke(func(authzDecisionUsecase usecases.AuthzDecisionUsecase) error {
+		return authzDecisionUsecase.StartRecording(context.Background())
+	})
+	if err != nil {
+		return err
+	}
+
+	// seed the first super admin, the usecase skips it once the platform has one
+	err = container.Invoke(func(appConf *config.AppConfig, platformRoleUsecase usecases.PlatformRoleUsecase) error {
+		return platformRoleUsecase.Bootstrap(context.Background(), appConf.PlatformBootstrapSuperAdmin)
+	})
+	if err != nil {
+		return err
+	}
+
+	// the local usecase fails to build without signing keys in production, only resolve it when enabled
+	return container.Invoke(func(appConf *config.AppConfig) error {
+		if !utils.IsSliceContains(appConf.AuthProviders, constants.AuthProviderLocal) {
+			return nil
+		}
+
+		return container.Invoke(func(localAuthUsecase usecases.LocalAuthUsecase) {
+			handlers.NewLocalAuthHandler(g, localAuthUsecase)
+		})
 	})
 }
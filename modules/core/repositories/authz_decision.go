@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/dzungtran/echo-rest-api/infrastructure/datastore"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	sqlTools "github.com/dzungtran/echo-rest-api/pkg/sql-tools"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	authzDecisionsTableName = "authz_decisions"
+)
+
+type AuthzDecisionRepository interface {
+	Create(ctx context.Context, decision *domains.AuthzDecision) error
+	Fetch(ctx context.Context, params ParamsForFetchAuthzDecisions) ([]*domains.AuthzDecision, int64, error)
+}
+
+type (
+	pgsqlAuthzDecisionRepository struct {
+		db  *sqlx.DB
+		sdb *sqlx.DB
+	}
+	ParamsForFetchAuthzDecisions struct {
+		UserId    int64
+		OrgId     int64
+		Allowed   *bool
+		RequestId string
+		Since     *time.Time
+		contexts.CommonParamsForFetch
+	}
+)
+
+// NewPgsqlAuthzDecisionRepository will create new an authzDecisionRepository object representation of AuthzDecisionRepository interface
+func NewPgsqlAuthzDecisionRepository(mdbi *datastore.MasterDbInstance, sdbi *datastore.SlaveDbInstance) AuthzDecisionRepository {
+	return &pgsqlAuthzDecisionRepository{
+		db:  mdbi.DBX(),
+		sdb: sdbi.DBX(),
+	}
+}
+
+// Create keeps the created_at of the decision, it is the time of the evaluation
+func (r *pgsqlAuthzDecisionRepository) Create(ctx context.Context, decision *domains.AuthzDecision) error {
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	cols, vals := sqlTools.GetColumnsAndValuesFromStruct(
+		ctx,
+		decision,
+		sqlTools.WithMapValuesIgnoreFields([]string{"id"}),
+	)
+
+	_, err := psql.Insert(authzDecisionsTableName).
+		Columns(cols...).
+		Values(vals...).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *pgsqlAuthzDecisionRepository) Fetch(ctx context.Context, params ParamsForFetchAuthzDecisions) (rs []*domains.AuthzDecision, count int64, err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.sdb)
+	type decisionWithCount struct {
+		domains.AuthzDecision
+		Count int64 `db:"_count"` // special field for count
+	}
+
+	cols, _ := sqlTools.GetColumnsAndValuesFromStruct(ctx, &decisionWithCount{})
+	query := psql.Select(sqlTools.ParseColumnsForSelect(cols)...).From(authzDecisionsTableName)
+	query = r.buildQueryFilters(query, params)
+
+	sqlQuery, args, err := sqlTools.
+		BindCommonParamsToSelectBuilder(query, params.CommonParamsForFetch).
+		OrderBy("id DESC").ToSql()
+	if err != nil {
+		return nil, count, err
+	}
+
+	rows, err := r.sdb.QueryxContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, count, err
+	}
+	defer rows.Close()
+
+	rs = make([]*domains.AuthzDecision, 0)
+	for rows.Next() {
+		var dwc decisionWithCount
+		err = rows.StructScan(&dwc)
+		if err != nil {
+			return nil, count, err
+		}
+
+		count = dwc.Count
+		d := dwc.AuthzDecision
+		rs = append(rs, &d)
+	}
+
+	return
+}
+
+func (r *pgsqlAuthzDecisionRepository) buildQueryFilters(builder squirrel.SelectBuilder, params ParamsForFetchAuthzDecisions) squirrel.SelectBuilder {
+	if params.UserId > 0 {
+		builder = builder.Where(squirrel.Eq{"user_id": params.UserId})
+	}
+	if params.OrgId > 0 {
+		builder = builder.Where(squirrel.Eq{"org_id": params.OrgId})
+	}
+	if params.Allowed != nil {
+		builder = builder.Where(squirrel.Eq{"allowed": *params.Allowed})
+	}
+	if params.RequestId != "" {
+		builder = builder.Where(squirrel.Eq{"request_id": params.RequestId})
+	}
+	if params.Since != nil {
+		builder = builder.Where(squirrel.GtOrEq{"created_at": *params.Since})
+	}
+	return builder
+}
@@ -0,0 +1,178 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/dzungtran/echo-rest-api/infrastructure/datastore"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	sqlTools "github.com/dzungtran/echo-rest-api/pkg/sql-tools"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	impersonationsTableName         = "impersonations"
+	impersonationAuditLogsTableName = "impersonation_audit_logs"
+)
+
+type ImpersonationRepository interface {
+	Create(ctx context.Context, impersonation *domains.Impersonation) (int64, error)
+	GetActiveByActorId(ctx context.Context, actorUserId int64) (*domains.Impersonation, error)
+	EndByActorId(ctx context.Context, actorUserId int64) error
+
+	CreateAuditLog(ctx context.Context, log *domains.ImpersonationAuditLog) error
+	FetchAuditLogs(ctx context.Context, params ParamsForFetchImpersonationAuditLogs) ([]*domains.ImpersonationAuditLog, int64, error)
+}
+
+type (
+	pgsqlImpersonationRepository struct {
+		db  *sqlx.DB
+		sdb *sqlx.DB
+	}
+	ParamsForFetchImpersonationAuditLogs struct {
+		ActorUserId  int64
+		TargetUserId int64
+		contexts.CommonParamsForFetch
+	}
+)
+
+// NewPgsqlImpersonationRepository will create new an impersonationRepository object representation of ImpersonationRepository interface
+func NewPgsqlImpersonationRepository(mdbi *datastore.MasterDbInstance, sdbi *datastore.SlaveDbInstance) ImpersonationRepository {
+	return &pgsqlImpersonationRepository{
+		db:  mdbi.DBX(),
+		sdb: sdbi.DBX(),
+	}
+}
+
+func (r *pgsqlImpersonationRepository) Create(ctx context.Context, impersonation *domains.Impersonation) (newId int64, err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	cols, vals := sqlTools.GetColumnsAndValuesFromStruct(
+		ctx,
+		impersonation,
+		sqlTools.WithMapValuesIgnoreFields([]string{"id"}),
+		sqlTools.WithMapValuesAutoDateTimeFields([]string{"created_at", "updated_at"}),
+	)
+
+	query := psql.Insert(impersonationsTableName).
+		Columns(cols...).
+		Values(vals...).
+		Suffix(`RETURNING id`)
+
+	err = query.QueryRowContext(ctx).Scan(&newId)
+	return
+}
+
+// GetActiveByActorId reads from the master, the impersonation is used by the next request of the actor
+func (r *pgsqlImpersonationRepository) GetActiveByActorId(ctx context.Context, actorUserId int64) (impersonation *domains.Impersonation, err error) {
+	if actorUserId <= 0 {
+		return nil, errors.New("invalid actor id")
+	}
+
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	cols, _ := sqlTools.GetColumnsAndValuesFromStruct(ctx, &domains.Impersonation{})
+	query, args, err := psql.Select(cols...).From(impersonationsTableName).
+		Where(squirrel.Eq{
+			"actor_user_id": actorUserId,
+			"ended_at":      nil,
+		}).
+		Where(squirrel.Gt{"expires_at": time.Now().UTC()}).
+		OrderBy("id DESC").
+		Limit(1).ToSql()
+	if err != nil {
+		return
+	}
+
+	impersonation = &domains.Impersonation{}
+	err = r.db.GetContext(ctx, impersonation, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, constants.ErrNotFound
+		}
+		return nil, err
+	}
+	return
+}
+
+func (r *pgsqlImpersonationRepository) EndByActorId(ctx context.Context, actorUserId int64) error {
+	if actorUserId <= 0 {
+		return errors.New("invalid actor id")
+	}
+
+	now := time.Now().UTC()
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	_, err := psql.Update(impersonationsTableName).
+		Set("ended_at", now).
+		Set("updated_at", now).
+		Where(squirrel.Eq{
+			"actor_user_id": actorUserId,
+			"ended_at":      nil,
+		}).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *pgsqlImpersonationRepository) CreateAuditLog(ctx context.Context, log *domains.ImpersonationAuditLog) error {
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	cols, vals := sqlTools.GetColumnsAndValuesFromStruct(
+		ctx,
+		log,
+		sqlTools.WithMapValuesIgnoreFields([]string{"id"}),
+		sqlTools.WithMapValuesAutoDateTimeFields([]string{"created_at"}),
+	)
+
+	_, err := psql.Insert(impersonationAuditLogsTableName).
+		Columns(cols...).
+		Values(vals...).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *pgsqlImpersonationRepository) FetchAuditLogs(ctx context.Context, params ParamsForFetchImpersonationAuditLogs) (rs []*domains.ImpersonationAuditLog, count int64, err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.sdb)
+	type auditLogWithCount struct {
+		domains.ImpersonationAuditLog
+		Count int64 `db:"_count"` // special field for count
+	}
+
+	cols, _ := sqlTools.GetColumnsAndValuesFromStruct(ctx, &auditLogWithCount{})
+	query := psql.Select(sqlTools.ParseColumnsForSelect(cols)...).From(impersonationAuditLogsTableName)
+	if params.ActorUserId > 0 {
+		query = query.Where(squirrel.Eq{"actor_user_id": params.ActorUserId})
+	}
+	if params.TargetUserId > 0 {
+		query = query.Where(squirrel.Eq{"target_user_id": params.TargetUserId})
+	}
+
+	sqlQuery, args, err := sqlTools.
+		BindCommonParamsToSelectBuilder(query, params.CommonParamsForFetch).
+		OrderBy("id DESC").ToSql()
+	if err != nil {
+		return nil, count, err
+	}
+
+	rows, err := r.sdb.QueryxContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, count, err
+	}
+	defer rows.Close()
+
+	rs = make([]*domains.ImpersonationAuditLog, 0)
+	for rows.Next() {
+		var lwc auditLogWithCount
+		err = rows.StructScan(&lwc)
+		if err != nil {
+			return nil, count, err
+		}
+
+		count = lwc.Count
+		l := lwc.ImpersonationAuditLog
+		rs = append(rs, &l)
+	}
+
+	return
+}
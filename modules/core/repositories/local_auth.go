@@ -0,0 +1,212 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/dzungtran/echo-rest-api/infrastructure/datastore"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	sqlTools "github.com/dzungtran/echo-rest-api/pkg/sql-tools"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	localCredentialsTableName = "local_credentials"
+	localAuthTokensTableName  = "local_auth_tokens"
+	refreshTokensTableName    = "refresh_tokens"
+)
+
+// LocalAuthRepository stores the credentials and tokens of the built-in identity provider
+type LocalAuthRepository interface {
+	CreateCredential(ctx context.Context, cred *domains.LocalCredential) error
+	GetCredentialByUserId(ctx context.Context, userId int64) (*domains.LocalCredential, error)
+	UpdateCredential(ctx context.Context, cred *domains.LocalCredential, fieldsToUpdate []string) error
+
+	CreateAuthToken(ctx context.Context, token *domains.LocalAuthToken) (int64, error)
+	GetAuthTokenByHash(ctx context.Context, tokenHash string) (*domains.LocalAuthToken, error)
+	// UseAuthToken marks the token as used unless it already is, false tells that it was used by someone else
+	UseAuthToken(ctx context.Context, id int64, usedAt time.Time) (bool, error)
+
+	CreateRefreshToken(ctx context.Context, token *domains.RefreshToken) (int64, error)
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*domains.RefreshToken, error)
+	// RotateRefreshToken marks the token as rotated unless it already is, false tells that it is reused
+	RotateRefreshToken(ctx context.Context, id int64, rotatedAt time.Time) (bool, error)
+}
+
+type pgsqlLocalAuthRepository struct {
+	db  *sqlx.DB
+	sdb *sqlx.DB
+}
+
+// NewPgsqlLocalAuthRepository will create new a localAuthRepository object representation of LocalAuthRepository interface
+func NewPgsqlLocalAuthRepository(mdbi *datastore.MasterDbInstance, sdbi *datastore.SlaveDbInstance) LocalAuthRepository {
+	return &pgsqlLocalAuthRepository{
+		db:  mdbi.DBX(),
+		sdb: sdbi.DBX(),
+	}
+}
+
+func (r *pgsqlLocalAuthRepository) CreateCredential(ctx context.Context, cred *domains.LocalCredential) (err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	cols, vals := sqlTools.GetColumnsAndValuesFromStruct(
+		ctx,
+		cred,
+		sqlTools.WithMapValuesAutoDateTimeFields([]string{"created_at", "updated_at"}),
+	)
+
+	_, err = psql.Insert(localCredentialsTableName).
+		Columns(cols...).
+		Values(vals...).
+		ExecContext(ctx)
+	if err != nil && utils.IsDuplicatedError(err) {
+		err = constants.ErrDuplicated
+	}
+	return
+}
+
+func (r *pgsqlLocalAuthRepository) GetCredentialByUserId(ctx context.Context, userId int64) (cred *domains.LocalCredential, err error) {
+	cred = &domains.LocalCredential{}
+	err = r.getBy(ctx, localCredentialsTableName, cred, squirrel.Eq{"user_id": userId})
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (r *pgsqlLocalAuthRepository) UpdateCredential(ctx context.Context, cred *domains.LocalCredential, fieldsToUpdate []string) error {
+	if cred.UserId <= 0 {
+		return errors.New("missing user id")
+	}
+	return r.update(ctx, localCredentialsTableName, cred, fieldsToUpdate, squirrel.Eq{"user_id": cred.UserId})
+}
+
+func (r *pgsqlLocalAuthRepository) CreateAuthToken(ctx context.Context, token *domains.LocalAuthToken) (int64, error) {
+	return r.create(ctx, localAuthTokensTableName, token)
+}
+
+func (r *pgsqlLocalAuthRepository) GetAuthTokenByHash(ctx context.Context, tokenHash string) (token *domains.LocalAuthToken, err error) {
+	token = &domains.LocalAuthToken{}
+	err = r.getBy(ctx, localAuthTokensTableName, token, squirrel.Eq{"token_hash": tokenHash})
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (r *pgsqlLocalAuthRepository) UseAuthToken(ctx context.Context, id int64, usedAt time.Time) (bool, error) {
+	return r.setOnce(ctx, localAuthTokensTableName, id, "used_at", usedAt)
+}
+
+func (r *pgsqlLocalAuthRepository) CreateRefreshToken(ctx context.Context, token *domains.RefreshToken) (int64, error) {
+	return r.create(ctx, refreshTokensTableName, token)
+}
+
+func (r *pgsqlLocalAuthRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (token *domains.RefreshToken, err error) {
+	token = &domains.RefreshToken{}
+	err = r.getBy(ctx, refreshTokensTableName, token, squirrel.Eq{"token_hash": tokenHash})
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (r *pgsqlLocalAuthRepository) RotateRefreshToken(ctx context.Context, id int64, rotatedAt time.Time) (bool, error) {
+	return r.setOnce(ctx, refreshTokensTableName, id, "rotated_at", rotatedAt)
+}
+
+func (r *pgsqlLocalAuthRepository) create(ctx context.Context, tableName string, st interface{}) (newId int64, err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	cols, vals := sqlTools.GetColumnsAndValuesFromStruct(
+		ctx,
+		st,
+		sqlTools.WithMapValuesIgnoreFields([]string{"id"}),
+		sqlTools.WithMapValuesAutoDateTimeFields([]string{"created_at", "updated_at"}),
+	)
+
+	query := psql.Insert(tableName).
+		Columns(cols...).
+		Values(vals...).
+		Suffix(`RETURNING id`)
+
+	err = query.QueryRowContext(ctx).Scan(&newId)
+	if err != nil {
+		if utils.IsDuplicatedError(err) {
+			err = constants.ErrDuplicated
+		}
+		return
+	}
+	return
+}
+
+func (r *pgsqlLocalAuthRepository) getBy(ctx context.Context, tableName string, dest interface{}, cond squirrel.Eq) error {
+	psql := sqlTools.NewPSQLStatementBuilder(r.sdb)
+	cols, _ := sqlTools.GetColumnsAndValuesFromStruct(ctx, dest)
+	query, args, err := psql.Select(cols...).From(tableName).
+		Where(cond).ToSql()
+	if err != nil {
+		return err
+	}
+
+	// tokens are read right after being written, use the master
+	err = r.db.GetContext(ctx, dest, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return constants.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *pgsqlLocalAuthRepository) update(ctx context.Context, tableName string, st interface{}, fieldsToUpdate []string, cond squirrel.Eq) error {
+	if len(fieldsToUpdate) == 0 {
+		fieldsToUpdate = make([]string, 0)
+	}
+
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	query := psql.Update(tableName).
+		SetMap(sqlTools.GetMapValuesFromStruct(
+			ctx, st,
+			sqlTools.WithMapValuesSelectFields(fieldsToUpdate),
+			sqlTools.WithMapValuesIgnoreFields([]string{"id"}),
+			sqlTools.WithMapValuesAutoDateTimeFields([]string{"updated_at"}),
+		)).
+		Where(cond)
+
+	affect, err := query.ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = affect.RowsAffected()
+	return err
+}
+
+// setOnce sets the timestamp column of the row while it is null, in one statement so that concurrent calls
+// cannot both succeed. It tells whether the row was updated
+func (r *pgsqlLocalAuthRepository) setOnce(ctx context.Context, tableName string, id int64, column string, at time.Time) (bool, error) {
+	if id <= 0 {
+		return false, errors.New("missing token id")
+	}
+
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	res, err := psql.Update(tableName).
+		Set(column, at).
+		Set("updated_at", time.Now().UTC()).
+		Where(squirrel.Eq{"id": id, column: nil}).
+		ExecContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,215 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/dzungtran/echo-rest-api/infrastructure/datastore"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	sqlTools "github.com/dzungtran/echo-rest-api/pkg/sql-tools"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	userMfaTableName          = "user_mfa"
+	mfaRecoveryCodesTableName = "mfa_recovery_codes"
+)
+
+// MfaRepository stores the second factors of the users and their recovery codes
+type MfaRepository interface {
+	Save(ctx context.Context, mfa *domains.UserMfa) error
+	GetByUserId(ctx context.Context, userId int64) (*domains.UserMfa, error)
+	Update(ctx context.Context, mfa *domains.UserMfa, fieldsToUpdate []string) error
+	// UseStep records the time step of an accepted code, false when the step or a later one was already used
+	UseStep(ctx context.Context, userId, step int64) (bool, error)
+	DeleteByUserId(ctx context.Context, userId int64) error
+
+	// ReplaceRecoveryCodes drops the codes of the user and stores the new ones
+	ReplaceRecoveryCodes(ctx context.Context, userId int64, codeHashes []string) error
+	// UseRecoveryCode marks an unused code as used, false when the code is unknown or already used
+	UseRecoveryCode(ctx context.Context, userId int64, codeHash string) (bool, error)
+	CountUnusedRecoveryCodes(ctx context.Context, userId int64) (int64, error)
+}
+
+type pgsqlMfaRepository struct {
+	db  *sqlx.DB
+	sdb *sqlx.DB
+}
+
+// NewPgsqlMfaRepository will create new a mfaRepository object representation of MfaRepository interface
+func NewPgsqlMfaRepository(mdbi *datastore.MasterDbInstance, sdbi *datastore.SlaveDbInstance) MfaRepository {
+	return &pgsqlMfaRepository{
+		db:  mdbi.DBX(),
+		sdb: sdbi.DBX(),
+	}
+}
+
+// Save creates the second factor of the user or replaces a pending one
+func (r *pgsqlMfaRepository) Save(ctx context.Context, mfa *domains.UserMfa) (err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	cols, vals := sqlTools.GetColumnsAndValuesFromStruct(
+		ctx,
+		mfa,
+		sqlTools.WithMapValuesAutoDateTimeFields([]string{"created_at", "updated_at"}),
+	)
+
+	_, err = psql.Insert(userMfaTableName).
+		Columns(cols...).
+		Values(vals...).
+		Suffix(`ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = 0,
+			confirmed_at = NULL, updated_at = EXCLUDED.updated_at WHERE user_mfa.confirmed_at IS NULL`).
+		ExecContext(ctx)
+	return
+}
+
+// GetByUserId reads from the master, a second factor is confirmed right after being enrolled
+func (r *pgsqlMfaRepository) GetByUserId(ctx context.Context, userId int64) (mfa *domains.UserMfa, err error) {
+	mfa = &domains.UserMfa{}
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	cols, _ := sqlTools.GetColumnsAndValuesFromStruct(ctx, mfa)
+	query, args, err := psql.Select(cols...).From(userMfaTableName).
+		Where(squirrel.Eq{"user_id": userId}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.GetContext(ctx, mfa, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, constants.ErrNotFound
+		}
+		return nil, err
+	}
+	return
+}
+
+func (r *pgsqlMfaRepository) Update(ctx context.Context, mfa *domains.UserMfa, fieldsToUpdate []string) error {
+	if mfa.UserId <= 0 {
+		return errors.New("missing user id")
+	}
+
+	if len(fieldsToUpdate) == 0 {
+		fieldsToUpdate = make([]string, 0)
+	}
+
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	_, err := psql.Update(userMfaTableName).
+		SetMap(sqlTools.GetMapValuesFromStruct(
+			ctx, mfa,
+			sqlTools.WithMapValuesSelectFields(fieldsToUpdate),
+			sqlTools.WithMapValuesIgnoreFields([]string{"user_id"}),
+			sqlTools.WithMapValuesAutoDateTimeFields([]string{"updated_at"}),
+		)).
+		Where(squirrel.Eq{"user_id": mfa.UserId}).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *pgsqlMfaRepository) UseStep(ctx context.Context, userId, step int64) (bool, error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	res, err := psql.Update(userMfaTableName).
+		Set("last_used_step", step).
+		Set("updated_at", time.Now().UTC()).
+		Where(squirrel.Eq{"user_id": userId}).
+		Where(squirrel.Lt{"last_used_step": step}).
+		ExecContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *pgsqlMfaRepository) DeleteByUserId(ctx context.Context, userId int64) error {
+	if userId <= 0 {
+		return errors.New("invalid user id")
+	}
+
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	_, err := psql.Delete(userMfaTableName).
+		Where(squirrel.Eq{"user_id": userId}).
+		ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = psql.Delete(mfaRecoveryCodesTableName).
+		Where(squirrel.Eq{"user_id": userId}).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *pgsqlMfaRepository) ReplaceRecoveryCodes(ctx context.Context, userId int64, codeHashes []string) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	psql := sqlTools.NewPSQLStatementBuilder(r.db).RunWith(tx.Tx)
+	_, err = psql.Delete(mfaRecoveryCodesTableName).
+		Where(squirrel.Eq{"user_id": userId}).
+		ExecContext(ctx)
+	if err != nil {
+		return
+	}
+
+	if len(codeHashes) > 0 {
+		now := time.Now().UTC()
+		query := psql.Insert(mfaRecoveryCodesTableName).
+			Columns("user_id", "code_hash", "created_at", "updated_at")
+		for _, h := range codeHashes {
+			query = query.Values(userId, h, now, now)
+		}
+
+		if _, err = query.ExecContext(ctx); err != nil {
+			return
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *pgsqlMfaRepository) UseRecoveryCode(ctx context.Context, userId int64, codeHash string) (bool, error) {
+	now := time.Now().UTC()
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	res, err := psql.Update(mfaRecoveryCodesTableName).
+		Set("used_at", now).
+		Set("updated_at", now).
+		Where(squirrel.Eq{
+			"user_id":   userId,
+			"code_hash": codeHash,
+			"used_at":   nil,
+		}).
+		ExecContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *pgsqlMfaRepository) CountUnusedRecoveryCodes(ctx context.Context, userId int64) (count int64, err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	query, args, err := psql.Select("COUNT(*)").From(mfaRecoveryCodesTableName).
+		Where(squirrel.Eq{
+			"user_id": userId,
+			"used_at": nil,
+		}).ToSql()
+	if err != nil {
+		return
+	}
+
+	err = r.db.GetContext(ctx, &count, query, args...)
+	return
+}
@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/dzungtran/echo-rest-api/infrastructure/datastore"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	sqlTools "github.com/dzungtran/echo-rest-api/pkg/sql-tools"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	orgRolesTableName = "org_roles"
+)
+
+type OrgRoleRepository interface {
+	Create(ctx context.Context, role *domains.OrgCustomRole) (int64, error)
+	GetByID(ctx context.Context, id int64) (*domains.OrgCustomRole, error)
+	FetchByOrgIds(ctx context.Context, orgIds []int64) ([]*domains.OrgCustomRole, error)
+	Update(ctx context.Context, role *domains.OrgCustomRole, fieldsToUpdate []string) error
+	DeleteById(ctx context.Context, id int64) error
+}
+
+type pgsqlOrgRoleRepository struct {
+	db  *sqlx.DB
+	sdb *sqlx.DB
+}
+
+// NewPgsqlOrgRoleRepository will create new an orgRoleRepository object representation of OrgRoleRepository interface
+func NewPgsqlOrgRoleRepository(mdbi *datastore.MasterDbInstance, sdbi *datastore.SlaveDbInstance) OrgRoleRepository {
+	return &pgsqlOrgRoleRepository{
+		db:  mdbi.DBX(),
+		sdb: sdbi.DBX(),
+	}
+}
+
+// Create returns ErrDuplicated when the org already has a role with the name
+func (r *pgsqlOrgRoleRepository) Create(ctx context.Context, role *domains.OrgCustomRole) (newId int64, err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	cols, vals := sqlTools.GetColumnsAndValuesFromStruct(
+		ctx,
+		role,
+		sqlTools.WithMapValuesIgnoreFields([]string{"id"}),
+		sqlTools.WithMapValuesAutoDateTimeFields([]string{"created_at", "updated_at"}),
+	)
+
+	query := psql.Insert(orgRolesTableName).
+		Columns(cols...).
+		Values(vals...).
+		Suffix(`RETURNING id`)
+
+	err = query.QueryRowContext(ctx).Scan(&newId)
+	if err != nil {
+		if utils.IsDuplicatedError(err) {
+			err = constants.ErrDuplicated
+		}
+		return
+	}
+	return
+}
+
+func (r *pgsqlOrgRoleRepository) GetByID(ctx context.Context, id int64) (role *domains.OrgCustomRole, err error) {
+	if id <= 0 {
+		return nil, errors.New("invalid id")
+	}
+
+	psql := sqlTools.NewPSQLStatementBuilder(r.sdb)
+	cols, _ := sqlTools.GetColumnsAndValuesFromStruct(ctx, &domains.OrgCustomRole{})
+	query, args, err := psql.Select(cols...).From(orgRolesTableName).
+		Where(squirrel.Eq{"id": id}).ToSql()
+	if err != nil {
+		return
+	}
+
+	role = &domains.OrgCustomRole{}
+	err = r.sdb.GetContext(ctx, role, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, constants.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return
+}
+
+// FetchByOrgIds returns the custom roles of the orgs, the policies load them on every decision
+func (r *pgsqlOrgRoleRepository) FetchByOrgIds(ctx context.Context, orgIds []int64) (rs []*domains.OrgCustomRole, err error) {
+	rs = make([]*domains.OrgCustomRole, 0)
+	if len(orgIds) == 0 {
+		return
+	}
+
+	psql := sqlTools.NewPSQLStatementBuilder(r.sdb)
+	cols, _ := sqlTools.GetColumnsAndValuesFromStruct(ctx, &domains.OrgCustomRole{})
+	query, args, err := psql.Select(cols...).From(orgRolesTableName).
+		Where(squirrel.Eq{"org_id": orgIds}).
+		OrderBy("id ASC").ToSql()
+	if err != nil {
+		return
+	}
+
+	err = r.sdb.SelectContext(ctx, &rs, query, args...)
+	return
+}
+
+func (r *pgsqlOrgRoleRepository) Update(ctx context.Context, role *domains.OrgCustomRole, fieldsToUpdate []string) (err error) {
+	if len(fieldsToUpdate) == 0 {
+		fieldsToUpdate = make([]string, 0)
+	}
+
+	if role.Id <= 0 {
+		return errors.New("missing org role id")
+	}
+
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	query := psql.Update(orgRolesTableName).
+		SetMap(sqlTools.GetMapValuesFromStruct(
+			ctx, role,
+			sqlTools.WithMapValuesSelectFields(fieldsToUpdate),
+			sqlTools.WithMapValuesIgnoreFields([]string{"id"}),
+			sqlTools.WithMapValuesAutoDateTimeFields([]string{"updated_at"}),
+		)).
+		Where(squirrel.Eq{
+			"id": role.Id,
+		})
+
+	affect, err := query.ExecContext(ctx)
+	if err != nil {
+		return
+	}
+
+	_, err = affect.RowsAffected()
+	return
+}
+
+func (r *pgsqlOrgRoleRepository) DeleteById(ctx context.Context, id int64) (err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	query := psql.Delete(orgRolesTableName).Where(squirrel.Eq{
+		"id": id,
+	})
+
+	_, err = query.ExecContext(ctx)
+	return
+}
@@ -0,0 +1,194 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/dzungtran/echo-rest-api/infrastructure/datastore"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	sqlTools "github.com/dzungtran/echo-rest-api/pkg/sql-tools"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	personalAccessTokensTableName = "personal_access_tokens"
+)
+
+type PersonalAccessTokenRepository interface {
+	Create(ctx context.Context, token *domains.PersonalAccessToken) (int64, error)
+	GetByID(ctx context.Context, id int64) (*domains.PersonalAccessToken, error)
+	GetByTokenHash(ctx context.Context, tokenHash string) (*domains.PersonalAccessToken, error)
+	Fetch(ctx context.Context, params ParamsForFetchPersonalAccessTokens) ([]*domains.PersonalAccessToken, int64, error)
+	Update(ctx context.Context, token *domains.PersonalAccessToken, fieldsToUpdate []string) error
+}
+
+type (
+	pgsqlPersonalAccessTokenRepository struct {
+		db  *sqlx.DB
+		sdb *sqlx.DB
+	}
+	ParamsForFetchPersonalAccessTokens struct {
+		UserId         int64
+		IncludeRevoked bool
+		contexts.CommonParamsForFetch
+	}
+)
+
+// NewPgsqlPersonalAccessTokenRepository will create new a personalAccessTokenRepository object representation of PersonalAccessTokenRepository interface
+func NewPgsqlPersonalAccessTokenRepository(mdbi *datastore.MasterDbInstance, sdbi *datastore.SlaveDbInstance) PersonalAccessTokenRepository {
+	return &pgsqlPersonalAccessTokenRepository{
+		db:  mdbi.DBX(),
+		sdb: sdbi.DBX(),
+	}
+}
+
+func (r *pgsqlPersonalAccessTokenRepository) Create(ctx context.Context, token *domains.PersonalAccessToken) (newId int64, err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	cols, vals := sqlTools.GetColumnsAndValuesFromStruct(
+		ctx,
+		token,
+		sqlTools.WithMapValuesIgnoreFields([]string{"id"}),
+		sqlTools.WithMapValuesAutoDateTimeFields([]string{"created_at", "updated_at"}),
+	)
+
+	query := psql.Insert(personalAccessTokensTableName).
+		Columns(cols...).
+		Values(vals...).
+		Suffix(`RETURNING id`)
+
+	err = query.QueryRowContext(ctx).Scan(&newId)
+	if err != nil {
+		if utils.IsDuplicatedError(err) {
+			err = constants.ErrDuplicated
+		}
+		return
+	}
+	return
+}
+
+func (r *pgsqlPersonalAccessTokenRepository) GetByID(ctx context.Context, id int64) (*domains.PersonalAccessToken, error) {
+	if id <= 0 {
+		return nil, errors.New("invalid id")
+	}
+	return r.getBy(ctx, squirrel.Eq{"id": id})
+}
+
+func (r *pgsqlPersonalAccessTokenRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*domains.PersonalAccessToken, error) {
+	if tokenHash == "" {
+		return nil, errors.New("invalid token hash")
+	}
+	return r.getBy(ctx, squirrel.Eq{"token_hash": tokenHash})
+}
+
+func (r *pgsqlPersonalAccessTokenRepository) getBy(ctx context.Context, cond squirrel.Eq) (token *domains.PersonalAccessToken, err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.sdb)
+	cols, _ := sqlTools.GetColumnsAndValuesFromStruct(ctx, &domains.PersonalAccessToken{})
+	query, args, err := psql.Select(cols...).From(personalAccessTokensTableName).
+		Where(cond).ToSql()
+	if err != nil {
+		return
+	}
+
+	token = &domains.PersonalAccessToken{}
+	err = r.sdb.GetContext(ctx, token, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, constants.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return
+}
+
+func (r *pgsqlPersonalAccessTokenRepository) Fetch(ctx context.Context, params ParamsForFetchPersonalAccessTokens) (rs []*domains.PersonalAccessToken, count int64, err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.sdb)
+	type tokenWithCount struct {
+		domains.PersonalAccessToken
+		Count int64 `db:"_count"` // special field for count
+	}
+
+	cols, _ := sqlTools.GetColumnsAndValuesFromStruct(ctx, &tokenWithCount{})
+	query := psql.Select(sqlTools.ParseColumnsForSelect(cols)...).From(personalAccessTokensTableName)
+	query = r.buildQueryFilters(query, params)
+	sqlQuery, args, err := sqlTools.
+		BindCommonParamsToSelectBuilder(query, params.CommonParamsForFetch).
+		OrderBy("created_at DESC").ToSql()
+	if err != nil {
+		return nil, count, err
+	}
+
+	rows, err := r.sdb.QueryxContext(ctx, sqlQuery, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, count, constants.ErrNotFound
+		}
+		return nil, count, err
+	}
+	defer rows.Close()
+
+	rs = make([]*domains.PersonalAccessToken, 0)
+	for rows.Next() {
+		var twc tokenWithCount
+		err = rows.StructScan(&twc)
+		if err != nil {
+			return nil, count, err
+		}
+
+		count = twc.Count
+		t := twc.PersonalAccessToken
+		rs = append(rs, &t)
+	}
+
+	return
+}
+
+func (r *pgsqlPersonalAccessTokenRepository) Update(ctx context.Context, token *domains.PersonalAccessToken, fieldsToUpdate []string) (err error) {
+	if len(fieldsToUpdate) == 0 {
+		fieldsToUpdate = make([]string, 0)
+	}
+
+	if token.Id <= 0 {
+		return errors.New("missing token id")
+	}
+
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	query := psql.Update(personalAccessTokensTableName).
+		SetMap(sqlTools.GetMapValuesFromStruct(
+			ctx, token,
+			sqlTools.WithMapValuesSelectFields(fieldsToUpdate),
+			sqlTools.WithMapValuesIgnoreFields([]string{"id"}),
+			sqlTools.WithMapValuesAutoDateTimeFields([]string{"updated_at"}),
+		)).
+		Where(squirrel.Eq{
+			"id": token.Id,
+		})
+
+	affect, err := query.ExecContext(ctx)
+	if err != nil {
+		return
+	}
+
+	_, err = affect.RowsAffected()
+	return
+}
+
+func (r *pgsqlPersonalAccessTokenRepository) buildQueryFilters(builder squirrel.SelectBuilder, params ParamsForFetchPersonalAccessTokens) squirrel.SelectBuilder {
+	if params.UserId > 0 {
+		builder = builder.Where(squirrel.Eq{
+			"user_id": params.UserId,
+		})
+	}
+
+	if !params.IncludeRevoked {
+		builder = builder.Where(squirrel.Eq{
+			"revoked_at": nil,
+		})
+	}
+	return builder
+}
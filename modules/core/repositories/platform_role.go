@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/dzungtran/echo-rest-api/infrastructure/datastore"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	sqlTools "github.com/dzungtran/echo-rest-api/pkg/sql-tools"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	platformRolesTableName = "platform_roles"
+)
+
+type PlatformRoleRepository interface {
+	Create(ctx context.Context, role *domains.UserPlatformRole) (int64, error)
+	FetchByUserId(ctx context.Context, userId int64) ([]*domains.UserPlatformRole, error)
+	DeleteByUserIdAndRole(ctx context.Context, userId int64, role domains.PlatformRole) error
+	CountByRole(ctx context.Context, role domains.PlatformRole) (int64, error)
+}
+
+type pgsqlPlatformRoleRepository struct {
+	db  *sqlx.DB
+	sdb *sqlx.DB
+}
+
+// NewPgsqlPlatformRoleRepository will create new a platformRoleRepository object representation of PlatformRoleRepository interface
+func NewPgsqlPlatformRoleRepository(mdbi *datastore.MasterDbInstance, sdbi *datastore.SlaveDbInstance) PlatformRoleRepository {
+	return &pgsqlPlatformRoleRepository{
+		db:  mdbi.DBX(),
+		sdb: sdbi.DBX(),
+	}
+}
+
+func (r *pgsqlPlatformRoleRepository) Create(ctx context.Context, role *domains.UserPlatformRole) (newId int64, err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	cols, vals := sqlTools.GetColumnsAndValuesFromStruct(
+		ctx,
+		role,
+		sqlTools.WithMapValuesIgnoreFields([]string{"id"}),
+		sqlTools.WithMapValuesAutoDateTimeFields([]string{"created_at"}),
+	)
+
+	query := psql.Insert(platformRolesTableName).
+		Columns(cols...).
+		Values(vals...).
+		Suffix(`RETURNING id`)
+
+	err = query.QueryRowContext(ctx).Scan(&newId)
+	if err != nil {
+		if utils.IsDuplicatedError(err) {
+			err = constants.ErrDuplicated
+		}
+		return
+	}
+	return
+}
+
+func (r *pgsqlPlatformRoleRepository) FetchByUserId(ctx context.Context, userId int64) (rs []*domains.UserPlatformRole, err error) {
+	if userId <= 0 {
+		return nil, errors.New("invalid user id")
+	}
+
+	psql := sqlTools.NewPSQLStatementBuilder(r.sdb)
+	cols, _ := sqlTools.GetColumnsAndValuesFromStruct(ctx, &domains.UserPlatformRole{})
+	query, args, err := psql.Select(cols...).From(platformRolesTableName).
+		Where(squirrel.Eq{"user_id": userId}).
+		OrderBy("id ASC").ToSql()
+	if err != nil {
+		return
+	}
+
+	rs = make([]*domains.UserPlatformRole, 0)
+	err = r.sdb.SelectContext(ctx, &rs, query, args...)
+	return
+}
+
+// DeleteByUserIdAndRole returns ErrNotFound when the user does not have the role
+func (r *pgsqlPlatformRoleRepository) DeleteByUserIdAndRole(ctx context.Context, userId int64, role domains.PlatformRole) error {
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	rs, err := psql.Delete(platformRolesTableName).
+		Where(squirrel.Eq{
+			"user_id": userId,
+			"role":    role,
+		}).
+		ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	affected, err := rs.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return constants.ErrNotFound
+	}
+	return nil
+}
+
+// CountByRole reads from the master, it guards the last super admin
+func (r *pgsqlPlatformRoleRepository) CountByRole(ctx context.Context, role domains.PlatformRole) (count int64, err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	query, args, err := psql.Select("COUNT(*)").From(platformRolesTableName).
+		Where(squirrel.Eq{"role": role}).ToSql()
+	if err != nil {
+		return
+	}
+
+	err = r.db.GetContext(ctx, &count, query, args...)
+	return
+}
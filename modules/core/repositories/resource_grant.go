@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/dzungtran/echo-rest-api/infrastructure/datastore"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	sqlTools "github.com/dzungtran/echo-rest-api/pkg/sql-tools"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	resourceGrantsTableName = "resource_grants"
+)
+
+type ResourceGrantRepository interface {
+	Create(ctx context.Context, grant *domains.ResourceGrant) (int64, error)
+	GetByID(ctx context.Context, id int64) (*domains.ResourceGrant, error)
+	Fetch(ctx context.Context, params ParamsForFetchResourceGrants) ([]*domains.ResourceGrant, error)
+	DeleteById(ctx context.Context, id int64) error
+}
+
+type pgsqlResourceGrantRepository struct {
+	db  *sqlx.DB
+	sdb *sqlx.DB
+}
+
+type ParamsForFetchResourceGrants struct {
+	OrgId        int64
+	UserId       int64
+	ResourceType domains.ResourceType
+	ResourceId   int64
+}
+
+// NewPgsqlResourceGrantRepository will create new a resourceGrantRepository object representation of ResourceGrantRepository interface
+func NewPgsqlResourceGrantRepository(mdbi *datastore.MasterDbInstance, sdbi *datastore.SlaveDbInstance) ResourceGrantRepository {
+	return &pgsqlResourceGrantRepository{
+		db:  mdbi.DBX(),
+		sdb: sdbi.DBX(),
+	}
+}
+
+// Create returns ErrDuplicated when the user already has the permission on the resource
+func (r *pgsqlResourceGrantRepository) Create(ctx context.Context, grant *domains.ResourceGrant) (newId int64, err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	cols, vals := sqlTools.GetColumnsAndValuesFromStruct(
+		ctx,
+		grant,
+		sqlTools.WithMapValuesIgnoreFields([]string{"id"}),
+		sqlTools.WithMapValuesAutoDateTimeFields([]string{"created_at", "updated_at"}),
+	)
+
+	query := psql.Insert(resourceGrantsTableName).
+		Columns(cols...).
+		Values(vals...).
+		Suffix(`RETURNING id`)
+
+	err = query.QueryRowContext(ctx).Scan(&newId)
+	if err != nil {
+		if utils.IsDuplicatedError(err) {
+			err = constants.ErrDuplicated
+		}
+		return
+	}
+	return
+}
+
+func (r *pgsqlResourceGrantRepository) GetByID(ctx context.Context, id int64) (grant *domains.ResourceGrant, err error) {
+	if id <= 0 {
+		return nil, errors.New("invalid id")
+	}
+
+	psql := sqlTools.NewPSQLStatementBuilder(r.sdb)
+	cols, _ := sqlTools.GetColumnsAndValuesFromStruct(ctx, &domains.ResourceGrant{})
+	query, args, err := psql.Select(cols...).From(resourceGrantsTableName).
+		Where(squirrel.Eq{"id": id}).ToSql()
+	if err != nil {
+		return
+	}
+
+	grant = &domains.ResourceGrant{}
+	err = r.sdb.GetContext(ctx, grant, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, constants.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return
+}
+
+// Fetch returns the grants matching the params, the policies load the ones of the user on the requested resource
+func (r *pgsqlResourceGrantRepository) Fetch(ctx context.Context, params ParamsForFetchResourceGrants) (rs []*domains.ResourceGrant, err error) {
+	rs = make([]*domains.ResourceGrant, 0)
+
+	psql := sqlTools.NewPSQLStatementBuilder(r.sdb)
+	cols, _ := sqlTools.GetColumnsAndValuesFromStruct(ctx, &domains.ResourceGrant{})
+	query, args, err := r.buildQueryFilters(psql.Select(cols...).From(resourceGrantsTableName), params).
+		OrderBy("id ASC").ToSql()
+	if err != nil {
+		return
+	}
+
+	err = r.sdb.SelectContext(ctx, &rs, query, args...)
+	return
+}
+
+func (r *pgsqlResourceGrantRepository) buildQueryFilters(builder squirrel.SelectBuilder, params ParamsForFetchResourceGrants) squirrel.SelectBuilder {
+	if params.OrgId > 0 {
+		builder = builder.Where(squirrel.Eq{"org_id": params.OrgId})
+	}
+	if params.UserId > 0 {
+		builder = builder.Where(squirrel.Eq{"user_id": params.UserId})
+	}
+	if params.ResourceType != "" {
+		builder = builder.Where(squirrel.Eq{"resource_type": params.ResourceType})
+	}
+	if params.ResourceId > 0 {
+		builder = builder.Where(squirrel.Eq{"resource_id": params.ResourceId})
+	}
+	return builder
+}
+
+func (r *pgsqlResourceGrantRepository) DeleteById(ctx context.Context, id int64) (err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	query := psql.Delete(resourceGrantsTableName).Where(squirrel.Eq{
+		"id": id,
+	})
+
+	_, err = query.ExecContext(ctx)
+	return
+}
@@ -0,0 +1,202 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/dzungtran/echo-rest-api/infrastructure/datastore"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	sqlTools "github.com/dzungtran/echo-rest-api/pkg/sql-tools"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	serviceAccountsTableName = "service_accounts"
+)
+
+type ServiceAccountRepository interface {
+	Create(ctx context.Context, sa *domains.ServiceAccount) (int64, error)
+	GetByID(ctx context.Context, id int64) (*domains.ServiceAccount, error)
+	GetByClientId(ctx context.Context, clientId string) (*domains.ServiceAccount, error)
+	Fetch(ctx context.Context, params ParamsForFetchServiceAccounts) ([]*domains.ServiceAccount, int64, error)
+	Update(ctx context.Context, sa *domains.ServiceAccount, fieldsToUpdate []string) error
+	DeleteById(ctx context.Context, id int64) error
+}
+
+type (
+	pgsqlServiceAccountRepository struct {
+		db  *sqlx.DB
+		sdb *sqlx.DB
+	}
+	ParamsForFetchServiceAccounts struct {
+		OrgId int64
+		contexts.CommonParamsForFetch
+	}
+)
+
+// NewPgsqlServiceAccountRepository will create new a serviceAccountRepository object representation of ServiceAccountRepository interface
+func NewPgsqlServiceAccountRepository(mdbi *datastore.MasterDbInstance, sdbi *datastore.SlaveDbInstance) ServiceAccountRepository {
+	return &pgsqlServiceAccountRepository{
+		db:  mdbi.DBX(),
+		sdb: sdbi.DBX(),
+	}
+}
+
+func (r *pgsqlServiceAccountRepository) Create(ctx context.Context, sa *domains.ServiceAccount) (newId int64, err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	cols, vals := sqlTools.GetColumnsAndValuesFromStruct(
+		ctx,
+		sa,
+		sqlTools.WithMapValuesIgnoreFields([]string{"id"}),
+		sqlTools.WithMapValuesAutoDateTimeFields([]string{"created_at", "updated_at"}),
+	)
+
+	query := psql.Insert(serviceAccountsTableName).
+		Columns(cols...).
+		Values(vals...).
+		Suffix(`RETURNING id`)
+
+	err = query.QueryRowContext(ctx).Scan(&newId)
+	if err != nil {
+		if utils.IsDuplicatedError(err) {
+			err = constants.ErrDuplicated
+		}
+		return
+	}
+	return
+}
+
+func (r *pgsqlServiceAccountRepository) GetByID(ctx context.Context, id int64) (*domains.ServiceAccount, error) {
+	if id <= 0 {
+		return nil, errors.New("invalid id")
+	}
+	return r.getBy(ctx, squirrel.Eq{"id": id})
+}
+
+func (r *pgsqlServiceAccountRepository) GetByClientId(ctx context.Context, clientId string) (*domains.ServiceAccount, error) {
+	if clientId == "" {
+		return nil, errors.New("invalid client id")
+	}
+	return r.getBy(ctx, squirrel.Eq{"client_id": clientId})
+}
+
+func (r *pgsqlServiceAccountRepository) getBy(ctx context.Context, cond squirrel.Eq) (sa *domains.ServiceAccount, err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.sdb)
+	cols, _ := sqlTools.GetColumnsAndValuesFromStruct(ctx, &domains.ServiceAccount{})
+	query, args, err := psql.Select(cols...).From(serviceAccountsTableName).
+		Where(cond).ToSql()
+	if err != nil {
+		return
+	}
+
+	sa = &domains.ServiceAccount{}
+	err = r.sdb.GetContext(ctx, sa, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, constants.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return
+}
+
+func (r *pgsqlServiceAccountRepository) Fetch(ctx context.Context, params ParamsForFetchServiceAccounts) (rs []*domains.ServiceAccount, count int64, err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.sdb)
+	type serviceAccountWithCount struct {
+		domains.ServiceAccount
+		Count int64 `db:"_count"` // special field for count
+	}
+
+	cols, _ := sqlTools.GetColumnsAndValuesFromStruct(ctx, &serviceAccountWithCount{})
+	query := psql.Select(sqlTools.ParseColumnsForSelect(cols)...).From(serviceAccountsTableName)
+	query = r.buildQueryFilters(query, params)
+	sqlQuery, args, err := sqlTools.
+		BindCommonParamsToSelectBuilder(query, params.CommonParamsForFetch).
+		OrderBy("created_at DESC").ToSql()
+	if err != nil {
+		return nil, count, err
+	}
+
+	rows, err := r.sdb.QueryxContext(ctx, sqlQuery, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, count, constants.ErrNotFound
+		}
+		return nil, count, err
+	}
+	defer rows.Close()
+
+	rs = make([]*domains.ServiceAccount, 0)
+	for rows.Next() {
+		var sawc serviceAccountWithCount
+		err = rows.StructScan(&sawc)
+		if err != nil {
+			return nil, count, err
+		}
+
+		count = sawc.Count
+		sa := sawc.ServiceAccount
+		rs = append(rs, &sa)
+	}
+
+	return
+}
+
+func (r *pgsqlServiceAccountRepository) Update(ctx context.Context, sa *domains.ServiceAccount, fieldsToUpdate []string) (err error) {
+	if len(fieldsToUpdate) == 0 {
+		fieldsToUpdate = make([]string, 0)
+	}
+
+	if sa.Id <= 0 {
+		return errors.New("missing service account id")
+	}
+
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	query := psql.Update(serviceAccountsTableName).
+		SetMap(sqlTools.GetMapValuesFromStruct(
+			ctx, sa,
+			sqlTools.WithMapValuesSelectFields(fieldsToUpdate),
+			sqlTools.WithMapValuesIgnoreFields([]string{"id"}),
+			sqlTools.WithMapValuesAutoDateTimeFields([]string{"updated_at"}),
+		)).
+		Where(squirrel.Eq{
+			"id": sa.Id,
+		})
+
+	affect, err := query.ExecContext(ctx)
+	if err != nil {
+		return
+	}
+
+	_, err = affect.RowsAffected()
+	return
+}
+
+func (r *pgsqlServiceAccountRepository) DeleteById(ctx context.Context, id int64) (err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	query := psql.Delete(serviceAccountsTableName).Where(squirrel.Eq{
+		"id": id,
+	})
+
+	_, err = query.ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return
+}
+
+func (r *pgsqlServiceAccountRepository) buildQueryFilters(builder squirrel.SelectBuilder, params ParamsForFetchServiceAccounts) squirrel.SelectBuilder {
+	if params.OrgId > 0 {
+		builder = builder.Where(squirrel.Eq{
+			"org_id": params.OrgId,
+		})
+	}
+	return builder
+}
@@ -0,0 +1,199 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/dzungtran/echo-rest-api/infrastructure/datastore"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	sqlTools "github.com/dzungtran/echo-rest-api/pkg/sql-tools"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	sessionsTableName = "sessions"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *domains.Session) (int64, error)
+	GetByID(ctx context.Context, id int64) (*domains.Session, error)
+	Fetch(ctx context.Context, params ParamsForFetchSessions) ([]*domains.Session, int64, error)
+	Update(ctx context.Context, session *domains.Session, fieldsToUpdate []string) error
+	RevokeByUserId(ctx context.Context, userId int64) error
+}
+
+type (
+	pgsqlSessionRepository struct {
+		db  *sqlx.DB
+		sdb *sqlx.DB
+	}
+	ParamsForFetchSessions struct {
+		UserId     int64
+		ActiveOnly bool
+		contexts.CommonParamsForFetch
+	}
+)
+
+// NewPgsqlSessionRepository will create new a sessionRepository object representation of SessionRepository interface
+func NewPgsqlSessionRepository(mdbi *datastore.MasterDbInstance, sdbi *datastore.SlaveDbInstance) SessionRepository {
+	return &pgsqlSessionRepository{
+		db:  mdbi.DBX(),
+		sdb: sdbi.DBX(),
+	}
+}
+
+func (r *pgsqlSessionRepository) Create(ctx context.Context, session *domains.Session) (newId int64, err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	cols, vals := sqlTools.GetColumnsAndValuesFromStruct(
+		ctx,
+		session,
+		sqlTools.WithMapValuesIgnoreFields([]string{"id"}),
+		sqlTools.WithMapValuesAutoDateTimeFields([]string{"created_at", "updated_at"}),
+	)
+
+	query := psql.Insert(sessionsTableName).
+		Columns(cols...).
+		Values(vals...).
+		Suffix(`RETURNING id`)
+
+	err = query.QueryRowContext(ctx).Scan(&newId)
+	return
+}
+
+// GetByID reads from the master, a revoked session must be rejected right away
+func (r *pgsqlSessionRepository) GetByID(ctx context.Context, id int64) (session *domains.Session, err error) {
+	if id <= 0 {
+		return nil, errors.New("invalid id")
+	}
+
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	cols, _ := sqlTools.GetColumnsAndValuesFromStruct(ctx, &domains.Session{})
+	query, args, err := psql.Select(cols...).From(sessionsTableName).
+		Where(squirrel.Eq{"id": id}).ToSql()
+	if err != nil {
+		return
+	}
+
+	session = &domains.Session{}
+	err = r.db.GetContext(ctx, session, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, constants.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return
+}
+
+func (r *pgsqlSessionRepository) Fetch(ctx context.Context, params ParamsForFetchSessions) (rs []*domains.Session, count int64, err error) {
+	psql := sqlTools.NewPSQLStatementBuilder(r.sdb)
+	type sessionWithCount struct {
+		domains.Session
+		Count int64 `db:"_count"` // special field for count
+	}
+
+	cols, _ := sqlTools.GetColumnsAndValuesFromStruct(ctx, &sessionWithCount{})
+	query := psql.Select(sqlTools.ParseColumnsForSelect(cols)...).From(sessionsTableName)
+	query = r.buildQueryFilters(query, params)
+	sqlQuery, args, err := sqlTools.
+		BindCommonParamsToSelectBuilder(query, params.CommonParamsForFetch).
+		OrderBy("last_seen_at DESC").ToSql()
+	if err != nil {
+		return nil, count, err
+	}
+
+	rows, err := r.sdb.QueryxContext(ctx, sqlQuery, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, count, constants.ErrNotFound
+		}
+		return nil, count, err
+	}
+	defer rows.Close()
+
+	rs = make([]*domains.Session, 0)
+	for rows.Next() {
+		var swc sessionWithCount
+		err = rows.StructScan(&swc)
+		if err != nil {
+			return nil, count, err
+		}
+
+		count = swc.Count
+		s := swc.Session
+		rs = append(rs, &s)
+	}
+
+	return
+}
+
+func (r *pgsqlSessionRepository) Update(ctx context.Context, session *domains.Session, fieldsToUpdate []string) (err error) {
+	if len(fieldsToUpdate) == 0 {
+		fieldsToUpdate = make([]string, 0)
+	}
+
+	if session.Id <= 0 {
+		return errors.New("missing session id")
+	}
+
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	query := psql.Update(sessionsTableName).
+		SetMap(sqlTools.GetMapValuesFromStruct(
+			ctx, session,
+			sqlTools.WithMapValuesSelectFields(fieldsToUpdate),
+			sqlTools.WithMapValuesIgnoreFields([]string{"id"}),
+			sqlTools.WithMapValuesAutoDateTimeFields([]string{"updated_at"}),
+		)).
+		Where(squirrel.Eq{
+			"id": session.Id,
+		})
+
+	affect, err := query.ExecContext(ctx)
+	if err != nil {
+		return
+	}
+
+	_, err = affect.RowsAffected()
+	return
+}
+
+func (r *pgsqlSessionRepository) RevokeByUserId(ctx context.Context, userId int64) error {
+	if userId <= 0 {
+		return errors.New("invalid user id")
+	}
+
+	now := time.Now().UTC()
+	psql := sqlTools.NewPSQLStatementBuilder(r.db)
+	_, err := psql.Update(sessionsTableName).
+		Set("revoked_at", now).
+		Set("updated_at", now).
+		Where(squirrel.Eq{
+			"user_id":    userId,
+			"revoked_at": nil,
+		}).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *pgsqlSessionRepository) buildQueryFilters(builder squirrel.SelectBuilder, params ParamsForFetchSessions) squirrel.SelectBuilder {
+	if params.UserId > 0 {
+		builder = builder.Where(squirrel.Eq{
+			"user_id": params.UserId,
+		})
+	}
+
+	if params.ActiveOnly {
+		builder = builder.Where(squirrel.Eq{
+			"revoked_at": nil,
+		}).Where(squirrel.Gt{
+			"expires_at": time.Now().UTC(),
+		})
+	}
+	return builder
+}
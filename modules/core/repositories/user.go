@@ -26,6 +26,7 @@ type UserRepository interface {
 	Update(ctx context.Context, user *domains.User, fieldsToUpdate []string) error
 	DeleteById(ctx context.Context, id int64) error
 	GetByCode(ctx context.Context, code string) (*domains.User, error)
+	GetByCodeFromMaster(ctx context.Context, code string) (*domains.User, error)
 	GetByEmail(ctx context.Context, email string) (*domains.User, error)
 }
 
@@ -160,6 +161,9 @@ func (r *pgsqlUserRepository) Update(ctx context.Context, user *domains.User, fi
 
 	affect, err := query.ExecContext(ctx)
 	if err != nil {
+		if utils.IsDuplicatedError(err) {
+			err = constants.ErrDuplicated
+		}
 		return
 	}
 
@@ -182,11 +186,20 @@ func (r *pgsqlUserRepository) DeleteById(ctx context.Context, id int64) (err err
 }
 
 func (r *pgsqlUserRepository) GetByCode(ctx context.Context, code string) (user *domains.User, err error) {
+	return r.getByCode(ctx, r.sdb, code)
+}
+
+// GetByCodeFromMaster reads from the master, the user may have just been registered by a concurrent call
+func (r *pgsqlUserRepository) GetByCodeFromMaster(ctx context.Context, code string) (user *domains.User, err error) {
+	return r.getByCode(ctx, r.db, code)
+}
+
+func (r *pgsqlUserRepository) getByCode(ctx context.Context, db *sqlx.DB, code string) (user *domains.User, err error) {
 	if len(code) == 0 {
 		return nil, errors.New("invalid code")
 	}
 
-	psql := sqlTools.NewPSQLStatementBuilder(r.sdb)
+	psql := sqlTools.NewPSQLStatementBuilder(db)
 	cols, _ := sqlTools.GetColumnsAndValuesFromStruct(ctx, &domains.User{})
 	query, args, err := psql.Select(cols...).From(usersTableName).
 		Where(squirrel.Eq{
@@ -197,7 +210,7 @@ func (r *pgsqlUserRepository) GetByCode(ctx context.Context, code string) (user
 	}
 
 	user = &domains.User{}
-	err = r.sdb.Get(user, query, args...)
+	err = db.Get(user, query, args...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, constants.ErrNotFound
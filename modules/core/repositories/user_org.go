@@ -37,6 +37,7 @@ type (
 		UserIds []int64
 		OrgId   int64
 		Emails  []string
+		Roles   []string
 		contexts.CommonParamsForFetch
 	}
 )
@@ -234,6 +235,12 @@ func (r *pgsqlUserOrgRepository) buildQueryFilters(builder squirrel.SelectBuilde
 		})
 	}
 
+	if len(params.Roles) > 0 {
+		builder = builder.Where(squirrel.Eq{
+			tAlias + ".role": params.Roles,
+		})
+	}
+
 	if len(params.Emails) > 0 {
 		builder = builder.Join(fmt.Sprintf("%s AS u ON u.id = %s.user_id", usersTableName, tAlias))
 		builder = builder.Where(squirrel.Eq{
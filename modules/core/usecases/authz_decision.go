@@ -0,0 +1,163 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/repositories"
+	projectRepo "github.com/dzungtran/echo-rest-api/modules/projects/repositories"
+	"github.com/dzungtran/echo-rest-api/pkg/authz"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	"github.com/dzungtran/echo-rest-api/pkg/cue"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+)
+
+var ErrCheckAuthzUser = errors.New("either user_id or user is required")
+
+// AuthzDecisionUsecase represent the authorization decision log's usecase contract
+type AuthzDecisionUsecase interface {
+	StartRecording(ctx context.Context) error
+	Fetch(ctx context.Context, request dto.SearchAuthzDecisionsReq) ([]*domains.AuthzDecision, int64, error)
+	ValidateCheck(request dto.CheckAuthzReq) error
+	Explain(ctx context.Context, user *domains.UserWithRoles, request dto.CheckAuthzReq) (*authz.Explanation, error)
+}
+
+type authzDecisionUsecase struct {
+	appConf           *config.AppConfig
+	authzDecisionRepo repositories.AuthzDecisionRepository
+	orgRepo           repositories.OrgRepository
+	projectRepo       projectRepo.ProjectRepository
+	authorizer        authz.Authorizer
+	policyAdmin       authz.PolicyAdmin
+	// decisionLogger is set by StartRecording, Fetch reads its recent decisions
+	decisionLogger *authz.DecisionLogger
+}
+
+// NewAuthzDecisionUsecase will create new an authzDecisionUsecase object representation of AuthzDecisionUsecase interface
+func NewAuthzDecisionUsecase(
+	appConf *config.AppConfig,
+	authzDecisionRepo repositories.AuthzDecisionRepository,
+	orgRepo repositories.OrgRepository,
+	projectRepo projectRepo.ProjectRepository,
+	authorizer authz.Authorizer,
+	policyAdmin authz.PolicyAdmin,
+) AuthzDecisionUsecase {
+	return &authzDecisionUsecase{
+		appConf:           appConf,
+		authzDecisionRepo: authzDecisionRepo,
+		orgRepo:           orgRepo,
+		projectRepo:       projectRepo,
+		authorizer:        authorizer,
+		policyAdmin:       policyAdmin,
+	}
+}
+
+// StartRecording makes the policies record their decisions to DECISION_LOG_SINK until ctx is done
+func (u *authzDecisionUsecase) StartRecording(ctx context.Context) error {
+	var sink authz.DecisionSink
+	switch u.appConf.DecisionLogSink {
+	case "":
+		return nil
+	case authz.DecisionSinkLog:
+		sink = authz.NewLogDecisionSink()
+	case authz.DecisionSinkDB:
+		sink = authz.DecisionSinkFunc(u.authzDecisionRepo.Create)
+	case authz.DecisionSinkFile:
+		var err error
+		if sink, err = authz.NewFileDecisionSink(u.appConf.DecisionLogFile); err != nil {
+			return fmt.Errorf("cannot open DECISION_LOG_FILE: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown DECISION_LOG_SINK: %s", u.appConf.DecisionLogSink)
+	}
+
+	l, err := authz.NewDecisionLogger(sink, authz.DecisionLoggerOptions{
+		SampleRate:   u.appConf.DecisionLogSampleRate,
+		RedactFields: u.appConf.DecisionLogRedactFields,
+	})
+	if err != nil {
+		return err
+	}
+
+	go l.Run(ctx)
+	u.decisionLogger = l
+	u.policyAdmin.SetDecisionLogger(l)
+
+	logger.Log().Infow("recording authz decisions", "sink", u.appConf.DecisionLogSink,
+		"sample_rate", u.appConf.DecisionLogSampleRate)
+	return nil
+}
+
+// Fetch reads the decisions stored in the database with the db sink, the recent ones kept in memory otherwise
+func (u *authzDecisionUsecase) Fetch(ctx context.Context, req dto.SearchAuthzDecisionsReq) ([]*domains.AuthzDecision, int64, error) {
+	if u.appConf.DecisionLogSink == authz.DecisionSinkDB {
+		return u.authzDecisionRepo.Fetch(ctx, repositories.ParamsForFetchAuthzDecisions{
+			UserId:    req.UserId,
+			OrgId:     req.OrgId,
+			Allowed:   req.Allowed,
+			RequestId: req.RequestId,
+			Since:     req.Since,
+			CommonParamsForFetch: contexts.CommonParamsForFetch{
+				Page:  uint64(req.Page),
+				Limit: uint64(req.Limit),
+			},
+		})
+	}
+
+	decisions := u.decisionLogger.RecentDecisions(authz.DecisionFilter{
+		UserId:    req.UserId,
+		OrgId:     req.OrgId,
+		Allowed:   req.Allowed,
+		RequestId: req.RequestId,
+		Since:     req.Since,
+		Limit:     int(req.Limit),
+	})
+	return decisions, int64(len(decisions)), nil
+}
+
+// ValidateCheck validates the request before the user to check is resolved
+func (u *authzDecisionUsecase) ValidateCheck(req dto.CheckAuthzReq) error {
+	if err := utils.CueValidateObject("CheckAuthzRequest", cue.CueDefinitionForAuthz, req); err != nil {
+		return err
+	}
+	if (req.UserId > 0) == (req.User != nil) {
+		return ErrCheckAuthzUser
+	}
+	return nil
+}
+
+// Explain evaluates the request for the user like the policies middlewares do, loading its org and project
+func (u *authzDecisionUsecase) Explain(ctx context.Context, user *domains.UserWithRoles, req dto.CheckAuthzReq) (*authz.Explanation, error) {
+	callOpts := []authz.CallOPAInputOption{
+		authz.WithInputRequestMethod(req.Method),
+		authz.WithInputRequestEndpoint(req.Endpoint),
+	}
+
+	if req.OrgId > 0 {
+		org, err := u.orgRepo.GetByID(ctx, req.OrgId)
+		if err != nil {
+			return nil, err
+		}
+		callOpts = append(callOpts, authz.WithInputOrg(org))
+	}
+	if req.ProjectId > 0 {
+		project, err := u.projectRepo.GetByID(ctx, req.ProjectId)
+		if err != nil {
+			return nil, err
+		}
+		callOpts = append(callOpts, authz.WithInputExtraData("project", project))
+	}
+	if req.Payload != nil {
+		callOpts = append(callOpts, authz.WithInputExtraData("payload", req.Payload))
+	}
+	if req.TokenPermissions != nil {
+		callOpts = append(callOpts, authz.WithInputTokenPermissions(req.TokenPermissions))
+	}
+
+	return u.authorizer.Explain(ctx, user, req.Trace, callOpts...), nil
+}
@@ -0,0 +1,142 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/repositories"
+	"github.com/dzungtran/echo-rest-api/pkg/authz"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	"github.com/dzungtran/echo-rest-api/pkg/cue"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+)
+
+var (
+	// ErrImpersonationNotAllowed is returned for targets a super admin cannot impersonate
+	ErrImpersonationNotAllowed = errors.New("impersonation is not allowed")
+)
+
+// ImpersonationUsecase represent the impersonation's usecase contract
+type ImpersonationUsecase interface {
+	Start(ctx context.Context, actorUserId int64, request dto.StartImpersonationReq) (*domains.Impersonation, error)
+	GetActive(ctx context.Context, actorUserId int64) (*domains.Impersonation, error)
+	Stop(ctx context.Context, actorUserId int64) error
+	ValidateTarget(ctx context.Context, actorUserId int64, target *domains.UserWithRoles) error
+	Audit(ctx context.Context, log *domains.ImpersonationAuditLog) error
+	FetchAuditLogs(ctx context.Context, request dto.SearchImpersonationAuditLogsReq) ([]*domains.ImpersonationAuditLog, int64, error)
+}
+
+type impersonationUsecase struct {
+	appConf           *config.AppConfig
+	impersonationRepo repositories.ImpersonationRepository
+	userRepo          repositories.UserRepository
+	platformRoleRepo  repositories.PlatformRoleRepository
+	authorizer        authz.Authorizer
+}
+
+// NewImpersonationUsecase will create new an impersonationUsecase object representation of ImpersonationUsecase interface
+func NewImpersonationUsecase(
+	appConf *config.AppConfig,
+	impersonationRepo repositories.ImpersonationRepository,
+	userRepo repositories.UserRepository,
+	platformRoleRepo repositories.PlatformRoleRepository,
+	authorizer authz.Authorizer,
+) ImpersonationUsecase {
+	return &impersonationUsecase{
+		appConf:           appConf,
+		impersonationRepo: impersonationRepo,
+		userRepo:          userRepo,
+		platformRoleRepo:  platformRoleRepo,
+		authorizer:        authorizer,
+	}
+}
+
+// Start ends the running impersonation of the actor and starts a new one for IMPERSONATION_TTL
+func (u *impersonationUsecase) Start(ctx context.Context, actorUserId int64, req dto.StartImpersonationReq) (*domains.Impersonation, error) {
+	if err := utils.CueValidateObject("StartImpersonationRequest", cue.CueDefinitionForImpersonation, req); err != nil {
+		return nil, err
+	}
+
+	user, err := u.userRepo.GetByID(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := u.platformRoleRepo.FetchByUserId(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	target := &domains.UserWithRoles{User: *user, Kind: domains.PrincipalKindUser}
+	for _, r := range roles {
+		target.PlatformRoles = append(target.PlatformRoles, r.Role)
+	}
+
+	if err = u.ValidateTarget(ctx, actorUserId, target); err != nil {
+		return nil, err
+	}
+
+	if err = u.impersonationRepo.EndByActorId(ctx, actorUserId); err != nil {
+		return nil, err
+	}
+
+	impersonation := &domains.Impersonation{
+		ActorUserId:  actorUserId,
+		TargetUserId: target.Id,
+		Reason:       req.Reason,
+		AllowWrites:  req.AllowWrites,
+		ExpiresAt:    time.Now().UTC().Add(u.appConf.ImpersonationTTL),
+	}
+
+	id, err := u.impersonationRepo.Create(ctx, impersonation)
+	if err != nil {
+		return nil, err
+	}
+
+	impersonation.Id = id
+	return impersonation, nil
+}
+
+func (u *impersonationUsecase) GetActive(ctx context.Context, actorUserId int64) (*domains.Impersonation, error) {
+	return u.impersonationRepo.GetActiveByActorId(ctx, actorUserId)
+}
+
+func (u *impersonationUsecase) Stop(ctx context.Context, actorUserId int64) error {
+	return u.impersonationRepo.EndByActorId(ctx, actorUserId)
+}
+
+// ValidateTarget rejects impersonating oneself, another super admin or an inactive user
+func (u *impersonationUsecase) ValidateTarget(ctx context.Context, actorUserId int64, target *domains.UserWithRoles) error {
+	if target.Id == actorUserId {
+		return fmt.Errorf("%w: cannot impersonate yourself", ErrImpersonationNotAllowed)
+	}
+
+	if target.Status != domains.UserStatusActive {
+		return fmt.Errorf("%w: user is not active", ErrImpersonationNotAllowed)
+	}
+
+	if u.authorizer.IsSuperAdmin(ctx, target) {
+		return fmt.Errorf("%w: cannot impersonate a super admin", ErrImpersonationNotAllowed)
+	}
+	return nil
+}
+
+func (u *impersonationUsecase) Audit(ctx context.Context, log *domains.ImpersonationAuditLog) error {
+	return u.impersonationRepo.CreateAuditLog(ctx, log)
+}
+
+func (u *impersonationUsecase) FetchAuditLogs(ctx context.Context, req dto.SearchImpersonationAuditLogsReq) ([]*domains.ImpersonationAuditLog, int64, error) {
+	return u.impersonationRepo.FetchAuditLogs(ctx, repositories.ParamsForFetchImpersonationAuditLogs{
+		ActorUserId:  req.ActorId,
+		TargetUserId: req.TargetId,
+		CommonParamsForFetch: contexts.CommonParamsForFetch{
+			Page:  uint64(req.Page),
+			Limit: uint64(req.Limit),
+		},
+	})
+}
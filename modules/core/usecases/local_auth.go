@@ -0,0 +1,534 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/repositories"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/cue"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/dzungtran/echo-rest-api/pkg/mailer"
+	"github.com/dzungtran/echo-rest-api/pkg/oidc"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	localAuthRefreshTokenPrefix = "rt_"
+	localAuthTokenSize          = 32
+	localAuthAccessTokenType    = "Bearer"
+	localAuthVerifyEmailTTL     = 48 * time.Hour
+	localAuthResetPasswordTTL   = time.Hour
+	localAuthEmailClaim         = "email"
+	localAuthSessionIdClaim     = "sid"
+)
+
+var (
+	// ErrNotLocalToken is returned by VerifyAccessToken for tokens issued by someone else
+	ErrNotLocalToken = errors.New("not a local access token")
+	// ErrEmailNotVerified is returned by Login until the email verification link is opened
+	ErrEmailNotVerified = errors.New("email is not verified")
+	// ErrInvalidLocalAuthToken is returned for unknown, used or expired verification and reset tokens
+	ErrInvalidLocalAuthToken = errors.New("invalid or expired token")
+	ErrMissingSigningKeys    = errors.New("LOCAL_AUTH_SIGNING_KEYS_FILE is required in production")
+	ErrMissingMailer         = errors.New("SMTP_HOST is required in production, the emails of the local auth provider would only be logged")
+
+	// compared when the email is unknown, so that login takes the same time for known and unknown emails
+	localAuthDummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+)
+
+// LocalAuthUsecase represent the built-in identity provider's usecase contract
+type LocalAuthUsecase interface {
+	Signup(ctx context.Context, request dto.LocalSignupReq) (*domains.User, error)
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, email string) error
+	Login(ctx context.Context, request dto.LocalLoginReq, client domains.SessionClient) (*dto.LocalAuthTokenResp, error)
+	Refresh(ctx context.Context, refreshToken string, client domains.SessionClient) (*dto.LocalAuthTokenResp, error)
+	Logout(ctx context.Context, refreshToken string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, request dto.LocalResetPasswordReq) error
+	VerifyAccessToken(ctx context.Context, rawToken string) (*LocalAccessToken, error)
+	PublicKeySet() jwk.Set
+}
+
+// LocalAccessToken is the verified content of an access token issued by the built-in identity provider
+type LocalAccessToken struct {
+	UserCode      string
+	SessionId     int64
+	MfaVerifiedAt *time.Time
+}
+
+type localAuthUsecase struct {
+	appConf       *config.AppConfig
+	userUC        UserUsecase
+	sessionUC     SessionUsecase
+	localAuthRepo repositories.LocalAuthRepository
+	mailer        mailer.Mailer
+	signer        *oidc.Signer
+	verifier      *oidc.Verifier
+}
+
+// NewLocalAuthUsecase will create new a localAuthUsecase object representation of LocalAuthUsecase interface.
+// Without LOCAL_AUTH_SIGNING_KEYS_FILE an ephemeral key is generated, tokens are then invalidated on restart.
+// In production the signing keys and a mailer sending the emails are required.
+func NewLocalAuthUsecase(
+	appConf *config.AppConfig,
+	userUC UserUsecase,
+	sessionUC SessionUsecase,
+	localAuthRepo repositories.LocalAuthRepository,
+	m mailer.Mailer,
+) (LocalAuthUsecase, error) {
+	var (
+		signer *oidc.Signer
+		err    error
+	)
+
+	// the verification and password reset links would never reach the users
+	if appConf.Environment == constants.EnvironmentProduction && mailer.IsLogMailer(m) {
+		return nil, ErrMissingMailer
+	}
+
+	switch {
+	case appConf.LocalAuthSigningKeysFile != "":
+		signer, err = oidc.NewSignerFromFile(appConf.LocalAuthSigningKeysFile, appConf.LocalAuthSigningKeyId)
+	case appConf.Environment == constants.EnvironmentProduction:
+		err = ErrMissingSigningKeys
+	default:
+		logger.Log().Warn("LOCAL_AUTH_SIGNING_KEYS_FILE is not set, local access tokens are signed with an ephemeral key")
+		signer, err = oidc.NewEphemeralSigner()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, err := oidc.NewVerifier(oidc.Config{
+		Issuer: appConf.LocalAuthIssuer,
+		KeySet: signer.PublicKeySet(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &localAuthUsecase{
+		appConf:       appConf,
+		userUC:        userUC,
+		sessionUC:     sessionUC,
+		localAuthRepo: localAuthRepo,
+		mailer:        m,
+		signer:        signer,
+		verifier:      verifier,
+	}, nil
+}
+
+// Signup registers the user with a password and sends the email verification link
+func (u *localAuthUsecase) Signup(ctx context.Context, req dto.LocalSignupReq) (*domains.User, error) {
+	req.Email = normalizeEmail(req.Email)
+	if err := utils.CueValidateObject("LocalSignupRequest", cue.CueDefinitionForLocalAuth, req); err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := u.userUC.Register(ctx, dto.CreateUserReq{
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Email:     req.Email,
+		Code:      utils.GenerateLongUUID(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.localAuthRepo.CreateCredential(ctx, &domains.LocalCredential{
+		UserId:       user.Id,
+		PasswordHash: string(passwordHash),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = u.sendVerificationEmail(ctx, user); err != nil {
+		logger.Log().Errorw("cannot send verification email", "user_id", user.Id, "error", err)
+	}
+	return user, nil
+}
+
+func (u *localAuthUsecase) VerifyEmail(ctx context.Context, token string) error {
+	authToken, err := u.redeemAuthToken(ctx, token, domains.LocalAuthTokenPurposeVerifyEmail)
+	if err != nil {
+		return err
+	}
+
+	cred, err := u.localAuthRepo.GetCredentialByUserId(ctx, authToken.UserId)
+	if err != nil {
+		return err
+	}
+
+	if cred.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	cred.EmailVerifiedAt = &now
+	return u.localAuthRepo.UpdateCredential(ctx, cred, []string{"email_verified_at"})
+}
+
+// ResendVerification sends a new verification link, unknown or verified emails are silently ignored
+func (u *localAuthUsecase) ResendVerification(ctx context.Context, email string) error {
+	user, cred, err := u.getUserWithCredential(ctx, email)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if cred.EmailVerifiedAt != nil {
+		return nil
+	}
+	return u.sendVerificationEmail(ctx, user)
+}
+
+func (u *localAuthUsecase) Login(ctx context.Context, req dto.LocalLoginReq, client domains.SessionClient) (*dto.LocalAuthTokenResp, error) {
+	user, cred, err := u.getUserWithCredential(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			_ = bcrypt.CompareHashAndPassword(localAuthDummyPasswordHash, []byte(req.Password))
+			return nil, constants.ErrUnauthorized
+		}
+		return nil, err
+	}
+
+	if err = bcrypt.CompareHashAndPassword([]byte(cred.PasswordHash), []byte(req.Password)); err != nil {
+		return nil, constants.ErrUnauthorized
+	}
+
+	if user.Status != domains.UserStatusActive {
+		return nil, constants.ErrUnauthorized
+	}
+
+	if cred.EmailVerifiedAt == nil {
+		return nil, ErrEmailNotVerified
+	}
+
+	client.Device = strings.TrimSpace(req.Device)
+	session, err := u.sessionUC.Start(ctx, user.Id, constants.AuthProviderLocal, client,
+		time.Now().UTC().Add(u.appConf.LocalAuthRefreshTokenTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	return u.issueTokens(ctx, user, session)
+}
+
+// Refresh rotates the refresh token, presenting a rotated token again revokes the session
+func (u *localAuthUsecase) Refresh(ctx context.Context, refreshToken string, client domains.SessionClient) (*dto.LocalAuthTokenResp, error) {
+	rt, err := u.getRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := u.sessionUC.Verify(ctx, rt.SessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	// only one of concurrent refreshes with the same token rotates it, the others are reuses
+	now := time.Now().UTC()
+	rotated, err := u.localAuthRepo.RotateRefreshToken(ctx, rt.Id, now)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		logger.Log().Warnw("rotated refresh token is reused, revoking the session", "user_id", rt.UserId, "session_id", rt.SessionId)
+		if err = u.sessionUC.Revoke(ctx, rt.UserId, rt.SessionId); err != nil {
+			return nil, err
+		}
+		return nil, constants.ErrUnauthorized
+	}
+
+	user, err := u.userUC.GetByID(ctx, rt.UserId)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return nil, constants.ErrUnauthorized
+		}
+		return nil, err
+	}
+
+	if user.Status != domains.UserStatusActive {
+		return nil, constants.ErrUnauthorized
+	}
+
+	if err = u.sessionUC.Extend(ctx, session, client, now.Add(u.appConf.LocalAuthRefreshTokenTTL)); err != nil {
+		return nil, err
+	}
+
+	return u.issueTokens(ctx, user, session)
+}
+
+// Logout revokes the session of the refresh token, its access tokens are rejected from now on
+func (u *localAuthUsecase) Logout(ctx context.Context, refreshToken string) error {
+	rt, err := u.getRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+	return u.sessionUC.Revoke(ctx, rt.UserId, rt.SessionId)
+}
+
+// ForgotPassword sends a reset password link, unknown emails are silently ignored
+func (u *localAuthUsecase) ForgotPassword(ctx context.Context, email string) error {
+	user, _, err := u.getUserWithCredential(ctx, email)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := u.createAuthToken(ctx, user.Id, domains.LocalAuthTokenPurposeResetPassword, localAuthResetPasswordTTL)
+	if err != nil {
+		return err
+	}
+
+	return u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Use the following token to reset your password, it expires in %s:\n\n%s\n",
+			localAuthResetPasswordTTL, token),
+	})
+}
+
+// ResetPassword sets a new password and signs the user out of all sessions
+func (u *localAuthUsecase) ResetPassword(ctx context.Context, req dto.LocalResetPasswordReq) error {
+	if err := utils.CueValidateObject("LocalResetPasswordRequest", cue.CueDefinitionForLocalAuth, req); err != nil {
+		return err
+	}
+
+	authToken, err := u.redeemAuthToken(ctx, req.Token, domains.LocalAuthTokenPurposeResetPassword)
+	if err != nil {
+		return err
+	}
+
+	cred, err := u.localAuthRepo.GetCredentialByUserId(ctx, authToken.UserId)
+	if err != nil {
+		return err
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	fields := []string{"password_hash"}
+	cred.PasswordHash = string(passwordHash)
+	if cred.EmailVerifiedAt == nil {
+		// the reset link proves the ownership of the email
+		now := time.Now().UTC()
+		cred.EmailVerifiedAt = &now
+		fields = append(fields, "email_verified_at")
+	}
+
+	if err = u.localAuthRepo.UpdateCredential(ctx, cred, fields); err != nil {
+		return err
+	}
+
+	return u.sessionUC.RevokeAll(ctx, cred.UserId)
+}
+
+// VerifyAccessToken checks an access token issued by Login or Refresh, tokens of a revoked session are rejected
+func (u *localAuthUsecase) VerifyAccessToken(ctx context.Context, rawToken string) (*LocalAccessToken, error) {
+	unverified, err := jwt.ParseInsecure([]byte(rawToken))
+	if err != nil {
+		return nil, ErrNotLocalToken
+	}
+
+	if iss, _ := unverified.Issuer(); iss != u.appConf.LocalAuthIssuer {
+		return nil, ErrNotLocalToken
+	}
+
+	claims, err := u.verifier.Verify(ctx, rawToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", constants.ErrUnauthorized, err)
+	}
+
+	code, _ := claims["sub"].(string)
+	sid, _ := claims[localAuthSessionIdClaim].(string)
+	sessionId, _ := strconv.ParseInt(sid, 10, 64)
+	if code == "" || sessionId <= 0 {
+		return nil, constants.ErrUnauthorized
+	}
+
+	session, err := u.sessionUC.Verify(ctx, sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalAccessToken{
+		UserCode:      code,
+		SessionId:     sessionId,
+		MfaVerifiedAt: session.MfaVerifiedAt,
+	}, nil
+}
+
+// PublicKeySet returns the keys published on `/.well-known/jwks.json`
+func (u *localAuthUsecase) PublicKeySet() jwk.Set {
+	return u.signer.PublicKeySet()
+}
+
+func (u *localAuthUsecase) issueTokens(ctx context.Context, user *domains.User, session *domains.Session) (*dto.LocalAuthTokenResp, error) {
+	now := time.Now().UTC()
+	tkn, err := jwt.NewBuilder().
+		Issuer(u.appConf.LocalAuthIssuer).
+		Subject(user.Code).
+		JwtID(utils.GenerateUUID()).
+		IssuedAt(now).
+		Expiration(now.Add(u.appConf.LocalAuthAccessTokenTTL)).
+		Claim(localAuthEmailClaim, user.Email).
+		Claim(localAuthSessionIdClaim, strconv.FormatInt(session.Id, 10)).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	signed, err := u.signer.Sign(tkn)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateSecureToken(localAuthTokenSize)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken := localAuthRefreshTokenPrefix + secret
+
+	_, err = u.localAuthRepo.CreateRefreshToken(ctx, &domains.RefreshToken{
+		UserId:    user.Id,
+		SessionId: session.Id,
+		TokenHash: utils.GetSHA256Hash(refreshToken),
+		ExpiresAt: now.Add(u.appConf.LocalAuthRefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.LocalAuthTokenResp{
+		AccessToken:  string(signed),
+		RefreshToken: refreshToken,
+		TokenType:    localAuthAccessTokenType,
+		ExpiresIn:    int64(u.appConf.LocalAuthAccessTokenTTL.Seconds()),
+	}, nil
+}
+
+func (u *localAuthUsecase) getRefreshToken(ctx context.Context, refreshToken string) (*domains.RefreshToken, error) {
+	if !strings.HasPrefix(refreshToken, localAuthRefreshTokenPrefix) {
+		return nil, constants.ErrUnauthorized
+	}
+
+	rt, err := u.localAuthRepo.GetRefreshTokenByHash(ctx, utils.GetSHA256Hash(refreshToken))
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return nil, constants.ErrUnauthorized
+		}
+		return nil, err
+	}
+
+	if !time.Now().Before(rt.ExpiresAt) {
+		return nil, constants.ErrUnauthorized
+	}
+	return rt, nil
+}
+
+func (u *localAuthUsecase) getUserWithCredential(ctx context.Context, email string) (*domains.User, *domains.LocalCredential, error) {
+	user, err := u.userUC.GetByEmail(ctx, normalizeEmail(email))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cred, err := u.localAuthRepo.GetCredentialByUserId(ctx, user.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, cred, nil
+}
+
+func (u *localAuthUsecase) sendVerificationEmail(ctx context.Context, user *domains.User) error {
+	token, err := u.createAuthToken(ctx, user.Id, domains.LocalAuthTokenPurposeVerifyEmail, localAuthVerifyEmailTTL)
+	if err != nil {
+		return err
+	}
+
+	link := strings.TrimRight(u.appConf.BaseURL, "/") + "/auth/verify-email?token=" + url.QueryEscape(token)
+	return u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body:    fmt.Sprintf("Open the following link to verify your email:\n\n%s\n", link),
+	})
+}
+
+func (u *localAuthUsecase) createAuthToken(ctx context.Context, userId int64, purpose domains.LocalAuthTokenPurpose, ttl time.Duration) (string, error) {
+	token, err := utils.GenerateSecureToken(localAuthTokenSize)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = u.localAuthRepo.CreateAuthToken(ctx, &domains.LocalAuthToken{
+		UserId:    userId,
+		Purpose:   purpose,
+		TokenHash: utils.GetSHA256Hash(token),
+		ExpiresAt: time.Now().UTC().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// redeemAuthToken marks a valid single use token as used
+func (u *localAuthUsecase) redeemAuthToken(ctx context.Context, token string, purpose domains.LocalAuthTokenPurpose) (*domains.LocalAuthToken, error) {
+	if token == "" {
+		return nil, ErrInvalidLocalAuthToken
+	}
+
+	authToken, err := u.localAuthRepo.GetAuthTokenByHash(ctx, utils.GetSHA256Hash(token))
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return nil, ErrInvalidLocalAuthToken
+		}
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if authToken.Purpose != purpose || !authToken.IsUsable(now) {
+		return nil, ErrInvalidLocalAuthToken
+	}
+
+	// a token redeemed concurrently is only used once
+	used, err := u.localAuthRepo.UseAuthToken(ctx, authToken.Id, now)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, ErrInvalidLocalAuthToken
+	}
+
+	authToken.UsedAt = &now
+	return authToken, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
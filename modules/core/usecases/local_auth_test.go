@@ -0,0 +1,223 @@
+package usecases
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/repositories"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/mailer"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeLocalAuthRepository sets used_at and rotated_at once, like the conditional updates of the database
+type fakeLocalAuthRepository struct {
+	repositories.LocalAuthRepository
+
+	mu            sync.Mutex
+	authTokens    map[string]*domains.LocalAuthToken
+	refreshTokens map[string]*domains.RefreshToken
+}
+
+func (r *fakeLocalAuthRepository) GetAuthTokenByHash(ctx context.Context, tokenHash string) (*domains.LocalAuthToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.authTokens[tokenHash]; ok {
+		cp := *t
+		return &cp, nil
+	}
+	return nil, constants.ErrNotFound
+}
+
+func (r *fakeLocalAuthRepository) UseAuthToken(ctx context.Context, id int64, usedAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.authTokens {
+		if t.Id == id && t.UsedAt == nil {
+			t.UsedAt = &usedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeLocalAuthRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*domains.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.refreshTokens[tokenHash]; ok {
+		cp := *t
+		return &cp, nil
+	}
+	return nil, constants.ErrNotFound
+}
+
+func (r *fakeLocalAuthRepository) RotateRefreshToken(ctx context.Context, id int64, rotatedAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.refreshTokens {
+		if t.Id == id && t.RotatedAt == nil {
+			t.RotatedAt = &rotatedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeLocalAuthRepository) CreateRefreshToken(ctx context.Context, token *domains.RefreshToken) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token.Id = int64(len(r.refreshTokens) + 1)
+	r.refreshTokens[token.TokenHash] = token
+	return token.Id, nil
+}
+
+type fakeSessionUsecase struct {
+	SessionUsecase
+
+	mu      sync.Mutex
+	revoked []int64
+}
+
+func (u *fakeSessionUsecase) Verify(ctx context.Context, id int64) (*domains.Session, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, revoked := range u.revoked {
+		if revoked == id {
+			return nil, constants.ErrUnauthorized
+		}
+	}
+	return &domains.Session{Id: id, UserId: 1}, nil
+}
+
+func (u *fakeSessionUsecase) Extend(ctx context.Context, session *domains.Session, client domains.SessionClient, expiresAt time.Time) error {
+	return nil
+}
+
+func (u *fakeSessionUsecase) Revoke(ctx context.Context, userId, id int64) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.revoked = append(u.revoked, id)
+	return nil
+}
+
+type fakeUserUsecase struct {
+	UserUsecase
+}
+
+func (u *fakeUserUsecase) GetByID(ctx context.Context, id int64) (*domains.User, error) {
+	return &domains.User{Id: id, Code: "local-user-1", Email: "ann@example.com", Status: domains.UserStatusActive}, nil
+}
+
+func newTestLocalAuthUsecase(t *testing.T, repo *fakeLocalAuthRepository, sessionUC *fakeSessionUsecase) *localAuthUsecase {
+	appConf := &config.AppConfig{
+		Environment:              constants.EnvironmentDevelopment,
+		LocalAuthIssuer:          "urn:echo-rest-api:local",
+		LocalAuthAccessTokenTTL:  time.Minute,
+		LocalAuthRefreshTokenTTL: time.Hour,
+	}
+	uc, err := NewLocalAuthUsecase(appConf, &fakeUserUsecase{}, sessionUC, repo, mailer.NewLogMailer())
+	assert.Nil(t, err)
+	return uc.(*localAuthUsecase)
+}
+
+// runConcurrently calls f n times at once and returns the errors
+func runConcurrently(n int, f func() error) []error {
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = f()
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
+
+func countNil(errs []error) (n int) {
+	for _, err := range errs {
+		if err == nil {
+			n++
+		}
+	}
+	return
+}
+
+func TestRefreshConcurrentReuse(t *testing.T) {
+	refreshToken := localAuthRefreshTokenPrefix + "concurrent"
+	repo := &fakeLocalAuthRepository{
+		refreshTokens: map[string]*domains.RefreshToken{
+			utils.GetSHA256Hash(refreshToken): {Id: 1, UserId: 1, SessionId: 7, ExpiresAt: time.Now().Add(time.Hour)},
+		},
+	}
+	sessionUC := &fakeSessionUsecase{}
+	uc := newTestLocalAuthUsecase(t, repo, sessionUC)
+
+	errs := runConcurrently(5, func() error {
+		_, err := uc.Refresh(context.Background(), refreshToken, domains.SessionClient{})
+		return err
+	})
+
+	assert.Equal(t, 1, countNil(errs))
+	for _, err := range errs {
+		if err != nil {
+			assert.ErrorIs(t, err, constants.ErrUnauthorized)
+		}
+	}
+	assert.Contains(t, sessionUC.revoked, int64(7))
+}
+
+func TestRedeemAuthTokenOnce(t *testing.T) {
+	token := "verification-token"
+	repo := &fakeLocalAuthRepository{
+		authTokens: map[string]*domains.LocalAuthToken{
+			utils.GetSHA256Hash(token): {
+				Id: 1, UserId: 1, Purpose: domains.LocalAuthTokenPurposeVerifyEmail, ExpiresAt: time.Now().Add(time.Hour),
+			},
+		},
+	}
+	uc := newTestLocalAuthUsecase(t, repo, &fakeSessionUsecase{})
+
+	errs := runConcurrently(5, func() error {
+		_, err := uc.redeemAuthToken(context.Background(), token, domains.LocalAuthTokenPurposeVerifyEmail)
+		return err
+	})
+
+	assert.Equal(t, 1, countNil(errs))
+	for _, err := range errs {
+		if err != nil {
+			assert.ErrorIs(t, err, ErrInvalidLocalAuthToken)
+		}
+	}
+}
+
+func TestNewLocalAuthUsecaseRequiresMailerInProduction(t *testing.T) {
+	m, err := mailer.NewSMTPMailer(mailer.SMTPOptions{Host: "smtp.example.com", From: "no-reply@example.com"})
+	assert.Nil(t, err)
+
+	tcs := []struct {
+		name        string
+		environment string
+		mailer      mailer.Mailer
+		expectedErr error
+	}{
+		{"should refuse log mailer in production", constants.EnvironmentProduction, mailer.NewLogMailer(), ErrMissingMailer},
+		{"should require signing keys in production", constants.EnvironmentProduction, m, ErrMissingSigningKeys},
+		{"should allow log mailer in development", constants.EnvironmentDevelopment, mailer.NewLogMailer(), nil},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			appConf := &config.AppConfig{Environment: tc.environment, LocalAuthIssuer: "urn:echo-rest-api:local"}
+			_, err := NewLocalAuthUsecase(appConf, nil, nil, nil, tc.mailer)
+			assert.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
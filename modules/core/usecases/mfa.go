@@ -0,0 +1,258 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/repositories"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/totp"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+)
+
+const (
+	mfaRecoveryCodeCount = 10
+	mfaRecoveryCodeSize  = 10
+	// no 0/o, 1/l/i, so that codes can be read back from paper
+	mfaRecoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+var (
+	// ErrMfaAlreadyEnabled is returned by Enroll when a confirmed second factor exists, it must be disabled first
+	ErrMfaAlreadyEnabled = errors.New("mfa is already enabled")
+	// ErrMfaNotEnabled is returned when the user has no confirmed second factor
+	ErrMfaNotEnabled = errors.New("mfa is not enabled")
+	// ErrMfaNotEnrolled is returned by Confirm when Enroll was not called first
+	ErrMfaNotEnrolled = errors.New("mfa is not enrolled")
+	// ErrInvalidMfaCode is returned for wrong, reused or expired codes
+	ErrInvalidMfaCode = errors.New("invalid mfa code")
+	// ErrMfaSessionRequired is returned by StepUp for principals without a server-side session
+	ErrMfaSessionRequired = errors.New("mfa step-up requires a session, sign in with the local provider")
+)
+
+// MfaUsecase represent the second factor's usecase contract
+type MfaUsecase interface {
+	GetStatus(ctx context.Context, userId int64) (*dto.MfaStatusResp, error)
+	Enroll(ctx context.Context, user *domains.User) (*dto.MfaEnrollmentResp, error)
+	Confirm(ctx context.Context, userId, sessionId int64, code string) (*dto.MfaRecoveryCodesResp, error)
+	StepUp(ctx context.Context, userId, sessionId int64, request dto.MfaCodeReq) error
+	RegenerateRecoveryCodes(ctx context.Context, userId int64, request dto.MfaCodeReq) (*dto.MfaRecoveryCodesResp, error)
+	Disable(ctx context.Context, userId int64, request dto.MfaCodeReq) error
+}
+
+type mfaUsecase struct {
+	appConf   *config.AppConfig
+	mfaRepo   repositories.MfaRepository
+	sessionUC SessionUsecase
+}
+
+// NewMfaUsecase will create new a mfaUsecase object representation of MfaUsecase interface
+func NewMfaUsecase(appConf *config.AppConfig, mfaRepo repositories.MfaRepository, sessionUC SessionUsecase) MfaUsecase {
+	return &mfaUsecase{
+		appConf:   appConf,
+		mfaRepo:   mfaRepo,
+		sessionUC: sessionUC,
+	}
+}
+
+func (u *mfaUsecase) GetStatus(ctx context.Context, userId int64) (*dto.MfaStatusResp, error) {
+	mfa, err := u.mfaRepo.GetByUserId(ctx, userId)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return &dto.MfaStatusResp{}, nil
+		}
+		return nil, err
+	}
+
+	if !mfa.IsEnabled() {
+		return &dto.MfaStatusResp{}, nil
+	}
+
+	remaining, err := u.mfaRepo.CountUnusedRecoveryCodes(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.MfaStatusResp{
+		Enabled:                true,
+		ConfirmedAt:            mfa.ConfirmedAt,
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+// Enroll generates a new pending secret, a previous pending one is replaced
+func (u *mfaUsecase) Enroll(ctx context.Context, user *domains.User) (*dto.MfaEnrollmentResp, error) {
+	mfa, err := u.mfaRepo.GetByUserId(ctx, user.Id)
+	if err != nil && !errors.Is(err, constants.ErrNotFound) {
+		return nil, err
+	}
+	if mfa != nil && mfa.IsEnabled() {
+		return nil, ErrMfaAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = u.mfaRepo.Save(ctx, &domains.UserMfa{
+		UserId: user.Id,
+		Secret: secret,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &dto.MfaEnrollmentResp{
+		Secret: secret,
+		URI:    totp.URI(u.appConf.MfaIssuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm enables the pending secret with a first code and issues the recovery codes,
+// the session of the request is verified on the way
+func (u *mfaUsecase) Confirm(ctx context.Context, userId, sessionId int64, code string) (*dto.MfaRecoveryCodesResp, error) {
+	mfa, err := u.mfaRepo.GetByUserId(ctx, userId)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return nil, ErrMfaNotEnrolled
+		}
+		return nil, err
+	}
+	if mfa.IsEnabled() {
+		return nil, ErrMfaAlreadyEnabled
+	}
+
+	if err = u.verifyTOTP(ctx, mfa, code); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	mfa.ConfirmedAt = &now
+	if err = u.mfaRepo.Update(ctx, mfa, []string{"confirmed_at"}); err != nil {
+		return nil, err
+	}
+
+	if sessionId > 0 {
+		if err = u.sessionUC.MarkMfaVerified(ctx, sessionId); err != nil {
+			return nil, err
+		}
+	}
+	return u.issueRecoveryCodes(ctx, userId)
+}
+
+// StepUp verifies a code and marks the session as verified for MFA_STEP_UP_TTL
+func (u *mfaUsecase) StepUp(ctx context.Context, userId, sessionId int64, req dto.MfaCodeReq) error {
+	if sessionId <= 0 {
+		return ErrMfaSessionRequired
+	}
+
+	if err := u.verify(ctx, userId, req); err != nil {
+		return err
+	}
+	return u.sessionUC.MarkMfaVerified(ctx, sessionId)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes, used or not
+func (u *mfaUsecase) RegenerateRecoveryCodes(ctx context.Context, userId int64, req dto.MfaCodeReq) (*dto.MfaRecoveryCodesResp, error) {
+	if err := u.verify(ctx, userId, req); err != nil {
+		return nil, err
+	}
+	return u.issueRecoveryCodes(ctx, userId)
+}
+
+func (u *mfaUsecase) Disable(ctx context.Context, userId int64, req dto.MfaCodeReq) error {
+	if err := u.verify(ctx, userId, req); err != nil {
+		return err
+	}
+	return u.mfaRepo.DeleteByUserId(ctx, userId)
+}
+
+// verify accepts either a TOTP code or an unused recovery code of an enabled second factor
+func (u *mfaUsecase) verify(ctx context.Context, userId int64, req dto.MfaCodeReq) error {
+	mfa, err := u.mfaRepo.GetByUserId(ctx, userId)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return ErrMfaNotEnabled
+		}
+		return err
+	}
+	if !mfa.IsEnabled() {
+		return ErrMfaNotEnabled
+	}
+
+	if req.RecoveryCode != "" {
+		ok, err := u.mfaRepo.UseRecoveryCode(ctx, userId, utils.GetSHA256Hash(normalizeRecoveryCode(req.RecoveryCode)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidMfaCode
+		}
+		return nil
+	}
+
+	return u.verifyTOTP(ctx, mfa, req.Code)
+}
+
+func (u *mfaUsecase) verifyTOTP(ctx context.Context, mfa *domains.UserMfa, code string) error {
+	step, ok := totp.Validate(mfa.Secret, code, time.Now())
+	if !ok {
+		return ErrInvalidMfaCode
+	}
+
+	ok, err := u.mfaRepo.UseStep(ctx, mfa.UserId, step)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidMfaCode
+	}
+	return nil
+}
+
+func (u *mfaUsecase) issueRecoveryCodes(ctx context.Context, userId int64) (*dto.MfaRecoveryCodesResp, error) {
+	codes := make([]string, 0, mfaRecoveryCodeCount)
+	hashes := make([]string, 0, mfaRecoveryCodeCount)
+	for i := 0; i < mfaRecoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, utils.GetSHA256Hash(normalizeRecoveryCode(code)))
+	}
+
+	if err := u.mfaRepo.ReplaceRecoveryCodes(ctx, userId, hashes); err != nil {
+		return nil, err
+	}
+	return &dto.MfaRecoveryCodesResp{RecoveryCodes: codes}, nil
+}
+
+// generateRecoveryCode returns a code formatted as xxxxx-xxxxx
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, mfaRecoveryCodeSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	sb := strings.Builder{}
+	for i, c := range b {
+		if i == mfaRecoveryCodeSize/2 {
+			sb.WriteByte('-')
+		}
+		// 256 is not a multiple of the alphabet size, the bias is negligible for single use codes
+		sb.WriteByte(mfaRecoveryCodeAlphabet[int(c)%len(mfaRecoveryCodeAlphabet)])
+	}
+	return sb.String(), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -5,17 +5,27 @@ package usecases
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net"
+	"time"
 
+	"github.com/dzungtran/echo-rest-api/config"
 	"github.com/dzungtran/echo-rest-api/modules/core/domains"
 	"github.com/dzungtran/echo-rest-api/modules/core/dto"
 	"github.com/dzungtran/echo-rest-api/modules/core/repositories"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
 	"github.com/dzungtran/echo-rest-api/pkg/contexts"
 	"github.com/dzungtran/echo-rest-api/pkg/cue"
+	"github.com/dzungtran/echo-rest-api/pkg/hook"
 	sqlTools "github.com/dzungtran/echo-rest-api/pkg/sql-tools"
 	"github.com/dzungtran/echo-rest-api/pkg/utils"
 	"github.com/jinzhu/copier"
 )
 
+// ErrInvalidOrgAccessSettings is returned when the timezone or a CIDR of the IP allowlist cannot be parsed,
+// or when require_mfa is set while the local provider, the only one members can step up with, is not enabled
+var ErrInvalidOrgAccessSettings = errors.New("invalid org access settings")
+
 // OrgUsecase represent the org's usecase contract
 type OrgUsecase interface {
 	Create(ctx context.Context, request dto.CreateOrgReq) (*domains.Org, error)
@@ -27,21 +37,27 @@ type OrgUsecase interface {
 }
 
 type orgUsecase struct {
+	appConf     *config.AppConfig
 	orgRepo     repositories.OrgRepository
 	userOrgRepo repositories.UserOrgRepository
 	sqlxTrans   *sqlTools.SqlxTransaction
+	hooker      hook.HookerInterface
 }
 
 // NewOrgUsecase will create new an orgUsecase object representation of OrgUsecase interface
 func NewOrgUsecase(
+	appConf *config.AppConfig,
 	orgRepo repositories.OrgRepository,
 	userOrgRepo repositories.UserOrgRepository,
 	sqlxTrans *sqlTools.SqlxTransaction,
+	hooker hook.HookerInterface,
 ) OrgUsecase {
 	return &orgUsecase{
+		appConf:     appConf,
 		orgRepo:     orgRepo,
 		userOrgRepo: userOrgRepo,
 		sqlxTrans:   sqlxTrans,
+		hooker:      hooker,
 	}
 }
 
@@ -60,6 +76,8 @@ func (u *orgUsecase) Create(ctx context.Context, req dto.CreateOrgReq) (org *dom
 
 	copier.Copy(org, req)
 	org.Code = utils.GenerateLongUUID()
+	org.Timezone = "UTC"
+	org.IpAllowlist = []string{}
 
 	// Start transaction
 	tx, err := u.sqlxTrans.Init()
@@ -80,12 +98,13 @@ func (u *orgUsecase) Create(ctx context.Context, req dto.CreateOrgReq) (org *dom
 		return
 	}
 
-	_, err = u.userOrgRepo.CreateWithTx(ctx, tx, &domains.UserOrg{
+	owner := &domains.UserOrg{
 		UserId: req.UserId,
 		OrgId:  orgId,
 		Role:   domains.UserRoleOwner,
 		Status: domains.UserStatusActive,
-	})
+	}
+	_, err = u.userOrgRepo.CreateWithTx(ctx, tx, owner)
 	if err != nil {
 		needRollback = true
 		return
@@ -98,6 +117,13 @@ func (u *orgUsecase) Create(ctx context.Context, req dto.CreateOrgReq) (org *dom
 	}
 	// END transction
 
+	u.hooker.Trigger(hook.EventPayload{
+		Name:    hook.Created,
+		Scope:   hook.UserOrgScope,
+		Source:  hook.SourceOrgAPI,
+		Payload: owner,
+	})
+
 	org, err = u.orgRepo.GetByID(ctx, orgId)
 	return
 }
@@ -135,7 +161,41 @@ func (u *orgUsecase) Update(ctx context.Context, id int64, req dto.UpdateOrgReq)
 	}
 
 	copier.Copy(org, req)
-	err = u.orgRepo.Update(ctx, org, []string{"name", "description", "logo", "domain"})
+	fields := []string{"name", "description", "logo", "domain"}
+	if req.RequireMfa != nil {
+		// the second factor is verified on the sessions of the local provider, members of other providers could not step up
+		if *req.RequireMfa && !utils.IsSliceContains(u.appConf.AuthProviders, constants.AuthProviderLocal) {
+			return fmt.Errorf("%w: require_mfa needs the %s auth provider", ErrInvalidOrgAccessSettings, constants.AuthProviderLocal)
+		}
+		org.RequireMfa = *req.RequireMfa
+		fields = append(fields, "require_mfa")
+	}
+	if req.Timezone != nil {
+		if _, err = time.LoadLocation(*req.Timezone); err != nil {
+			return fmt.Errorf("%w: unknown timezone %s", ErrInvalidOrgAccessSettings, *req.Timezone)
+		}
+		org.Timezone = *req.Timezone
+		fields = append(fields, "timezone")
+	}
+	if req.IpAllowlist != nil {
+		for _, cidr := range *req.IpAllowlist {
+			if _, _, err = net.ParseCIDR(cidr); err != nil {
+				return fmt.Errorf("%w: invalid CIDR %s", ErrInvalidOrgAccessSettings, cidr)
+			}
+		}
+		org.IpAllowlist = *req.IpAllowlist
+		fields = append(fields, "ip_allowlist")
+	}
+	if req.AccessHoursStart != nil {
+		org.AccessHoursStart = *req.AccessHoursStart
+		fields = append(fields, "access_hours_start")
+	}
+	if req.AccessHoursEnd != nil {
+		org.AccessHoursEnd = *req.AccessHoursEnd
+		fields = append(fields, "access_hours_end")
+	}
+
+	err = u.orgRepo.Update(ctx, org, fields)
 	return
 }
 
@@ -145,7 +205,29 @@ func (u *orgUsecase) Delete(ctx context.Context, id int64) (err error) {
 		return
 	}
 
+	members, _, err := u.userOrgRepo.Fetch(ctx, repositories.ParamsForFetchUserOrgs{
+		CommonParamsForFetch: contexts.CommonParamsForFetch{
+			NoLimit: true,
+		},
+		OrgId: id,
+	})
+	if err != nil {
+		return
+	}
+
 	err = u.orgRepo.DeleteById(ctx, id)
+	if err != nil {
+		return
+	}
+
+	for _, m := range members {
+		u.hooker.Trigger(hook.EventPayload{
+			Name:       hook.Deleted,
+			Scope:      hook.UserOrgScope,
+			Source:     hook.SourceOrgAPI,
+			PayloadOld: m,
+		})
+	}
 	return
 }
 
@@ -0,0 +1,327 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/repositories"
+	"github.com/dzungtran/echo-rest-api/pkg/authz"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	"github.com/dzungtran/echo-rest-api/pkg/cue"
+	"github.com/dzungtran/echo-rest-api/pkg/hook"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+)
+
+var (
+	ErrBuiltinRoleName = errors.New("the name of a built-in role cannot be used")
+	ErrUnknownRole     = errors.New("unknown role")
+	ErrRoleCycle       = errors.New("the role cannot inherit from itself")
+	// ErrRoleEscalation is returned when a role would grant permissions the caller does not hold
+	ErrRoleEscalation = errors.New("the role grants permissions the caller does not hold")
+	// ErrRoleInUse is returned by Delete while members hold the role or other roles inherit from it
+	ErrRoleInUse = errors.New("the role is held by members or inherited by other roles")
+	// ErrLastOrgOwner is returned by AssignMember, the org would be left without owner
+	ErrLastOrgOwner = errors.New("cannot change the role of the last owner")
+)
+
+// OrgRoleUsecase represent the custom org role's usecase contract
+type OrgRoleUsecase interface {
+	Fetch(ctx context.Context, orgId int64) ([]*domains.OrgCustomRole, error)
+	Create(ctx context.Context, actor *domains.Principal, request dto.CreateOrgRoleReq) (*domains.OrgCustomRole, error)
+	Update(ctx context.Context, actor *domains.Principal, request dto.UpdateOrgRoleReq) (*domains.OrgCustomRole, error)
+	Delete(ctx context.Context, orgId, id int64) error
+	AssignMember(ctx context.Context, actor *domains.Principal, request dto.UpdateMemberRoleReq) (*domains.UserOrg, error)
+}
+
+type orgRoleUsecase struct {
+	orgRoleRepo repositories.OrgRoleRepository
+	userOrgRepo repositories.UserOrgRepository
+	hooker      hook.HookerInterface
+	authorizer  authz.Authorizer
+}
+
+// NewOrgRoleUsecase will create new an orgRoleUsecase object representation of OrgRoleUsecase interface
+func NewOrgRoleUsecase(
+	orgRoleRepo repositories.OrgRoleRepository,
+	userOrgRepo repositories.UserOrgRepository,
+	hooker hook.HookerInterface,
+	authorizer authz.Authorizer,
+) OrgRoleUsecase {
+	return &orgRoleUsecase{
+		orgRoleRepo: orgRoleRepo,
+		userOrgRepo: userOrgRepo,
+		hooker:      hooker,
+		authorizer:  authorizer,
+	}
+}
+
+func (u *orgRoleUsecase) Fetch(ctx context.Context, orgId int64) ([]*domains.OrgCustomRole, error) {
+	return u.orgRoleRepo.FetchByOrgIds(ctx, []int64{orgId})
+}
+
+// Create returns ErrDuplicated when the org already has a custom role with the name
+func (u *orgRoleUsecase) Create(ctx context.Context, actor *domains.Principal, req dto.CreateOrgRoleReq) (*domains.OrgCustomRole, error) {
+	if err := utils.CueValidateObject("CreateOrgRoleRequest", cue.CueDefinitionForOrgRole, req); err != nil {
+		return nil, err
+	}
+
+	if u.authorizer.IsBuiltinRole(ctx, req.Name) {
+		return nil, ErrBuiltinRoleName
+	}
+
+	roles, err := u.orgRoleRepo.FetchByOrgIds(ctx, []int64{req.OrgId})
+	if err != nil {
+		return nil, err
+	}
+
+	role := &domains.OrgCustomRole{
+		OrgId:       req.OrgId,
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+		Parent:      req.Parent,
+	}
+	if actor.User != nil && actor.User.Id > 0 {
+		role.CreatedBy = &actor.User.Id
+	}
+
+	if err = u.validate(ctx, actor, role, roles); err != nil {
+		return nil, err
+	}
+
+	id, err := u.orgRoleRepo.Create(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+	return u.orgRoleRepo.GetByID(ctx, id)
+}
+
+func (u *orgRoleUsecase) Update(ctx context.Context, actor *domains.Principal, req dto.UpdateOrgRoleReq) (*domains.OrgCustomRole, error) {
+	if err := utils.CueValidateObject("UpdateOrgRoleRequest", cue.CueDefinitionForOrgRole, req); err != nil {
+		return nil, err
+	}
+
+	role, err := u.getInOrg(ctx, req.OrgId, req.RoleId)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := u.orgRoleRepo.FetchByOrgIds(ctx, []int64{req.OrgId})
+	if err != nil {
+		return nil, err
+	}
+
+	role.Description = req.Description
+	role.Permissions = req.Permissions
+	role.Parent = req.Parent
+	if err = u.validate(ctx, actor, role, roles); err != nil {
+		return nil, err
+	}
+
+	err = u.orgRoleRepo.Update(ctx, role, []string{"description", "permissions", "parent"})
+	if err != nil {
+		return nil, err
+	}
+	return u.orgRoleRepo.GetByID(ctx, role.Id)
+}
+
+func (u *orgRoleUsecase) Delete(ctx context.Context, orgId, id int64) error {
+	role, err := u.getInOrg(ctx, orgId, id)
+	if err != nil {
+		return err
+	}
+
+	roles, err := u.orgRoleRepo.FetchByOrgIds(ctx, []int64{orgId})
+	if err != nil {
+		return err
+	}
+	for _, r := range roles {
+		if r.Parent == role.Name {
+			return ErrRoleInUse
+		}
+	}
+
+	_, count, err := u.userOrgRepo.Fetch(ctx, repositories.ParamsForFetchUserOrgs{
+		OrgId: orgId,
+		Roles: []string{role.Name},
+		CommonParamsForFetch: contexts.CommonParamsForFetch{
+			Limit: 1,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrRoleInUse
+	}
+
+	return u.orgRoleRepo.DeleteById(ctx, role.Id)
+}
+
+// AssignMember changes the built-in or custom role of a member,
+// the caller cannot assign a role granting more than it holds
+func (u *orgRoleUsecase) AssignMember(ctx context.Context, actor *domains.Principal, req dto.UpdateMemberRoleReq) (*domains.UserOrg, error) {
+	if err := utils.CueValidateObject("UpdateMemberRoleRequest", cue.CueDefinitionForOrgRole, req); err != nil {
+		return nil, err
+	}
+
+	members, _, err := u.userOrgRepo.Fetch(ctx, repositories.ParamsForFetchUserOrgs{
+		OrgId:   req.OrgId,
+		UserIds: []int64{req.UserId},
+		CommonParamsForFetch: contexts.CommonParamsForFetch{
+			Limit: 1,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, constants.ErrNotFound
+	}
+	member := members[0]
+
+	roles, err := u.orgRoleRepo.FetchByOrgIds(ctx, []int64{req.OrgId})
+	if err != nil {
+		return nil, err
+	}
+	if !u.authorizer.IsBuiltinRole(ctx, req.Role) && findRole(roles, req.Role) == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRole, req.Role)
+	}
+
+	if err = u.checkRoleEscalation(ctx, actor, req.OrgId, req.Role, roles, roles); err != nil {
+		return nil, err
+	}
+
+	if member.Role == domains.UserRoleOwner && req.Role != string(domains.UserRoleOwner) {
+		_, count, err := u.userOrgRepo.Fetch(ctx, repositories.ParamsForFetchUserOrgs{
+			OrgId: req.OrgId,
+			Roles: []string{string(domains.UserRoleOwner)},
+			CommonParamsForFetch: contexts.CommonParamsForFetch{
+				Limit: 1,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if count <= 1 {
+			return nil, ErrLastOrgOwner
+		}
+	}
+
+	old := *member
+	member.Role = domains.UserOrgRole(req.Role)
+	if err = u.userOrgRepo.UpdateByUserIdAndOrgId(ctx, member, []string{"role"}); err != nil {
+		return nil, err
+	}
+
+	u.hooker.Trigger(hook.EventPayload{
+		Name:       hook.Updated,
+		Scope:      hook.UserOrgScope,
+		Source:     hook.SourceOrgAPI,
+		PayloadOld: &old,
+		Payload:    member,
+	})
+	return member, nil
+}
+
+func (u *orgRoleUsecase) getInOrg(ctx context.Context, orgId, id int64) (*domains.OrgCustomRole, error) {
+	role, err := u.orgRoleRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if role.OrgId != orgId {
+		return nil, constants.ErrNotFound
+	}
+	return role, nil
+}
+
+// validate checks the permissions and the parent of the role, then that the actor holds
+// every permission the role would grant. roles are the custom roles of the org before the change
+func (u *orgRoleUsecase) validate(ctx context.Context, actor *domains.Principal, role *domains.OrgCustomRole, roles []*domains.OrgCustomRole) error {
+	for _, perm := range role.Permissions {
+		if !u.authorizer.IsKnownPermission(perm) {
+			return fmt.Errorf("%w: %s", ErrUnknownPermission, perm)
+		}
+	}
+
+	// the parent chain must exist and never come back to the role
+	for parent := role.Parent; parent != "" && !u.authorizer.IsBuiltinRole(ctx, parent); {
+		if parent == role.Name {
+			return ErrRoleCycle
+		}
+
+		r := findRole(roles, parent)
+		if r == nil {
+			return fmt.Errorf("%w: %s", ErrUnknownRole, parent)
+		}
+		parent = r.Parent
+	}
+
+	updated := make([]*domains.OrgCustomRole, 0, len(roles)+1)
+	for _, r := range roles {
+		if r.Name != role.Name {
+			updated = append(updated, r)
+		}
+	}
+	updated = append(updated, role)
+
+	return u.checkRoleEscalation(ctx, actor, role.OrgId, role.Name, roles, updated)
+}
+
+// checkRoleEscalation returns ErrRoleEscalation when the role grants permissions the actor does not hold in the org.
+// The actor permissions are evaluated with the current roles, the ones of the role with the updated roles
+func (u *orgRoleUsecase) checkRoleEscalation(ctx context.Context, actor *domains.Principal, orgId int64, role string, current, updated []*domains.OrgCustomRole) error {
+	granted, err := u.authorizer.RolePermissions(ctx, orgId, role, updated)
+	if err != nil {
+		return err
+	}
+
+	held := map[string]struct{}{}
+	if u.authorizer.IsSuperAdmin(ctx, actor.User) {
+		for _, perm := range granted {
+			held[perm] = struct{}{}
+		}
+	} else if actor.User != nil {
+		perms, err := u.authorizer.RolePermissions(ctx, orgId, actor.User.OrgRole[orgId], current)
+		if err != nil {
+			return err
+		}
+		for _, perm := range perms {
+			held[perm] = struct{}{}
+		}
+	}
+
+	// a scoped token cannot grant more than its own permissions
+	if actor.Permissions != nil {
+		for perm := range held {
+			if !utils.IsSliceContains(actor.Permissions, perm) {
+				delete(held, perm)
+			}
+		}
+	}
+
+	missing := make([]string, 0)
+	for _, perm := range granted {
+		if _, ok := held[perm]; !ok {
+			missing = append(missing, perm)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrRoleEscalation, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func findRole(roles []*domains.OrgCustomRole, name string) *domains.OrgCustomRole {
+	for _, r := range roles {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
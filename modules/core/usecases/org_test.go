@@ -0,0 +1,59 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/repositories"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeOrgRepository struct {
+	repositories.OrgRepository
+
+	org     *domains.Org
+	updated []string
+}
+
+func (r *fakeOrgRepository) GetByID(ctx context.Context, id int64) (*domains.Org, error) {
+	cp := *r.org
+	return &cp, nil
+}
+
+func (r *fakeOrgRepository) Update(ctx context.Context, org *domains.Org, fieldsToUpdate []string) error {
+	r.org = org
+	r.updated = fieldsToUpdate
+	return nil
+}
+
+func TestUpdateOrgRequireMfa(t *testing.T) {
+	enable := true
+	disable := false
+
+	tcs := []struct {
+		name          string
+		authProviders []string
+		requireMfa    *bool
+		expectedErr   error
+		expected      bool
+	}{
+		{"should require mfa with the local provider", []string{constants.AuthProviderOidc, constants.AuthProviderLocal}, &enable, nil, true},
+		{"should refuse to require mfa without the local provider", []string{constants.AuthProviderOidc}, &enable, ErrInvalidOrgAccessSettings, false},
+		{"should stop requiring mfa without the local provider", []string{constants.AuthProviderOidc}, &disable, nil, false},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeOrgRepository{org: &domains.Org{Id: 1, Name: "Acme Corporation"}}
+			uc := NewOrgUsecase(&config.AppConfig{AuthProviders: tc.authProviders}, repo, nil, nil, nil)
+
+			err := uc.Update(context.Background(), 1, dto.UpdateOrgReq{OrgId: 1, Name: "Acme Corporation", RequireMfa: tc.requireMfa})
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Equal(t, tc.expected, repo.org.RequireMfa)
+		})
+	}
+}
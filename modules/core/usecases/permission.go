@@ -0,0 +1,189 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/repositories"
+	projectDomains "github.com/dzungtran/echo-rest-api/modules/projects/domains"
+	projectRepo "github.com/dzungtran/echo-rest-api/modules/projects/repositories"
+	"github.com/dzungtran/echo-rest-api/pkg/authz"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/cue"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+)
+
+// PermissionUsecase represent the effective permissions' usecase contract
+type PermissionUsecase interface {
+	Fetch(ctx context.Context, principal *domains.Principal, orgId int64) (*authz.EffectivePermissions, error)
+	Check(ctx context.Context, principal *domains.Principal, attrs authz.RequestAttributes, request dto.CheckPermissionsReq) ([]*dto.PermissionCheckResp, error)
+}
+
+type permissionUsecase struct {
+	appConf     *config.AppConfig
+	orgRepo     repositories.OrgRepository
+	grantRepo   repositories.ResourceGrantRepository
+	projectRepo projectRepo.ProjectRepository
+	authorizer  authz.Authorizer
+}
+
+// NewPermissionUsecase will create new a permissionUsecase object representation of PermissionUsecase interface
+func NewPermissionUsecase(
+	appConf *config.AppConfig,
+	orgRepo repositories.OrgRepository,
+	grantRepo repositories.ResourceGrantRepository,
+	projectRepo projectRepo.ProjectRepository,
+	authorizer authz.Authorizer,
+) PermissionUsecase {
+	return &permissionUsecase{
+		appConf:     appConf,
+		orgRepo:     orgRepo,
+		grantRepo:   grantRepo,
+		projectRepo: projectRepo,
+		authorizer:  authorizer,
+	}
+}
+
+// Fetch evaluates the role and the permissions of the caller in the org, only the platform ones without org.
+// The resource permissions are the grants of the caller on the org and its projects
+func (u *permissionUsecase) Fetch(ctx context.Context, principal *domains.Principal, orgId int64) (*authz.EffectivePermissions, error) {
+	callOpts := principalOptions(principal)
+	if orgId > 0 {
+		org, err := u.orgRepo.GetByID(ctx, orgId)
+		if err != nil {
+			return nil, err
+		}
+
+		grants, err := u.grantRepo.Fetch(ctx, repositories.ParamsForFetchResourceGrants{
+			OrgId:  org.Id,
+			UserId: principal.User.Id,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		callOpts = append(callOpts,
+			authz.WithInputOrg(org),
+			authz.WithInputResourcePermissions(authz.ResourceGrantPermissions(grants)),
+		)
+	}
+
+	return u.authorizer.UserPermissions(ctx, principal.User, callOpts...)
+}
+
+// Check evaluates every request of the batch for the caller like the policies middlewares do, as if it was made
+// with the attributes of the current request. The project checks apply the access settings of the org of the project
+func (u *permissionUsecase) Check(ctx context.Context, principal *domains.Principal, attrs authz.RequestAttributes, req dto.CheckPermissionsReq) ([]*dto.PermissionCheckResp, error) {
+	if err := utils.CueValidateObject("CheckPermissionsRequest", cue.CueDefinitionForAuthz, req); err != nil {
+		return nil, err
+	}
+
+	grants, err := u.grantRepo.Fetch(ctx, repositories.ParamsForFetchResourceGrants{UserId: principal.User.Id})
+	if err != nil {
+		return nil, err
+	}
+	resourcePerms := authz.ResourceGrantPermissions(grants)
+
+	// the checks of a batch often target the same org or project
+	orgs := map[int64]*domains.Org{}
+	projects := map[int64]*projectDomains.Project{}
+
+	loadOrg := func(id int64) (*domains.Org, error) {
+		org, ok := orgs[id]
+		if !ok {
+			var err error
+			if org, err = u.orgRepo.GetByID(ctx, id); err != nil && !errors.Is(err, constants.ErrNotFound) {
+				return nil, err
+			}
+			orgs[id] = org
+		}
+		return org, nil
+	}
+
+	rs := make([]*dto.PermissionCheckResp, 0, len(req.Checks))
+	for _, check := range req.Checks {
+		res := &dto.PermissionCheckResp{
+			PermissionCheck: check,
+			DenyMessages:    []string{},
+		}
+		rs = append(rs, res)
+
+		callOpts := append(principalOptions(principal),
+			authz.WithInputRequestMethod(check.Method),
+			authz.WithInputRequestEndpoint(check.Endpoint),
+			authz.WithInputResourcePermissions(resourcePerms),
+			authz.WithInputRequest(attrs),
+		)
+
+		var org *domains.Org
+		if check.OrgId > 0 {
+			if org, err = loadOrg(check.OrgId); err != nil {
+				return nil, err
+			}
+			if org == nil {
+				res.Error = "org not found"
+				continue
+			}
+			callOpts = append(callOpts,
+				authz.WithInputOrg(org),
+				authz.WithInputResource(domains.ResourceTypeOrg, org.Id),
+			)
+		}
+
+		if check.ProjectId > 0 {
+			project, ok := projects[check.ProjectId]
+			if !ok {
+				var err error
+				if project, err = u.projectRepo.GetByID(ctx, check.ProjectId); err != nil && !errors.Is(err, constants.ErrNotFound) {
+					return nil, err
+				}
+				projects[check.ProjectId] = project
+			}
+			if project == nil {
+				res.Error = "project not found"
+				continue
+			}
+			// the project is the requested resource and its org gives the access settings like in CheckPoliciesWithProject
+			callOpts = append(callOpts,
+				authz.WithInputExtraData("project", project),
+				authz.WithInputResource(domains.ResourceTypeProject, project.Id),
+			)
+			if org == nil {
+				if org, err = loadOrg(project.OrgId); err != nil {
+					return nil, err
+				}
+				if org != nil {
+					callOpts = append(callOpts, authz.WithInputOrg(org))
+				}
+			}
+		}
+
+		denyMsg, err := u.authorizer.Check(ctx, principal.User, callOpts...)
+		if err != nil && !errors.Is(err, authz.ErrForbidden) {
+			return nil, err
+		}
+		res.Allowed = err == nil
+		if denyMsg != nil {
+			res.DenyMessages = denyMsg
+		}
+
+		if res.Allowed && org != nil && org.RequireMfa && !principal.IsMfaVerified(u.appConf.MfaStepUpTTL, attrs.Time) {
+			res.Allowed = false
+			res.DenyMessages = []string{constants.MessageMfaRequired}
+			res.Code = constants.ErrorCodeMfaRequired
+		}
+	}
+	return rs, nil
+}
+
+// principalOptions scopes the policies to the permissions of the token of the caller
+func principalOptions(principal *domains.Principal) []authz.CallOPAInputOption {
+	callOpts := make([]authz.CallOPAInputOption, 0)
+	if principal.Permissions != nil {
+		callOpts = append(callOpts, authz.WithInputTokenPermissions(principal.Permissions))
+	}
+	return callOpts
+}
@@ -0,0 +1,67 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/repositories"
+	"github.com/dzungtran/echo-rest-api/pkg/authz"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeResourceGrantRepository struct {
+	repositories.ResourceGrantRepository
+}
+
+func (r fakeResourceGrantRepository) Fetch(ctx context.Context, params repositories.ParamsForFetchResourceGrants) ([]*domains.ResourceGrant, error) {
+	return nil, nil
+}
+
+func TestCheckPermissionsAppliesOrgAccess(t *testing.T) {
+	verifiedAt := time.Now()
+	check := dto.PermissionCheck{Method: "GET", Endpoint: "/admin/orgs/:orgId", OrgId: 9}
+
+	tcs := []struct {
+		name          string
+		org           *domains.Org
+		clientIp      string
+		mfaVerifiedAt *time.Time
+		expected      bool
+		expectedCode  string
+	}{
+		{"should allow from the IP allowlist", &domains.Org{Id: 9, IpAllowlist: []string{"10.0.0.0/8"}}, "10.1.2.3", nil, true, ""},
+		{"should deny outside the IP allowlist", &domains.Org{Id: 9, IpAllowlist: []string{"10.0.0.0/8"}}, "203.0.113.1", nil, false, ""},
+		{"should require mfa for an unverified session", &domains.Org{Id: 9, RequireMfa: true}, "10.1.2.3", nil, false, constants.ErrorCodeMfaRequired},
+		{"should allow a verified session", &domains.Org{Id: 9, RequireMfa: true}, "10.1.2.3", &verifiedAt, true, ""},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			uc := NewPermissionUsecase(
+				&config.AppConfig{MfaStepUpTTL: time.Hour},
+				&fakeOrgRepository{org: tc.org},
+				fakeResourceGrantRepository{},
+				nil,
+				authz.NewOPAAuthorizer(),
+			)
+			principal := &domains.Principal{
+				Kind:          domains.PrincipalKindUser,
+				User:          &domains.UserWithRoles{User: domains.User{Id: 1}, OrgRole: map[int64]string{9: "owner"}},
+				MfaVerifiedAt: tc.mfaVerifiedAt,
+			}
+
+			rs, err := uc.Check(context.Background(), principal,
+				authz.RequestAttributes{ClientIp: tc.clientIp, Time: time.Now()},
+				dto.CheckPermissionsReq{Checks: []dto.PermissionCheck{check}})
+			assert.Nil(t, err)
+			assert.Len(t, rs, 1)
+			assert.Equal(t, tc.expected, rs[0].Allowed)
+			assert.Equal(t, tc.expectedCode, rs[0].Code)
+		})
+	}
+}
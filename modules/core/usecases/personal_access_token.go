@@ -0,0 +1,198 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/repositories"
+	"github.com/dzungtran/echo-rest-api/pkg/authz"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	"github.com/dzungtran/echo-rest-api/pkg/cue"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+)
+
+const (
+	personalAccessTokenSize          = 32
+	personalAccessTokenPrefixLength  = 9
+	personalAccessTokenTouchInterval = time.Minute
+)
+
+var (
+	ErrUnknownPermission  = errors.New("unknown permission")
+	ErrOrgNotJoined       = errors.New("user is not a member of the org")
+	ErrExpiresAtInThePast = errors.New("expires_at must be in the future")
+)
+
+// PersonalAccessTokenUsecase represent the personal access token's usecase contract
+type PersonalAccessTokenUsecase interface {
+	Create(ctx context.Context, userId int64, request dto.CreatePersonalAccessTokenReq) (*dto.PersonalAccessTokenCreatedResp, error)
+	Fetch(ctx context.Context, userId int64) ([]*domains.PersonalAccessToken, int64, error)
+	Revoke(ctx context.Context, userId, id int64) error
+	Authenticate(ctx context.Context, token string) (*domains.PersonalAccessToken, error)
+}
+
+type personalAccessTokenUsecase struct {
+	tokenRepo   repositories.PersonalAccessTokenRepository
+	userOrgRepo repositories.UserOrgRepository
+	authorizer  authz.Authorizer
+}
+
+// NewPersonalAccessTokenUsecase will create new a personalAccessTokenUsecase object representation of PersonalAccessTokenUsecase interface
+func NewPersonalAccessTokenUsecase(
+	tokenRepo repositories.PersonalAccessTokenRepository,
+	userOrgRepo repositories.UserOrgRepository,
+	authorizer authz.Authorizer,
+) PersonalAccessTokenUsecase {
+	return &personalAccessTokenUsecase{
+		tokenRepo:   tokenRepo,
+		userOrgRepo: userOrgRepo,
+		authorizer:  authorizer,
+	}
+}
+
+func (u *personalAccessTokenUsecase) Create(ctx context.Context, userId int64, req dto.CreatePersonalAccessTokenReq) (*dto.PersonalAccessTokenCreatedResp, error) {
+	if err := utils.CueValidateObject("CreatePersonalAccessTokenRequest", cue.CueDefinitionForToken, req); err != nil {
+		return nil, err
+	}
+
+	for _, perm := range req.Permissions {
+		if !u.authorizer.IsKnownPermission(perm) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownPermission, perm)
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrExpiresAtInThePast
+	}
+
+	if err := u.verifyOrgs(ctx, userId, req.OrgIds); err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateSecureToken(personalAccessTokenSize)
+	if err != nil {
+		return nil, err
+	}
+	plainToken := domains.PersonalAccessTokenPrefix + secret
+
+	token := &domains.PersonalAccessToken{
+		UserId:      userId,
+		Name:        req.Name,
+		TokenHash:   utils.GetSHA256Hash(plainToken),
+		TokenPrefix: plainToken[:personalAccessTokenPrefixLength],
+		Permissions: req.Permissions,
+		OrgIds:      req.OrgIds,
+		ExpiresAt:   req.ExpiresAt,
+	}
+	if token.Permissions == nil {
+		token.Permissions = []string{}
+	}
+	if token.OrgIds == nil {
+		token.OrgIds = []int64{}
+	}
+
+	id, err := u.tokenRepo.Create(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err = u.tokenRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.PersonalAccessTokenCreatedResp{
+		PersonalAccessToken: *token,
+		Token:               plainToken,
+	}, nil
+}
+
+func (u *personalAccessTokenUsecase) Fetch(ctx context.Context, userId int64) ([]*domains.PersonalAccessToken, int64, error) {
+	return u.tokenRepo.Fetch(ctx, repositories.ParamsForFetchPersonalAccessTokens{
+		UserId: userId,
+		CommonParamsForFetch: contexts.CommonParamsForFetch{
+			NoLimit: true,
+		},
+	})
+}
+
+func (u *personalAccessTokenUsecase) Revoke(ctx context.Context, userId, id int64) error {
+	token, err := u.tokenRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// do not leak tokens of other users
+	if token.UserId != userId || token.RevokedAt != nil {
+		return constants.ErrNotFound
+	}
+
+	now := time.Now().UTC()
+	token.RevokedAt = &now
+	return u.tokenRepo.Update(ctx, token, []string{"revoked_at"})
+}
+
+// Authenticate returns the active token matching the given plain token
+func (u *personalAccessTokenUsecase) Authenticate(ctx context.Context, plainToken string) (*domains.PersonalAccessToken, error) {
+	if !strings.HasPrefix(plainToken, domains.PersonalAccessTokenPrefix) {
+		return nil, constants.ErrUnauthorized
+	}
+
+	token, err := u.tokenRepo.GetByTokenHash(ctx, utils.GetSHA256Hash(plainToken))
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return nil, constants.ErrUnauthorized
+		}
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if !token.IsActive(now) {
+		return nil, constants.ErrUnauthorized
+	}
+
+	// avoid a write on every request
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > personalAccessTokenTouchInterval {
+		token.LastUsedAt = &now
+		if err = u.tokenRepo.Update(ctx, token, []string{"last_used_at"}); err != nil {
+			logger.Log().Warnw("cannot update last used time of token", "token_id", token.Id, "error", err)
+		}
+	}
+
+	return token, nil
+}
+
+func (u *personalAccessTokenUsecase) verifyOrgs(ctx context.Context, userId int64, orgIds []int64) error {
+	if len(orgIds) == 0 {
+		return nil
+	}
+
+	userOrgs, _, err := u.userOrgRepo.Fetch(ctx, repositories.ParamsForFetchUserOrgs{
+		UserIds: []int64{userId},
+		CommonParamsForFetch: contexts.CommonParamsForFetch{
+			NoLimit: true,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	joined := make(map[int64]bool, len(userOrgs))
+	for _, uo := range userOrgs {
+		joined[uo.OrgId] = true
+	}
+
+	for _, orgId := range orgIds {
+		if !joined[orgId] {
+			return fmt.Errorf("%w: %d", ErrOrgNotJoined, orgId)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/repositories"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/cue"
+	"github.com/dzungtran/echo-rest-api/pkg/hook"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+)
+
+var (
+	// ErrLastSuperAdmin is returned by Revoke, the platform would be left without super admin
+	ErrLastSuperAdmin = errors.New("cannot revoke the last super admin")
+)
+
+// PlatformRoleUsecase represent the platform role's usecase contract
+type PlatformRoleUsecase interface {
+	FetchByUserId(ctx context.Context, userId int64) ([]*domains.UserPlatformRole, error)
+	Grant(ctx context.Context, grantedBy int64, request dto.GrantPlatformRoleReq) (*domains.UserPlatformRole, error)
+	Revoke(ctx context.Context, userId int64, role domains.PlatformRole) error
+	Bootstrap(ctx context.Context, email string) error
+}
+
+type platformRoleUsecase struct {
+	platformRoleRepo repositories.PlatformRoleRepository
+	userRepo         repositories.UserRepository
+	hooker           hook.HookerInterface
+}
+
+// NewPlatformRoleUsecase will create new a platformRoleUsecase object representation of PlatformRoleUsecase interface
+func NewPlatformRoleUsecase(
+	platformRoleRepo repositories.PlatformRoleRepository,
+	userRepo repositories.UserRepository,
+	hooker hook.HookerInterface,
+) PlatformRoleUsecase {
+	return &platformRoleUsecase{
+		platformRoleRepo: platformRoleRepo,
+		userRepo:         userRepo,
+		hooker:           hooker,
+	}
+}
+
+func (u *platformRoleUsecase) FetchByUserId(ctx context.Context, userId int64) ([]*domains.UserPlatformRole, error) {
+	return u.platformRoleRepo.FetchByUserId(ctx, userId)
+}
+
+// Grant returns ErrDuplicated when the user already has the role
+func (u *platformRoleUsecase) Grant(ctx context.Context, grantedBy int64, req dto.GrantPlatformRoleReq) (*domains.UserPlatformRole, error) {
+	if err := utils.CueValidateObject("GrantPlatformRoleRequest", cue.CueDefinitionForPlatformRole, req); err != nil {
+		return nil, err
+	}
+
+	user, err := u.userRepo.GetByID(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &domains.UserPlatformRole{
+		UserId:    user.Id,
+		Role:      domains.PlatformRole(req.Role),
+		GrantedBy: &grantedBy,
+	}
+	return role, u.create(ctx, role)
+}
+
+func (u *platformRoleUsecase) Revoke(ctx context.Context, userId int64, role domains.PlatformRole) error {
+	if role == domains.PlatformRoleSuperAdmin {
+		count, err := u.platformRoleRepo.CountByRole(ctx, role)
+		if err != nil {
+			return err
+		}
+		if count == 1 {
+			roles, err := u.platformRoleRepo.FetchByUserId(ctx, userId)
+			if err != nil {
+				return err
+			}
+			for _, r := range roles {
+				if r.Role == role {
+					return ErrLastSuperAdmin
+				}
+			}
+		}
+	}
+
+	if err := u.platformRoleRepo.DeleteByUserIdAndRole(ctx, userId, role); err != nil {
+		return err
+	}
+
+	u.hooker.Trigger(hook.EventPayload{
+		Name:       hook.Deleted,
+		Scope:      hook.PlatformRoleScope,
+		Source:     hook.SourcePlatformRoleAPI,
+		PayloadOld: &domains.UserPlatformRole{UserId: userId, Role: role},
+	})
+	return nil
+}
+
+// Bootstrap makes the user with the given email super admin, as long as the platform has none.
+// The user must have signed in once, the seed is skipped otherwise and runs again on the next start
+func (u *platformRoleUsecase) Bootstrap(ctx context.Context, email string) error {
+	if email == "" {
+		return nil
+	}
+
+	count, err := u.platformRoleRepo.CountByRole(ctx, domains.PlatformRoleSuperAdmin)
+	if err != nil || count > 0 {
+		return err
+	}
+
+	user, err := u.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			logger.Log().Warnw("cannot bootstrap super admin, the user must sign in once", "email", email)
+			return nil
+		}
+		return err
+	}
+
+	err = u.create(ctx, &domains.UserPlatformRole{
+		UserId: user.Id,
+		Role:   domains.PlatformRoleSuperAdmin,
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Log().Infow("bootstrapped super admin", "user_id", user.Id, "email", email)
+	return nil
+}
+
+func (u *platformRoleUsecase) create(ctx context.Context, role *domains.UserPlatformRole) error {
+	id, err := u.platformRoleRepo.Create(ctx, role)
+	if err != nil {
+		return err
+	}
+	role.Id = id
+
+	u.hooker.Trigger(hook.EventPayload{
+		Name:    hook.Created,
+		Scope:   hook.PlatformRoleScope,
+		Source:  hook.SourcePlatformRoleAPI,
+		Payload: role,
+	})
+	return nil
+}
@@ -0,0 +1,153 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/repositories"
+	projectRepo "github.com/dzungtran/echo-rest-api/modules/projects/repositories"
+	"github.com/dzungtran/echo-rest-api/pkg/authz"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/cue"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+)
+
+var (
+	// ErrGrantResource is returned when the resource of a grant is not owned by the org
+	ErrGrantResource = errors.New("the resource does not belong to the org")
+	// ErrGrantEscalation is returned when a grant would give a permission the caller does not hold
+	ErrGrantEscalation = errors.New("the grant gives a permission the caller does not hold")
+)
+
+// ResourceGrantUsecase represent the resource grant's usecase contract
+type ResourceGrantUsecase interface {
+	Fetch(ctx context.Context, request dto.SearchResourceGrantsReq) ([]*domains.ResourceGrant, error)
+	Create(ctx context.Context, actor *domains.Principal, request dto.CreateResourceGrantReq) (*domains.ResourceGrant, error)
+	Delete(ctx context.Context, orgId, id int64) error
+}
+
+type resourceGrantUsecase struct {
+	grantRepo   repositories.ResourceGrantRepository
+	orgRepo     repositories.OrgRepository
+	userRepo    repositories.UserRepository
+	projectRepo projectRepo.ProjectRepository
+	authorizer  authz.Authorizer
+}
+
+// NewResourceGrantUsecase will create new a resourceGrantUsecase object representation of ResourceGrantUsecase interface
+func NewResourceGrantUsecase(
+	grantRepo repositories.ResourceGrantRepository,
+	orgRepo repositories.OrgRepository,
+	userRepo repositories.UserRepository,
+	projectRepo projectRepo.ProjectRepository,
+	authorizer authz.Authorizer,
+) ResourceGrantUsecase {
+	return &resourceGrantUsecase{
+		grantRepo:   grantRepo,
+		orgRepo:     orgRepo,
+		userRepo:    userRepo,
+		projectRepo: projectRepo,
+		authorizer:  authorizer,
+	}
+}
+
+func (u *resourceGrantUsecase) Fetch(ctx context.Context, req dto.SearchResourceGrantsReq) ([]*domains.ResourceGrant, error) {
+	return u.grantRepo.Fetch(ctx, repositories.ParamsForFetchResourceGrants{
+		OrgId:        req.OrgId,
+		UserId:       req.UserId,
+		ResourceType: domains.ResourceType(req.ResourceType),
+		ResourceId:   req.ResourceId,
+	})
+}
+
+// Create returns ErrDuplicated when the user already has the permission on the resource
+func (u *resourceGrantUsecase) Create(ctx context.Context, actor *domains.Principal, req dto.CreateResourceGrantReq) (*domains.ResourceGrant, error) {
+	if err := utils.CueValidateObject("CreateResourceGrantRequest", cue.CueDefinitionForResourceGrant, req); err != nil {
+		return nil, err
+	}
+
+	if !u.authorizer.IsKnownPermission(req.Permission) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPermission, req.Permission)
+	}
+
+	org, err := u.orgRepo.GetByID(ctx, req.OrgId)
+	if err != nil {
+		return nil, err
+	}
+
+	grant := &domains.ResourceGrant{
+		OrgId:        org.Id,
+		UserId:       req.UserId,
+		Permission:   req.Permission,
+		ResourceType: domains.ResourceType(req.ResourceType),
+		ResourceId:   req.ResourceId,
+	}
+	if actor.User != nil && actor.User.Id > 0 {
+		grant.CreatedBy = &actor.User.Id
+	}
+
+	if err = u.checkResource(ctx, grant); err != nil {
+		return nil, err
+	}
+
+	if _, err = u.userRepo.GetByID(ctx, grant.UserId); err != nil {
+		return nil, err
+	}
+
+	// the caller cannot share more than it holds in the org
+	callOpts := []authz.CallOPAInputOption{authz.WithInputOrg(org)}
+	if actor.Permissions != nil {
+		callOpts = append(callOpts, authz.WithInputTokenPermissions(actor.Permissions))
+	}
+	held, err := u.authorizer.UserPermissions(ctx, actor.User, callOpts...)
+	if err != nil {
+		return nil, err
+	}
+	if !utils.IsSliceContains(held.Permissions, grant.Permission) {
+		return nil, fmt.Errorf("%w: %s", ErrGrantEscalation, grant.Permission)
+	}
+
+	id, err := u.grantRepo.Create(ctx, grant)
+	if err != nil {
+		return nil, err
+	}
+	return u.grantRepo.GetByID(ctx, id)
+}
+
+func (u *resourceGrantUsecase) Delete(ctx context.Context, orgId, id int64) error {
+	grant, err := u.grantRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if grant.OrgId != orgId {
+		return constants.ErrNotFound
+	}
+	return u.grantRepo.DeleteById(ctx, id)
+}
+
+// checkResource returns ErrGrantResource unless the org of the grant owns its resource
+func (u *resourceGrantUsecase) checkResource(ctx context.Context, grant *domains.ResourceGrant) error {
+	switch grant.ResourceType {
+	case domains.ResourceTypeOrg:
+		if grant.ResourceId != grant.OrgId {
+			return ErrGrantResource
+		}
+	case domains.ResourceTypeProject:
+		project, err := u.projectRepo.GetByID(ctx, grant.ResourceId)
+		if err != nil {
+			if errors.Is(err, constants.ErrNotFound) {
+				return ErrGrantResource
+			}
+			return err
+		}
+		if project.OrgId != grant.OrgId {
+			return ErrGrantResource
+		}
+	default:
+		return ErrGrantResource
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/repositories"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	"github.com/dzungtran/echo-rest-api/pkg/cue"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+)
+
+const (
+	// ServiceAccountTokenIssuer is the issuer of the access tokens signed for service accounts
+	ServiceAccountTokenIssuer = "urn:echo-rest-api:service-account"
+	// ServiceAccountTokenSecretMinSize is the minimum size of the HS256 signing secret
+	ServiceAccountTokenSecretMinSize = 32
+
+	serviceAccountClientIdPrefix  = "sa_"
+	serviceAccountSecretSize      = 32
+	serviceAccountTokenClockSkew  = 30 * time.Second
+	serviceAccountTokenOrgIdClaim = "org_id"
+	serviceAccountAccessTokenType = "Bearer"
+)
+
+var (
+	ErrServiceAccountTokenNotConfigured = fmt.Errorf("SERVICE_ACCOUNT_TOKEN_SECRET must have at least %d bytes", ServiceAccountTokenSecretMinSize)
+	// ErrNotServiceAccountToken is returned by VerifyToken for tokens issued by someone else
+	ErrNotServiceAccountToken = errors.New("not a service account token")
+)
+
+// ServiceAccountUsecase represent the service account's usecase contract
+type ServiceAccountUsecase interface {
+	Create(ctx context.Context, request dto.CreateServiceAccountReq) (*dto.ServiceAccountCreatedResp, error)
+	Fetch(ctx context.Context, orgId int64) ([]*domains.ServiceAccount, int64, error)
+	Update(ctx context.Context, request dto.UpdateServiceAccountReq) (*domains.ServiceAccount, error)
+	Delete(ctx context.Context, orgId, id int64) error
+	RotateSecret(ctx context.Context, orgId, id int64) (*dto.ServiceAccountCreatedResp, error)
+	IssueToken(ctx context.Context, request dto.ServiceAccountTokenReq) (*dto.ServiceAccountTokenResp, error)
+	VerifyToken(ctx context.Context, rawToken string) (*domains.ServiceAccount, error)
+}
+
+type serviceAccountUsecase struct {
+	appConf *config.AppConfig
+	saRepo  repositories.ServiceAccountRepository
+}
+
+// NewServiceAccountUsecase will create new a serviceAccountUsecase object representation of ServiceAccountUsecase interface
+func NewServiceAccountUsecase(appConf *config.AppConfig, saRepo repositories.ServiceAccountRepository) ServiceAccountUsecase {
+	return &serviceAccountUsecase{
+		appConf: appConf,
+		saRepo:  saRepo,
+	}
+}
+
+func (u *serviceAccountUsecase) Create(ctx context.Context, req dto.CreateServiceAccountReq) (*dto.ServiceAccountCreatedResp, error) {
+	if err := utils.CueValidateObject("CreateServiceAccountRequest", cue.CueDefinitionForServiceAccount, req); err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateSecureToken(serviceAccountSecretSize)
+	if err != nil {
+		return nil, err
+	}
+
+	sa := &domains.ServiceAccount{
+		OrgId:            req.OrgId,
+		Name:             req.Name,
+		ClientId:         serviceAccountClientIdPrefix + utils.GenerateUUID(),
+		ClientSecretHash: utils.GetSHA256Hash(secret),
+		Role:             domains.UserOrgRole(req.Role),
+		Status:           domains.ServiceAccountStatusActive,
+	}
+
+	id, err := u.saRepo.Create(ctx, sa)
+	if err != nil {
+		return nil, err
+	}
+
+	sa, err = u.saRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.ServiceAccountCreatedResp{
+		ServiceAccount: *sa,
+		ClientSecret:   secret,
+	}, nil
+}
+
+func (u *serviceAccountUsecase) Fetch(ctx context.Context, orgId int64) ([]*domains.ServiceAccount, int64, error) {
+	return u.saRepo.Fetch(ctx, repositories.ParamsForFetchServiceAccounts{
+		OrgId: orgId,
+		CommonParamsForFetch: contexts.CommonParamsForFetch{
+			NoLimit: true,
+		},
+	})
+}
+
+func (u *serviceAccountUsecase) Update(ctx context.Context, req dto.UpdateServiceAccountReq) (*domains.ServiceAccount, error) {
+	if err := utils.CueValidateObject("UpdateServiceAccountRequest", cue.CueDefinitionForServiceAccount, req); err != nil {
+		return nil, err
+	}
+
+	sa, err := u.getInOrg(ctx, req.OrgId, req.ServiceAccountId)
+	if err != nil {
+		return nil, err
+	}
+
+	sa.Name = req.Name
+	sa.Role = domains.UserOrgRole(req.Role)
+	sa.Status = domains.ServiceAccountStatus(req.Status)
+	err = u.saRepo.Update(ctx, sa, []string{"name", "role", "status"})
+	if err != nil {
+		return nil, err
+	}
+
+	return u.saRepo.GetByID(ctx, sa.Id)
+}
+
+func (u *serviceAccountUsecase) Delete(ctx context.Context, orgId, id int64) error {
+	sa, err := u.getInOrg(ctx, orgId, id)
+	if err != nil {
+		return err
+	}
+	return u.saRepo.DeleteById(ctx, sa.Id)
+}
+
+func (u *serviceAccountUsecase) RotateSecret(ctx context.Context, orgId, id int64) (*dto.ServiceAccountCreatedResp, error) {
+	sa, err := u.getInOrg(ctx, orgId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateSecureToken(serviceAccountSecretSize)
+	if err != nil {
+		return nil, err
+	}
+
+	sa.ClientSecretHash = utils.GetSHA256Hash(secret)
+	if err = u.saRepo.Update(ctx, sa, []string{"client_secret_hash"}); err != nil {
+		return nil, err
+	}
+
+	return &dto.ServiceAccountCreatedResp{
+		ServiceAccount: *sa,
+		ClientSecret:   secret,
+	}, nil
+}
+
+// IssueToken exchanges client credentials for a short-lived access token
+func (u *serviceAccountUsecase) IssueToken(ctx context.Context, req dto.ServiceAccountTokenReq) (*dto.ServiceAccountTokenResp, error) {
+	key, err := u.signingKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ClientId == "" || req.ClientSecret == "" {
+		return nil, constants.ErrUnauthorized
+	}
+
+	sa, err := u.saRepo.GetByClientId(ctx, req.ClientId)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return nil, constants.ErrUnauthorized
+		}
+		return nil, err
+	}
+
+	if !utils.IsSecureEqual(utils.GetSHA256Hash(req.ClientSecret), sa.ClientSecretHash) ||
+		sa.Status != domains.ServiceAccountStatusActive {
+		return nil, constants.ErrUnauthorized
+	}
+
+	now := time.Now().UTC()
+	tkn, err := jwt.NewBuilder().
+		Issuer(ServiceAccountTokenIssuer).
+		Subject(sa.ClientId).
+		JwtID(utils.GenerateUUID()).
+		IssuedAt(now).
+		Expiration(now.Add(u.appConf.ServiceAccountTokenTTL)).
+		Claim(serviceAccountTokenOrgIdClaim, sa.OrgId).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	signed, err := jwt.Sign(tkn, jwt.WithKey(jwa.HS256(), key))
+	if err != nil {
+		return nil, err
+	}
+
+	sa.LastUsedAt = &now
+	if err = u.saRepo.Update(ctx, sa, []string{"last_used_at"}); err != nil {
+		logger.Log().Warnw("cannot update last used time of service account", "service_account_id", sa.Id, "error", err)
+	}
+
+	return &dto.ServiceAccountTokenResp{
+		AccessToken: string(signed),
+		TokenType:   serviceAccountAccessTokenType,
+		ExpiresIn:   int64(u.appConf.ServiceAccountTokenTTL.Seconds()),
+	}, nil
+}
+
+// VerifyToken returns the active service account of an access token signed by IssueToken
+func (u *serviceAccountUsecase) VerifyToken(ctx context.Context, rawToken string) (*domains.ServiceAccount, error) {
+	unverified, err := jwt.ParseInsecure([]byte(rawToken))
+	if err != nil {
+		return nil, ErrNotServiceAccountToken
+	}
+
+	if iss, _ := unverified.Issuer(); iss != ServiceAccountTokenIssuer {
+		return nil, ErrNotServiceAccountToken
+	}
+
+	key, err := u.signingKey()
+	if err != nil {
+		return nil, err
+	}
+
+	tkn, err := jwt.Parse([]byte(rawToken),
+		jwt.WithKey(jwa.HS256(), key),
+		jwt.WithIssuer(ServiceAccountTokenIssuer),
+		jwt.WithAcceptableSkew(serviceAccountTokenClockSkew),
+		jwt.WithRequiredClaim("exp"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", constants.ErrUnauthorized, err)
+	}
+
+	clientId, _ := tkn.Subject()
+	sa, err := u.saRepo.GetByClientId(ctx, clientId)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return nil, constants.ErrUnauthorized
+		}
+		return nil, err
+	}
+
+	var orgId float64
+	if err = tkn.Get(serviceAccountTokenOrgIdClaim, &orgId); err != nil || int64(orgId) != sa.OrgId {
+		return nil, constants.ErrUnauthorized
+	}
+
+	if sa.Status != domains.ServiceAccountStatusActive {
+		return nil, constants.ErrUnauthorized
+	}
+	return sa, nil
+}
+
+func (u *serviceAccountUsecase) getInOrg(ctx context.Context, orgId, id int64) (*domains.ServiceAccount, error) {
+	sa, err := u.saRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if sa.OrgId != orgId {
+		return nil, constants.ErrNotFound
+	}
+	return sa, nil
+}
+
+func (u *serviceAccountUsecase) signingKey() ([]byte, error) {
+	if len(u.appConf.ServiceAccountTokenSecret) < ServiceAccountTokenSecretMinSize {
+		return nil, ErrServiceAccountTokenNotConfigured
+	}
+	return []byte(u.appConf.ServiceAccountTokenSecret), nil
+}
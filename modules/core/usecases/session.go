@@ -0,0 +1,154 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/repositories"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+)
+
+const (
+	sessionTouchInterval  = time.Minute
+	sessionDeviceMaxSize  = 100
+	sessionAgentMaxSize   = 255
+	sessionAddressMaxSize = 45
+)
+
+// SessionUsecase represent the session's usecase contract
+type SessionUsecase interface {
+	Start(ctx context.Context, userId int64, authMethod string, client domains.SessionClient, expiresAt time.Time) (*domains.Session, error)
+	Extend(ctx context.Context, session *domains.Session, client domains.SessionClient, expiresAt time.Time) error
+	Verify(ctx context.Context, id int64) (*domains.Session, error)
+	Fetch(ctx context.Context, userId int64) ([]*domains.Session, int64, error)
+	Revoke(ctx context.Context, userId, id int64) error
+	RevokeAll(ctx context.Context, userId int64) error
+	MarkMfaVerified(ctx context.Context, id int64) error
+}
+
+type sessionUsecase struct {
+	sessionRepo repositories.SessionRepository
+}
+
+// NewSessionUsecase will create new a sessionUsecase object representation of SessionUsecase interface
+func NewSessionUsecase(sessionRepo repositories.SessionRepository) SessionUsecase {
+	return &sessionUsecase{
+		sessionRepo: sessionRepo,
+	}
+}
+
+func (u *sessionUsecase) Start(ctx context.Context, userId int64, authMethod string, client domains.SessionClient, expiresAt time.Time) (*domains.Session, error) {
+	session := &domains.Session{
+		UserId:     userId,
+		AuthMethod: authMethod,
+		Device:     truncate(client.Device, sessionDeviceMaxSize),
+		IpAddress:  truncate(client.IpAddress, sessionAddressMaxSize),
+		UserAgent:  truncate(client.UserAgent, sessionAgentMaxSize),
+		LastSeenAt: time.Now().UTC(),
+		ExpiresAt:  expiresAt,
+	}
+
+	id, err := u.sessionRepo.Create(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	session.Id = id
+	return session, nil
+}
+
+// Extend pushes the expiry of the session, e.g. when its refresh token is rotated
+func (u *sessionUsecase) Extend(ctx context.Context, session *domains.Session, client domains.SessionClient, expiresAt time.Time) error {
+	session.LastSeenAt = time.Now().UTC()
+	session.ExpiresAt = expiresAt
+	fields := []string{"last_seen_at", "expires_at"}
+
+	if client.IpAddress != "" {
+		session.IpAddress = truncate(client.IpAddress, sessionAddressMaxSize)
+		fields = append(fields, "ip_address")
+	}
+	if client.UserAgent != "" {
+		session.UserAgent = truncate(client.UserAgent, sessionAgentMaxSize)
+		fields = append(fields, "user_agent")
+	}
+
+	return u.sessionRepo.Update(ctx, session, fields)
+}
+
+// Verify returns the session when it is still active, last seen time is updated at most once a minute
+func (u *sessionUsecase) Verify(ctx context.Context, id int64) (*domains.Session, error) {
+	session, err := u.sessionRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return nil, constants.ErrUnauthorized
+		}
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if !session.IsActive(now) {
+		return nil, constants.ErrUnauthorized
+	}
+
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		session.LastSeenAt = now
+		if err = u.sessionRepo.Update(ctx, session, []string{"last_seen_at"}); err != nil {
+			logger.Log().Warnw("cannot update last seen time of session", "session_id", session.Id, "error", err)
+		}
+	}
+	return session, nil
+}
+
+func (u *sessionUsecase) Fetch(ctx context.Context, userId int64) ([]*domains.Session, int64, error) {
+	return u.sessionRepo.Fetch(ctx, repositories.ParamsForFetchSessions{
+		UserId:     userId,
+		ActiveOnly: true,
+		CommonParamsForFetch: contexts.CommonParamsForFetch{
+			NoLimit: true,
+		},
+	})
+}
+
+func (u *sessionUsecase) Revoke(ctx context.Context, userId, id int64) error {
+	session, err := u.sessionRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if session.UserId != userId {
+		return constants.ErrNotFound
+	}
+
+	if session.RevokedAt != nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	session.RevokedAt = &now
+	return u.sessionRepo.Update(ctx, session, []string{"revoked_at"})
+}
+
+func (u *sessionUsecase) RevokeAll(ctx context.Context, userId int64) error {
+	return u.sessionRepo.RevokeByUserId(ctx, userId)
+}
+
+// MarkMfaVerified records a second factor verification on the session, see MFA_STEP_UP_TTL
+func (u *sessionUsecase) MarkMfaVerified(ctx context.Context, id int64) error {
+	now := time.Now().UTC()
+	return u.sessionRepo.Update(ctx, &domains.Session{
+		Id:            id,
+		MfaVerifiedAt: &now,
+	}, []string{"mfa_verified_at"})
+}
+
+func truncate(s string, size int) string {
+	r := []rune(s)
+	if len(r) <= size {
+		return s
+	}
+	return string(r[:size])
+}
@@ -2,12 +2,15 @@ package usecases
 
 import (
 	"context"
+	"errors"
 
 	"github.com/dzungtran/echo-rest-api/modules/core/domains"
 	"github.com/dzungtran/echo-rest-api/modules/core/dto"
 	"github.com/dzungtran/echo-rest-api/modules/core/repositories"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
 	"github.com/dzungtran/echo-rest-api/pkg/contexts"
 	"github.com/dzungtran/echo-rest-api/pkg/cue"
+	"github.com/dzungtran/echo-rest-api/pkg/hook"
 	"github.com/dzungtran/echo-rest-api/pkg/utils"
 	"github.com/jinzhu/copier"
 )
@@ -22,18 +25,21 @@ type UserUsecase interface {
 	GetByCode(ctx context.Context, code string) (*domains.User, error)
 	GetByEmail(ctx context.Context, email string) (*domains.User, error)
 	Register(ctx context.Context, request dto.CreateUserReq) (*domains.User, error)
+	UpsertByCode(ctx context.Context, request dto.CreateUserReq) (*domains.User, error)
 }
 
 type userUsecase struct {
 	userRepo   repositories.UserRepository
 	orgUsecase OrgUsecase
+	hooker     hook.HookerInterface
 }
 
 // NewUserUsecase will create new an userUsecase object representation of UserUsecase interface
-func NewUserUsecase(userRepo repositories.UserRepository, orgUsecase OrgUsecase) UserUsecase {
+func NewUserUsecase(userRepo repositories.UserRepository, orgUsecase OrgUsecase, hooker hook.HookerInterface) UserUsecase {
 	return &userUsecase{
 		userRepo:   userRepo,
 		orgUsecase: orgUsecase,
+		hooker:     hooker,
 	}
 }
 
@@ -89,18 +95,40 @@ func (u *userUsecase) Update(ctx context.Context, id int64, req dto.UpdateUserRe
 		return err
 	}
 
+	old := *user
 	copier.Copy(user, req)
 	err = u.userRepo.Update(ctx, user, []string{"first_name", "last_name", "phone", "status"})
+	if err != nil {
+		return
+	}
+
+	u.hooker.Trigger(hook.EventPayload{
+		Name:       hook.Updated,
+		Scope:      hook.UserScope,
+		Source:     hook.SourceUserAPI,
+		PayloadOld: &old,
+		Payload:    user,
+	})
 	return
 }
 
 func (u *userUsecase) Delete(ctx context.Context, id int64) (err error) {
-	_, err = u.userRepo.GetByID(ctx, id)
+	user, err := u.userRepo.GetByID(ctx, id)
 	if err != nil {
 		return
 	}
 
 	err = u.userRepo.DeleteById(ctx, id)
+	if err != nil {
+		return
+	}
+
+	u.hooker.Trigger(hook.EventPayload{
+		Name:       hook.Deleted,
+		Scope:      hook.UserScope,
+		Source:     hook.SourceUserAPI,
+		PayloadOld: user,
+	})
 	return
 }
 
@@ -127,3 +155,51 @@ func (u userUsecase) Register(ctx context.Context, req dto.CreateUserReq) (user
 
 	return
 }
+
+// UpsertByCode registers the user behind an external identity or syncs its profile if it already exists.
+// It is safe to call several times for the same code.
+func (u userUsecase) UpsertByCode(ctx context.Context, req dto.CreateUserReq) (user *domains.User, err error) {
+	if err = utils.CueValidateObject("CreateUserRequest", cue.CueDefinitionForUser, req); err != nil {
+		return nil, err
+	}
+
+	user, err = u.userRepo.GetByCode(ctx, req.Code)
+	if errors.Is(err, constants.ErrNotFound) {
+		user, err = u.Register(ctx, req)
+		if !errors.Is(err, constants.ErrDuplicated) {
+			return
+		}
+
+		// The same identity was registered by a concurrent call, otherwise the email belongs to another user.
+		// The replica may not have the row yet
+		user, err = u.userRepo.GetByCodeFromMaster(ctx, req.Code)
+		if errors.Is(err, constants.ErrNotFound) {
+			return nil, constants.ErrDuplicated
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	old := *user
+	user.FirstName = req.FirstName
+	user.LastName = req.LastName
+	user.Email = req.Email
+	if old.FirstName == user.FirstName && old.LastName == user.LastName && old.Email == user.Email {
+		return
+	}
+
+	err = u.userRepo.Update(ctx, user, []string{"first_name", "last_name", "email"})
+	if err != nil {
+		return
+	}
+
+	u.hooker.Trigger(hook.EventPayload{
+		Name:       hook.Updated,
+		Scope:      hook.UserScope,
+		Source:     hook.SourceUserAPI,
+		PayloadOld: &old,
+		Payload:    user,
+	})
+	return
+}
@@ -0,0 +1,165 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	"github.com/dzungtran/echo-rest-api/modules/core/repositories"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/hook"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeUserRepository keeps the users of the replica and the master apart, to test reads after writes
+type fakeUserRepository struct {
+	repositories.UserRepository
+
+	replica   map[string]*domains.User
+	master    map[string]*domains.User
+	createErr error
+	updateErr error
+	updated   []string
+}
+
+func (r *fakeUserRepository) Create(ctx context.Context, user *domains.User) (int64, error) {
+	if r.createErr != nil {
+		return 0, r.createErr
+	}
+	user.Id = int64(len(r.master) + 1)
+	r.master[user.Code] = user
+	return user.Id, nil
+}
+
+func (r *fakeUserRepository) GetByID(ctx context.Context, id int64) (*domains.User, error) {
+	for _, u := range r.master {
+		if u.Id == id {
+			cp := *u
+			return &cp, nil
+		}
+	}
+	return nil, constants.ErrNotFound
+}
+
+func (r *fakeUserRepository) GetByCode(ctx context.Context, code string) (*domains.User, error) {
+	if u, ok := r.replica[code]; ok {
+		cp := *u
+		return &cp, nil
+	}
+	return nil, constants.ErrNotFound
+}
+
+func (r *fakeUserRepository) GetByCodeFromMaster(ctx context.Context, code string) (*domains.User, error) {
+	if u, ok := r.master[code]; ok {
+		cp := *u
+		return &cp, nil
+	}
+	return nil, constants.ErrNotFound
+}
+
+func (r *fakeUserRepository) Update(ctx context.Context, user *domains.User, fieldsToUpdate []string) error {
+	if r.updateErr != nil {
+		return r.updateErr
+	}
+	r.updated = fieldsToUpdate
+	r.master[user.Code] = user
+	return nil
+}
+
+type fakeOrgUsecase struct {
+	OrgUsecase
+	created int
+}
+
+func (u *fakeOrgUsecase) Create(ctx context.Context, req dto.CreateOrgReq) (*domains.Org, error) {
+	u.created++
+	return &domains.Org{Id: 1, Name: req.Name}, nil
+}
+
+type fakeHooker struct {
+	hook.HookerInterface
+	triggered []hook.EventPayload
+}
+
+func (h *fakeHooker) Trigger(payload hook.EventPayload) {
+	h.triggered = append(h.triggered, payload)
+}
+
+func TestUpsertByCode(t *testing.T) {
+	existing := domains.User{Id: 7, Code: "kratos-identity-1", Email: "old@example.com", FirstName: "Ann", LastName: "Lee"}
+	req := dto.CreateUserReq{Code: "kratos-identity-1", Email: "new@example.com", FirstName: "Ann", LastName: "Lee"}
+
+	tcs := []struct {
+		name            string
+		replica         map[string]*domains.User
+		master          map[string]*domains.User
+		createErr       error
+		updateErr       error
+		req             dto.CreateUserReq
+		expectedErr     error
+		expectedEmail   string
+		expectedOrgs    int
+		expectedUpdated []string
+		expectedHooks   int
+	}{
+		{
+			"should register unknown identity", map[string]*domains.User{}, map[string]*domains.User{}, nil, nil,
+			req, nil, "new@example.com", 1, nil, 0,
+		},
+		{
+			"should sync changed profile",
+			map[string]*domains.User{"kratos-identity-1": &existing}, map[string]*domains.User{"kratos-identity-1": &existing}, nil, nil,
+			req, nil, "new@example.com", 0, []string{"first_name", "last_name", "email"}, 1,
+		},
+		{
+			"should not update unchanged profile",
+			map[string]*domains.User{"kratos-identity-1": &existing}, map[string]*domains.User{"kratos-identity-1": &existing}, nil, nil,
+			dto.CreateUserReq{Code: "kratos-identity-1", Email: "old@example.com", FirstName: "Ann", LastName: "Lee"},
+			nil, "old@example.com", 0, nil, 0,
+		},
+		{
+			"should read the identity registered concurrently from the master",
+			map[string]*domains.User{}, map[string]*domains.User{"kratos-identity-1": &existing}, constants.ErrDuplicated, nil,
+			req, nil, "new@example.com", 0, []string{"first_name", "last_name", "email"}, 1,
+		},
+		{
+			"should reject email of another user on register",
+			map[string]*domains.User{}, map[string]*domains.User{}, constants.ErrDuplicated, nil,
+			req, constants.ErrDuplicated, "", 0, nil, 0,
+		},
+		{
+			"should reject email of another user on update",
+			map[string]*domains.User{"kratos-identity-1": &existing}, map[string]*domains.User{"kratos-identity-1": &existing},
+			nil, constants.ErrDuplicated,
+			req, constants.ErrDuplicated, "", 0, nil, 0,
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeUserRepository{
+				replica:   tc.replica,
+				master:    map[string]*domains.User{},
+				createErr: tc.createErr,
+				updateErr: tc.updateErr,
+			}
+			for code, u := range tc.master {
+				cp := *u
+				repo.master[code] = &cp
+			}
+			orgUC := &fakeOrgUsecase{}
+			hooker := &fakeHooker{}
+
+			user, err := NewUserUsecase(repo, orgUC, hooker).UpsertByCode(context.Background(), tc.req)
+			assert.ErrorIs(t, err, tc.expectedErr)
+			if tc.expectedErr == nil {
+				assert.Equal(t, tc.expectedEmail, user.Email)
+			}
+			assert.Equal(t, tc.expectedOrgs, orgUC.created)
+			assert.Equal(t, tc.expectedUpdated, repo.updated)
+			assert.Len(t, hooker.triggered, tc.expectedHooks)
+		})
+	}
+}
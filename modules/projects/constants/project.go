@@ -1 +1,4 @@
 package constants
+
+// PermissionReadProject is the permission a project needs to be listed, see authz.Authorizer.PartialEval
+const PermissionReadProject = "read:project"
@@ -102,10 +102,12 @@ func (h *ProjectHandler) GetByID(c echo.Context) wrapper.Response {
 
 // ListProjects godoc
 // @Summary      List projects
-// @Description  Get list of projects
+// @Description  Get list of projects the current user may read, through its role in their org or a resource grant.
+// @Description  The total only counts these projects
 // @Tags         projects
 // @Accept       json
 // @Produce      json
+// @Param        org_id  query     int  false  "Only the projects of the org"
 // @Param        limit   query     int  false  "Number of records should be returned"
 // @Param        page    query     int  false  "Page"
 // @Success      200  {object}  wrapper.SuccessResponse{data=[]domains.Project}
@@ -125,7 +127,8 @@ func (h *ProjectHandler) Fetch(c echo.Context) wrapper.Response {
 		}
 	}
 
-	projects, count, err := h.ProjectUC.Fetch(ctx, *req)
+	principal, _ := contexts.GetPrincipalFromContext(c)
+	projects, count, err := h.ProjectUC.Fetch(ctx, principal, *req)
 	if err != nil {
 		return wrapper.Response{
 			Error:  err,
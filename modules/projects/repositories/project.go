@@ -34,6 +34,11 @@ type (
 	}
 	ParamsForFetchProjects struct {
 		contexts.CommonParamsForFetch
+		OrgId int64
+		// RowFilter keeps the projects the caller may see, the count covers only them
+		RowFilter squirrel.Sqlizer
+		// ExcludeMfaRequiredOrgs drops the projects of the orgs with require_mfa, for callers without a verified second factor
+		ExcludeMfaRequiredOrgs bool
 	}
 )
 
@@ -105,6 +110,7 @@ func (r *pgsqlProjectRepository) Fetch(ctx context.Context, params ParamsForFetc
 
 	cols, _ := sqlTools.GetColumnsAndValuesFromStruct(ctx, &projectWithCount{})
 	query := psql.Select(sqlTools.ParseColumnsForSelect(cols)...).From(projectsTableName)
+	query = r.buildQueryFilters(query, params)
 	sqlQuery, args, err := sqlTools.BindCommonParamsToSelectBuilder(query, params.CommonParamsForFetch).
 		OrderBy("created_at DESC").ToSql()
 	if err != nil {
@@ -135,6 +141,19 @@ func (r *pgsqlProjectRepository) Fetch(ctx context.Context, params ParamsForFetc
 	return
 }
 
+func (r *pgsqlProjectRepository) buildQueryFilters(builder squirrel.SelectBuilder, params ParamsForFetchProjects) squirrel.SelectBuilder {
+	if params.OrgId > 0 {
+		builder = builder.Where(squirrel.Eq{"org_id": params.OrgId})
+	}
+	if params.RowFilter != nil {
+		builder = builder.Where(params.RowFilter)
+	}
+	if params.ExcludeMfaRequiredOrgs {
+		builder = builder.Where("org_id NOT IN (SELECT id FROM orgs WHERE require_mfa)")
+	}
+	return builder
+}
+
 func (r *pgsqlProjectRepository) Update(ctx context.Context, project *domains.Project, fieldsToUpdate []string) (err error) {
 	if len(fieldsToUpdate) == 0 {
 		fieldsToUpdate = make([]string, 0)
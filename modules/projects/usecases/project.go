@@ -2,10 +2,16 @@ package usecases
 
 import (
 	"context"
+	"time"
 
+	"github.com/dzungtran/echo-rest-api/config"
+	coreDomains "github.com/dzungtran/echo-rest-api/modules/core/domains"
+	coreRepositories "github.com/dzungtran/echo-rest-api/modules/core/repositories"
+	"github.com/dzungtran/echo-rest-api/modules/projects/constants"
 	"github.com/dzungtran/echo-rest-api/modules/projects/domains"
 	"github.com/dzungtran/echo-rest-api/modules/projects/dto"
 	"github.com/dzungtran/echo-rest-api/modules/projects/repositories"
+	"github.com/dzungtran/echo-rest-api/pkg/authz"
 	"github.com/dzungtran/echo-rest-api/pkg/contexts"
 	"github.com/dzungtran/echo-rest-api/pkg/cue"
 	"github.com/dzungtran/echo-rest-api/pkg/utils"
@@ -16,19 +22,25 @@ import (
 type ProjectUsecase interface {
 	Create(ctx context.Context, request dto.CreateProjectReq) (*domains.Project, error)
 	GetByID(ctx context.Context, id int64) (*domains.Project, error)
-	Fetch(ctx context.Context, req dto.SearchProjectsReq) ([]*domains.Project, int64, error)
+	Fetch(ctx context.Context, principal *coreDomains.Principal, req dto.SearchProjectsReq) ([]*domains.Project, int64, error)
 	Update(ctx context.Context, id int64, request dto.UpdateProjectReq) error
 	Delete(ctx context.Context, id int64) error
 }
 
 type projectUsecase struct {
+	appConf     *config.AppConfig
 	projectRepo repositories.ProjectRepository
+	grantRepo   coreRepositories.ResourceGrantRepository
+	authorizer  authz.Authorizer
 }
 
 // NewProjectUsecase will create new an projectUsecase object representation of ProjectUsecase interface
-func NewProjectUsecase(projectRepo repositories.ProjectRepository) ProjectUsecase {
+func NewProjectUsecase(appConf *config.AppConfig, projectRepo repositories.ProjectRepository, grantRepo coreRepositories.ResourceGrantRepository, authorizer authz.Authorizer) ProjectUsecase {
 	return &projectUsecase{
+		appConf:     appConf,
 		projectRepo: projectRepo,
+		grantRepo:   grantRepo,
+		authorizer:  authorizer,
 	}
 }
 
@@ -58,12 +70,37 @@ func (u *projectUsecase) GetByID(ctx context.Context, id int64) (project *domain
 	return
 }
 
-func (u *projectUsecase) Fetch(ctx context.Context, req dto.SearchProjectsReq) (projects []*domains.Project, count int64, err error) {
+// Fetch lists the projects the caller may read, through its roles in their orgs or a grant on the project.
+// The projects of orgs requiring a second factor are left out until the caller verifies one
+func (u *projectUsecase) Fetch(ctx context.Context, principal *coreDomains.Principal, req dto.SearchProjectsReq) (projects []*domains.Project, count int64, err error) {
+	grants, err := u.grantRepo.Fetch(ctx, coreRepositories.ParamsForFetchResourceGrants{
+		UserId:       principal.User.Id,
+		ResourceType: coreDomains.ResourceTypeProject,
+	})
+	if err != nil {
+		return
+	}
+
+	callOpts := []authz.CallOPAInputOption{
+		authz.WithInputResourcePermissions(authz.ResourceGrantPermissions(grants)),
+	}
+	if principal.Permissions != nil {
+		callOpts = append(callOpts, authz.WithInputTokenPermissions(principal.Permissions))
+	}
+
+	rowFilter, err := u.authorizer.PartialEval(ctx, principal.User, constants.PermissionReadProject, coreDomains.ResourceTypeProject, callOpts...)
+	if err != nil {
+		return
+	}
+
 	p := repositories.ParamsForFetchProjects{
 		CommonParamsForFetch: contexts.CommonParamsForFetch{
 			Page:  uint64(req.Page),
 			Limit: uint64(req.Limit),
 		},
+		OrgId:                  req.OrgId,
+		RowFilter:              rowFilter,
+		ExcludeMfaRequiredOrgs: !principal.IsMfaVerified(u.appConf.MfaStepUpTTL, time.Now()),
 	}
 
 	projects, count, err = u.projectRepo.Fetch(ctx, p)
@@ -0,0 +1,179 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// PublicRoutePrefix names the routes the policies never check, e.g. public:login
+const PublicRoutePrefix = "public:"
+
+var permissionNamePattern = regexp.MustCompile(`^[a-z_]+:[a-z_]+$`)
+
+// ACLDrift tells where the routes and the roles of the policies disagree
+type ACLDrift struct {
+	// UnnamedRoutes are named neither by a permission nor as public, they resolve to req_permission "not_found"
+	UnnamedRoutes []string `json:"unnamed_routes"`
+	// UngrantedPermissions are named by routes, no role, platform role nor no_need_role_check grants them
+	// and they are not listed in super_admin_only_permissions
+	UngrantedPermissions []string `json:"ungranted_permissions"`
+	// UnknownRolePermissions are granted by roles, to service accounts or to super admins only and named by no route,
+	// as chart.role: permission or list: permission, e.g. service_account_permissions: permission
+	UnknownRolePermissions []string `json:"unknown_role_permissions"`
+	// RemoteACLMismatches are the routes the endpoints_acl of the remote policy decision point names differently,
+	// as method path: permission, remote permission. Empty without POLICY_REMOTE_URL
+	RemoteACLMismatches []string `json:"remote_acl_mismatches"`
+}
+
+// Empty tells whether the routes and the roles agree
+func (d *ACLDrift) Empty() bool {
+	return len(d.UnnamedRoutes) == 0 && len(d.UngrantedPermissions) == 0 && len(d.UnknownRolePermissions) == 0 &&
+		len(d.RemoteACLMismatches) == 0
+}
+
+// BuildEndpointsACL maps the routes named by a permission by path and method. Public routes are left out,
+// the other ones are returned as unnamed
+func BuildEndpointsACL(routes []*echo.Route) (acl map[string]map[string]string, unnamed []string) {
+	acl = map[string]map[string]string{}
+	unnamed = make([]string, 0)
+	for _, r := range routes {
+		switch {
+		case r.Method == echo.RouteNotFound, strings.HasPrefix(r.Name, PublicRoutePrefix):
+			continue
+		case !permissionNamePattern.MatchString(r.Name):
+			unnamed = append(unnamed, r.Method+" "+r.Path)
+			continue
+		}
+
+		if acl[r.Path] == nil {
+			acl[r.Path] = map[string]string{}
+		}
+		acl[r.Path][r.Method] = r.Name
+	}
+
+	sort.Strings(unnamed)
+	return acl, unnamed
+}
+
+// SetEndpointsACL compiles the active policies again with the routes as endpoints_acl, the ones of routes.json
+// are replaced
+func (a *OPAAuthorizer) SetEndpointsACL(ctx context.Context, routes []*echo.Route) (*ACLDrift, error) {
+	acl, unnamed := BuildEndpointsACL(routes)
+
+	endpointsAcl := make(map[string]interface{}, len(acl))
+	for path, methods := range acl {
+		perms := make(map[string]interface{}, len(methods))
+		for method, perm := range methods {
+			perms[method] = perm
+		}
+		endpointsAcl[path] = perms
+	}
+
+	a.compileMu.Lock()
+	defer a.compileMu.Unlock()
+
+	engine, err := compilePolicies(a.engine().files, endpointsAcl)
+	if err != nil {
+		return nil, err
+	}
+	a.endpointsAcl = endpointsAcl
+	a.current.Store(engine)
+
+	drift, err := engine.aclDrift(ctx)
+	if err != nil {
+		return nil, err
+	}
+	drift.UnnamedRoutes = unnamed
+	return drift, nil
+}
+
+// aclDrift compares endpoints_acl with roles_chart, platform_roles_chart and service_account_permissions of the engine.
+// The permissions of super_admin_only_permissions are granted by no role on purpose, super admins are allowed every route
+func (engine *policyEngine) aclDrift(ctx context.Context) (*ACLDrift, error) {
+	drift := &ACLDrift{
+		UnnamedRoutes:          make([]string, 0),
+		UngrantedPermissions:   make([]string, 0),
+		UnknownRolePermissions: make([]string, 0),
+		RemoteACLMismatches:    make([]string, 0),
+	}
+
+	granted := map[string]struct{}{}
+	for _, chartName := range []string{"roles_chart", "platform_roles_chart"} {
+		chart, _ := engine.data[chartName].(map[string]interface{})
+		for role, def := range chart {
+			access, _ := def.(map[string]interface{})["access"].([]interface{})
+			for _, v := range access {
+				perm := fmt.Sprint(v)
+				granted[perm] = struct{}{}
+				if _, ok := engine.permissions[perm]; !ok {
+					drift.UnknownRolePermissions = append(drift.UnknownRolePermissions,
+						fmt.Sprintf("%s.%s: %s", chartName, role, perm))
+				}
+			}
+		}
+	}
+
+	for _, listName := range []string{"service_account_permissions", "super_admin_only_permissions"} {
+		list, _ := engine.data[listName].([]interface{})
+		for _, v := range list {
+			perm := fmt.Sprint(v)
+			if listName == "super_admin_only_permissions" {
+				granted[perm] = struct{}{}
+			}
+			if _, ok := engine.permissions[perm]; !ok {
+				drift.UnknownRolePermissions = append(drift.UnknownRolePermissions, listName+": "+perm)
+			}
+		}
+	}
+
+	// a permission no role grants can still be open to anyone by no_need_role_check
+	ungranted := map[string]struct{}{}
+	for endpoint, methods := range engine.endpointsAcl {
+		perms, _ := methods.(map[string]interface{})
+		for method, v := range perms {
+			perm := fmt.Sprint(v)
+			if _, ok := granted[perm]; ok {
+				continue
+			}
+
+			open, err := engine.allowsGuest(ctx, method, endpoint)
+			if err != nil {
+				return nil, err
+			}
+			if open {
+				granted[perm] = struct{}{}
+				delete(ungranted, perm)
+				continue
+			}
+			ungranted[perm] = struct{}{}
+		}
+	}
+	for perm := range ungranted {
+		drift.UngrantedPermissions = append(drift.UngrantedPermissions, perm)
+	}
+
+	sort.Strings(drift.UngrantedPermissions)
+	sort.Strings(drift.UnknownRolePermissions)
+	return drift, nil
+}
+
+// allowsGuest evaluates the route for a user without any role, the decision is not recorded
+func (engine *policyEngine) allowsGuest(ctx context.Context, method, endpoint string) (bool, error) {
+	rs, err := engine.query.Eval(ctx, rego.EvalInput(map[string]interface{}{
+		"user":     map[string]interface{}{"org_role": map[string]interface{}{}},
+		"method":   method,
+		"endpoint": endpoint,
+	}))
+	if err != nil || len(rs) == 0 {
+		return false, err
+	}
+
+	allowed, _ := rs[0].Bindings["allow"].(bool)
+	return allowed, nil
+}
@@ -0,0 +1,86 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildEndpointsACL(t *testing.T) {
+	acl, unnamed := BuildEndpointsACL([]*echo.Route{
+		{Method: "GET", Path: "/admin/orgs/:orgId", Name: "read:org"},
+		{Method: "PUT", Path: "/admin/orgs/:orgId", Name: "update:org"},
+		{Method: "POST", Path: "/auth/login", Name: "public:login"},
+		{Method: echo.RouteNotFound, Path: "/admin/*", Name: "github.com/labstack/echo/v4.glob..func1"},
+		{Method: "GET", Path: "/admin/reports", Name: "github.com/x/handlers.(*ReportHandler).List-fm"},
+	})
+
+	assert.Equal(t, map[string]map[string]string{
+		"/admin/orgs/:orgId": {"GET": "read:org", "PUT": "update:org"},
+	}, acl)
+	assert.Equal(t, []string{"GET /admin/reports"}, unnamed)
+}
+
+func TestSetEndpointsACL(t *testing.T) {
+	authorizer := NewOPAAuthorizer()
+
+	tests := []struct {
+		name      string
+		routes    []*echo.Route
+		ungranted []string
+	}{
+		{
+			name: "granted by a role or open",
+			routes: []*echo.Route{
+				{Method: "GET", Path: "/admin/orgs/:orgId", Name: "read:org"},
+				{Method: "GET", Path: "/me", Name: "read:me"},
+			},
+			ungranted: []string{},
+		},
+		{
+			name: "granted by no role",
+			routes: []*echo.Route{
+				{Method: "GET", Path: "/admin/orgs/:orgId", Name: "read:org"},
+				{Method: "GET", Path: "/admin/reports", Name: "list:report"},
+			},
+			ungranted: []string{"list:report"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drift, err := authorizer.SetEndpointsACL(context.Background(), tt.routes)
+			assert.Nil(t, err)
+			assert.Empty(t, drift.UnnamedRoutes)
+			assert.Equal(t, tt.ungranted, drift.UngrantedPermissions)
+			// the roles of data.json reference permissions of routes left out
+			assert.Contains(t, drift.UnknownRolePermissions, "roles_chart.owner: update:org")
+			assert.NotContains(t, drift.UnknownRolePermissions, "roles_chart.viewer: read:org")
+			assert.Contains(t, drift.UnknownRolePermissions, "service_account_permissions: update:org")
+			assert.NotContains(t, drift.UnknownRolePermissions, "service_account_permissions: read:org")
+			assert.False(t, drift.Empty())
+
+			assert.True(t, authorizer.IsKnownPermission(tt.routes[0].Name))
+			assert.False(t, authorizer.IsKnownPermission("update:org"))
+		})
+	}
+}
+
+func TestEmbeddedPoliciesHaveNoDrift(t *testing.T) {
+	acl := map[string]map[string]string{}
+	assert.Nil(t, json.Unmarshal(routesFile, &acl))
+
+	routes := make([]*echo.Route, 0)
+	for path, methods := range acl {
+		for method, perm := range methods {
+			routes = append(routes, &echo.Route{Method: method, Path: path, Name: perm})
+		}
+	}
+
+	drift, err := NewOPAAuthorizer().SetEndpointsACL(context.Background(), routes)
+	assert.Nil(t, err)
+	assert.True(t, drift.Empty(), "%+v", drift)
+}
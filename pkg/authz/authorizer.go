@@ -0,0 +1,254 @@
+package authz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/labstack/echo/v4"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/topdown"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// Authorizer decides on the requests and answers the queries on the policies, see NewOPAAuthorizer,
+// NewRemoteAuthorizer, AllowAll and DenyAll. It is provided by the container, never build one per request
+type Authorizer interface {
+	// Check returns ErrForbidden, with the deny messages of the policies, when the request is denied
+	Check(ctx context.Context, user *domains.UserWithRoles, callOpts ...CallOPAInputOption) ([]string, error)
+	// Explain decides like Check and tells why, the decision is not recorded
+	Explain(ctx context.Context, user *domains.UserWithRoles, withTrace bool, callOpts ...CallOPAInputOption) *Explanation
+	// PartialEval returns the SQL filter of the rows of the resource type the user may see with the permission
+	PartialEval(ctx context.Context, user *domains.UserWithRoles, permission string, resourceType domains.ResourceType, callOpts ...CallOPAInputOption) (*RowFilter, error)
+
+	// IsSuperAdmin tells whether the policies consider the user a super admin
+	IsSuperAdmin(ctx context.Context, user *domains.UserWithRoles) bool
+	// UserPermissions evaluates the role and the permissions of the user in the org of the options, like Check
+	// would for every route. Deny rules depend on the request and are not applied, Check stays authoritative
+	UserPermissions(ctx context.Context, user *domains.UserWithRoles, callOpts ...CallOPAInputOption) (*EffectivePermissions, error)
+	// RolePermissions returns the permissions of a role of the org, inherited ones included.
+	// customRoles are the roles of the org merged into roles_chart, an unknown role has no permission
+	RolePermissions(ctx context.Context, orgId int64, role string, customRoles []*domains.OrgCustomRole) ([]string, error)
+	// IsBuiltinRole tells whether the role is one of roles_chart, custom roles cannot take their names
+	IsBuiltinRole(ctx context.Context, role string) bool
+	// IsKnownPermission tells whether a route is named by the permission
+	IsKnownPermission(perm string) bool
+}
+
+// PolicyAdmin configures the policies of an Authorizer and reports on them, see NewOPAAuthorizer and
+// NewRemoteAuthorizer. It is only used at startup and by the policy endpoints, never to decide on a request
+type PolicyAdmin interface {
+	// SetEndpointsACL decides with the routes as endpoints_acl from now on and reports the drift between the
+	// routes and the roles of the policies
+	SetEndpointsACL(ctx context.Context, routes []*echo.Route) (*ACLDrift, error)
+	// SetOrgRoleStore makes the decisions merge the custom roles held by the user into roles_chart,
+	// they only know the built-in roles without it
+	SetOrgRoleStore(store OrgRoleStore)
+	// SetDecisionLogger makes Check record its decisions, nil stops recording
+	SetDecisionLogger(l *DecisionLogger)
+	// PolicyStatus tells which policies decide the requests
+	PolicyStatus() PolicyStatus
+}
+
+// OPAAuthorizer evaluates the rego policies in process
+type OPAAuthorizer struct {
+	// current is swapped as a whole on reload, a decision never mixes two revisions. Read it with engine
+	current atomic.Pointer[policyEngine]
+	// compileMu orders the swaps of current, by a reload or by SetEndpointsACL
+	compileMu sync.Mutex
+	// endpointsAcl the policies are compiled with, from routes.json until SetEndpointsACL. The routes are part of
+	// the code and never loaded from a bundle, guarded by compileMu
+	endpointsAcl map[string]interface{}
+	// orgRoleStore is set once at startup, see SetOrgRoleStore
+	orgRoleStore OrgRoleStore
+	// decisionLogger is nil until SetDecisionLogger, the decisions are not recorded meanwhile
+	decisionLogger atomic.Pointer[DecisionLogger]
+
+	statusMu sync.RWMutex
+	// lastPolicyError is kept until a reload succeeds
+	lastPolicyError   string
+	lastPolicyErrorAt *time.Time
+	// failedPolicyHash avoids compiling and reporting the same broken policies on every poll
+	failedPolicyHash string
+}
+
+// NewOPAAuthorizer decides with the embedded policies, with the routes of routes.json until SetEndpointsACL.
+// When they cannot be compiled, the fallback policies deny every request and the error is kept in the policy status
+func NewOPAAuthorizer() *OPAAuthorizer {
+	a := &OPAAuthorizer{}
+	if err := util.UnmarshalJSON(routesFile, &a.endpointsAcl); err != nil {
+		a.recordPolicyError("", err)
+	}
+
+	engine, err := compilePolicies(embeddedPolicies(), a.endpointsAcl)
+	if err != nil {
+		logger.Log().Errorw("cannot compile the embedded policies, every request is denied", "error", err)
+		a.recordPolicyError("", err)
+		if engine, err = compilePolicies(fallbackPolicies(), a.endpointsAcl); err != nil {
+			panic(err)
+		}
+	}
+	a.current.Store(engine)
+	return a
+}
+
+// NewOPAAuthorizerFromPath decides with the policies of a directory or an OPA bundle tarball instead of the
+// embedded ones, see ReloadPolicies
+func NewOPAAuthorizerFromPath(path string) (*OPAAuthorizer, error) {
+	a := NewOPAAuthorizer()
+	if err := a.ReloadPolicies(path); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// SetDecisionLogger makes Check record its decisions, nil stops recording
+func (a *OPAAuthorizer) SetDecisionLogger(l *DecisionLogger) {
+	a.decisionLogger.Store(l)
+}
+
+// engine returns the policies deciding, read on every call so reloads apply
+func (a *OPAAuthorizer) engine() *policyEngine {
+	return a.current.Load()
+}
+
+func (a *OPAAuthorizer) Check(ctx context.Context, user *domains.UserWithRoles, callOpts ...CallOPAInputOption) (denyMsg []string, err error) {
+	opts := appliedOPAInputOption(callOpts)
+	engine := a.engine()
+
+	var latency time.Duration
+	defer func() {
+		if opts.tracer == nil {
+			a.decisionLogger.Load().logDecision(user, opts, engine.endpointPermission(opts.RequestEndpoint, opts.RequestMethod), engine.revision,
+				latency, denyMsg, err)
+		}
+	}()
+
+	customRoles, err := a.loadCustomRoles(ctx, user)
+	if err != nil {
+		return
+	}
+	input := buildInput(user, opts, customRoles)
+
+	// Run evaluation.
+	evalOpts := []rego.EvalOption{rego.EvalInput(input)}
+	if opts.tracer != nil {
+		evalOpts = append(evalOpts, rego.EvalQueryTracer(opts.tracer))
+	}
+
+	start := time.Now()
+	rs, err := engine.query.Eval(ctx, evalOpts...)
+	latency = time.Since(start)
+	if err != nil {
+		logger.Log().Errorf("error while eval opa input, details %v", err.Error())
+		return
+	}
+
+	if len(rs) == 0 {
+		err = errors.New("empty decision result")
+		return
+	}
+
+	var result bool
+	result, ok := rs[0].Bindings["allow"].(bool)
+	if msg, _ := rs[0].Bindings["deny"].([]interface{}); len(msg) > 0 {
+		denyMsg = make([]string, 0)
+		for _, v := range msg {
+			denyMsg = append(denyMsg, fmt.Sprint(v))
+		}
+	}
+
+	if !ok {
+		logger.Log().Errorf("unexpected decision result, details %v", result)
+		err = errors.New("unexpected decision result")
+		return
+	}
+
+	if !result {
+		err = ErrForbidden
+		return
+	}
+
+	return
+}
+
+// Explain evaluates the request like Check with tracing enabled, the full trace is only returned with withTrace
+func (a *OPAAuthorizer) Explain(ctx context.Context, user *domains.UserWithRoles, withTrace bool, callOpts ...CallOPAInputOption) *Explanation {
+	tracer := topdown.NewBufferTracer()
+	callOpts = append(callOpts, CallOPAInputOption{
+		applyFunc: func(oio *opaInputOpts) {
+			oio.tracer = tracer
+		},
+	})
+
+	engine := a.engine()
+	denyMsg, err := a.Check(ctx, user, callOpts...)
+	opts := appliedOPAInputOption(callOpts)
+
+	e := newExplanation(user, opts, denyMsg, err)
+	e.Permission = engine.endpointPermission(opts.RequestEndpoint, opts.RequestMethod)
+	e.Rules = contributingRules(*tracer)
+	e.PolicyRevision = engine.revision
+
+	if withTrace {
+		var buf bytes.Buffer
+		topdown.PrettyTraceWithLocation(&buf, *tracer)
+		e.Trace = strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	}
+	return e
+}
+
+// PartialEval partially evaluates data.authz.allow_row for the permission, the rows are of the resource type.
+// The conditions on input.row become the SQL filter, input.row.org_id the org_id column of the table
+func (a *OPAAuthorizer) PartialEval(ctx context.Context, user *domains.UserWithRoles, permission string, resourceType domains.ResourceType, callOpts ...CallOPAInputOption) (*RowFilter, error) {
+	opts := appliedOPAInputOption(callOpts)
+
+	customRoles, err := a.loadCustomRoles(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	input := buildInput(user, opts, customRoles)
+	input["permission"] = permission
+	input["row_type"] = string(resourceType)
+
+	pq, err := a.engine().rowFilterQuery.Partial(ctx, rego.EvalInput(input))
+	if err != nil {
+		return nil, err
+	}
+	if len(pq.Support) > 0 {
+		return nil, fmt.Errorf("row filter of %s needs support rules, they cannot be turned into SQL", permission)
+	}
+	return rowFilterFromQueries(pq.Queries)
+}
+
+// CheckContext decides with the authorizer on the request of the echo context, described with its route,
+// the token scope of the principal and the request attributes
+func CheckContext(a Authorizer, c echo.Context, callOpts ...CallOPAInputOption) (denyMsg []string, err error) {
+	u, err := contexts.GetUserFromContext(c)
+	if err != nil {
+		return
+	}
+
+	if p, err := contexts.GetPrincipalFromContext(c); err == nil {
+		if p.Permissions != nil {
+			callOpts = append(callOpts, WithInputTokenPermissions(p.Permissions))
+		}
+	}
+
+	callOpts = append(callOpts,
+		WithInputRequestMethod(c.Request().Method),
+		WithInputRequestEndpoint(c.Path()),
+		WithRequestId(c.Response().Header().Get(echo.HeaderXRequestID)),
+		WithInputRequest(RequestAttributesFromContext(c)),
+	)
+
+	return a.Check(c.Request().Context(), u, callOpts...)
+}
@@ -0,0 +1,100 @@
+package authz
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/stretchr/testify/assert"
+)
+
+const maintenanceDenyPolicy = `package authz
+
+import data.deny
+
+default deny = []
+
+deny = deny.deny_user_endpoint | deny.deny_service_account | deny.deny_org_access | {"under maintenance"}
+`
+
+// testAuthorizer decides with the embedded policies, the tests changing the policies build their own
+var testAuthorizer = NewOPAAuthorizer()
+
+func TestStaticAuthorizers(t *testing.T) {
+	user := &domains.UserWithRoles{User: domains.User{Id: 1}, OrgRole: map[int64]string{3: "viewer"}}
+
+	tcs := []struct {
+		name          string
+		authorizer    Authorizer
+		expectedErr   error
+		expectedSql   string
+		expectedPerms bool
+	}{
+		{"should allow every request and row", AllowAll(), nil, "(1=1)", true},
+		{"should deny every request and row", DenyAll(), ErrForbidden, "(1=0)", false},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.authorizer.Check(context.Background(), user,
+				WithInputRequestMethod(getListUserEndpoint.Method),
+				WithInputRequestEndpoint(getListUserEndpoint.Endpoint),
+			)
+			assert.Equal(t, tc.expectedErr, err)
+
+			e := tc.authorizer.Explain(context.Background(), user, true, WithInputOrg(&domains.Org{Id: 3}))
+			assert.Equal(t, tc.expectedErr == nil, e.Allowed)
+			assert.Equal(t, int64(3), e.OrgId)
+			assert.Equal(t, StaticPolicyRevision, e.PolicyRevision)
+			assert.Empty(t, e.Error)
+
+			filter, err := tc.authorizer.PartialEval(context.Background(), user, "read:project", domains.ResourceTypeProject)
+			assert.Nil(t, err)
+			sql, _, err := filter.ToSql()
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedSql, sql)
+
+			assert.False(t, tc.authorizer.IsSuperAdmin(context.Background(), user))
+			assert.True(t, tc.authorizer.IsBuiltinRole(context.Background(), "viewer"))
+			assert.False(t, tc.authorizer.IsBuiltinRole(context.Background(), "auditor"))
+			assert.True(t, tc.authorizer.IsKnownPermission("read:project"))
+			assert.False(t, tc.authorizer.IsKnownPermission("fly:project"))
+
+			ep, err := tc.authorizer.UserPermissions(context.Background(), user, WithInputOrg(&domains.Org{Id: 3}))
+			assert.Nil(t, err)
+			assert.Equal(t, "viewer", ep.Role)
+			assert.Equal(t, tc.expectedPerms, slices.Contains(ep.Permissions, "update:org"))
+		})
+	}
+}
+
+func TestOPAAuthorizerFromPath(t *testing.T) {
+	support := &domains.UserWithRoles{
+		User:          domains.User{Id: 2},
+		Kind:          domains.PrincipalKindUser,
+		PlatformRoles: []domains.PlatformRole{domains.PlatformRoleSupport},
+	}
+	opts := []CallOPAInputOption{
+		WithInputRequestMethod(getListUserEndpoint.Method),
+		WithInputRequestEndpoint(getListUserEndpoint.Endpoint),
+	}
+
+	dir := writePolicyDir(t, map[string]string{"rego/deny.rego": maintenanceDenyPolicy})
+	maintenance, err := NewOPAAuthorizerFromPath(dir)
+	assert.Nil(t, err)
+
+	// both policy sets decide side by side, every authorizer keeps its own
+	denyMsg, err := maintenance.Check(context.Background(), support, opts...)
+	assert.Equal(t, ErrForbidden, err)
+	assert.Equal(t, []string{"under maintenance"}, denyMsg)
+	assert.Equal(t, "rev-1", maintenance.Explain(context.Background(), support, false, opts...).PolicyRevision)
+
+	_, err = testAuthorizer.Check(context.Background(), support, opts...)
+	assert.Nil(t, err)
+	assert.Equal(t, PolicySourceEmbedded, testAuthorizer.PolicyStatus().Revision)
+
+	_, err = NewOPAAuthorizerFromPath(writePolicyDir(t, map[string]string{"rego/broken.rego": "package authz\n\nallow {"}))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,460 @@
+package authz
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/tester"
+	"github.com/open-policy-agent/opa/util"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	PolicySourceEmbedded = "embedded"
+	// PolicySourceFallback allows nothing, it decides while the embedded policies cannot be compiled
+	PolicySourceFallback = "fallback"
+
+	policyDataFileName     = "data.json"
+	policyManifestFileName = ".manifest"
+	policyRevisionSize     = 12
+)
+
+type (
+	policyEngine struct {
+		query                rego.PreparedEvalQuery
+		superAdminQuery      rego.PreparedEvalQuery
+		rolePermissionsQuery rego.PreparedEvalQuery
+		permissionsQuery     rego.PreparedEvalQuery
+		// rowFilterQuery partially evaluates data.authz.allow_row with input.row unknown, see PartialEval
+		rowFilterQuery rego.PreparedPartialQuery
+		// builtinRoles are the roles of roles_chart, custom roles cannot take their names
+		builtinRoles map[string]struct{}
+		// endpointsAcl is data.endpoints_acl, permissions are the ones it names
+		endpointsAcl map[string]interface{}
+		permissions  map[string]struct{}
+		// data is the data document of the policies, endpoints_acl included
+		data map[string]interface{}
+		// files are kept to compile the policies again with other routes
+		files *policyFiles
+
+		revision string
+		source   string
+		hash     string
+		loadedAt time.Time
+	}
+
+	// policyFiles are the rego modules and the data of a policy source, keyed by path
+	policyFiles struct {
+		modules  map[string]string
+		data     []byte
+		revision string
+		source   string
+		hash     string
+	}
+
+	// PolicyStatus tells which policies decide the requests
+	PolicyStatus struct {
+		// Revision of the bundle manifest, a hash of the policies otherwise
+		Revision    string     `json:"revision" example:"2026-10-18.1"`
+		Source      string     `json:"source" example:"/etc/api/policies"`
+		LoadedAt    time.Time  `json:"loaded_at"`
+		LastError   string     `json:"last_error,omitempty"`
+		LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+	}
+)
+
+// PolicyStatus returns the active revision and the error of the last rejected reload
+func (a *OPAAuthorizer) PolicyStatus() PolicyStatus {
+	engine := a.engine()
+
+	a.statusMu.RLock()
+	defer a.statusMu.RUnlock()
+	return PolicyStatus{
+		Revision:    engine.revision,
+		Source:      engine.source,
+		LoadedAt:    engine.loadedAt,
+		LastError:   a.lastPolicyError,
+		LastErrorAt: a.lastPolicyErrorAt,
+	}
+}
+
+// WatchPolicies loads the policies of a directory or an OPA bundle tarball, then polls it every interval
+// until ctx is done. The embedded policies stay active while the path cannot be loaded
+func (a *OPAAuthorizer) WatchPolicies(ctx context.Context, path string, interval time.Duration) {
+	if err := a.ReloadPolicies(path); err != nil {
+		logger.Log().Errorw("cannot load policies, keep the embedded ones", "path", path, "error", err)
+	}
+
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := a.ReloadPolicies(path); err != nil {
+					logger.Log().Errorw("cannot reload policies, keep the active ones", "path", path,
+						"revision", a.engine().revision, "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// ReloadPolicies swaps the active policies for the ones of the path, only when they changed,
+// compile and pass the test_ rules they contain
+func (a *OPAAuthorizer) ReloadPolicies(path string) error {
+	files, err := loadPolicyFiles(path)
+	if err != nil {
+		return a.recordPolicyError("", err)
+	}
+
+	if files.hash == a.engine().hash {
+		return nil
+	}
+
+	a.statusMu.RLock()
+	failed := files.hash == a.failedPolicyHash
+	a.statusMu.RUnlock()
+	if failed {
+		return nil
+	}
+
+	a.compileMu.Lock()
+	defer a.compileMu.Unlock()
+
+	engine, err := compilePolicies(files, a.endpointsAcl)
+	if err != nil {
+		return a.recordPolicyError(files.hash, err)
+	}
+
+	a.current.Store(engine)
+
+	a.statusMu.Lock()
+	a.lastPolicyError, a.lastPolicyErrorAt, a.failedPolicyHash = "", nil, ""
+	a.statusMu.Unlock()
+
+	logger.Log().Infow("policies loaded", "source", engine.source, "revision", engine.revision)
+	return nil
+}
+
+func (a *OPAAuthorizer) recordPolicyError(hash string, err error) error {
+	now := time.Now().UTC()
+
+	a.statusMu.Lock()
+	defer a.statusMu.Unlock()
+	a.lastPolicyError, a.lastPolicyErrorAt, a.failedPolicyHash = err.Error(), &now, hash
+	return err
+}
+
+func embeddedPolicies() *policyFiles {
+	modules := map[string]string{}
+	readRegoFiles(regoFs, "rego", modules)
+	readRegoFiles(regoDenyFs, "rego/deny", modules)
+	readRegoFiles(regoUtilsFs, "rego/utils", modules)
+
+	return &policyFiles{
+		modules:  modules,
+		data:     dataFile,
+		revision: PolicySourceEmbedded,
+		source:   PolicySourceEmbedded,
+	}
+}
+
+// fallbackPolicies allow nothing, the decisions of the queries are undefined or false
+func fallbackPolicies() *policyFiles {
+	return &policyFiles{
+		modules:  map[string]string{"fallback.rego": "package authz\n\ndefault allow := false\n"},
+		data:     []byte("{}"),
+		revision: PolicySourceFallback,
+		source:   PolicySourceFallback,
+	}
+}
+
+func loadPolicyFiles(path string) (*policyFiles, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return loadPolicyDir(path)
+	}
+	return loadPolicyBundle(path)
+}
+
+// loadPolicyDir reads the .rego files of the directory and its sub directories,
+// with data.json and the revision of .manifest at its root
+func loadPolicyDir(dir string) (*policyFiles, error) {
+	files := &policyFiles{
+		modules: map[string]string{},
+		source:  dir,
+	}
+
+	var manifest []byte
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		switch {
+		case strings.HasSuffix(rel, ".rego"):
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			files.modules[rel] = string(content)
+		case rel == policyDataFileName:
+			files.data, err = os.ReadFile(path)
+		case rel == policyManifestFileName:
+			manifest, err = os.ReadFile(path)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(manifest) > 0 {
+		var m bundle.Manifest
+		if err = util.UnmarshalJSON(manifest, &m); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", policyManifestFileName, err)
+		}
+		files.revision = m.Revision
+	}
+
+	return files.withHash(), nil
+}
+
+// loadPolicyBundle reads an OPA bundle tarball, as built by `opa build`
+func loadPolicyBundle(path string) (*policyFiles, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b, err := bundle.NewReader(f).WithSkipBundleVerification(true).Read()
+	if err != nil {
+		return nil, err
+	}
+
+	files := &policyFiles{
+		modules:  map[string]string{},
+		revision: b.Manifest.Revision,
+		source:   path,
+	}
+	for _, m := range b.Modules {
+		files.modules[strings.TrimPrefix(m.Path, "/")] = string(m.Raw)
+	}
+
+	if len(b.Data) > 0 {
+		if files.data, err = json.Marshal(b.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	return files.withHash(), nil
+}
+
+// withHash fingerprints the policies, it is the revision when the source has none
+func (p *policyFiles) withHash() *policyFiles {
+	names := make([]string, 0, len(p.modules))
+	for name := range p.modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		h.Write([]byte(p.modules[name]))
+		h.Write([]byte{0})
+	}
+	h.Write(p.data)
+	h.Write([]byte(p.revision))
+
+	p.hash = hex.EncodeToString(h.Sum(nil))
+	if p.revision == "" {
+		p.revision = p.hash[:policyRevisionSize]
+	}
+	return p
+}
+
+// compilePolicies prepares the queries of the policies with the routes of endpointsAcl,
+// the embedded data.json is used when they have none
+func compilePolicies(files *policyFiles, endpointsAcl map[string]interface{}) (*policyEngine, error) {
+	if len(files.modules) == 0 {
+		return nil, errors.New("no rego module found")
+	}
+
+	compiler, err := ast.CompileModules(files.modules)
+	if err != nil {
+		return nil, err
+	}
+
+	data := files.data
+	if len(data) == 0 {
+		data = dataFile
+	}
+
+	data, err = sjson.SetBytes(data, "endpoints_acl", endpointsAcl)
+	if err != nil {
+		return nil, err
+	}
+
+	var jsonData map[string]interface{}
+	if err = util.UnmarshalJSON(data, &jsonData); err != nil {
+		return nil, err
+	}
+
+	// Manually create the storage layer. inmem.NewFromObject returns an
+	// in-memory store containing the supplied data.
+	store := inmem.NewFromObject(jsonData)
+
+	ctx := context.Background()
+	if err = runPolicyTests(ctx, files.modules, store); err != nil {
+		return nil, err
+	}
+
+	engine := &policyEngine{
+		builtinRoles: map[string]struct{}{},
+		endpointsAcl: endpointsAcl,
+		permissions:  map[string]struct{}{},
+		data:         jsonData,
+		files:        files,
+		revision:     files.revision,
+		source:       files.source,
+		hash:         files.hash,
+		loadedAt:     time.Now().UTC(),
+	}
+	if chart, ok := jsonData["roles_chart"].(map[string]interface{}); ok {
+		for role := range chart {
+			engine.builtinRoles[role] = struct{}{}
+		}
+	}
+	for _, methods := range endpointsAcl {
+		perms, _ := methods.(map[string]interface{})
+		for _, perm := range perms {
+			if p, ok := perm.(string); ok {
+				engine.permissions[p] = struct{}{}
+			}
+		}
+	}
+
+	// Create new query that returns the value
+	engine.query, err = rego.New(
+		rego.Query(`
+			allow = data.authz.allow
+			deny = data.authz.deny
+		`),
+		rego.Store(store),
+		rego.Compiler(compiler),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// evaluates utils.is_super_admin alone, for checks made outside of a route
+	engine.superAdminQuery, err = rego.New(
+		rego.Query(`is_super_admin = data.utils.is_super_admin`),
+		rego.Store(store),
+		rego.Compiler(compiler),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// evaluates the permissions of a role, inherited ones included, see RolePermissions
+	engine.rolePermissionsQuery, err = rego.New(
+		rego.Query(`permissions = data.authz.roles_chart_permissions[input.role]`),
+		rego.Store(store),
+		rego.Compiler(compiler),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// evaluates the role and the permissions of the user in the org of the input, see UserPermissions
+	engine.permissionsQuery, err = rego.New(
+		rego.Query(`
+			role = data.authz.usr_role
+			permissions = data.authz.effective_permissions
+		`),
+		rego.Store(store),
+		rego.Compiler(compiler),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	engine.rowFilterQuery, err = rego.New(
+		rego.Query(`data.authz.allow_row == true`),
+		rego.Unknowns([]string{"input.row"}),
+		rego.Store(store),
+		rego.Compiler(compiler),
+	).PrepareForPartial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return engine, nil
+}
+
+// runPolicyTests runs the test_ rules shipped with the policies, like `opa test` does
+func runPolicyTests(ctx context.Context, modules map[string]string, store storage.Store) error {
+	parsed := make(map[string]*ast.Module, len(modules))
+	for name, content := range modules {
+		m, err := ast.ParseModule(name, content)
+		if err != nil {
+			return err
+		}
+		parsed[name] = m
+	}
+
+	results, err := tester.NewRunner().SetStore(store).Run(ctx, parsed)
+	if err != nil {
+		return err
+	}
+
+	failed := make([]string, 0)
+	for r := range results {
+		if r.Fail || r.Error != nil {
+			failed = append(failed, r.Package+"."+r.Name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("policy tests failed: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
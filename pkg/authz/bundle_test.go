@@ -0,0 +1,140 @@
+package authz
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	passingPolicyTest = `package authz
+
+test_super_admin_is_allowed {
+	allow with input as {"user": {"platform_roles": ["super_admin"]}, "method": "GET", "endpoint": "/admin/users"}
+}
+`
+	failingPolicyTest = `package authz
+
+test_guest_is_allowed {
+	allow with input as {"user": {}, "method": "GET", "endpoint": "/admin/users"}
+}
+`
+)
+
+func writePolicyDir(t *testing.T, extra map[string]string) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		policyDataFileName:     string(dataFile),
+		policyManifestFileName: `{"revision": "rev-1"}`,
+	}
+	for name, content := range embeddedPolicies().modules {
+		files[name] = content
+	}
+	for name, content := range extra {
+		files[name] = content
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestReloadPoliciesFromDirectory(t *testing.T) {
+	authorizer := NewOPAAuthorizer()
+	superAdmin := &domains.UserWithRoles{
+		PlatformRoles: []domains.PlatformRole{domains.PlatformRoleSuperAdmin},
+	}
+
+	dir := writePolicyDir(t, map[string]string{"tests/authz_test.rego": passingPolicyTest})
+	assert.Nil(t, authorizer.ReloadPolicies(dir))
+	assert.Equal(t, "rev-1", authorizer.PolicyStatus().Revision)
+	assert.Equal(t, dir, authorizer.PolicyStatus().Source)
+
+	_, err := authorizer.Check(context.Background(), superAdmin,
+		WithInputRequestMethod(getListUserEndpoint.Method),
+		WithInputRequestEndpoint(getListUserEndpoint.Endpoint),
+	)
+	assert.Nil(t, err)
+
+	// the policies are only swapped when they change
+	loadedAt := authorizer.PolicyStatus().LoadedAt
+	assert.Nil(t, authorizer.ReloadPolicies(dir))
+	assert.Equal(t, loadedAt, authorizer.PolicyStatus().LoadedAt)
+}
+
+func TestReloadPoliciesKeepsActiveOnError(t *testing.T) {
+	authorizer := NewOPAAuthorizer()
+
+	tcs := []struct {
+		name          string
+		extra         map[string]string
+		expectedError string
+	}{
+		{"should reject policies failing their tests", map[string]string{"tests/authz_test.rego": failingPolicyTest}, "test_guest_is_allowed"},
+		{"should reject policies not compiling", map[string]string{"broken.rego": "package authz\n\nallow {"}, "rego_parse_error"},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			before := authorizer.PolicyStatus()
+
+			err := authorizer.ReloadPolicies(writePolicyDir(t, tc.extra))
+			assert.ErrorContains(t, err, tc.expectedError)
+
+			after := authorizer.PolicyStatus()
+			assert.Equal(t, before.Revision, after.Revision)
+			assert.Equal(t, before.Source, after.Source)
+			assert.Equal(t, err.Error(), after.LastError)
+			assert.NotNil(t, after.LastErrorAt)
+		})
+	}
+}
+
+func TestReloadPoliciesFromBundle(t *testing.T) {
+	authorizer := NewOPAAuthorizer()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	files := map[string]string{
+		"/" + policyDataFileName:     string(dataFile),
+		"/" + policyManifestFileName: `{"revision": "bundle-7"}`,
+	}
+	for name, content := range embeddedPolicies().modules {
+		files["/"+name] = content
+	}
+	for name, content := range files {
+		assert.Nil(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, tw.Close())
+	assert.Nil(t, gw.Close())
+
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	assert.Nil(t, os.WriteFile(path, buf.Bytes(), 0o644))
+
+	assert.Nil(t, authorizer.ReloadPolicies(path))
+	assert.Equal(t, "bundle-7", authorizer.PolicyStatus().Revision)
+	assert.True(t, authorizer.IsSuperAdmin(context.Background(), &domains.UserWithRoles{
+		PlatformRoles: []domains.PlatformRole{domains.PlatformRoleSuperAdmin},
+	}))
+}
+
+func TestReloadPoliciesMissingPath(t *testing.T) {
+	authorizer := NewOPAAuthorizer()
+
+	assert.NotNil(t, authorizer.ReloadPolicies(filepath.Join(t.TempDir(), "missing")))
+	assert.Equal(t, PolicySourceEmbedded, authorizer.PolicyStatus().Source)
+}
@@ -0,0 +1,319 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+)
+
+const (
+	DecisionSinkLog  = "log"
+	DecisionSinkDB   = "db"
+	DecisionSinkFile = "file"
+
+	decisionRedacted         = "[redacted]"
+	decisionQueueSize        = 1024
+	defaultDecisionRecentMax = 1000
+)
+
+// decisionRedactableFields are the json names of the AuthzDecision fields RedactFields accepts
+var decisionRedactableFields = map[string]struct{}{
+	"request_id":    {},
+	"user_id":       {},
+	"org_id":        {},
+	"endpoint":      {},
+	"method":        {},
+	"permission":    {},
+	"deny_messages": {},
+	"error":         {},
+}
+
+type (
+	// DecisionSink writes the decision records, it is only called by DecisionLogger.Run
+	DecisionSink interface {
+		Write(ctx context.Context, decision *domains.AuthzDecision) error
+	}
+
+	// DecisionSinkFunc adapts a function to a DecisionSink
+	DecisionSinkFunc func(ctx context.Context, decision *domains.AuthzDecision) error
+
+	DecisionLoggerOptions struct {
+		// SampleRate is the share of allowed decisions recorded, from 0 to 1. Denied ones are always recorded
+		SampleRate float64
+		// RedactFields are the json names of the fields blanked before a decision is recorded
+		RedactFields []string
+		// RecentSize is the number of decisions kept in memory for RecentDecisions
+		RecentSize int
+	}
+
+	// DecisionFilter selects RecentDecisions, zero values match everything
+	DecisionFilter struct {
+		UserId    int64
+		OrgId     int64
+		Allowed   *bool
+		RequestId string
+		Since     *time.Time
+		Limit     int
+	}
+
+	// DecisionLogger records the decisions of Authorizer.Check without blocking it,
+	// records are dropped when the sink cannot keep up
+	DecisionLogger struct {
+		sink    DecisionSink
+		opts    DecisionLoggerOptions
+		redact  map[string]struct{}
+		queue   chan *domains.AuthzDecision
+		dropped atomic.Int64
+
+		recentMu sync.RWMutex
+		recent   []*domains.AuthzDecision
+		next     int
+	}
+)
+
+func (f DecisionSinkFunc) Write(ctx context.Context, decision *domains.AuthzDecision) error {
+	return f(ctx, decision)
+}
+
+// NewDecisionLogger returns a logger writing to sink, call Run to start writing
+func NewDecisionLogger(sink DecisionSink, opts DecisionLoggerOptions) (*DecisionLogger, error) {
+	if opts.SampleRate < 0 || opts.SampleRate > 1 {
+		return nil, fmt.Errorf("decision log sample rate must be between 0 and 1, got %v", opts.SampleRate)
+	}
+	if opts.RecentSize <= 0 {
+		opts.RecentSize = defaultDecisionRecentMax
+	}
+
+	redact := map[string]struct{}{}
+	for _, field := range opts.RedactFields {
+		if _, ok := decisionRedactableFields[field]; !ok {
+			return nil, fmt.Errorf("unknown decision log field to redact: %s", field)
+		}
+		redact[field] = struct{}{}
+	}
+
+	return &DecisionLogger{
+		sink:   sink,
+		opts:   opts,
+		redact: redact,
+		queue:  make(chan *domains.AuthzDecision, decisionQueueSize),
+		recent: make([]*domains.AuthzDecision, 0, opts.RecentSize),
+	}, nil
+}
+
+// Run writes the recorded decisions to the sink until ctx is done
+func (l *DecisionLogger) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-l.queue:
+			if dropped := l.dropped.Swap(0); dropped > 0 {
+				logger.Log().Warnw("decision log queue full, decisions dropped", "count", dropped)
+			}
+
+			if err := l.sink.Write(ctx, d); err != nil {
+				logger.Log().Errorw("cannot write authz decision", "request_id", d.RequestId, "error", err)
+			}
+		}
+	}
+}
+
+// RecentDecisions returns the last decisions recorded in memory, newest first. A nil logger has none
+func (l *DecisionLogger) RecentDecisions(filter DecisionFilter) []*domains.AuthzDecision {
+	rs := make([]*domains.AuthzDecision, 0)
+	if l == nil {
+		return rs
+	}
+
+	l.recentMu.RLock()
+	defer l.recentMu.RUnlock()
+
+	size := len(l.recent)
+	for i := 1; i <= size; i++ {
+		d := l.recent[(l.next-i+size)%size]
+		if filter.matches(d) {
+			rs = append(rs, d)
+			if filter.Limit > 0 && len(rs) >= filter.Limit {
+				break
+			}
+		}
+	}
+	return rs
+}
+
+func (f DecisionFilter) matches(d *domains.AuthzDecision) bool {
+	if f.UserId > 0 && d.UserId != f.UserId {
+		return false
+	}
+	if f.OrgId > 0 && (d.OrgId == nil || *d.OrgId != f.OrgId) {
+		return false
+	}
+	if f.Allowed != nil && d.Allowed != *f.Allowed {
+		return false
+	}
+	if f.RequestId != "" && d.RequestId != f.RequestId {
+		return false
+	}
+	if f.Since != nil && d.CreatedAt.Before(*f.Since) {
+		return false
+	}
+	return true
+}
+
+// record samples, redacts and queues the decision
+func (l *DecisionLogger) record(d *domains.AuthzDecision) {
+	if d.Allowed && l.opts.SampleRate < 1 && rand.Float64() >= l.opts.SampleRate {
+		return
+	}
+
+	l.redactFields(d)
+
+	l.recentMu.Lock()
+	if len(l.recent) < l.opts.RecentSize {
+		l.recent = append(l.recent, d)
+	} else {
+		l.recent[l.next] = d
+	}
+	l.next = (l.next + 1) % l.opts.RecentSize
+	l.recentMu.Unlock()
+
+	select {
+	case l.queue <- d:
+	default:
+		l.dropped.Add(1)
+	}
+}
+
+func (l *DecisionLogger) redactFields(d *domains.AuthzDecision) {
+	for field := range l.redact {
+		switch field {
+		case "request_id":
+			d.RequestId = decisionRedacted
+		case "user_id":
+			d.UserId = 0
+		case "org_id":
+			d.OrgId = nil
+		case "endpoint":
+			d.Endpoint = decisionRedacted
+		case "method":
+			d.Method = decisionRedacted
+		case "permission":
+			d.Permission = decisionRedacted
+		case "deny_messages":
+			d.DenyMessages = []string{}
+		case "error":
+			if d.Error != "" {
+				d.Error = decisionRedacted
+			}
+		}
+	}
+}
+
+// logDecision records the outcome of Authorizer.Check, a nil logger records nothing
+func (l *DecisionLogger) logDecision(user *domains.UserWithRoles, opts *opaInputOpts, permission, revision string, latency time.Duration, denyMsg []string, err error) {
+	if l == nil {
+		return
+	}
+
+	d := &domains.AuthzDecision{
+		RequestId:      opts.RequestId,
+		Endpoint:       opts.RequestEndpoint,
+		Method:         opts.RequestMethod,
+		Permission:     permission,
+		Allowed:        err == nil,
+		DenyMessages:   denyMsg,
+		LatencyUs:      latency.Microseconds(),
+		PolicyRevision: revision,
+		CreatedAt:      time.Now().UTC(),
+	}
+	if d.DenyMessages == nil {
+		d.DenyMessages = []string{}
+	}
+	if err != nil && err != ErrForbidden {
+		d.Error = err.Error()
+	}
+	if user != nil {
+		d.UserId = user.Id
+	}
+	if orgId := decisionOrgId(opts); orgId > 0 {
+		d.OrgId = &orgId
+	}
+
+	l.record(d)
+}
+
+// endpointPermission returns the permission endpoints_acl names for the route
+func (engine *policyEngine) endpointPermission(endpoint, method string) string {
+	methods, _ := engine.endpointsAcl[endpoint].(map[string]interface{})
+	perm, _ := methods[method].(string)
+	return perm
+}
+
+// decisionOrgId resolves the org of the request like main.rego does, from the org, the project or the payload
+func decisionOrgId(opts *opaInputOpts) int64 {
+	if opts.Org != nil {
+		return opts.Org.Id
+	}
+
+	for _, key := range []string{"project", "payload"} {
+		v, ok := opts.ExtraData[key]
+		if !ok {
+			continue
+		}
+
+		b, err := json.Marshal(v)
+		if err != nil {
+			continue
+		}
+
+		var withOrg struct {
+			OrgId int64 `json:"org_id"`
+		}
+		if json.Unmarshal(b, &withOrg) == nil && withOrg.OrgId > 0 {
+			return withOrg.OrgId
+		}
+	}
+	return 0
+}
+
+// NewLogDecisionSink writes the decisions to the application log
+func NewLogDecisionSink() DecisionSink {
+	return DecisionSinkFunc(func(_ context.Context, d *domains.AuthzDecision) error {
+		logger.Log().Infow("authz decision",
+			"request_id", d.RequestId,
+			"user_id", d.UserId,
+			"org_id", d.OrgId,
+			"endpoint", d.Endpoint,
+			"method", d.Method,
+			"permission", d.Permission,
+			"allowed", d.Allowed,
+			"deny_messages", []string(d.DenyMessages),
+			"error", d.Error,
+			"latency_us", d.LatencyUs,
+			"policy_revision", d.PolicyRevision,
+		)
+		return nil
+	})
+}
+
+// NewFileDecisionSink appends the decisions to the file as JSON lines
+func NewFileDecisionSink(path string) (DecisionSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, err
+	}
+
+	enc := json.NewEncoder(f)
+	return DecisionSinkFunc(func(_ context.Context, d *domains.AuthzDecision) error {
+		return enc.Encode(d)
+	}), nil
+}
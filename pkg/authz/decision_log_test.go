@@ -0,0 +1,113 @@
+package authz
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRecordingAuthorizer returns an authorizer with the embedded policies recording its decisions to sink
+func newRecordingAuthorizer(t *testing.T, sink DecisionSink, opts DecisionLoggerOptions) (*OPAAuthorizer, *DecisionLogger) {
+	l, err := NewDecisionLogger(sink, opts)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go l.Run(ctx)
+	t.Cleanup(cancel)
+
+	a := NewOPAAuthorizer()
+	a.SetDecisionLogger(l)
+	return a, l
+}
+
+func TestDecisionLog(t *testing.T) {
+	written := make(chan *domains.AuthzDecision, 10)
+	a, l := newRecordingAuthorizer(t, DecisionSinkFunc(func(_ context.Context, d *domains.AuthzDecision) error {
+		written <- d
+		return nil
+	}), DecisionLoggerOptions{SampleRate: 1})
+
+	viewer := &domains.UserWithRoles{
+		User:    domains.User{Id: 5},
+		OrgRole: map[int64]string{9: "viewer"},
+	}
+
+	_, err := a.Check(context.Background(), viewer,
+		WithInputRequestMethod(updateOrgEndpoint.Method),
+		WithInputRequestEndpoint(updateOrgEndpoint.Endpoint),
+		WithInputOrg(&domains.Org{Id: 9}),
+		WithRequestId("req-1"),
+	)
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	select {
+	case d := <-written:
+		assert.Equal(t, "req-1", d.RequestId)
+		assert.Equal(t, int64(5), d.UserId)
+		assert.Equal(t, int64(9), *d.OrgId)
+		assert.Equal(t, "update:org", d.Permission)
+		assert.False(t, d.Allowed)
+		assert.Empty(t, d.Error)
+		assert.Equal(t, PolicySourceEmbedded, d.PolicyRevision)
+	case <-time.After(time.Second):
+		t.Fatal("the decision was not written to the sink")
+	}
+
+	recent := l.RecentDecisions(DecisionFilter{RequestId: "req-1"})
+	assert.Len(t, recent, 1)
+	assert.Empty(t, l.RecentDecisions(DecisionFilter{UserId: 6}))
+}
+
+func TestDecisionLogSamplingAndRedaction(t *testing.T) {
+	a, l := newRecordingAuthorizer(t, DecisionSinkFunc(func(context.Context, *domains.AuthzDecision) error {
+		return nil
+	}), DecisionLoggerOptions{SampleRate: 0, RedactFields: []string{"user_id", "endpoint"}})
+
+	viewer := &domains.UserWithRoles{
+		User:    domains.User{Id: 5},
+		OrgRole: map[int64]string{9: "viewer"},
+	}
+
+	tcs := []struct {
+		name     string
+		endpoint TestEndpoint
+		recorded bool
+	}{
+		{"should skip allowed decisions out of the sample", getOrgEndpoint, false},
+		{"should always record denied decisions", updateOrgEndpoint, true},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, _ = a.Check(context.Background(), viewer,
+				WithInputRequestMethod(tc.endpoint.Method),
+				WithInputRequestEndpoint(tc.endpoint.Endpoint),
+				WithInputOrg(&domains.Org{Id: 9}),
+				WithRequestId(tc.name),
+			)
+
+			recent := l.RecentDecisions(DecisionFilter{RequestId: tc.name})
+			if !tc.recorded {
+				assert.Empty(t, recent)
+				return
+			}
+
+			assert.Len(t, recent, 1)
+			assert.Zero(t, recent[0].UserId)
+			assert.Equal(t, decisionRedacted, recent[0].Endpoint)
+			assert.Equal(t, tc.endpoint.Method, recent[0].Method)
+		})
+	}
+}
+
+func TestNewDecisionLoggerRejectsInvalidOptions(t *testing.T) {
+	_, err := NewDecisionLogger(NewLogDecisionSink(), DecisionLoggerOptions{SampleRate: 2})
+	assert.NotNil(t, err)
+
+	_, err = NewDecisionLogger(NewLogDecisionSink(), DecisionLoggerOptions{SampleRate: 1, RedactFields: []string{"password"}})
+	assert.ErrorContains(t, err, "password")
+}
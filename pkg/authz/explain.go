@@ -0,0 +1,84 @@
+package authz
+
+import (
+	"fmt"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/topdown"
+)
+
+type (
+	// ExplainedRule is a rule whose body matched while deciding the request
+	ExplainedRule struct {
+		Rule     string `json:"rule" example:"data.authz.allow"`
+		Location string `json:"location" example:"rego/main.rego:77"`
+	}
+
+	// Explanation tells why the policies allow or deny a request
+	Explanation struct {
+		Allowed      bool     `json:"allowed"`
+		DenyMessages []string `json:"deny_messages"`
+		// Error is set when the policies could not be evaluated
+		Error string `json:"error,omitempty"`
+		// Permission named by endpoints_acl for the endpoint and method
+		Permission string `json:"permission" example:"update:org"`
+		OrgId      int64  `json:"org_id,omitempty" example:"1"`
+		// OrgRole is the role of the user in the org, guest when not a member
+		OrgRole        string          `json:"org_role" example:"viewer"`
+		Rules          []ExplainedRule `json:"rules"`
+		Trace          []string        `json:"trace,omitempty"`
+		PolicyRevision string          `json:"policy_revision"`
+	}
+)
+
+// newExplanation fills the decision, the org and the role of the user in it
+func newExplanation(user *domains.UserWithRoles, opts *opaInputOpts, denyMsg []string, err error) *Explanation {
+	e := &Explanation{
+		Allowed:      err == nil,
+		DenyMessages: denyMsg,
+		OrgId:        decisionOrgId(opts),
+		OrgRole:      string(domains.UserRoleGuest),
+		Rules:        []ExplainedRule{},
+	}
+	if e.DenyMessages == nil {
+		e.DenyMessages = []string{}
+	}
+	if err != nil && err != ErrForbidden {
+		e.Error = err.Error()
+	}
+	if user != nil {
+		if role, ok := user.OrgRole[e.OrgId]; ok {
+			e.OrgRole = role
+		}
+	}
+	return e
+}
+
+// contributingRules returns the rules whose body the trace exits, in evaluation order
+func contributingRules(trace []*topdown.Event) []ExplainedRule {
+	rules := make([]ExplainedRule, 0)
+	seen := map[ExplainedRule]struct{}{}
+	for _, evt := range trace {
+		if evt.Op != topdown.ExitOp {
+			continue
+		}
+
+		// default rules only tell the other bodies did not match
+		rule, ok := evt.Node.(*ast.Rule)
+		if !ok || rule.Default {
+			continue
+		}
+
+		r := ExplainedRule{Rule: rule.Path().String()}
+		if rule.Location != nil {
+			r.Location = fmt.Sprintf("%s:%d", rule.Location.File, rule.Location.Row)
+		}
+		if _, ok := seen[r]; ok {
+			continue
+		}
+		seen[r] = struct{}{}
+		rules = append(rules, r)
+	}
+	return rules
+}
@@ -0,0 +1,73 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	viewer := &domains.UserWithRoles{
+		User:    domains.User{Id: 5},
+		OrgRole: map[int64]string{9: "viewer"},
+	}
+
+	tcs := []struct {
+		name       string
+		endpoint   TestEndpoint
+		org        *domains.Org
+		allowed    bool
+		permission string
+		orgRole    string
+	}{
+		{"should explain an allowed request", getOrgEndpoint, &domains.Org{Id: 9}, true, "read:org", "viewer"},
+		{"should explain a denied request", updateOrgEndpoint, &domains.Org{Id: 9}, false, "update:org", "viewer"},
+		{"should resolve guest out of the user orgs", updateOrgEndpoint, &domains.Org{Id: 10}, false, "update:org", "guest"},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			e := testAuthorizer.Explain(context.Background(), viewer, false,
+				WithInputRequestMethod(tc.endpoint.Method),
+				WithInputRequestEndpoint(tc.endpoint.Endpoint),
+				WithInputOrg(tc.org),
+			)
+			assert.Equal(t, tc.allowed, e.Allowed)
+			assert.Equal(t, tc.permission, e.Permission)
+			assert.Equal(t, tc.orgRole, e.OrgRole)
+			assert.Equal(t, tc.org.Id, e.OrgId)
+			assert.Empty(t, e.Error)
+			assert.Empty(t, e.Trace)
+
+			rules := make([]string, 0, len(e.Rules))
+			for _, r := range e.Rules {
+				rules = append(rules, r.Rule)
+			}
+			if tc.allowed {
+				assert.Contains(t, rules, "data.authz.allow")
+				assert.Empty(t, e.DenyMessages)
+			} else {
+				assert.NotContains(t, rules, "data.authz.allow")
+			}
+		})
+	}
+}
+
+func TestExplainIsNotRecorded(t *testing.T) {
+	a, l := newRecordingAuthorizer(t, DecisionSinkFunc(func(context.Context, *domains.AuthzDecision) error {
+		return nil
+	}), DecisionLoggerOptions{SampleRate: 1})
+
+	e := a.Explain(context.Background(), &domains.UserWithRoles{OrgRole: map[int64]string{}}, true,
+		WithInputRequestMethod(updateOrgEndpoint.Method),
+		WithInputRequestEndpoint(updateOrgEndpoint.Endpoint),
+		WithInputOrg(&domains.Org{Id: 9}),
+		WithRequestId("explain"),
+	)
+	assert.False(t, e.Allowed)
+	assert.NotEmpty(t, e.Trace)
+	assert.Empty(t, l.RecentDecisions(DecisionFilter{RequestId: "explain"}))
+}
@@ -0,0 +1,66 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	startImpersonationEndpoint    = TestEndpoint{"POST", "/admin/users/:userId/impersonation"}
+	stopImpersonationEndpoint     = TestEndpoint{"DELETE", "/admin/impersonation"}
+	listImpersonationLogsEndpoint = TestEndpoint{"GET", "/admin/impersonation/logs"}
+)
+
+func TestPoliciesForImpersonationEndpoint(t *testing.T) {
+	superAdmin := &domains.UserWithRoles{
+		User:          domains.User{Id: 1},
+		Kind:          domains.PrincipalKindUser,
+		PlatformRoles: []domains.PlatformRole{domains.PlatformRoleSuperAdmin},
+	}
+
+	orgAdmin := &domains.UserWithRoles{
+		User: domains.User{Id: 2, Email: "admin@org.com"},
+		Kind: domains.PrincipalKindUser,
+		OrgRole: map[int64]string{
+			3: "owner",
+		},
+	}
+
+	tcs := []struct {
+		name         string
+		loggedInUser *domains.UserWithRoles
+		hasError     bool
+		endpoint     TestEndpoint
+	}{
+		{"should allow super admin to impersonate", superAdmin, false, startImpersonationEndpoint},
+		{"should allow super admin to stop impersonating", superAdmin, false, stopImpersonationEndpoint},
+		{"should allow super admin to read audit logs", superAdmin, false, listImpersonationLogsEndpoint},
+		{"should deny org admin to impersonate", orgAdmin, true, startImpersonationEndpoint},
+		{"should deny org admin to read audit logs", orgAdmin, true, listImpersonationLogsEndpoint},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := testAuthorizer.Check(context.Background(), tc.loggedInUser,
+				WithInputRequestMethod(tc.endpoint.Method),
+				WithInputRequestEndpoint(tc.endpoint.Endpoint),
+			)
+			if tc.hasError {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestIsSuperAdmin(t *testing.T) {
+	assert.True(t, testAuthorizer.IsSuperAdmin(context.Background(), &domains.UserWithRoles{PlatformRoles: []domains.PlatformRole{domains.PlatformRoleSuperAdmin}}))
+	assert.False(t, testAuthorizer.IsSuperAdmin(context.Background(), &domains.UserWithRoles{PlatformRoles: []domains.PlatformRole{domains.PlatformRoleSupport}}))
+	assert.False(t, testAuthorizer.IsSuperAdmin(context.Background(), &domains.UserWithRoles{User: domains.User{Email: "your_admin@email.com"}}))
+	assert.False(t, testAuthorizer.IsSuperAdmin(context.Background(), nil))
+}
@@ -0,0 +1,150 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+)
+
+// matrixOrgId is the org of the synthetic users the matrix is evaluated for
+const matrixOrgId = 1
+
+type (
+	// PermissionMatrix tells which roles of roles_chart are allowed on every route of endpoints_acl
+	PermissionMatrix struct {
+		Roles  []string      `json:"roles"`
+		Routes []MatrixRoute `json:"routes"`
+	}
+
+	MatrixRoute struct {
+		Method     string `json:"method"`
+		Endpoint   string `json:"endpoint"`
+		Permission string `json:"permission"`
+		// Allowed by role, without payload, so the deny rules depending on the request may not apply
+		Allowed map[string]bool `json:"allowed"`
+	}
+)
+
+// BuildPermissionMatrix evaluates Check for a member of each built-in role on every route
+func (a *OPAAuthorizer) BuildPermissionMatrix(ctx context.Context) (*PermissionMatrix, error) {
+	engine := a.engine()
+	m := &PermissionMatrix{
+		Roles:  make([]string, 0),
+		Routes: make([]MatrixRoute, 0),
+	}
+	for role := range engine.builtinRoles {
+		m.Roles = append(m.Roles, role)
+	}
+	sort.Strings(m.Roles)
+
+	for endpoint, methods := range engine.endpointsAcl {
+		perms, _ := methods.(map[string]interface{})
+		for method := range perms {
+			route := MatrixRoute{
+				Method:     method,
+				Endpoint:   endpoint,
+				Permission: engine.endpointPermission(endpoint, method),
+				Allowed:    map[string]bool{},
+			}
+
+			for _, role := range m.Roles {
+				user := &domains.UserWithRoles{
+					User:    domains.User{Id: 1},
+					OrgRole: map[int64]string{matrixOrgId: role},
+				}
+				_, err := a.Check(ctx, user,
+					WithInputRequestMethod(method),
+					WithInputRequestEndpoint(endpoint),
+					WithInputOrg(&domains.Org{Id: matrixOrgId}),
+				)
+				if err != nil && err != ErrForbidden {
+					return nil, fmt.Errorf("%s %s as %s: %w", method, endpoint, role, err)
+				}
+				route.Allowed[role] = err == nil
+			}
+			m.Routes = append(m.Routes, route)
+		}
+	}
+
+	sort.Slice(m.Routes, func(i, j int) bool {
+		if m.Routes[i].Endpoint != m.Routes[j].Endpoint {
+			return m.Routes[i].Endpoint < m.Routes[j].Endpoint
+		}
+		return m.Routes[i].Method < m.Routes[j].Method
+	})
+	return m, nil
+}
+
+// Diff returns the changes from the baseline, one line per route and role, empty when the matrices match
+func (m *PermissionMatrix) Diff(baseline *PermissionMatrix) []string {
+	changes := make([]string, 0)
+
+	baseRoutes := map[string]MatrixRoute{}
+	for _, r := range baseline.Routes {
+		baseRoutes[r.Method+" "+r.Endpoint] = r
+	}
+
+	for _, r := range m.Routes {
+		key := r.Method + " " + r.Endpoint
+		base, ok := baseRoutes[key]
+		delete(baseRoutes, key)
+		if !ok {
+			changes = append(changes, fmt.Sprintf("%s: new route, allowed to %v", key, allowedRoles(r)))
+			continue
+		}
+
+		if r.Permission != base.Permission {
+			changes = append(changes, fmt.Sprintf("%s: permission %s -> %s", key, base.Permission, r.Permission))
+		}
+		for _, role := range unionRoles(m.Roles, baseline.Roles) {
+			before, after := decisionName(base.Allowed, role), decisionName(r.Allowed, role)
+			if before != after {
+				changes = append(changes, fmt.Sprintf("%s: %s %s -> %s", key, role, before, after))
+			}
+		}
+	}
+
+	for key, r := range baseRoutes {
+		changes = append(changes, fmt.Sprintf("%s: removed route, was allowed to %v", key, allowedRoles(r)))
+	}
+
+	sort.Strings(changes)
+	return changes
+}
+
+func allowedRoles(r MatrixRoute) []string {
+	roles := make([]string, 0)
+	for role, allowed := range r.Allowed {
+		if allowed {
+			roles = append(roles, role)
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+func unionRoles(a, b []string) []string {
+	seen := map[string]struct{}{}
+	roles := make([]string, 0, len(a))
+	for _, role := range append(append([]string{}, a...), b...) {
+		if _, ok := seen[role]; !ok {
+			seen[role] = struct{}{}
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// decisionName reads a cell of the matrix, a role missing from one side is unknown
+func decisionName(allowed map[string]bool, role string) string {
+	v, ok := allowed[role]
+	switch {
+	case !ok:
+		return "unknown"
+	case v:
+		return "allow"
+	}
+	return "deny"
+}
@@ -0,0 +1,57 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildPermissionMatrix(t *testing.T) {
+	m, err := testAuthorizer.BuildPermissionMatrix(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"editor", "guest", "manager", "owner", "viewer"}, m.Roles)
+
+	routes := map[string]MatrixRoute{}
+	for _, r := range m.Routes {
+		routes[r.Method+" "+r.Endpoint] = r
+	}
+
+	updateOrg := routes[updateOrgEndpoint.Method+" "+updateOrgEndpoint.Endpoint]
+	assert.Equal(t, "update:org", updateOrg.Permission)
+	assert.True(t, updateOrg.Allowed["owner"])
+	assert.False(t, updateOrg.Allowed["manager"])
+	assert.False(t, updateOrg.Allowed["viewer"])
+
+	getOrg := routes[getOrgEndpoint.Method+" "+getOrgEndpoint.Endpoint]
+	assert.True(t, getOrg.Allowed["viewer"])
+	assert.False(t, getOrg.Allowed["guest"])
+}
+
+func TestPermissionMatrixDiff(t *testing.T) {
+	baseline := &PermissionMatrix{
+		Roles: []string{"owner", "viewer"},
+		Routes: []MatrixRoute{
+			{Method: "GET", Endpoint: "/a", Permission: "read:a", Allowed: map[string]bool{"owner": true, "viewer": true}},
+			{Method: "PUT", Endpoint: "/a", Permission: "update:a", Allowed: map[string]bool{"owner": true, "viewer": false}},
+			{Method: "DELETE", Endpoint: "/a", Permission: "delete:a", Allowed: map[string]bool{"owner": true, "viewer": false}},
+		},
+	}
+
+	assert.Empty(t, baseline.Diff(baseline))
+
+	changed := &PermissionMatrix{
+		Roles: []string{"owner", "viewer"},
+		Routes: []MatrixRoute{
+			{Method: "GET", Endpoint: "/a", Permission: "read:a", Allowed: map[string]bool{"owner": true, "viewer": true}},
+			{Method: "PUT", Endpoint: "/a", Permission: "update:a", Allowed: map[string]bool{"owner": true, "viewer": true}},
+			{Method: "POST", Endpoint: "/a", Permission: "create:a", Allowed: map[string]bool{"owner": true, "viewer": false}},
+		},
+	}
+
+	assert.Equal(t, []string{
+		"DELETE /a: removed route, was allowed to [owner]",
+		"POST /a: new route, allowed to [owner]",
+		"PUT /a: viewer deny -> allow",
+	}, changed.Diff(baseline))
+}
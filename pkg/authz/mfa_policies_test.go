@@ -0,0 +1,56 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	readMfaEndpoint   = TestEndpoint{"GET", "/me/mfa"}
+	enrollMfaEndpoint = TestEndpoint{"POST", "/me/mfa"}
+	verifyMfaEndpoint = TestEndpoint{"POST", "/me/mfa/verify"}
+)
+
+func TestPoliciesForMfaEndpoint(t *testing.T) {
+	user := &domains.UserWithRoles{
+		User: domains.User{Id: 8},
+		Kind: domains.PrincipalKindUser,
+	}
+
+	serviceAccount := domains.ServiceAccount{
+		OrgId:    9,
+		ClientId: "sa_manager",
+		Role:     domains.UserRoleManager,
+		Status:   domains.ServiceAccountStatusActive,
+	}.ToUserWithRoles()
+
+	tcs := []struct {
+		name         string
+		loggedInUser *domains.UserWithRoles
+		hasError     bool
+		endpoint     TestEndpoint
+	}{
+		{"should allow user without org to read own mfa status", user, false, readMfaEndpoint},
+		{"should allow user to enroll mfa", user, false, enrollMfaEndpoint},
+		{"should allow user to step up", user, false, verifyMfaEndpoint},
+		{"should deny service account to enroll mfa", serviceAccount, true, enrollMfaEndpoint},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := testAuthorizer.Check(context.Background(), tc.loggedInUser,
+				WithInputRequestMethod(tc.endpoint.Method),
+				WithInputRequestEndpoint(tc.endpoint.Endpoint),
+			)
+			if tc.hasError {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
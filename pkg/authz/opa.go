@@ -4,18 +4,12 @@ import (
 	"context"
 	"embed"
 	"errors"
-	"fmt"
 	"strings"
 
 	"github.com/dzungtran/echo-rest-api/modules/core/domains"
-	"github.com/dzungtran/echo-rest-api/pkg/contexts"
 	"github.com/dzungtran/echo-rest-api/pkg/logger"
-	"github.com/labstack/echo/v4"
-	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/rego"
-	"github.com/open-policy-agent/opa/storage/inmem"
-	"github.com/open-policy-agent/opa/util"
-	"github.com/tidwall/sjson"
+	"github.com/open-policy-agent/opa/topdown"
 )
 
 var (
@@ -31,84 +25,37 @@ var (
 	//go:embed rego/utils/*.rego
 	regoUtilsFs embed.FS
 
-	regoInstance  *rego.Rego
-	preparedQuery rego.PreparedEvalQuery
+	// ErrForbidden is returned by Authorizer.Check when the policies deny the request
+	ErrForbidden = errors.New("forbidden")
 )
 
 type opaInputOpts struct {
 	ResourcePermissions map[string][]string
-	ExtraData           map[string]interface{}
-	RequestEndpoint     string
-	RequestMethod       string
+	// ResourceId is the requested resource, e.g. project:42, see WithInputResource
+	ResourceId       string
+	ExtraData        map[string]interface{}
+	RequestEndpoint  string
+	RequestMethod    string
+	TokenPermissions []string
+	// RequestId is recorded with the decision, it is not part of the input
+	RequestId string
+	// tracer is set by Explain, traced evaluations are not recorded
+	tracer topdown.QueryTracer
 
 	Org *domains.Org
+	// Request is input.request, see WithInputRequest
+	Request *RequestAttributes
 }
 
 type CallOPAInputOption struct {
 	applyFunc func(*opaInputOpts)
 }
 
-func init() {
-
-	regoContents := map[string]string{}
-	readRegoFiles(regoFs, "rego", regoContents)
-	readRegoFiles(regoDenyFs, "rego/deny", regoContents)
-	readRegoFiles(regoUtilsFs, "rego/utils", regoContents)
-
-	// Compile the module. The keys are used as identifiers in error messages.
-	compiler, err := ast.CompileModules(regoContents)
-	if err != nil {
-		logger.Log().Fatalf("error while init rego compiler, details: %s", err.Error())
-	}
-
-	var jsonData map[string]interface{}
-	var routesData map[string]interface{}
-	err = util.UnmarshalJSON(routesFile, &routesData)
-	if err != nil {
-		logger.Log().Fatalf("error while init rego instance, details: %s", err.Error())
-	}
-
-	dataFile, err = sjson.SetBytes(dataFile, "endpoints_acl", routesData)
-	if err != nil {
-		logger.Log().Fatalf("error while build data file, details: %s", err.Error())
-	}
-
-	err = util.UnmarshalJSON(dataFile, &jsonData)
-	if err != nil {
-		logger.Log().Fatalf("error while init rego instance, details: %s", err.Error())
-	}
-
-	// Manually create the storage layer. inmem.NewFromObject returns an
-	// in-memory store containing the supplied data.
-	store := inmem.NewFromObject(jsonData)
-
-	/*
-		allow = data.authz.allow
-		deny = data.authz.deny
-		req = data.authz.usr_role
-	*/
-
-	// Create new query that returns the value
-	regoInstance = rego.New(
-		rego.Query(`
-			allow = data.authz.allow
-			deny = data.authz.deny
-		`),
-		rego.Store(store),
-		rego.Compiler(compiler),
-	)
-
-	ctx := context.Background()
-	preparedQuery, err = regoInstance.PrepareForEval(ctx)
-	if err != nil {
-		logger.Log().Fatalf("error while prepared eval opa, details: %v", err.Error())
-	}
-}
-
 func readRegoFiles(dfs embed.FS, folderName string, filesContent map[string]string) {
 	dirs, err := dfs.ReadDir(folderName)
 	if err != nil {
-		logger.Log().Fatalf("error while init rego compiler, details: %s", err.Error())
+		logger.Log().Errorf("error while read rego files, details: %s", err.Error())
+		return
 	}
 
 	for _, d := range dirs {
@@ -156,6 +103,15 @@ func WithInputResourcePermissions(resPerms map[string][]string) CallOPAInputOpti
 	}
 }
 
+// WithInputResource sets the requested resource, the permissions granted on it allow the request
+func WithInputResource(resourceType domains.ResourceType, id int64) CallOPAInputOption {
+	return CallOPAInputOption{
+		applyFunc: func(oio *opaInputOpts) {
+			oio.ResourceId = ResourceKey(resourceType, id)
+		},
+	}
+}
+
 func WithInputRequestMethod(reqMethod string) CallOPAInputOption {
 	return CallOPAInputOption{
 		applyFunc: func(oio *opaInputOpts) {
@@ -197,9 +153,35 @@ func WithInputOrg(org *domains.Org) CallOPAInputOption {
 	}
 }
 
-func CheckPolicies(user *domains.UserWithRoles, callOpts ...CallOPAInputOption) (denyMsg []string, err error) {
-	ctx := context.Background()
-	opts := appliedOPAInputOption(callOpts)
+// WithInputTokenPermissions restricts the decision to the permissions granted to the token of the request
+func WithInputTokenPermissions(perms []string) CallOPAInputOption {
+	return CallOPAInputOption{
+		applyFunc: func(oio *opaInputOpts) {
+			if perms == nil {
+				perms = []string{}
+			}
+			oio.TokenPermissions = perms
+		},
+	}
+}
+
+// WithRequestId records the decision with the id of the request
+func WithRequestId(requestId string) CallOPAInputOption {
+	return CallOPAInputOption{
+		applyFunc: func(oio *opaInputOpts) {
+			oio.RequestId = requestId
+		},
+	}
+}
+
+// IsKnownPermission tells whether a route is named by the permission
+func (a *OPAAuthorizer) IsKnownPermission(perm string) bool {
+	_, ok := a.engine().permissions[perm]
+	return ok
+}
+
+// buildInput returns the input document of the policies for the user, the options and the custom roles of the user
+func buildInput(user *domains.UserWithRoles, opts *opaInputOpts, customRoles map[string]map[string]interface{}) map[string]interface{} {
 	input := map[string]interface{}{
 		"user": user,
 	}
@@ -208,65 +190,62 @@ func CheckPolicies(user *domains.UserWithRoles, callOpts ...CallOPAInputOption)
 		input["org"] = opts.Org
 	}
 
-	if len(opts.ExtraData) > 0 {
-		for k, v := range opts.ExtraData {
-			input[k] = v
+	if opts.TokenPermissions != nil {
+		input["token"] = map[string]interface{}{
+			"permissions": opts.TokenPermissions,
 		}
 	}
 
-	// Apply options to input
-	if opts.RequestMethod != "" && opts.RequestEndpoint != "" {
-		input["method"] = opts.RequestMethod
-		input["endpoint"] = opts.RequestEndpoint
+	if opts.ResourcePermissions != nil {
+		input["resource_perms"] = opts.ResourcePermissions
 	}
 
-	// Run evaluation.
-	rs, err := preparedQuery.Eval(ctx, rego.EvalInput(input))
-	if err != nil {
-		logger.Log().Errorf("error while eval opa input, details %v", err.Error())
-		return
+	if opts.ResourceId != "" {
+		input["resource_id"] = opts.ResourceId
 	}
 
-	if len(rs) == 0 {
-		err = errors.New("empty decision result")
-		return
+	if opts.Request != nil {
+		input["request"] = requestInput(opts.Request, opts.Org)
 	}
 
-	var result bool
-	result, ok := rs[0].Bindings["allow"].(bool)
-	if msg, _ := rs[0].Bindings["deny"].([]interface{}); len(msg) > 0 {
-		denyMsg = make([]string, 0)
-		for _, v := range msg {
-			denyMsg = append(denyMsg, fmt.Sprint(v))
-		}
+	if len(customRoles) > 0 {
+		input["custom_roles"] = customRoles
 	}
 
-	if !ok {
-		logger.Log().Errorf("unexpected decision result, details %v", result)
-		err = errors.New("unexpected decision result")
-		return
+	if len(opts.ExtraData) > 0 {
+		for k, v := range opts.ExtraData {
+			input[k] = v
+		}
 	}
 
-	if !result {
-		err = errors.New("forbidden")
-		return
+	// Apply options to input
+	if opts.RequestMethod != "" && opts.RequestEndpoint != "" {
+		input["method"] = opts.RequestMethod
+		input["endpoint"] = opts.RequestEndpoint
 	}
-
-	return
+	return input
 }
 
-func CheckPoliciesContext(c echo.Context, callOpts ...CallOPAInputOption) (denyMsg []string, err error) {
-	u, err := contexts.GetUserFromContext(c)
+// IsSuperAdmin tells whether the policies consider the user a super admin
+func (a *OPAAuthorizer) IsSuperAdmin(ctx context.Context, user *domains.UserWithRoles) bool {
+	if user == nil {
+		return false
+	}
+
+	rs, err := a.engine().superAdminQuery.Eval(ctx, rego.EvalInput(map[string]interface{}{
+		"user": user,
+	}))
 	if err != nil {
-		return
+		logger.Log().Errorf("error while eval opa input, details %v", err.Error())
+		return false
 	}
 
-	callOpts = append(callOpts,
-		WithInputRequestMethod(c.Request().Method),
-		WithInputRequestEndpoint(c.Path()),
-	)
+	if len(rs) == 0 {
+		return false
+	}
 
-	return CheckPolicies(u, callOpts...)
+	result, _ := rs[0].Bindings["is_super_admin"].(bool)
+	return result
 }
 
 func appliedOPAInputOption(callOptions []CallOPAInputOption) *opaInputOpts {
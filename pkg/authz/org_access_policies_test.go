@@ -0,0 +1,123 @@
+package authz
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoliciesForOrgAccessSettings(t *testing.T) {
+	member := &domains.UserWithRoles{
+		User:    domains.User{Id: 8},
+		OrgRole: map[int64]string{10: "owner"},
+	}
+	superAdmin := &domains.UserWithRoles{
+		User:          domains.User{Id: 99},
+		PlatformRoles: []domains.PlatformRole{domains.PlatformRoleSuperAdmin},
+	}
+
+	office := &domains.Org{Id: 10, Timezone: "UTC", IpAllowlist: []string{"203.0.113.0/24"}}
+	// 08:00 to 18:00 in Ho Chi Minh City, UTC+7
+	workHours := &domains.Org{Id: 10, Timezone: "Asia/Ho_Chi_Minh", AccessHoursStart: "08:00", AccessHoursEnd: "18:00"}
+	nightShift := &domains.Org{Id: 10, Timezone: "UTC", AccessHoursStart: "22:00", AccessHoursEnd: "06:00"}
+
+	at := func(ip string, hour int) *RequestAttributes {
+		return &RequestAttributes{ClientIp: ip, Time: time.Date(2026, 10, 19, hour, 30, 0, 0, time.UTC)}
+	}
+
+	tcs := []struct {
+		name     string
+		user     *domains.UserWithRoles
+		org      *domains.Org
+		request  *RequestAttributes
+		hasError bool
+		denyMsg  []string
+	}{
+		{
+			"should allow a member from the allowlist",
+			member, office, at("203.0.113.7", 10), false, []string{},
+		},
+		{
+			"should deny a member outside of the allowlist",
+			member, office, at("198.51.100.7", 10), true,
+			[]string{"the org does not allow access from this IP address"},
+		},
+		{
+			"should allow a super admin outside of the allowlist",
+			superAdmin, office, at("198.51.100.7", 10), false, []string{},
+		},
+		{
+			"should not restrict a check made without request",
+			member, office, nil, false, []string{},
+		},
+		{
+			"should allow a member within the access hours of the org timezone",
+			member, workHours, at("198.51.100.7", 2), false, []string{},
+		},
+		{
+			"should deny a member outside of the access hours of the org timezone",
+			member, workHours, at("198.51.100.7", 12), true,
+			[]string{"the org does not allow access at this time"},
+		},
+		{
+			"should allow a member within overnight access hours",
+			member, nightShift, at("198.51.100.7", 23), false, []string{},
+		},
+		{
+			"should deny a member outside of overnight access hours",
+			member, nightShift, at("198.51.100.7", 12), true,
+			[]string{"the org does not allow access at this time"},
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			opts := []CallOPAInputOption{
+				WithInputRequestMethod(updateOrgEndpoint.Method),
+				WithInputRequestEndpoint(updateOrgEndpoint.Endpoint),
+				WithInputOrg(tc.org),
+			}
+			if tc.request != nil {
+				opts = append(opts, WithInputRequest(*tc.request))
+			}
+
+			msg, err := testAuthorizer.Check(context.Background(), tc.user, opts...)
+			if tc.hasError {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, len(tc.denyMsg), len(msg))
+			if len(tc.denyMsg) > 0 {
+				assert.Equal(t, tc.denyMsg, msg)
+			}
+		})
+	}
+}
+
+func TestRequestInput(t *testing.T) {
+	req := &RequestAttributes{
+		ClientIp:   "203.0.113.7",
+		UserAgent:  "curl/8.0",
+		AuthMethod: "local",
+		Time:       time.Date(2026, 10, 18, 23, 30, 0, 0, time.UTC),
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"client_ip":   "203.0.113.7",
+		"user_agent":  "curl/8.0",
+		"auth_method": "local",
+		"time":        "2026-10-18T23:30:00Z",
+		"org_time":    "2026-10-19T06:30:00+07:00",
+		"org_clock":   "06:30",
+		"org_weekday": "monday",
+	}, requestInput(req, &domains.Org{Timezone: "Asia/Ho_Chi_Minh"}))
+
+	rs := requestInput(req, &domains.Org{})
+	assert.Equal(t, "23:30", rs["org_clock"])
+	assert.NotContains(t, requestInput(req, nil), "org_clock")
+}
@@ -1,6 +1,7 @@
 package authz
 
 import (
+	"context"
 	"testing"
 
 	"github.com/dzungtran/echo-rest-api/modules/core/domains"
@@ -27,8 +28,9 @@ func TestPoliciesForOrgEndpoint(t *testing.T) {
 	}
 
 	superAdmin := &domains.UserWithRoles{
-		User:    domains.User{Id: 99, Email: "hello@iamdzung.com"},
-		OrgRole: nil,
+		User:          domains.User{Id: 99, Email: "hello@iamdzung.com"},
+		OrgRole:       nil,
+		PlatformRoles: []domains.PlatformRole{domains.PlatformRoleSuperAdmin},
 	}
 
 	tcs := []struct {
@@ -100,7 +102,7 @@ func TestPoliciesForOrgEndpoint(t *testing.T) {
 	for _, tc := range tcs {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
-			msg, err := CheckPolicies(tc.loggedInUser,
+			msg, err := testAuthorizer.Check(context.Background(), tc.loggedInUser,
 				WithInputRequestMethod(tc.endpoint.Method),
 				WithInputRequestEndpoint(tc.endpoint.Endpoint),
 				WithInputOrg(tc.requestedOrg),
@@ -0,0 +1,112 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// OrgRoleStore loads the custom roles of orgs, see Authorizer.SetOrgRoleStore
+type OrgRoleStore interface {
+	FetchByOrgIds(ctx context.Context, orgIds []int64) ([]*domains.OrgCustomRole, error)
+}
+
+// SetOrgRoleStore is called once at startup, before the first decision
+func (a *OPAAuthorizer) SetOrgRoleStore(store OrgRoleStore) {
+	a.orgRoleStore = store
+}
+
+// IsBuiltinRole tells whether the role is one of roles_chart of the active policies
+func (a *OPAAuthorizer) IsBuiltinRole(ctx context.Context, role string) bool {
+	_, ok := a.engine().builtinRoles[role]
+	return ok
+}
+
+// RolePermissions evaluates roles_chart_permissions of the active policies for the role
+func (a *OPAAuthorizer) RolePermissions(ctx context.Context, orgId int64, role string, customRoles []*domains.OrgCustomRole) ([]string, error) {
+	rs, err := a.engine().rolePermissionsQuery.Eval(ctx, rego.EvalInput(rolePermissionsInput(orgId, role, customRoles)))
+	if err != nil {
+		return nil, err
+	}
+
+	perms := make([]string, 0)
+	if len(rs) == 0 {
+		return perms, nil
+	}
+
+	values, _ := rs[0].Bindings["permissions"].([]interface{})
+	for _, v := range values {
+		perms = append(perms, fmt.Sprint(v))
+	}
+	sort.Strings(perms)
+	return perms, nil
+}
+
+// rolePermissionsInput is the input of roles_chart_permissions for a role of the org
+func rolePermissionsInput(orgId int64, role string, customRoles []*domains.OrgCustomRole) map[string]interface{} {
+	input := map[string]interface{}{
+		"org":  map[string]interface{}{"id": orgId},
+		"role": role,
+	}
+	if len(customRoles) > 0 {
+		input["custom_roles"] = customRolesChart(customRoles)
+	}
+	return input
+}
+
+// loadCustomRoles returns the input.custom_roles of the user, the custom roles of the orgs where it holds one
+func (a *OPAAuthorizer) loadCustomRoles(ctx context.Context, user *domains.UserWithRoles) (map[string]map[string]interface{}, error) {
+	return a.fetchCustomRoles(ctx, user, func(role string) bool {
+		return a.IsBuiltinRole(ctx, role)
+	})
+}
+
+// fetchCustomRoles loads the custom roles of the orgs where the user holds a role isBuiltin rejects
+func (a *OPAAuthorizer) fetchCustomRoles(ctx context.Context, user *domains.UserWithRoles, isBuiltin func(role string) bool) (map[string]map[string]interface{}, error) {
+	if a.orgRoleStore == nil || user == nil {
+		return nil, nil
+	}
+
+	orgIds := make([]int64, 0)
+	for orgId, role := range user.OrgRole {
+		if !isBuiltin(role) {
+			orgIds = append(orgIds, orgId)
+		}
+	}
+	if len(orgIds) == 0 {
+		return nil, nil
+	}
+
+	roles, err := a.orgRoleStore.FetchByOrgIds(ctx, orgIds)
+	if err != nil {
+		logger.Log().Errorf("error while load custom roles, details %v", err.Error())
+		return nil, err
+	}
+	return customRolesChart(roles), nil
+}
+
+// customRolesChart shapes the custom roles like roles_chart, per org id
+func customRolesChart(roles []*domains.OrgCustomRole) map[string]map[string]interface{} {
+	chart := map[string]map[string]interface{}{}
+	for _, r := range roles {
+		orgId := strconv.FormatInt(r.OrgId, 10)
+		if chart[orgId] == nil {
+			chart[orgId] = map[string]interface{}{}
+		}
+
+		access := []string(r.Permissions)
+		if access == nil {
+			access = []string{}
+		}
+		chart[orgId][r.Name] = map[string]interface{}{
+			"access": access,
+			"parent": r.Parent,
+		}
+	}
+	return chart
+}
@@ -0,0 +1,124 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	createOrgRoleEndpoint    = TestEndpoint{"POST", "/admin/orgs/:orgId/roles"}
+	updateMemberRoleEndpoint = TestEndpoint{"PUT", "/admin/orgs/:orgId/members/:userId"}
+)
+
+type fakeOrgRoleStore []*domains.OrgCustomRole
+
+func (s fakeOrgRoleStore) FetchByOrgIds(_ context.Context, orgIds []int64) ([]*domains.OrgCustomRole, error) {
+	rs := make([]*domains.OrgCustomRole, 0)
+	for _, r := range s {
+		for _, id := range orgIds {
+			if r.OrgId == id {
+				rs = append(rs, r)
+			}
+		}
+	}
+	return rs, nil
+}
+
+func TestPoliciesForCustomOrgRoles(t *testing.T) {
+	authorizer := NewOPAAuthorizer()
+	authorizer.SetOrgRoleStore(fakeOrgRoleStore{
+		{OrgId: 9, Name: "sa_admin", Permissions: []string{"create:service_account"}, Parent: "viewer"},
+		{OrgId: 9, Name: "sa_lead", Permissions: []string{}, Parent: "sa_admin"},
+		{OrgId: 10, Name: "sa_admin", Permissions: []string{}},
+	})
+
+	member := &domains.UserWithRoles{
+		User: domains.User{Id: 8},
+		OrgRole: map[int64]string{
+			9:  "sa_admin",
+			10: "sa_admin",
+		},
+	}
+	lead := &domains.UserWithRoles{
+		User:    domains.User{Id: 7},
+		OrgRole: map[int64]string{9: "sa_lead"},
+	}
+	owner := &domains.UserWithRoles{
+		User:    domains.User{Id: 6},
+		OrgRole: map[int64]string{9: "owner"},
+	}
+
+	tcs := []struct {
+		name         string
+		loggedInUser *domains.UserWithRoles
+		requestedOrg *domains.Org
+		hasError     bool
+		endpoint     TestEndpoint
+	}{
+		{"should allow the permissions of the custom role", member, &domains.Org{Id: 9}, false, createServiceAccountEndpoint},
+		{"should allow the permissions inherited from the parent", member, &domains.Org{Id: 9}, false, getOrgEndpoint},
+		{"should deny permissions the custom role does not grant", member, &domains.Org{Id: 9}, true, updateOrgEndpoint},
+		{"should use the custom roles of the requested org", member, &domains.Org{Id: 10}, true, createServiceAccountEndpoint},
+		{"should follow a chain of custom parents", lead, &domains.Org{Id: 9}, false, createServiceAccountEndpoint},
+		{"should allow owner to create custom roles", owner, &domains.Org{Id: 9}, false, createOrgRoleEndpoint},
+		{"should allow owner to change the role of members", owner, &domains.Org{Id: 9}, false, updateMemberRoleEndpoint},
+		{"should deny custom role to create custom roles", member, &domains.Org{Id: 9}, true, createOrgRoleEndpoint},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := authorizer.Check(context.Background(), tc.loggedInUser,
+				WithInputRequestMethod(tc.endpoint.Method),
+				WithInputRequestEndpoint(tc.endpoint.Endpoint),
+				WithInputOrg(tc.requestedOrg),
+			)
+			if tc.hasError {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestRolePermissions(t *testing.T) {
+	customRoles := []*domains.OrgCustomRole{
+		{OrgId: 9, Name: "sa_admin", Permissions: []string{"create:service_account"}, Parent: "viewer"},
+		{OrgId: 9, Name: "auditor", Permissions: []string{"list:service_account"}},
+	}
+
+	tcs := []struct {
+		name      string
+		role      string
+		contains  []string
+		excludes  []string
+		noneAtAll bool
+	}{
+		{"should merge the parent permissions", "sa_admin", []string{"create:service_account", "read:org"}, []string{"update:org"}, false},
+		{"should only grant its own permissions without parent", "auditor", []string{"list:service_account"}, []string{"read:org"}, false},
+		{"should inherit owned roles for built-in roles", "owner", []string{"update:org", "read:org", "create:org_role"}, nil, false},
+		{"should not grant anything to unknown roles", "missing", nil, nil, true},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			perms, err := testAuthorizer.RolePermissions(context.Background(), 9, tc.role, customRoles)
+			assert.Nil(t, err)
+			assert.Subset(t, perms, tc.contains)
+			for _, p := range tc.excludes {
+				assert.NotContains(t, perms, p)
+			}
+			if tc.noneAtAll {
+				assert.Empty(t, perms)
+			}
+		})
+	}
+
+	assert.True(t, testAuthorizer.IsBuiltinRole(context.Background(), "viewer"))
+	assert.False(t, testAuthorizer.IsBuiltinRole(context.Background(), "sa_admin"))
+}
@@ -0,0 +1,66 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// EffectivePermissions are the permissions a user holds in an org, for frontends to show what the user can do
+type EffectivePermissions struct {
+	OrgId int64 `json:"org_id,omitempty" example:"1"`
+	// Role of the user in the org, guest when not a member
+	Role        string   `json:"role" example:"viewer"`
+	Permissions []string `json:"permissions" example:"read:org,list:project"`
+	// ResourcePermissions are the resource ids granted per permission on top of the role
+	ResourcePermissions map[string][]string `json:"resource_permissions"`
+}
+
+// UserPermissions evaluates usr_role and effective_permissions of the active policies
+func (a *OPAAuthorizer) UserPermissions(ctx context.Context, user *domains.UserWithRoles, callOpts ...CallOPAInputOption) (*EffectivePermissions, error) {
+	opts := appliedOPAInputOption(callOpts)
+
+	customRoles, err := a.loadCustomRoles(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	rs, err := a.engine().permissionsQuery.Eval(ctx, rego.EvalInput(buildInput(user, opts, customRoles)))
+	if err != nil {
+		return nil, err
+	}
+	if len(rs) == 0 {
+		return nil, errors.New("empty permissions result")
+	}
+
+	role, _ := rs[0].Bindings["role"].(string)
+	values, _ := rs[0].Bindings["permissions"].([]interface{})
+	perms := make([]string, 0, len(values))
+	for _, v := range values {
+		perms = append(perms, fmt.Sprint(v))
+	}
+	return newEffectivePermissions(opts, role, perms), nil
+}
+
+// newEffectivePermissions sorts the permissions of the role, with the resource permissions of the options
+func newEffectivePermissions(opts *opaInputOpts, role string, perms []string) *EffectivePermissions {
+	ep := &EffectivePermissions{
+		OrgId:               decisionOrgId(opts),
+		Role:                role,
+		Permissions:         perms,
+		ResourcePermissions: opts.ResourcePermissions,
+	}
+	if ep.Permissions == nil {
+		ep.Permissions = make([]string, 0)
+	}
+	sort.Strings(ep.Permissions)
+
+	if ep.ResourcePermissions == nil {
+		ep.ResourcePermissions = map[string][]string{}
+	}
+	return ep
+}
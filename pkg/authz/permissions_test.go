@@ -0,0 +1,87 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	listPermissionEndpoint  = TestEndpoint{"GET", "/me/permissions"}
+	checkPermissionEndpoint = TestEndpoint{"POST", "/me/permissions/check"}
+)
+
+func TestUserPermissions(t *testing.T) {
+	member := &domains.UserWithRoles{
+		User: domains.User{Id: 8},
+		OrgRole: map[int64]string{
+			8:  "viewer",
+			10: "owner",
+		},
+	}
+	superAdmin := &domains.UserWithRoles{
+		User:          domains.User{Id: 1},
+		OrgRole:       map[int64]string{},
+		PlatformRoles: []domains.PlatformRole{domains.PlatformRoleSuperAdmin},
+	}
+
+	support := &domains.UserWithRoles{
+		User:          domains.User{Id: 2},
+		OrgRole:       map[int64]string{},
+		PlatformRoles: []domains.PlatformRole{domains.PlatformRoleSupport},
+	}
+
+	tcs := []struct {
+		name       string
+		user       *domains.UserWithRoles
+		org        *domains.Org
+		tokenPerms []string
+		role       string
+		contains   []string
+		excludes   []string
+	}{
+		{"should return the permissions of the role", member, &domains.Org{Id: 8}, nil, "viewer", []string{"read:org"}, []string{"update:org"}},
+		{"should include the permissions of owned roles", member, &domains.Org{Id: 10}, nil, "owner", []string{"read:org", "update:org"}, nil},
+		{"should return guest out of the user orgs", member, &domains.Org{Id: 11}, nil, "guest", nil, []string{"read:org"}},
+		{"should keep the permissions granted to the token", member, &domains.Org{Id: 10}, []string{"read:org"}, "owner", []string{"read:org"}, []string{"update:org"}},
+		{"should include the permissions of platform roles", support, &domains.Org{Id: 8}, nil, "guest", []string{"read:org", "list:user"}, []string{"update:org"}},
+		{"should return every route permission to super admin", superAdmin, &domains.Org{Id: 8}, nil, "guest", []string{"update:org", "list:authz_decision", "check:authz"}, nil},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			callOpts := []CallOPAInputOption{WithInputOrg(tc.org)}
+			if tc.tokenPerms != nil {
+				callOpts = append(callOpts, WithInputTokenPermissions(tc.tokenPerms))
+			}
+
+			ep, err := testAuthorizer.UserPermissions(context.Background(), tc.user, callOpts...)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.org.Id, ep.OrgId)
+			assert.Equal(t, tc.role, ep.Role)
+			assert.Subset(t, ep.Permissions, tc.contains)
+			for _, p := range tc.excludes {
+				assert.NotContains(t, ep.Permissions, p)
+			}
+			assert.NotNil(t, ep.ResourcePermissions)
+		})
+	}
+}
+
+func TestPoliciesForPermissionsEndpoints(t *testing.T) {
+	guest := &domains.UserWithRoles{
+		User:    domains.User{Id: 8},
+		OrgRole: map[int64]string{},
+	}
+
+	for _, endpoint := range []TestEndpoint{listPermissionEndpoint, checkPermissionEndpoint} {
+		_, err := testAuthorizer.Check(context.Background(), guest,
+			WithInputRequestMethod(endpoint.Method),
+			WithInputRequestEndpoint(endpoint.Endpoint),
+		)
+		assert.Nil(t, err, endpoint.Endpoint)
+	}
+}
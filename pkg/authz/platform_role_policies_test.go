@@ -0,0 +1,75 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	grantPlatformRoleEndpoint = TestEndpoint{"POST", "/admin/users/:userId/platform-roles"}
+	listPlatformRoleEndpoint  = TestEndpoint{"GET", "/admin/users/:userId/platform-roles"}
+)
+
+func TestPoliciesForPlatformRoles(t *testing.T) {
+	superAdmin := &domains.UserWithRoles{
+		User:          domains.User{Id: 1},
+		Kind:          domains.PrincipalKindUser,
+		PlatformRoles: []domains.PlatformRole{domains.PlatformRoleSuperAdmin},
+	}
+
+	support := &domains.UserWithRoles{
+		User:          domains.User{Id: 2},
+		Kind:          domains.PrincipalKindUser,
+		PlatformRoles: []domains.PlatformRole{domains.PlatformRoleSupport},
+	}
+
+	billingAdmin := &domains.UserWithRoles{
+		User:          domains.User{Id: 3},
+		Kind:          domains.PrincipalKindUser,
+		PlatformRoles: []domains.PlatformRole{domains.PlatformRoleBillingAdmin},
+	}
+
+	user := &domains.UserWithRoles{
+		User: domains.User{Id: 4},
+		Kind: domains.PrincipalKindUser,
+	}
+
+	tcs := []struct {
+		name          string
+		loggedInUser  *domains.UserWithRoles
+		requestedUser *domains.User
+		requestedOrg  *domains.Org
+		hasError      bool
+		endpoint      TestEndpoint
+	}{
+		{"should allow super admin to grant platform roles", superAdmin, nil, nil, false, grantPlatformRoleEndpoint},
+		{"should deny support to grant platform roles", support, nil, nil, true, grantPlatformRoleEndpoint},
+		{"should deny user to list platform roles", user, nil, nil, true, listPlatformRoleEndpoint},
+		{"should allow support to get list user", support, nil, nil, false, getListUserEndpoint},
+		{"should allow support to get info of other user", support, &domains.User{Id: 8}, nil, false, getUserEndpoint},
+		{"should allow support to read org of others", support, nil, &domains.Org{Id: 9}, false, getOrgEndpoint},
+		{"should deny support to update org of others", support, nil, &domains.Org{Id: 9}, true, updateOrgEndpoint},
+		{"should allow billing admin to read org of others", billingAdmin, nil, &domains.Org{Id: 9}, false, getOrgEndpoint},
+		{"should deny billing admin to get list user", billingAdmin, nil, nil, true, getListUserEndpoint},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := testAuthorizer.Check(context.Background(), tc.loggedInUser,
+				WithInputRequestMethod(tc.endpoint.Method),
+				WithInputRequestEndpoint(tc.endpoint.Endpoint),
+				WithInputExtraData("user_info", tc.requestedUser),
+				WithInputOrg(tc.requestedOrg),
+			)
+			if tc.hasError {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
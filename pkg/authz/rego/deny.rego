@@ -4,4 +4,4 @@ import data.deny
 
 default deny = []
 
-deny = deny.deny_user_endpoint
+deny = deny.deny_user_endpoint | deny.deny_service_account | deny.deny_org_access | deny.deny_token
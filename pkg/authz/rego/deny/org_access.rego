@@ -0,0 +1,48 @@
+package deny
+
+import future.keywords.if
+import future.keywords.in
+import data.utils
+
+# Orgs restrict their members to the IP allowlist and the access hours of their settings,
+# platform roles are not restricted. They apply when the request is described in input.request
+deny_org_access := deny_org_ip | deny_org_hours
+
+deny_org_ip[msg] {
+	input.request
+	count(input.org.ip_allowlist) > 0
+	not utils.is_platform_allowed
+	not org_ip_allowed
+	msg := "the org does not allow access from this IP address"
+}
+
+org_ip_allowed if {
+	some cidr in input.org.ip_allowlist
+	net.cidr_contains(cidr, input.request.client_ip)
+}
+
+deny_org_hours[msg] {
+	input.request
+	input.org.access_hours_start != ""
+	input.org.access_hours_end != ""
+	not utils.is_platform_allowed
+	not within_org_access_hours
+	msg := "the org does not allow access at this time"
+}
+
+within_org_access_hours if {
+	input.org.access_hours_start <= input.org.access_hours_end
+	input.org.access_hours_start <= input.request.org_clock
+	input.request.org_clock < input.org.access_hours_end
+}
+
+# Overnight hours, e.g. 22:00 to 06:00
+within_org_access_hours if {
+	input.org.access_hours_start > input.org.access_hours_end
+	input.request.org_clock >= input.org.access_hours_start
+}
+
+within_org_access_hours if {
+	input.org.access_hours_start > input.org.access_hours_end
+	input.request.org_clock < input.org.access_hours_end
+}
@@ -0,0 +1,20 @@
+package deny
+
+import future.keywords.if
+import future.keywords.in
+
+# Service accounts only get the permissions of their role listed in data.service_account_permissions,
+# a new permission is denied to them until it is added there
+is_service_account if {
+	input.user.kind == "service_account"
+}
+
+service_account_permitted if {
+	data.endpoints_acl[input.endpoint][input.method] in data.service_account_permissions
+}
+
+deny_service_account[msg] {
+	is_service_account
+	not service_account_permitted
+	msg := "service accounts are not allowed to perform this action"
+}
@@ -0,0 +1,12 @@
+package deny
+
+import future.keywords.if
+import future.keywords.in
+
+# A personal access token cannot create personal access tokens, the new token would not keep the scope of the
+# token which created it. They apply when the request is described in input.request
+deny_token[msg] {
+	input.request.auth_method == "pat"
+	data.endpoints_acl[input.endpoint][input.method] == "create:token"
+	msg := "personal access tokens are not allowed to create personal access tokens"
+}
@@ -11,13 +11,13 @@ deny_user_endpoint := deny_get_user |
 	deny_create_user
 
 is_get_user_info if {
-	not utils.is_super_admin
+	not utils.is_platform_allowed
 	input.method == "GET"
 	input.endpoint == "/admin/users/:userId"
 }
 
 is_update_user_info if {
-	not utils.is_super_admin
+	not utils.is_platform_allowed
 	input.method == "PUT"
 	input.endpoint == "/admin/users/:userId"
 }
@@ -31,7 +31,7 @@ deny_get_user[msg] {
 
 deny_get_user[msg] {
 	is_get_user_info
-	not utils.is_super_admin
+	not utils.is_platform_allowed
 	input.user_info.id <= 0
 	msg := "user id is invalid"
 }
@@ -80,20 +80,20 @@ deny_update_user[msg] {
 deny_delete_user[msg] {
 	input.method == "DELETE"
 	input.endpoint == "/admin/users/:userId"
-	not utils.is_super_admin
+	not utils.is_platform_allowed
 	msg := "you don't have the permission"
 }
 
 deny_get_list_user[msg] {
 	input.method == "GET"
 	input.endpoint == "/admin/users"
-	not utils.is_super_admin
+	not utils.is_platform_allowed
 	msg := "you don't have the permission"
 }
 
 deny_create_user[msg] {
 	input.method == "POST"
 	input.endpoint == "/admin/users"
-	not utils.is_super_admin
+	not utils.is_platform_allowed
 	msg := "you don't have the permission"
 }
@@ -1,5 +1,6 @@
 package authz
 
+import future.keywords.contains
 import future.keywords.if
 import future.keywords.in
 import data.utils
@@ -13,20 +14,34 @@ default org_id := ""
 
 default req_permission := "not_found"
 
-roles_chart_graph[role_name] := edges {
-	data.roles_chart[role_name]
-	edges := {neighbor |
-		data.roles_chart[neighbor].owner == role_name
-	}
-}
+# Built-in roles with the custom roles of the org, a custom role cannot shadow a built-in one
+roles_chart := org_roles_chart(org_id)
 
 roles_chart_permissions[role_name] := access {
-	data.roles_chart[role_name]
-	reachable := graph.reachable(roles_chart_graph, {role_name})
-	access := {item |
-		k in reachable
-		item := data.roles_chart[k].access[_]
+	roles_chart[role_name]
+	access := role_permissions(roles_chart, role_name)
+}
+
+# input.custom_roles is read alone, input.row stays the only unknown when allow_row is partially evaluated
+default input_custom_roles := {}
+
+input_custom_roles := input.custom_roles
+
+org_roles_chart(org) := object.union(object.get(input_custom_roles, org, {}), data.roles_chart)
+
+# A role inherits the access of the roles it owns and of its parent
+role_permissions(chart, role_name) := {item |
+	chart_graph := {name: edges |
+		chart[name]
+		owned := {neighbor | chart[neighbor].owner == name}
+		parents := {parent |
+			parent := chart[name].parent
+			parent != ""
+		}
+		edges := owned | parents
 	}
+	some k in graph.reachable(chart_graph, {role_name})
+	item := chart[k].access[_]
 }
 
 org_id := id {
@@ -47,20 +62,66 @@ req_permission := access {
 	access := data.endpoints_acl[input.endpoint][input.method]
 }
 
+# Requests made with a scoped token only keep the permissions granted to the token
+default token_permits := false
+
+token_permits if {
+	not input.token
+}
+
+token_permits if {
+	req_permission in input.token.permissions
+}
+
+# Permissions the user holds in the org of the input, deny rules are per request and not applied
+granted_permissions contains access if {
+	utils.is_super_admin
+	some methods in data.endpoints_acl
+	some access in methods
+}
+
+granted_permissions contains access if {
+	some access in utils.platform_permissions
+}
+
+granted_permissions contains access if {
+	some access in roles_chart_permissions[usr_role]
+}
+
+effective_permissions contains access if {
+	some access in granted_permissions
+	not input.token
+}
+
+effective_permissions contains access if {
+	some access in granted_permissions
+	access in input.token.permissions
+}
+
 # Alway allow Super Admin
 allow if {
 	utils.is_super_admin
+	token_permits
+}
+
+# Platform roles apply to every org
+allow if {
+	utils.has_platform_permission
+	token_permits
+	count(deny) == 0
 }
 
 # Start check ACL
 allow if {
 	req_permission in roles_chart_permissions[usr_role]
+	token_permits
 	count(deny) == 0
 }
 
 # Check additional permissions with resource from input
 allow if {
 	input.resource_id in input.resource_perms[req_permission]
+	token_permits
 	count(deny) == 0
 }
 
@@ -73,5 +134,6 @@ allow if {
 		x := no_need_role_check[_].method
 	}
 
+	token_permits
 	count(deny) == 0
 }
@@ -9,13 +9,13 @@ default no_need_role_check := []
 no_need_role_check := no_need_role_check_org_endpoint | no_need_role_check_user_endpoint
 
 is_get_user_info if {
-	not utils.is_super_admin
+	not utils.is_platform_allowed
 	input.method == "GET"
 	input.endpoint == "/admin/users/:userId"
 }
 
 is_update_user_info if {
-	not utils.is_super_admin
+	not utils.is_platform_allowed
 	input.method == "PUT"
 	input.endpoint == "/admin/users/:userId"
 }
@@ -46,6 +46,42 @@ no_need_role_check_user_endpoint[act] {
 	}
 }
 
+no_need_role_check_user_endpoint[act] {
+	# Manage personal access tokens of current user
+	input.endpoint in {"/me/tokens", "/me/tokens/:tokenId"}
+	act := {
+		"endpoint": input.endpoint,
+		"method": input.method,
+	}
+}
+
+no_need_role_check_user_endpoint[act] {
+	# Manage sessions of current user
+	input.endpoint in {"/me/sessions", "/me/sessions/:sessionId"}
+	act := {
+		"endpoint": input.endpoint,
+		"method": input.method,
+	}
+}
+
+no_need_role_check_user_endpoint[act] {
+	# Manage second factor of current user
+	input.endpoint in {"/me/mfa", "/me/mfa/confirm", "/me/mfa/verify", "/me/mfa/recovery-codes"}
+	act := {
+		"endpoint": input.endpoint,
+		"method": input.method,
+	}
+}
+
+no_need_role_check_user_endpoint[act] {
+	# Permissions of current user
+	input.endpoint in {"/me/permissions", "/me/permissions/check"}
+	act := {
+		"endpoint": input.endpoint,
+		"method": input.method,
+	}
+}
+
 no_need_role_check_org_endpoint[act] {
 	# Get list org
 	input.endpoint == "/admin/orgs"
@@ -0,0 +1,51 @@
+package authz
+
+import future.keywords.contains
+import future.keywords.if
+import future.keywords.in
+import data.utils
+
+# Rows of a list the user may see with input.permission, e.g. read:project.
+# input.row is unknown when partially evaluated, the conditions left on it become the SQL filter of the list.
+
+allow_row if {
+	utils.is_super_admin
+	row_token_permits
+}
+
+allow_row if {
+	input.permission in utils.platform_permissions
+	row_token_permits
+}
+
+allow_row if {
+	some org in row_orgs
+	input.row.org_id == org
+	row_token_permits
+}
+
+allow_row if {
+	some key in input.resource_perms[input.permission]
+	[kind, id] := split(key, ":")
+	kind == input.row_type
+	input.row.id == to_number(id)
+	row_token_permits
+}
+
+# Orgs where the role of the user grants the permission, the custom roles of each org included
+row_orgs contains to_number(org) if {
+	some org, role in input.user.org_role
+	chart := org_roles_chart(org)
+	chart[role]
+	input.permission in role_permissions(chart, role)
+}
+
+default row_token_permits := false
+
+row_token_permits if {
+	not input.token
+}
+
+row_token_permits if {
+	input.permission in input.token.permissions
+}
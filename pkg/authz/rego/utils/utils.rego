@@ -6,7 +6,24 @@ import future.keywords.in
 default is_super_admin := false
 
 is_super_admin if {
-	input.user.email in [
-		"your_admin@email.com",
-	]
+	"super_admin" in input.user.platform_roles
 }
+
+# Permissions granted by the platform roles of the user, in every org
+platform_permissions := {access |
+	some role in input.user.platform_roles
+	some access in data.platform_roles_chart[role].access
+}
+
+default has_platform_permission := false
+
+has_platform_permission if {
+	data.endpoints_acl[input.endpoint][input.method] in platform_permissions
+}
+
+# Super admins and platform roles granting the permission of the endpoint skip the per user checks
+default is_platform_allowed := false
+
+is_platform_allowed if is_super_admin
+
+is_platform_allowed if has_platform_permission
@@ -0,0 +1,565 @@
+package authz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/labstack/echo/v4"
+	"github.com/open-policy-agent/opa/ast"
+)
+
+const (
+	// PolicySourceRemote is the policy revision of the decisions of a remote policy decision point
+	PolicySourceRemote = "remote"
+
+	// RemoteFallbackClosed denies the requests while the remote policy decision point is unavailable
+	RemoteFallbackClosed = "closed"
+	// RemoteFallbackEmbedded decides with the active policies while the remote policy decision point is unavailable
+	RemoteFallbackEmbedded = "embedded"
+
+	remoteDecisionPath        = "/v1/data/authz"
+	remoteRolePermissionsPath = "/v1/data/authz/roles_chart_permissions"
+	remoteSuperAdminPath      = "/v1/data/utils/is_super_admin"
+	remoteRolesChartPath      = "/v1/data/roles_chart"
+	remoteEndpointsACLPath    = "/v1/data/endpoints_acl"
+	remoteCompilePath         = "/v1/compile"
+
+	defaultRemoteTimeout = 500 * time.Millisecond
+	defaultRemoteBackoff = 50 * time.Millisecond
+	// maxRemoteBackoff bounds the wait before a retry, whatever the attempt
+	maxRemoteBackoff    = 2 * time.Second
+	remoteErrorBodySize = 512
+)
+
+// ErrRemotePolicyUnavailable is returned when the remote policy decision point cannot be reached or answers an error
+var ErrRemotePolicyUnavailable = errors.New("remote policy decision point unavailable")
+
+// RemotePolicyUnavailableMessage is the error of the requests denied with ErrRemotePolicyUnavailable,
+// the details of the failure are only logged
+const RemotePolicyUnavailableMessage = "the authorization service is unavailable, retry later"
+
+type (
+	// RemoteAuthorizerOptions configure a RemoteAuthorizer, see POLICY_REMOTE_URL
+	RemoteAuthorizerOptions struct {
+		// Url is the base url of the OPA server, e.g. http://opa:8181
+		Url string
+		// Timeout of every attempt, 500ms when zero
+		Timeout time.Duration
+		// Retries are the attempts after the first one, on network errors and 5xx answers
+		Retries int
+		// Backoff is the longest wait before the first retry, doubled on every retry. The wait is drawn at random
+		// up to it, so the instances do not retry in step. 50ms when zero
+		Backoff time.Duration
+		// Fallback is closed or embedded, closed when empty
+		Fallback string
+		// Client sends the requests, http.DefaultClient when nil
+		Client *http.Client
+		// Embedded decides with RemoteFallbackEmbedded and answers the queries on the roles, NewOPAAuthorizer when nil
+		Embedded *OPAAuthorizer
+	}
+
+	// RemoteAuthorizer POSTs the input of the policies to an OPA compatible server instead of evaluating them in process.
+	// The server runs the same package authz, the decisions of the embedded and the remote policies read alike
+	RemoteAuthorizer struct {
+		url      string
+		timeout  time.Duration
+		retries  int
+		backoff  time.Duration
+		fallback string
+		client   *http.Client
+		// embedded decides with RemoteFallbackEmbedded and answers the queries on the roles and the permissions
+		*OPAAuthorizer
+	}
+
+	remoteDecisionResponse struct {
+		Result *struct {
+			Allow *bool    `json:"allow"`
+			Deny  []string `json:"deny"`
+			// UsrRole and EffectivePermissions are read by UserPermissions
+			UsrRole              string   `json:"usr_role"`
+			EffectivePermissions []string `json:"effective_permissions"`
+		} `json:"result"`
+	}
+
+	// remoteDataResponse is the answer of the data API, Result is empty when the document is undefined
+	remoteDataResponse struct {
+		Result json.RawMessage `json:"result"`
+	}
+
+	remoteCompileRequest struct {
+		Query    string                 `json:"query"`
+		Input    map[string]interface{} `json:"input"`
+		Unknowns []string               `json:"unknowns"`
+	}
+
+	// remoteExpressions are the expressions of a query of the compile API, see queryBody
+	remoteExpressions []struct {
+		Negated bool            `json:"negated"`
+		Terms   json.RawMessage `json:"terms"`
+	}
+
+	remoteCompileResponse struct {
+		Result *struct {
+			Queries []remoteExpressions `json:"queries"`
+			Support []json.RawMessage   `json:"support"`
+		} `json:"result"`
+	}
+)
+
+// NewRemoteAuthorizer checks the options, the server is only called on the first decision
+func NewRemoteAuthorizer(opts RemoteAuthorizerOptions) (*RemoteAuthorizer, error) {
+	u, err := url.Parse(opts.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid remote policy url %q", opts.Url)
+	}
+
+	a := &RemoteAuthorizer{
+		url:      strings.TrimRight(opts.Url, "/"),
+		timeout:  opts.Timeout,
+		retries:  opts.Retries,
+		backoff:  opts.Backoff,
+		fallback: opts.Fallback,
+		client:   opts.Client,
+	}
+	if a.timeout <= 0 {
+		a.timeout = defaultRemoteTimeout
+	}
+	if a.retries < 0 {
+		a.retries = 0
+	}
+	if a.backoff <= 0 {
+		a.backoff = defaultRemoteBackoff
+	}
+	if a.fallback == "" {
+		a.fallback = RemoteFallbackClosed
+	}
+	if a.fallback != RemoteFallbackClosed && a.fallback != RemoteFallbackEmbedded {
+		return nil, fmt.Errorf("invalid remote policy fallback %q, expected %s or %s",
+			opts.Fallback, RemoteFallbackClosed, RemoteFallbackEmbedded)
+	}
+	if a.client == nil {
+		a.client = http.DefaultClient
+	}
+	a.OPAAuthorizer = opts.Embedded
+	if a.OPAAuthorizer == nil {
+		a.OPAAuthorizer = NewOPAAuthorizer()
+	}
+	return a, nil
+}
+
+// Check asks data.authz of the remote server, its allow and deny decide like the embedded policies
+func (a *RemoteAuthorizer) Check(ctx context.Context, user *domains.UserWithRoles, callOpts ...CallOPAInputOption) (denyMsg []string, err error) {
+	opts := appliedOPAInputOption(callOpts)
+
+	start := time.Now()
+	denyMsg, err = a.decide(ctx, user, opts)
+	if a.fallsBack(err) {
+		return a.OPAAuthorizer.Check(ctx, user, callOpts...)
+	}
+
+	a.decisionLogger.Load().logDecision(user, opts, a.engine().endpointPermission(opts.RequestEndpoint, opts.RequestMethod), PolicySourceRemote,
+		time.Since(start), denyMsg, err)
+	return
+}
+
+// Explain decides like Check, the remote server returns no trace so Rules and Trace are empty
+func (a *RemoteAuthorizer) Explain(ctx context.Context, user *domains.UserWithRoles, withTrace bool, callOpts ...CallOPAInputOption) *Explanation {
+	opts := appliedOPAInputOption(callOpts)
+
+	denyMsg, err := a.decide(ctx, user, opts)
+	if a.fallsBack(err) {
+		return a.OPAAuthorizer.Explain(ctx, user, withTrace, callOpts...)
+	}
+
+	e := newExplanation(user, opts, denyMsg, err)
+	e.Permission = a.engine().endpointPermission(opts.RequestEndpoint, opts.RequestMethod)
+	e.PolicyRevision = PolicySourceRemote
+	return e
+}
+
+// PartialEval asks the compile API of the remote server for the conditions of data.authz.allow_row
+func (a *RemoteAuthorizer) PartialEval(ctx context.Context, user *domains.UserWithRoles, permission string, resourceType domains.ResourceType, callOpts ...CallOPAInputOption) (*RowFilter, error) {
+	opts := appliedOPAInputOption(callOpts)
+
+	input, err := a.input(ctx, user, opts)
+	if err != nil {
+		return nil, err
+	}
+	input["permission"] = permission
+	input["row_type"] = string(resourceType)
+
+	var resp remoteCompileResponse
+	err = a.post(ctx, remoteCompilePath, remoteCompileRequest{
+		Query:    "data.authz.allow_row == true",
+		Input:    input,
+		Unknowns: []string{"input.row"},
+	}, &resp)
+	if err == nil && resp.Result == nil {
+		err = fmt.Errorf("%w: empty partial evaluation result", ErrRemotePolicyUnavailable)
+	}
+	if a.fallsBack(err) {
+		return a.OPAAuthorizer.PartialEval(ctx, user, permission, resourceType, callOpts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Result.Support) > 0 {
+		return nil, fmt.Errorf("row filter of %s needs support rules, they cannot be turned into SQL", permission)
+	}
+	queries := make([]ast.Body, 0, len(resp.Result.Queries))
+	for _, q := range resp.Result.Queries {
+		body, err := q.queryBody()
+		if err != nil {
+			return nil, fmt.Errorf("invalid row filter of %s: %w", permission, err)
+		}
+		queries = append(queries, body)
+	}
+	// no query means allow_row is false for every row
+	return rowFilterFromQueries(queries)
+}
+
+// PolicyStatus tells the remote server decides, LoadedAt and the errors are the ones of the embedded policies
+func (a *RemoteAuthorizer) PolicyStatus() PolicyStatus {
+	status := a.OPAAuthorizer.PolicyStatus()
+	status.Revision, status.Source = PolicySourceRemote, a.url
+	return status
+}
+
+// IsSuperAdmin asks utils.is_super_admin of the remote server. The user is not a super admin while the server is
+// unavailable, unless the embedded policies fall back
+func (a *RemoteAuthorizer) IsSuperAdmin(ctx context.Context, user *domains.UserWithRoles) bool {
+	if user == nil {
+		return false
+	}
+
+	var isSuperAdmin bool
+	err := a.query(ctx, remoteSuperAdminPath, map[string]interface{}{"user": user}, &isSuperAdmin)
+	if a.fallsBack(err) {
+		return a.OPAAuthorizer.IsSuperAdmin(ctx, user)
+	}
+	if err != nil {
+		logger.Log().Errorf("error while eval remote is_super_admin, details %v", err.Error())
+		return false
+	}
+	return isSuperAdmin
+}
+
+// UserPermissions reads usr_role and effective_permissions of the decision of the remote server
+func (a *RemoteAuthorizer) UserPermissions(ctx context.Context, user *domains.UserWithRoles, callOpts ...CallOPAInputOption) (*EffectivePermissions, error) {
+	opts := appliedOPAInputOption(callOpts)
+
+	input, err := a.input(ctx, user, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp remoteDecisionResponse
+	err = a.post(ctx, remoteDecisionPath, map[string]interface{}{"input": input}, &resp)
+	if err == nil && resp.Result == nil {
+		err = fmt.Errorf("%w: empty permissions result", ErrRemotePolicyUnavailable)
+	}
+	if a.fallsBack(err) {
+		return a.OPAAuthorizer.UserPermissions(ctx, user, callOpts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	role := resp.Result.UsrRole
+	if role == "" {
+		role = string(domains.UserRoleGuest)
+	}
+	return newEffectivePermissions(opts, role, resp.Result.EffectivePermissions), nil
+}
+
+// RolePermissions asks roles_chart_permissions of the remote server for the role
+func (a *RemoteAuthorizer) RolePermissions(ctx context.Context, orgId int64, role string, customRoles []*domains.OrgCustomRole) ([]string, error) {
+	var chart map[string][]string
+	err := a.query(ctx, remoteRolePermissionsPath, rolePermissionsInput(orgId, role, customRoles), &chart)
+	if a.fallsBack(err) {
+		return a.OPAAuthorizer.RolePermissions(ctx, orgId, role, customRoles)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	perms := append(make([]string, 0, len(chart[role])), chart[role]...)
+	sort.Strings(perms)
+	return perms, nil
+}
+
+// IsBuiltinRole asks roles_chart of the remote server. While it is unavailable every role is taken as built-in,
+// so no custom role takes the name of a remote one, unless the embedded policies fall back
+func (a *RemoteAuthorizer) IsBuiltinRole(ctx context.Context, role string) bool {
+	var chart map[string]json.RawMessage
+	err := a.query(ctx, remoteRolesChartPath, nil, &chart)
+	if a.fallsBack(err) {
+		return a.OPAAuthorizer.IsBuiltinRole(ctx, role)
+	}
+	if err != nil {
+		logger.Log().Errorf("error while read remote roles_chart, details %v", err.Error())
+		return true
+	}
+
+	_, ok := chart[role]
+	return ok
+}
+
+// SetEndpointsACL compiles the embedded policies with the routes, they decide on fallback, and compares them with
+// endpoints_acl of the remote server, loaded from routes.json. The differences are reported as RemoteACLMismatches
+func (a *RemoteAuthorizer) SetEndpointsACL(ctx context.Context, routes []*echo.Route) (*ACLDrift, error) {
+	drift, err := a.OPAAuthorizer.SetEndpointsACL(ctx, routes)
+	if err != nil {
+		return nil, err
+	}
+
+	var remoteAcl map[string]map[string]string
+	if err = a.query(ctx, remoteEndpointsACLPath, nil, &remoteAcl); err != nil {
+		drift.RemoteACLMismatches = []string{fmt.Sprintf("cannot read endpoints_acl of %s: %v", a.url, err)}
+		return drift, nil
+	}
+
+	acl, _ := BuildEndpointsACL(routes)
+	drift.RemoteACLMismatches = endpointsACLMismatches(acl, remoteAcl)
+	return drift, nil
+}
+
+// endpointsACLMismatches lists the routes named differently by the remote endpoints_acl, one line per method and path
+func endpointsACLMismatches(acl, remoteAcl map[string]map[string]string) []string {
+	mismatches := make([]string, 0)
+	for path, methods := range acl {
+		for method, perm := range methods {
+			remotePerm, ok := remoteAcl[path][method]
+			switch {
+			case !ok:
+				mismatches = append(mismatches, fmt.Sprintf("%s %s: %s is missing", method, path, perm))
+			case remotePerm != perm:
+				mismatches = append(mismatches, fmt.Sprintf("%s %s: %s, remote %s", method, path, perm, remotePerm))
+			}
+		}
+	}
+	for path, methods := range remoteAcl {
+		for method, remotePerm := range methods {
+			if _, ok := acl[path][method]; !ok {
+				mismatches = append(mismatches, fmt.Sprintf("%s %s: no route, remote %s", method, path, remotePerm))
+			}
+		}
+	}
+
+	sort.Strings(mismatches)
+	return mismatches
+}
+
+// input builds the input document, with the custom roles of every org of the user. The built-in roles are those of
+// the remote server, they are not asked on every decision
+func (a *RemoteAuthorizer) input(ctx context.Context, user *domains.UserWithRoles, opts *opaInputOpts) (map[string]interface{}, error) {
+	customRoles, err := a.fetchCustomRoles(ctx, user, func(string) bool { return false })
+	if err != nil {
+		return nil, err
+	}
+	return buildInput(user, opts, customRoles), nil
+}
+
+// queryBody reads the expressions as rego. References are either arrays of terms or, as OPA 1.x writes them,
+// their string form, which ast.Term does not read back
+func (q remoteExpressions) queryBody() (ast.Body, error) {
+	body := make(ast.Body, 0, len(q))
+	for i, e := range q {
+		var raws []json.RawMessage
+		if err := json.Unmarshal(e.Terms, &raws); err != nil {
+			// a single term is not a comparison, rowCondition rejects it
+			raws = []json.RawMessage{e.Terms}
+		}
+
+		terms := make([]*ast.Term, 0, len(raws))
+		for _, raw := range raws {
+			term, err := remoteTerm(raw)
+			if err != nil {
+				return nil, err
+			}
+			terms = append(terms, term)
+		}
+
+		expr := &ast.Expr{Index: i, Negated: e.Negated, Terms: terms}
+		if len(terms) == 1 {
+			expr.Terms = terms[0]
+		}
+		body = append(body, expr)
+	}
+	return body, nil
+}
+
+func remoteTerm(raw json.RawMessage) (*ast.Term, error) {
+	var t struct {
+		Type  string          `json:"type"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return nil, err
+	}
+
+	var ref string
+	if t.Type == "ref" && json.Unmarshal(t.Value, &ref) == nil {
+		term, err := ast.ParseTerm(ref)
+		if err != nil {
+			return nil, err
+		}
+		// operators such as eq are one var refs
+		switch v := term.Value.(type) {
+		case ast.Var:
+			return ast.RefTerm(ast.NewTerm(v)), nil
+		case ast.Ref:
+			return term, nil
+		}
+		return nil, fmt.Errorf("expected ref but got %v", term)
+	}
+
+	term := &ast.Term{}
+	if err := term.UnmarshalJSON(raw); err != nil {
+		return nil, err
+	}
+	return term, nil
+}
+
+// decide evaluates data.authz on the remote server, ErrForbidden is returned when it denies
+func (a *RemoteAuthorizer) decide(ctx context.Context, user *domains.UserWithRoles, opts *opaInputOpts) (denyMsg []string, err error) {
+	input, err := a.input(ctx, user, opts)
+	if err != nil {
+		return
+	}
+
+	var resp remoteDecisionResponse
+	if err = a.post(ctx, remoteDecisionPath, map[string]interface{}{"input": input}, &resp); err != nil {
+		return
+	}
+
+	// data.authz is undefined when the server does not have the policies
+	if resp.Result == nil || resp.Result.Allow == nil {
+		err = fmt.Errorf("%w: no allow in the decision result", ErrRemotePolicyUnavailable)
+		return
+	}
+	if len(resp.Result.Deny) > 0 {
+		denyMsg = resp.Result.Deny
+	}
+
+	if !*resp.Result.Allow {
+		err = ErrForbidden
+	}
+	return
+}
+
+// fallsBack tells whether the embedded policies decide instead of the unavailable remote server
+func (a *RemoteAuthorizer) fallsBack(err error) bool {
+	if !errors.Is(err, ErrRemotePolicyUnavailable) {
+		return false
+	}
+
+	logger.Log().Errorw("remote policy decision point unavailable", "url", a.url, "fallback", a.fallback, "error", err)
+	return a.fallback == RemoteFallbackEmbedded
+}
+
+// query evaluates the document of the data API path with the input, nil for none. An undefined document is
+// ErrRemotePolicyUnavailable, the server does not have the policies
+func (a *RemoteAuthorizer) query(ctx context.Context, path string, input interface{}, result interface{}) error {
+	body := map[string]interface{}{}
+	if input != nil {
+		body["input"] = input
+	}
+
+	var resp remoteDataResponse
+	if err := a.post(ctx, path, body, &resp); err != nil {
+		return err
+	}
+	if len(resp.Result) == 0 {
+		return fmt.Errorf("%w: %s is undefined", ErrRemotePolicyUnavailable, path)
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+// post sends the body to the path of the server, retrying network errors and 5xx answers after a backoff.
+// The failures are ErrRemotePolicyUnavailable
+func (a *RemoteAuthorizer) post(ctx context.Context, path string, body interface{}, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= a.retries; attempt++ {
+		if attempt > 0 {
+			if err = a.wait(ctx, attempt); err != nil {
+				break
+			}
+		}
+
+		retry, err := a.postOnce(ctx, path, payload, out)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return fmt.Errorf("%w: %v", ErrRemotePolicyUnavailable, lastErr)
+}
+
+// wait sleeps before the retry, a random duration up to the backoff doubled on every retry, capped to
+// maxRemoteBackoff. It returns early with the error of ctx
+func (a *RemoteAuthorizer) wait(ctx context.Context, retry int) error {
+	ceiling := a.backoff << (retry - 1)
+	if ceiling <= 0 || ceiling > maxRemoteBackoff {
+		ceiling = maxRemoteBackoff
+	}
+
+	timer := time.NewTimer(rand.N(ceiling) + 1)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (a *RemoteAuthorizer) postOnce(ctx context.Context, path string, payload []byte, out interface{}) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url+path, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, remoteErrorBodySize))
+		return res.StatusCode >= http.StatusInternalServerError,
+			fmt.Errorf("%s answered %d: %s", path, res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	if err = json.NewDecoder(res.Body).Decode(out); err != nil {
+		return false, err
+	}
+	return false, nil
+}
@@ -0,0 +1,335 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/labstack/echo/v4"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRemotePDP serves the data and compile APIs of OPA with the embedded policies, status answers the attempts
+// before the policies are evaluated
+func newRemotePDP(t *testing.T, status func(attempt int32) int, delay time.Duration) (*httptest.Server, *int32) {
+	attempts := new(int32)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempt := atomic.AddInt32(attempts, 1)
+		time.Sleep(delay)
+		if code := status(attempt); code != http.StatusOK {
+			http.Error(w, `{"code": "internal_error"}`, code)
+			return
+		}
+
+		var req struct {
+			Input map[string]interface{} `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		engine := testAuthorizer.engine()
+		switch r.URL.Path {
+		case remoteDecisionPath:
+			rs, err := engine.query.Eval(context.Background(), rego.EvalInput(req.Input))
+			if err != nil || len(rs) == 0 {
+				http.Error(w, "cannot evaluate", http.StatusInternalServerError)
+				return
+			}
+			deny, _ := rs[0].Bindings["deny"].([]interface{})
+			perms, err := engine.permissionsQuery.Eval(context.Background(), rego.EvalInput(req.Input))
+			if err != nil || len(perms) == 0 {
+				http.Error(w, "cannot evaluate", http.StatusInternalServerError)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"result": map[string]interface{}{
+					"allow":                 rs[0].Bindings["allow"],
+					"deny":                  deny,
+					"usr_role":              perms[0].Bindings["role"],
+					"effective_permissions": perms[0].Bindings["permissions"],
+				},
+			})
+		case remoteSuperAdminPath:
+			rs, err := engine.superAdminQuery.Eval(context.Background(), rego.EvalInput(req.Input))
+			if err != nil || len(rs) == 0 {
+				http.Error(w, "cannot evaluate", http.StatusInternalServerError)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"result": rs[0].Bindings["is_super_admin"]})
+		case remoteRolePermissionsPath:
+			rs, err := engine.rolePermissionsQuery.Eval(context.Background(), rego.EvalInput(req.Input))
+			if err != nil {
+				http.Error(w, "cannot evaluate", http.StatusInternalServerError)
+				return
+			}
+			chart := map[string]interface{}{}
+			if len(rs) > 0 {
+				chart[req.Input["role"].(string)] = rs[0].Bindings["permissions"]
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"result": chart})
+		case remoteRolesChartPath:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"result": engine.data["roles_chart"]})
+		case remoteEndpointsACLPath:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"result": engine.data["endpoints_acl"]})
+		case remoteCompilePath:
+			pq, err := engine.rowFilterQuery.Partial(context.Background(), rego.EvalInput(req.Input))
+			if err != nil {
+				http.Error(w, "cannot evaluate", http.StatusInternalServerError)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"result": map[string]interface{}{"queries": pq.Queries},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, attempts
+}
+
+func alwaysStatus(code int) func(int32) int {
+	return func(int32) int { return code }
+}
+
+func TestRemoteAuthorizerDecidesLikeEmbedded(t *testing.T) {
+	srv, _ := newRemotePDP(t, alwaysStatus(http.StatusOK), 0)
+	remote, err := NewRemoteAuthorizer(RemoteAuthorizerOptions{Url: srv.URL})
+	assert.Nil(t, err)
+	embedded := NewOPAAuthorizer()
+
+	serviceAccount := domains.ServiceAccount{
+		OrgId:    9,
+		ClientId: "sa_manager",
+		Role:     domains.UserRoleManager,
+		Status:   domains.ServiceAccountStatusActive,
+	}.ToUserWithRoles()
+	support := &domains.UserWithRoles{
+		User:          domains.User{Id: 2},
+		Kind:          domains.PrincipalKindUser,
+		PlatformRoles: []domains.PlatformRole{domains.PlatformRoleSupport},
+	}
+	viewer := &domains.UserWithRoles{
+		User:    domains.User{Id: 3},
+		Kind:    domains.PrincipalKindUser,
+		OrgRole: map[int64]string{5: "viewer"},
+	}
+
+	tcs := []struct {
+		name     string
+		user     *domains.UserWithRoles
+		org      *domains.Org
+		endpoint TestEndpoint
+	}{
+		{"should allow like embedded", support, nil, getListUserEndpoint},
+		{"should deny like embedded", viewer, nil, getListUserEndpoint},
+		{"should return deny messages like embedded", serviceAccount, &domains.Org{Id: 9}, createServiceAccountEndpoint},
+		{"should allow org members like embedded", viewer, &domains.Org{Id: 5}, getOrgEndpoint},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			opts := []CallOPAInputOption{
+				WithInputRequestMethod(tc.endpoint.Method),
+				WithInputRequestEndpoint(tc.endpoint.Endpoint),
+			}
+			if tc.org != nil {
+				opts = append(opts, WithInputOrg(tc.org))
+			}
+
+			expectedMsg, expectedErr := embedded.Check(context.Background(), tc.user, opts...)
+			denyMsg, err := remote.Check(context.Background(), tc.user, opts...)
+			assert.Equal(t, expectedErr, err)
+			assert.Equal(t, expectedMsg, denyMsg)
+
+			e := remote.Explain(context.Background(), tc.user, false, opts...)
+			assert.Equal(t, expectedErr == nil, e.Allowed)
+			assert.Equal(t, PolicySourceRemote, e.PolicyRevision)
+			assert.Equal(t, embedded.Explain(context.Background(), tc.user, false, opts...).Permission, e.Permission)
+
+			expectedFilter, err := embedded.PartialEval(context.Background(), tc.user, "read:project", domains.ResourceTypeProject)
+			assert.Nil(t, err)
+			filter, err := remote.PartialEval(context.Background(), tc.user, "read:project", domains.ResourceTypeProject)
+			assert.Nil(t, err)
+			assert.Equal(t, expectedFilter, filter)
+		})
+	}
+}
+
+func TestRemoteAuthorizerQueriesLikeEmbedded(t *testing.T) {
+	srv, _ := newRemotePDP(t, alwaysStatus(http.StatusOK), 0)
+	remote, err := NewRemoteAuthorizer(RemoteAuthorizerOptions{Url: srv.URL})
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	superAdmin := &domains.UserWithRoles{PlatformRoles: []domains.PlatformRole{domains.PlatformRoleSuperAdmin}}
+	viewer := &domains.UserWithRoles{User: domains.User{Id: 3}, OrgRole: map[int64]string{5: "viewer"}}
+	assert.True(t, remote.IsSuperAdmin(ctx, superAdmin))
+	assert.False(t, remote.IsSuperAdmin(ctx, viewer))
+
+	for _, org := range []*domains.Org{{Id: 5}, {Id: 6}} {
+		expected, err := testAuthorizer.UserPermissions(ctx, viewer, WithInputOrg(org))
+		assert.Nil(t, err)
+		ep, err := remote.UserPermissions(ctx, viewer, WithInputOrg(org))
+		assert.Nil(t, err)
+		assert.Equal(t, expected, ep)
+	}
+
+	customRoles := []*domains.OrgCustomRole{
+		{OrgId: 9, Name: "sa_admin", Permissions: []string{"create:service_account"}, Parent: "viewer"},
+	}
+	for _, role := range []string{"owner", "sa_admin", "missing"} {
+		expected, err := testAuthorizer.RolePermissions(ctx, 9, role, customRoles)
+		assert.Nil(t, err)
+		perms, err := remote.RolePermissions(ctx, 9, role, customRoles)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, perms)
+	}
+
+	assert.True(t, remote.IsBuiltinRole(ctx, "viewer"))
+	assert.False(t, remote.IsBuiltinRole(ctx, "sa_admin"))
+}
+
+func TestRemoteAuthorizerSetEndpointsACL(t *testing.T) {
+	routes := []*echo.Route{
+		{Method: getListUserEndpoint.Method, Path: getListUserEndpoint.Endpoint, Name: "list:user"},
+		{Method: getOrgEndpoint.Method, Path: getOrgEndpoint.Endpoint, Name: "update:org"},
+		{Method: "GET", Path: "/admin/reports", Name: "list:report"},
+	}
+
+	srv, _ := newRemotePDP(t, alwaysStatus(http.StatusOK), 0)
+	remote, err := NewRemoteAuthorizer(RemoteAuthorizerOptions{Url: srv.URL})
+	assert.Nil(t, err)
+
+	drift, err := remote.SetEndpointsACL(context.Background(), routes)
+	assert.Nil(t, err)
+	assert.False(t, drift.Empty())
+	assert.NotContains(t, drift.RemoteACLMismatches, "GET /admin/users: list:user is missing")
+	assert.Contains(t, drift.RemoteACLMismatches, "GET /admin/orgs/:orgId: update:org, remote read:org")
+	assert.Contains(t, drift.RemoteACLMismatches, "GET /admin/reports: list:report is missing")
+	assert.Contains(t, drift.RemoteACLMismatches, "GET /admin/users/:userId: no route, remote read:user")
+	// the routes decide on fallback
+	assert.True(t, remote.IsKnownPermission("list:report"))
+
+	down, _ := newRemotePDP(t, alwaysStatus(http.StatusServiceUnavailable), 0)
+	remote, err = NewRemoteAuthorizer(RemoteAuthorizerOptions{Url: down.URL})
+	assert.Nil(t, err)
+
+	drift, err = remote.SetEndpointsACL(context.Background(), routes)
+	assert.Nil(t, err)
+	assert.Len(t, drift.RemoteACLMismatches, 1)
+	assert.Contains(t, drift.RemoteACLMismatches[0], "cannot read endpoints_acl")
+}
+
+func TestRemoteAuthorizerBackoff(t *testing.T) {
+	support := &domains.UserWithRoles{
+		User:          domains.User{Id: 2},
+		Kind:          domains.PrincipalKindUser,
+		PlatformRoles: []domains.PlatformRole{domains.PlatformRoleSupport},
+	}
+	opts := []CallOPAInputOption{
+		WithInputRequestMethod(getListUserEndpoint.Method),
+		WithInputRequestEndpoint(getListUserEndpoint.Endpoint),
+	}
+
+	// the request is canceled while waiting for the retry
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv, attempts := newRemotePDP(t, func(int32) int {
+		cancel()
+		return http.StatusServiceUnavailable
+	}, 0)
+	remote, err := NewRemoteAuthorizer(RemoteAuthorizerOptions{Url: srv.URL, Retries: 3, Backoff: time.Hour})
+	assert.Nil(t, err)
+
+	start := time.Now()
+	_, err = remote.Check(ctx, support, opts...)
+	assert.ErrorIs(t, err, ErrRemotePolicyUnavailable)
+	assert.Equal(t, int32(1), atomic.LoadInt32(attempts))
+	assert.Less(t, time.Since(start), time.Second)
+
+	// the waits are drawn up to the backoff, doubled on every retry
+	remote, err = NewRemoteAuthorizer(RemoteAuthorizerOptions{Url: srv.URL, Backoff: 10 * time.Millisecond})
+	assert.Nil(t, err)
+	for retry := 1; retry <= 3; retry++ {
+		start := time.Now()
+		assert.Nil(t, remote.wait(context.Background(), retry))
+		assert.Less(t, time.Since(start), 10*time.Millisecond<<retry)
+	}
+}
+
+func TestRemoteAuthorizerUnavailable(t *testing.T) {
+	support := &domains.UserWithRoles{
+		User:          domains.User{Id: 2},
+		Kind:          domains.PrincipalKindUser,
+		PlatformRoles: []domains.PlatformRole{domains.PlatformRoleSupport},
+	}
+	opts := []CallOPAInputOption{
+		WithInputRequestMethod(getListUserEndpoint.Method),
+		WithInputRequestEndpoint(getListUserEndpoint.Endpoint),
+	}
+
+	tcs := []struct {
+		name             string
+		status           func(int32) int
+		delay            time.Duration
+		retries          int
+		fallback         string
+		expectedErr      error
+		expectedAttempts int32
+	}{
+		{"should retry server errors", func(attempt int32) int {
+			if attempt < 3 {
+				return http.StatusServiceUnavailable
+			}
+			return http.StatusOK
+		}, 0, 2, RemoteFallbackClosed, nil, 3},
+		{"should fail closed after the retries", alwaysStatus(http.StatusServiceUnavailable), 0, 1,
+			RemoteFallbackClosed, ErrRemotePolicyUnavailable, 2},
+		{"should not retry client errors", alwaysStatus(http.StatusBadRequest), 0, 2,
+			RemoteFallbackClosed, ErrRemotePolicyUnavailable, 1},
+		{"should fail closed on timeout", alwaysStatus(http.StatusOK), 200 * time.Millisecond, 0,
+			RemoteFallbackClosed, ErrRemotePolicyUnavailable, 1},
+		{"should fall back to embedded policies", alwaysStatus(http.StatusServiceUnavailable), 0, 1,
+			RemoteFallbackEmbedded, nil, 2},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			srv, attempts := newRemotePDP(t, tc.status, tc.delay)
+			remote, err := NewRemoteAuthorizer(RemoteAuthorizerOptions{
+				Url:      srv.URL,
+				Timeout:  50 * time.Millisecond,
+				Retries:  tc.retries,
+				Fallback: tc.fallback,
+			})
+			assert.Nil(t, err)
+
+			_, err = remote.Check(context.Background(), support, opts...)
+			if tc.expectedErr == nil {
+				assert.Nil(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			}
+			assert.Equal(t, tc.expectedAttempts, atomic.LoadInt32(attempts))
+		})
+	}
+}
+
+func TestNewRemoteAuthorizerInvalidOptions(t *testing.T) {
+	_, err := NewRemoteAuthorizer(RemoteAuthorizerOptions{Url: "opa:8181"})
+	assert.NotNil(t, err)
+
+	_, err = NewRemoteAuthorizer(RemoteAuthorizerOptions{Url: "http://opa:8181", Fallback: "open"})
+	assert.NotNil(t, err)
+}
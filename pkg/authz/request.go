@@ -0,0 +1,64 @@
+package authz
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	"github.com/labstack/echo/v4"
+)
+
+// RequestAttributes describe how the request was made, for rules on the client and the time of the request
+type RequestAttributes struct {
+	// ClientIp is the address of the client, X-Forwarded-For is only read behind a trusted proxy
+	ClientIp   string
+	UserAgent  string
+	AuthMethod string
+	Time       time.Time
+}
+
+// RequestAttributesFromContext describes the request of the echo context, with the auth method of its principal
+func RequestAttributesFromContext(c echo.Context) RequestAttributes {
+	req := RequestAttributes{
+		ClientIp:  c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+		Time:      time.Now(),
+	}
+	if p, err := contexts.GetPrincipalFromContext(c); err == nil {
+		req.AuthMethod = p.AuthMethod
+	}
+	return req
+}
+
+// WithInputRequest sets input.request, the time is also given in the timezone of the org of the input
+func WithInputRequest(req RequestAttributes) CallOPAInputOption {
+	return CallOPAInputOption{
+		applyFunc: func(oio *opaInputOpts) {
+			oio.Request = &req
+		},
+	}
+}
+
+// requestInput returns input.request. org_clock and org_weekday are the time in the timezone of the org,
+// UTC when the org has none
+func requestInput(req *RequestAttributes, org *domains.Org) map[string]interface{} {
+	rs := map[string]interface{}{
+		"client_ip":   req.ClientIp,
+		"user_agent":  req.UserAgent,
+		"auth_method": req.AuthMethod,
+		"time":        req.Time.UTC().Format(time.RFC3339),
+	}
+
+	if org != nil {
+		loc, err := time.LoadLocation(org.Timezone)
+		if err != nil || org.Timezone == "" {
+			loc = time.UTC
+		}
+		local := req.Time.In(loc)
+		rs["org_time"] = local.Format(time.RFC3339)
+		rs["org_clock"] = local.Format("15:04")
+		rs["org_weekday"] = strings.ToLower(local.Weekday().String())
+	}
+	return rs
+}
@@ -0,0 +1,21 @@
+package authz
+
+import (
+	"fmt"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+)
+
+// ResourceKey identifies a resource in input.resource_id and input.resource_perms, e.g. project:42
+func ResourceKey(resourceType domains.ResourceType, id int64) string {
+	return fmt.Sprintf("%s:%d", resourceType, id)
+}
+
+// ResourceGrantPermissions shapes the grants like WithInputResourcePermissions expects, the resource keys per permission
+func ResourceGrantPermissions(grants []*domains.ResourceGrant) map[string][]string {
+	perms := map[string][]string{}
+	for _, g := range grants {
+		perms[g.Permission] = append(perms[g.Permission], ResourceKey(g.ResourceType, g.ResourceId))
+	}
+	return perms
+}
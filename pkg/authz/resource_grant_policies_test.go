@@ -0,0 +1,155 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	projectDomains "github.com/dzungtran/echo-rest-api/modules/projects/domains"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	updateProjectEndpoint = TestEndpoint{"PUT", "/admin/projects/:projectId"}
+	deleteProjectEndpoint = TestEndpoint{"DELETE", "/admin/projects/:projectId"}
+)
+
+func TestPoliciesForResourceGrants(t *testing.T) {
+	collaborator := &domains.UserWithRoles{
+		User:    domains.User{Id: 8},
+		OrgRole: map[int64]string{},
+	}
+	grants := ResourceGrantPermissions([]*domains.ResourceGrant{
+		{UserId: 8, Permission: "update:project", ResourceType: domains.ResourceTypeProject, ResourceId: 42},
+		{UserId: 8, Permission: "read:org", ResourceType: domains.ResourceTypeOrg, ResourceId: 9},
+	})
+
+	tcs := []struct {
+		name       string
+		resource   []CallOPAInputOption
+		tokenPerms []string
+		hasError   bool
+		endpoint   TestEndpoint
+	}{
+		{
+			"should allow the permission granted on the project",
+			[]CallOPAInputOption{
+				WithInputExtraData("project", &projectDomains.Project{Id: 42, OrgId: 9}),
+				WithInputResource(domains.ResourceTypeProject, 42),
+			},
+			nil, false, updateProjectEndpoint,
+		},
+		{
+			"should deny permissions not granted on the project",
+			[]CallOPAInputOption{
+				WithInputExtraData("project", &projectDomains.Project{Id: 42, OrgId: 9}),
+				WithInputResource(domains.ResourceTypeProject, 42),
+			},
+			nil, true, deleteProjectEndpoint,
+		},
+		{
+			"should deny the permission on other projects",
+			[]CallOPAInputOption{
+				WithInputExtraData("project", &projectDomains.Project{Id: 43, OrgId: 9}),
+				WithInputResource(domains.ResourceTypeProject, 43),
+			},
+			nil, true, updateProjectEndpoint,
+		},
+		{
+			"should not mistake an org for a project with the same id",
+			[]CallOPAInputOption{
+				WithInputOrg(&domains.Org{Id: 42}),
+				WithInputResource(domains.ResourceTypeOrg, 42),
+			},
+			nil, true, updateProjectEndpoint,
+		},
+		{
+			"should allow the permission granted on the org",
+			[]CallOPAInputOption{
+				WithInputOrg(&domains.Org{Id: 9}),
+				WithInputResource(domains.ResourceTypeOrg, 9),
+			},
+			nil, false, getOrgEndpoint,
+		},
+		{
+			"should deny a granted permission the token does not hold",
+			[]CallOPAInputOption{
+				WithInputExtraData("project", &projectDomains.Project{Id: 42, OrgId: 9}),
+				WithInputResource(domains.ResourceTypeProject, 42),
+			},
+			[]string{"read:project"}, true, updateProjectEndpoint,
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			callOpts := append(tc.resource,
+				WithInputRequestMethod(tc.endpoint.Method),
+				WithInputRequestEndpoint(tc.endpoint.Endpoint),
+				WithInputResourcePermissions(grants),
+			)
+			if tc.tokenPerms != nil {
+				callOpts = append(callOpts, WithInputTokenPermissions(tc.tokenPerms))
+			}
+
+			_, err := testAuthorizer.Check(context.Background(), collaborator, callOpts...)
+			if tc.hasError {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestResourceGrantPermissions(t *testing.T) {
+	perms := ResourceGrantPermissions([]*domains.ResourceGrant{
+		{Permission: "update:project", ResourceType: domains.ResourceTypeProject, ResourceId: 42},
+		{Permission: "update:project", ResourceType: domains.ResourceTypeProject, ResourceId: 43},
+		{Permission: "read:org", ResourceType: domains.ResourceTypeOrg, ResourceId: 9},
+	})
+
+	assert.Equal(t, map[string][]string{
+		"update:project": {"project:42", "project:43"},
+		"read:org":       {"org:9"},
+	}, perms)
+}
+
+func TestPoliciesForManagingResourceGrants(t *testing.T) {
+	createGrantEndpoint := TestEndpoint{"POST", "/admin/orgs/:orgId/grants"}
+	loggedInUser := &domains.UserWithRoles{
+		User: domains.User{Id: 8},
+		OrgRole: map[int64]string{
+			8:  "viewer",
+			10: "manager",
+			11: "owner",
+		},
+	}
+
+	tcs := []struct {
+		name     string
+		orgId    int64
+		hasError bool
+	}{
+		{"should allow manager to grant permissions", 10, false},
+		{"should allow owner to grant permissions", 11, false},
+		{"should deny viewer to grant permissions", 8, true},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := testAuthorizer.Check(context.Background(), loggedInUser,
+				WithInputRequestMethod(createGrantEndpoint.Method),
+				WithInputRequestEndpoint(createGrantEndpoint.Endpoint),
+				WithInputOrg(&domains.Org{Id: tc.orgId}),
+			)
+			if tc.hasError {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package authz
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/open-policy-agent/opa/ast"
+)
+
+var rowColumnPattern = regexp.MustCompile(`^[a-z_]+$`)
+
+// RowFilter is the SQL condition on the rows of a list a user may see, a row matches when it matches one of the
+// Conditions. It is a squirrel.Sqlizer, to be given to the Where of a select
+type RowFilter struct {
+	// All is set when every row may be seen, e.g. for super admins
+	All bool
+	// Conditions are column = value conjunctions
+	Conditions []squirrel.Eq
+}
+
+// ToSql matches every row with All and none without Conditions
+func (f *RowFilter) ToSql() (string, []interface{}, error) {
+	if f.All {
+		return "(1=1)", nil, nil
+	}
+
+	or := squirrel.Or{}
+	for _, cond := range f.Conditions {
+		or = append(or, cond)
+	}
+	return or.ToSql()
+}
+
+// rowFilterFromQueries turns the queries of a partial evaluation into a RowFilter, matching every row
+// when a query has no condition left
+func rowFilterFromQueries(queries []ast.Body) (*RowFilter, error) {
+	filter := &RowFilter{Conditions: make([]squirrel.Eq, 0)}
+	for _, body := range queries {
+		cond, ok, err := rowCondition(body)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if len(cond) == 0 {
+			return &RowFilter{All: true}, nil
+		}
+		filter.Conditions = append(filter.Conditions, cond)
+	}
+	return filter, nil
+}
+
+// rowCondition turns the expressions of a partial query into column = value conditions.
+// A query comparing a column to two values can match no row, it is dropped
+func rowCondition(body ast.Body) (squirrel.Eq, bool, error) {
+	cond := squirrel.Eq{}
+	for _, expr := range body {
+		if expr.Negated || !(expr.IsEquality() || expr.Operator().Equal(ast.Equal.Ref())) {
+			return nil, false, fmt.Errorf("unsupported row filter expression: %v", expr)
+		}
+
+		terms := expr.Operands()
+		column, value, err := rowComparison(terms[0], terms[1])
+		if err != nil {
+			column, value, err = rowComparison(terms[1], terms[0])
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("unsupported row filter expression: %v", expr)
+		}
+
+		if prev, ok := cond[column]; ok && prev != value {
+			return nil, false, nil
+		}
+		cond[column] = value
+	}
+	return cond, true, nil
+}
+
+// rowComparison reads a comparison of input.row.<column> to a scalar
+func rowComparison(ref, val *ast.Term) (string, interface{}, error) {
+	r, ok := ref.Value.(ast.Ref)
+	if !ok || len(r) != 3 || !r.HasPrefix(ast.MustParseRef("input.row")) {
+		return "", nil, fmt.Errorf("not a row column: %v", ref)
+	}
+
+	column, ok := r[2].Value.(ast.String)
+	if !ok || !rowColumnPattern.MatchString(string(column)) {
+		return "", nil, fmt.Errorf("invalid row column: %v", r[2])
+	}
+
+	if !ast.IsScalar(val.Value) {
+		return "", nil, fmt.Errorf("not a scalar: %v", val)
+	}
+	v, err := ast.JSON(val.Value)
+	if err != nil {
+		return "", nil, err
+	}
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			v = i
+		} else if f, err := n.Float64(); err == nil {
+			v = f
+		}
+	}
+	return string(column), v, nil
+}
@@ -0,0 +1,80 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildRowFilter(t *testing.T) {
+	grants := ResourceGrantPermissions([]*domains.ResourceGrant{
+		{UserId: 8, Permission: "read:project", ResourceType: domains.ResourceTypeProject, ResourceId: 42},
+		{UserId: 8, Permission: "read:project", ResourceType: domains.ResourceTypeOrg, ResourceId: 7},
+	})
+
+	tcs := []struct {
+		name     string
+		user     *domains.UserWithRoles
+		callOpts []CallOPAInputOption
+		filter   *RowFilter
+		sql      string
+	}{
+		{
+			"should see every row as super admin",
+			&domains.UserWithRoles{User: domains.User{Id: 1}, PlatformRoles: []domains.PlatformRole{"super_admin"}},
+			nil,
+			&RowFilter{All: true},
+			"(1=1)",
+		},
+		{
+			"should see every row with a platform role granting the permission",
+			&domains.UserWithRoles{User: domains.User{Id: 2}, PlatformRoles: []domains.PlatformRole{"support"}},
+			nil,
+			&RowFilter{All: true},
+			"(1=1)",
+		},
+		{
+			"should see the rows of the orgs where the role grants the permission",
+			&domains.UserWithRoles{User: domains.User{Id: 3}, OrgRole: map[int64]string{5: "viewer", 6: "guest"}},
+			nil,
+			&RowFilter{Conditions: []squirrel.Eq{{"org_id": int64(5)}}},
+			"(org_id = ?)",
+		},
+		{
+			"should see the rows granted to the user",
+			&domains.UserWithRoles{User: domains.User{Id: 8}, OrgRole: map[int64]string{}},
+			[]CallOPAInputOption{WithInputResourcePermissions(grants)},
+			&RowFilter{Conditions: []squirrel.Eq{{"id": int64(42)}}},
+			"(id = ?)",
+		},
+		{
+			"should see the rows of the orgs and the rows granted",
+			&domains.UserWithRoles{User: domains.User{Id: 8}, OrgRole: map[int64]string{5: "viewer"}},
+			[]CallOPAInputOption{WithInputResourcePermissions(grants)},
+			&RowFilter{Conditions: []squirrel.Eq{{"org_id": int64(5)}, {"id": int64(42)}}},
+			"(org_id = ? OR id = ?)",
+		},
+		{
+			"should see no row with a token not granting the permission",
+			&domains.UserWithRoles{User: domains.User{Id: 3}, OrgRole: map[int64]string{5: "owner"}},
+			[]CallOPAInputOption{WithInputTokenPermissions([]string{"list:project"})},
+			&RowFilter{Conditions: []squirrel.Eq{}},
+			"(1=0)",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := testAuthorizer.PartialEval(context.Background(), tc.user, "read:project", domains.ResourceTypeProject, tc.callOpts...)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.filter, filter)
+
+			sql, _, err := filter.ToSql()
+			assert.Nil(t, err)
+			assert.Equal(t, tc.sql, sql)
+		})
+	}
+}
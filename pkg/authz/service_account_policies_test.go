@@ -0,0 +1,149 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	inviteOrgEndpoint            = TestEndpoint{"POST", "/admin/orgs/:orgId/invites"}
+	listServiceAccountEndpoint   = TestEndpoint{"GET", "/admin/orgs/:orgId/service-accounts"}
+	createServiceAccountEndpoint = TestEndpoint{"POST", "/admin/orgs/:orgId/service-accounts"}
+)
+
+func TestPoliciesForServiceAccount(t *testing.T) {
+	serviceAccount := domains.ServiceAccount{
+		OrgId:    9,
+		ClientId: "sa_manager",
+		Role:     domains.UserRoleManager,
+		Status:   domains.ServiceAccountStatusActive,
+	}.ToUserWithRoles()
+
+	manager := &domains.UserWithRoles{
+		User: domains.User{Id: 8},
+		Kind: domains.PrincipalKindUser,
+		OrgRole: map[int64]string{
+			9: "manager",
+		},
+	}
+
+	forbiddenMsg := []string{"service accounts are not allowed to perform this action"}
+
+	tcs := []struct {
+		name         string
+		loggedInUser *domains.UserWithRoles
+		requestedOrg *domains.Org
+		hasError     bool
+		denyMsg      []string
+		endpoint     TestEndpoint
+	}{
+		{
+			"should allow service account to use the permissions of its role",
+			serviceAccount,
+			&domains.Org{Id: 9},
+			false,
+			[]string{},
+			getOrgEndpoint,
+		},
+		{
+			"should deny service account on other orgs",
+			serviceAccount,
+			&domains.Org{Id: 10},
+			true,
+			[]string{},
+			getOrgEndpoint,
+		},
+		{
+			"should deny service account permissions above its role",
+			serviceAccount,
+			&domains.Org{Id: 9},
+			true,
+			[]string{},
+			updateOrgEndpoint,
+		},
+		{
+			"should allow manager to invite people",
+			manager,
+			&domains.Org{Id: 9},
+			false,
+			[]string{},
+			inviteOrgEndpoint,
+		},
+		{
+			"should deny service account to invite people even as manager",
+			serviceAccount,
+			&domains.Org{Id: 9},
+			true,
+			forbiddenMsg,
+			inviteOrgEndpoint,
+		},
+		{
+			"should allow service account to list service accounts",
+			serviceAccount,
+			&domains.Org{Id: 9},
+			false,
+			[]string{},
+			listServiceAccountEndpoint,
+		},
+		{
+			"should allow manager to create service accounts",
+			manager,
+			&domains.Org{Id: 9},
+			false,
+			[]string{},
+			createServiceAccountEndpoint,
+		},
+		{
+			"should deny service account to create service accounts",
+			serviceAccount,
+			&domains.Org{Id: 9},
+			true,
+			forbiddenMsg,
+			createServiceAccountEndpoint,
+		},
+		{
+			"should deny service account to create orgs",
+			serviceAccount,
+			nil,
+			true,
+			forbiddenMsg,
+			createOrgEndpoint,
+		},
+		{
+			"should deny service account permissions not listed for service accounts",
+			serviceAccount,
+			nil,
+			true,
+			forbiddenMsg,
+			TestEndpoint{"PUT", "/me"},
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			opts := []CallOPAInputOption{
+				WithInputRequestMethod(tc.endpoint.Method),
+				WithInputRequestEndpoint(tc.endpoint.Endpoint),
+			}
+			if tc.requestedOrg != nil {
+				opts = append(opts, WithInputOrg(tc.requestedOrg))
+			}
+
+			msg, err := testAuthorizer.Check(context.Background(), tc.loggedInUser, opts...)
+			if tc.hasError {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+
+			assert.Equal(t, len(tc.denyMsg), len(msg))
+			if len(tc.denyMsg) > 0 {
+				assert.Equal(t, tc.denyMsg, msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	listSessionEndpoint      = TestEndpoint{"GET", "/me/sessions"}
+	deleteSessionEndpoint    = TestEndpoint{"DELETE", "/me/sessions/:sessionId"}
+	revokeAllSessionEndpoint = TestEndpoint{"DELETE", "/admin/users/:userId/sessions"}
+)
+
+func TestPoliciesForSessionEndpoint(t *testing.T) {
+	user := &domains.UserWithRoles{
+		User: domains.User{Id: 8},
+		Kind: domains.PrincipalKindUser,
+		OrgRole: map[int64]string{
+			9: "owner",
+		},
+	}
+
+	serviceAccount := domains.ServiceAccount{
+		OrgId:    9,
+		ClientId: "sa_manager",
+		Role:     domains.UserRoleManager,
+		Status:   domains.ServiceAccountStatusActive,
+	}.ToUserWithRoles()
+
+	tcs := []struct {
+		name         string
+		loggedInUser *domains.UserWithRoles
+		hasError     bool
+		endpoint     TestEndpoint
+	}{
+		{"should allow user to list own sessions", user, false, listSessionEndpoint},
+		{"should allow user to revoke own session", user, false, deleteSessionEndpoint},
+		{"should deny org owner to revoke all sessions of a user", user, true, revokeAllSessionEndpoint},
+		{"should deny service account to list sessions", serviceAccount, true, listSessionEndpoint},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := testAuthorizer.Check(context.Background(), tc.loggedInUser,
+				WithInputRequestMethod(tc.endpoint.Method),
+				WithInputRequestEndpoint(tc.endpoint.Endpoint),
+			)
+			if tc.hasError {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+)
+
+// StaticPolicyRevision is the policy revision of the explanations of the static authorizers
+const StaticPolicyRevision = "static"
+
+// staticAuthorizer takes the same decision on every request without evaluating the policies. The roles and the
+// permissions it knows are read from data.json and routes.json, no user is a super admin
+type staticAuthorizer struct {
+	allow        bool
+	builtinRoles map[string]struct{}
+	// permissions are those of routes.json, sorted, every role holds them all when allow
+	permissions []string
+}
+
+// AllowAll returns an Authorizer allowing every request and every row, for tests
+func AllowAll() Authorizer {
+	return newStaticAuthorizer(true)
+}
+
+// DenyAll returns an Authorizer denying every request and every row, for tests
+func DenyAll() Authorizer {
+	return newStaticAuthorizer(false)
+}
+
+func newStaticAuthorizer(allow bool) staticAuthorizer {
+	a := staticAuthorizer{allow: allow, builtinRoles: map[string]struct{}{}, permissions: make([]string, 0)}
+
+	var data struct {
+		RolesChart map[string]json.RawMessage `json:"roles_chart"`
+	}
+	if err := json.Unmarshal(dataFile, &data); err != nil {
+		panic(err)
+	}
+	for role := range data.RolesChart {
+		a.builtinRoles[role] = struct{}{}
+	}
+
+	var routes map[string]map[string]string
+	if err := json.Unmarshal(routesFile, &routes); err != nil {
+		panic(err)
+	}
+	seen := map[string]struct{}{}
+	for _, methods := range routes {
+		for _, perm := range methods {
+			if _, ok := seen[perm]; !ok {
+				seen[perm] = struct{}{}
+				a.permissions = append(a.permissions, perm)
+			}
+		}
+	}
+	sort.Strings(a.permissions)
+	return a
+}
+
+func (a staticAuthorizer) Check(ctx context.Context, user *domains.UserWithRoles, callOpts ...CallOPAInputOption) ([]string, error) {
+	if !a.allow {
+		return nil, ErrForbidden
+	}
+	return nil, nil
+}
+
+func (a staticAuthorizer) Explain(ctx context.Context, user *domains.UserWithRoles, withTrace bool, callOpts ...CallOPAInputOption) *Explanation {
+	denyMsg, err := a.Check(ctx, user, callOpts...)
+	e := newExplanation(user, appliedOPAInputOption(callOpts), denyMsg, err)
+	e.PolicyRevision = StaticPolicyRevision
+	if withTrace {
+		e.Trace = []string{}
+	}
+	return e
+}
+
+func (a staticAuthorizer) PartialEval(ctx context.Context, user *domains.UserWithRoles, permission string, resourceType domains.ResourceType, callOpts ...CallOPAInputOption) (*RowFilter, error) {
+	if !a.allow {
+		return &RowFilter{Conditions: make([]squirrel.Eq, 0)}, nil
+	}
+	return &RowFilter{All: true}, nil
+}
+
+func (a staticAuthorizer) IsSuperAdmin(ctx context.Context, user *domains.UserWithRoles) bool {
+	return false
+}
+
+// UserPermissions returns the role of the user in the org of the options, guest without one
+func (a staticAuthorizer) UserPermissions(ctx context.Context, user *domains.UserWithRoles, callOpts ...CallOPAInputOption) (*EffectivePermissions, error) {
+	opts := appliedOPAInputOption(callOpts)
+
+	role := "guest"
+	if user != nil {
+		if r, ok := user.OrgRole[decisionOrgId(opts)]; ok {
+			role = r
+		}
+	}
+	perms, _ := a.RolePermissions(ctx, 0, role, nil)
+	return newEffectivePermissions(opts, role, perms), nil
+}
+
+func (a staticAuthorizer) RolePermissions(ctx context.Context, orgId int64, role string, customRoles []*domains.OrgCustomRole) ([]string, error) {
+	if !a.allow {
+		return make([]string, 0), nil
+	}
+	return append(make([]string, 0, len(a.permissions)), a.permissions...), nil
+}
+
+func (a staticAuthorizer) IsBuiltinRole(ctx context.Context, role string) bool {
+	_, ok := a.builtinRoles[role]
+	return ok
+}
+
+func (a staticAuthorizer) IsKnownPermission(perm string) bool {
+	i := sort.SearchStrings(a.permissions, perm)
+	return i < len(a.permissions) && a.permissions[i] == perm
+}
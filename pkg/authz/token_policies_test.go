@@ -0,0 +1,156 @@
+package authz
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	listTokenEndpoint   = TestEndpoint{"GET", "/me/tokens"}
+	createTokenEndpoint = TestEndpoint{"POST", "/me/tokens"}
+)
+
+func TestPoliciesForTokenPermissions(t *testing.T) {
+	loggedInUser := &domains.UserWithRoles{
+		User: domains.User{Id: 8},
+		OrgRole: map[int64]string{
+			8:  "viewer",
+			10: "owner",
+		},
+	}
+
+	tcs := []struct {
+		name         string
+		tokenPerms   []string
+		authMethod   string
+		requestedOrg *domains.Org
+		hasError     bool
+		endpoint     TestEndpoint
+	}{
+		{
+			"should allow token without permissions restriction to keep role permissions",
+			nil,
+			"",
+			&domains.Org{Id: 10},
+			false,
+			updateOrgEndpoint,
+		},
+		{
+			"should allow token granted a permission of the role",
+			[]string{"read:org"},
+			"",
+			&domains.Org{Id: 8},
+			false,
+			getOrgEndpoint,
+		},
+		{
+			"should deny token not granted the permission even if the role has it",
+			[]string{"read:org"},
+			"",
+			&domains.Org{Id: 10},
+			true,
+			updateOrgEndpoint,
+		},
+		{
+			"should deny token granted a permission which the role does not have",
+			[]string{"update:org"},
+			"",
+			&domains.Org{Id: 8},
+			true,
+			updateOrgEndpoint,
+		},
+		{
+			"should deny token with empty permissions",
+			[]string{},
+			"",
+			&domains.Org{Id: 8},
+			true,
+			getOrgEndpoint,
+		},
+		{
+			"should deny token without token permissions to manage tokens",
+			[]string{"read:org"},
+			"",
+			nil,
+			true,
+			createTokenEndpoint,
+		},
+		{
+			"should allow token granted to list tokens",
+			[]string{"list:token"},
+			"",
+			nil,
+			false,
+			listTokenEndpoint,
+		},
+		{
+			"should deny token without permissions restriction to create tokens",
+			nil,
+			constants.AuthProviderPat,
+			nil,
+			true,
+			createTokenEndpoint,
+		},
+		{
+			"should deny token granted to create tokens",
+			[]string{"create:token"},
+			constants.AuthProviderPat,
+			nil,
+			true,
+			createTokenEndpoint,
+		},
+		{
+			"should allow personal access token granted to list tokens",
+			[]string{"list:token"},
+			constants.AuthProviderPat,
+			nil,
+			false,
+			listTokenEndpoint,
+		},
+		{
+			"should allow user logged in to create tokens",
+			nil,
+			constants.AuthProviderOidc,
+			nil,
+			false,
+			createTokenEndpoint,
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			opts := []CallOPAInputOption{
+				WithInputRequestMethod(tc.endpoint.Method),
+				WithInputRequestEndpoint(tc.endpoint.Endpoint),
+			}
+			if tc.requestedOrg != nil {
+				opts = append(opts, WithInputOrg(tc.requestedOrg))
+			}
+			if tc.tokenPerms != nil {
+				opts = append(opts, WithInputTokenPermissions(tc.tokenPerms))
+			}
+			if tc.authMethod != "" {
+				opts = append(opts, WithInputRequest(RequestAttributes{AuthMethod: tc.authMethod, Time: time.Now()}))
+			}
+
+			_, err := testAuthorizer.Check(context.Background(), loggedInUser, opts...)
+			if tc.hasError {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestIsKnownPermission(t *testing.T) {
+	assert.True(t, testAuthorizer.IsKnownPermission("read:project"))
+	assert.True(t, testAuthorizer.IsKnownPermission("create:token"))
+	assert.False(t, testAuthorizer.IsKnownPermission("fly:project"))
+}
@@ -1,6 +1,7 @@
 package authz
 
 import (
+	"context"
 	"testing"
 
 	"github.com/dzungtran/echo-rest-api/modules/core/domains"
@@ -29,8 +30,9 @@ func TestPoliciesForUserEndpoint(t *testing.T) {
 	}
 
 	superAdmin := &domains.UserWithRoles{
-		User:    domains.User{Id: 99, Email: "hello@iamdzung.com"},
-		OrgRole: map[int64]string{},
+		User:          domains.User{Id: 99, Email: "hello@iamdzung.com"},
+		OrgRole:       map[int64]string{},
+		PlatformRoles: []domains.PlatformRole{domains.PlatformRoleSuperAdmin},
 	}
 
 	tcs := []struct {
@@ -162,7 +164,7 @@ func TestPoliciesForUserEndpoint(t *testing.T) {
 	for _, tc := range tcs {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
-			msg, err := CheckPolicies(tc.loggedInUser,
+			msg, err := testAuthorizer.Check(context.Background(), tc.loggedInUser,
 				WithInputRequestMethod(tc.endpoint.Method),
 				WithInputRequestEndpoint(tc.endpoint.Endpoint),
 				WithInputExtraData("user_info", tc.requestedUser),
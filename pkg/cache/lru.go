@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+	lruStore struct {
+		mu      sync.Mutex
+		size    int
+		items   map[string]*list.Element
+		order   *list.List
+		nowFunc func() time.Time
+	}
+
+	lruEntry struct {
+		key       string
+		val       []byte
+		expiresAt time.Time
+	}
+)
+
+// NewLRUStore will create new an in-process Store keeping at most size keys,
+// the least recently used key is evicted first
+func NewLRUStore(size int) Store {
+	if size <= 0 {
+		size = 1
+	}
+
+	return &lruStore{
+		size:    size,
+		items:   make(map[string]*list.Element, size),
+		order:   list.New(),
+		nowFunc: time.Now,
+	}
+}
+
+func (s *lruStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := el.Value.(*lruEntry)
+	if !s.nowFunc().Before(entry.expiresAt) {
+		s.remove(el)
+		return nil, false, nil
+	}
+
+	s.order.MoveToFront(el)
+	return entry.val, true, nil
+}
+
+func (s *lruStore) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt := s.nowFunc().Add(ttl)
+	if el, ok := s.items[key]; ok {
+		entry := el.Value.(*lruEntry)
+		entry.val = val
+		entry.expiresAt = expiresAt
+		s.order.MoveToFront(el)
+		return nil
+	}
+
+	s.items[key] = s.order.PushFront(&lruEntry{
+		key:       key,
+		val:       val,
+		expiresAt: expiresAt,
+	})
+
+	for s.order.Len() > s.size {
+		s.remove(s.order.Back())
+	}
+	return nil
+}
+
+func (s *lruStore) Delete(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		if el, ok := s.items[key]; ok {
+			s.remove(el)
+		}
+	}
+	return nil
+}
+
+func (s *lruStore) remove(el *list.Element) {
+	s.order.Remove(el)
+	delete(s.items, el.Value.(*lruEntry).key)
+}
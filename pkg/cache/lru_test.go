@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+
+	newStore := func() *lruStore {
+		s := NewLRUStore(2).(*lruStore)
+		s.nowFunc = func() time.Time { return now }
+		return s
+	}
+
+	tcs := []struct {
+		name     string
+		run      func(s *lruStore)
+		key      string
+		expected []byte
+	}{
+		{"should return stored value", func(s *lruStore) {
+			_ = s.Set(ctx, "a", []byte("1"), time.Minute)
+		}, "a", []byte("1")},
+		{"should miss unknown key", func(s *lruStore) {}, "a", nil},
+		{"should evict least recently used key", func(s *lruStore) {
+			_ = s.Set(ctx, "a", []byte("1"), time.Minute)
+			_ = s.Set(ctx, "b", []byte("2"), time.Minute)
+			_, _, _ = s.Get(ctx, "a")
+			_ = s.Set(ctx, "c", []byte("3"), time.Minute)
+		}, "b", nil},
+		{"should keep recently used key", func(s *lruStore) {
+			_ = s.Set(ctx, "a", []byte("1"), time.Minute)
+			_ = s.Set(ctx, "b", []byte("2"), time.Minute)
+			_, _, _ = s.Get(ctx, "a")
+			_ = s.Set(ctx, "c", []byte("3"), time.Minute)
+		}, "a", []byte("1")},
+		{"should miss expired key", func(s *lruStore) {
+			_ = s.Set(ctx, "a", []byte("1"), time.Minute)
+			s.nowFunc = func() time.Time { return now.Add(time.Minute) }
+		}, "a", nil},
+		{"should miss deleted key", func(s *lruStore) {
+			_ = s.Set(ctx, "a", []byte("1"), time.Minute)
+			_ = s.Delete(ctx, "a")
+		}, "a", nil},
+		{"should overwrite value", func(s *lruStore) {
+			_ = s.Set(ctx, "a", []byte("1"), time.Minute)
+			_ = s.Set(ctx, "a", []byte("2"), time.Minute)
+		}, "a", []byte("2")},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newStore()
+			tc.run(s)
+
+			val, ok, err := s.Get(ctx, tc.key)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected != nil, ok)
+			assert.Equal(t, tc.expected, val)
+		})
+	}
+}
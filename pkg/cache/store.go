@@ -0,0 +1,16 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Store is a key value cache with expiry.
+// The in-process LRU is the default, a store shared by all instances (e.g. Redis) can implement it
+// so that invalidations are seen everywhere.
+type Store interface {
+	// Get returns false when the key is missing or expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, val []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
@@ -0,0 +1,12 @@
+package constants
+
+const (
+	AuthProviderFirebase  = "firebase"
+	AuthProviderKratos    = "kratos"
+	AuthProviderOidc      = "oidc"
+	AuthProviderDevHeader = "dev-header"
+	AuthProviderPat       = "pat"
+	AuthProviderLocal     = "local"
+
+	AuthProviderServiceAccount = "service-account"
+)
@@ -4,3 +4,8 @@ const (
 	DefaultPerPage uint64 = 100
 	MaximumPerPage uint64 = 250
 )
+
+const (
+	EnvironmentDevelopment = "development"
+	EnvironmentProduction  = "production"
+)
@@ -2,9 +2,11 @@ package constants
 
 const (
 	ContextKeyUser      = "user"
+	ContextKeyPrincipal = "principal"
 	ContextKeyRequestId = "rid"
 	ContextKeyLogger    = "logger"
 	ContextKeyProject   = "project"
 	ContextKeyOrg       = "org"
 	ContextKeyPayload   = "payload"
+	ContextKeyActor     = "actor"
 )
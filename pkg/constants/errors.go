@@ -8,3 +8,15 @@ var (
 	ErrUnauthorized error = errors.New("unauthorized")
 	ErrForbidden    error = errors.New("forbidden")
 )
+
+const (
+	// ErrorCodeMfaRequired tells the frontend to prompt for a second factor and call `POST /me/mfa/verify`
+	ErrorCodeMfaRequired = "mfa_required"
+	// MessageMfaRequired comes with ErrorCodeMfaRequired
+	MessageMfaRequired = "the org requires a second factor, verify it with POST /me/mfa/verify"
+	// ErrorCodeImpersonationReadOnly is returned for mutating calls while impersonating, see IMPERSONATION_ALLOW_WRITES
+	ErrorCodeImpersonationReadOnly = "impersonation_read_only"
+	// ErrorCodePolicyUnavailable is returned while the remote policy decision point is unavailable and
+	// POLICY_REMOTE_FALLBACK is closed, the request can be retried
+	ErrorCodePolicyUnavailable = "policy_unavailable"
+)
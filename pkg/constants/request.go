@@ -1,7 +1,13 @@
 package constants
 
 const (
-	HeaderXApiKey    = "X-Api-Key"
-	HeaderXUserEmail = "X-User-Email"
-	HeaderXRequestID = "X-Request-Id"
+	HeaderXApiKey       = "X-Api-Key"
+	HeaderXUserEmail    = "X-User-Email"
+	HeaderXRequestID    = "X-Request-Id"
+	HeaderXSessionToken = "X-Session-Token"
+
+	// HeaderXImpersonateUser is sent by super admins with the id or email of the user to act as
+	HeaderXImpersonateUser = "X-Impersonate-User"
+	// HeaderXImpersonatedBy is set on the responses of impersonated requests with the id of the super admin
+	HeaderXImpersonatedBy = "X-Impersonated-By"
 )
@@ -16,6 +16,24 @@ func GetUserFromContext(c echo.Context) (usr *coreDomains.UserWithRoles, err err
 	return
 }
 
+func GetPrincipalFromContext(c echo.Context) (p *coreDomains.Principal, err error) {
+	pCtx := c.Get(constants.ContextKeyPrincipal)
+	p, ok := pCtx.(*coreDomains.Principal)
+	if !ok || p == nil {
+		err = constants.ErrUnauthorized
+	}
+	return
+}
+
+// GetActorFromContext returns the super admin behind an impersonated request, nil when not impersonated
+func GetActorFromContext(c echo.Context) *coreDomains.UserWithRoles {
+	actor := c.Get(constants.ContextKeyActor)
+	if actor != nil {
+		return actor.(*coreDomains.UserWithRoles)
+	}
+	return nil
+}
+
 func GetOrgFromContext(c echo.Context) *coreDomains.Org {
 	org := c.Get(constants.ContextKeyOrg)
 	if org != nil {
@@ -0,0 +1,28 @@
+package definitions
+
+import (
+	"list"
+)
+
+#CheckAuthzRequest: {
+	user_id?:    int & >0
+	user?:       {...}
+	method:      "GET" | "POST" | "PUT" | "PATCH" | "DELETE"
+	endpoint:    =~"^/"
+	org_id?:     int & >0
+	project_id?: int & >0
+	payload?:    {...}
+	token_permissions?: [...=~"^[a-z_]+:[a-z_]+$"] | null
+	trace?: bool
+}
+
+#PermissionCheck: {
+	method:      "GET" | "POST" | "PUT" | "PATCH" | "DELETE"
+	endpoint:    =~"^/"
+	org_id?:     int & >0
+	project_id?: int & >0
+}
+
+#CheckPermissionsRequest: {
+	checks: [...#PermissionCheck] & list.MinItems(1) & list.MaxItems(100)
+}
@@ -0,0 +1,11 @@
+package definitions
+
+import (
+	"strings"
+)
+
+#StartImpersonationRequest: {
+	// Why the support staff needs to impersonate the user, kept in the audit trail
+	reason:        string & strings.MinRunes(3) & strings.MaxRunes(255)
+	allow_writes?: bool
+}
@@ -0,0 +1,20 @@
+package definitions
+
+import (
+	"strings"
+)
+
+_LocalEmail:    string & strings.MaxRunes(255) & =~"(^[a-zA-Z0-9_.+-]+@[a-zA-Z0-9-]+\\.[a-zA-Z0-9-.]+$)"
+_LocalPassword: string & strings.MinRunes(8) & strings.MaxRunes(72)
+
+#LocalSignupRequest: {
+	email:       _LocalEmail
+	password:    _LocalPassword
+	first_name?: string & strings.MaxRunes(100)
+	last_name?:  string & strings.MaxRunes(100)
+}
+
+#LocalResetPasswordRequest: {
+	token:    string & !=""
+	password: _LocalPassword
+}
@@ -1,12 +1,14 @@
 package definitions
 
 import (
+	"list"
 	"strings"
 )
 
 _ASCIIChars:  string & =~"^[\\x00-\\x7F]+$"
 _OrgStatuses: "active" | "inactive"
 _EmailRegex:  =~"(^[a-zA-Z0-9_.+-]+@[a-zA-Z0-9-]+\\.[a-zA-Z0-9-.]+$)"
+_ClockRegex:  =~"^([01][0-9]|2[0-3]):[0-5][0-9]$" | ""
 
 // Org info
 #Org: {
@@ -14,11 +16,16 @@ _EmailRegex:  =~"(^[a-zA-Z0-9_.+-]+@[a-zA-Z0-9-]+\\.[a-zA-Z0-9-.]+$)"
 	// Org name
 	name: _ASCIIChars & !="" & strings.MinRunes(10) & strings.MaxRunes(100)
 	// Generated Code
-	code:         string & !="" & strings.MinRunes(10) & strings.MaxRunes(50)
-	description?: string
-	domain?:      string
-	logo?:        string
-	status:       _OrgStatuses
+	code:               string & !="" & strings.MinRunes(10) & strings.MaxRunes(50)
+	description?:        string
+	domain?:             string
+	logo?:               string
+	status:              _OrgStatuses
+	require_mfa?:        bool
+	timezone?:           string
+	ip_allowlist?:       [...string]
+	access_hours_start?: _ClockRegex
+	access_hours_end?:   _ClockRegex
 
 	created_at?: string
 	updated_at?: string
@@ -35,10 +42,15 @@ _EmailRegex:  =~"(^[a-zA-Z0-9_.+-]+@[a-zA-Z0-9-]+\\.[a-zA-Z0-9-.]+$)"
 #UpdateOrgRequest: {
 	// Org name
 	name:         _ASCIIChars & !="" & strings.MinRunes(10) & strings.MaxRunes(100)
-	description?: string
-	domain?:      string
-	logo?:        string
-	status:       _OrgStatuses
+	description?:        string
+	domain?:             string
+	logo?:               string
+	status:              _OrgStatuses
+	require_mfa?:        bool
+	timezone?:           string & !=""
+	ip_allowlist?:       [...string] & list.MaxItems(50)
+	access_hours_start?: _ClockRegex
+	access_hours_end?:   _ClockRegex
 }
 
 #InviteOrgRequest: {
@@ -0,0 +1,26 @@
+package definitions
+
+import (
+	"strings"
+)
+
+_OrgRolePermission: =~"^[a-z_]+:[a-z_]+$"
+_OrgRoleName:       =~"^[a-z][a-z0-9_-]*$" & strings.MaxRunes(50)
+
+#CreateOrgRoleRequest: {
+	name:         _OrgRoleName
+	description?: string & strings.MaxRunes(255)
+	permissions:  [..._OrgRolePermission] | null
+	// Role whose permissions are inherited, built-in or custom
+	parent?: "" | _OrgRoleName
+}
+
+#UpdateOrgRoleRequest: {
+	description?: string & strings.MaxRunes(255)
+	permissions:  [..._OrgRolePermission] | null
+	parent?:      "" | _OrgRoleName
+}
+
+#UpdateMemberRoleRequest: {
+	role: _OrgRoleName
+}
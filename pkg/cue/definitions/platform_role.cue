@@ -0,0 +1,7 @@
+package definitions
+
+_PlatformRoles: "super_admin" | "support" | "billing_admin"
+
+#GrantPlatformRoleRequest: {
+	role: _PlatformRoles
+}
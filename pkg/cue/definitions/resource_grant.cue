@@ -0,0 +1,8 @@
+package definitions
+
+#CreateResourceGrantRequest: {
+	user_id:       int & >0
+	permission:    =~"^[a-z_]+:[a-z_]+$"
+	resource_type: "org" | "project"
+	resource_id:   int & >0
+}
@@ -0,0 +1,21 @@
+package definitions
+
+import (
+	"strings"
+)
+
+_ServiceAccountRoles:    "manager" | "editor" | "viewer"
+_ServiceAccountStatuses: "active" | "disabled"
+
+#CreateServiceAccountRequest: {
+	// Service account name
+	name: string & !="" & strings.MaxRunes(100)
+	role: _ServiceAccountRoles
+}
+
+#UpdateServiceAccountRequest: {
+	// Service account name
+	name:   string & !="" & strings.MaxRunes(100)
+	role:   _ServiceAccountRoles
+	status: _ServiceAccountStatuses
+}
@@ -0,0 +1,15 @@
+package definitions
+
+import (
+	"strings"
+)
+
+_Permission: =~"^[a-z_]+:[a-z_]+$"
+
+#CreatePersonalAccessTokenRequest: {
+	// Token name
+	name:         string & !="" & strings.MaxRunes(100)
+	permissions?: [..._Permission] | null
+	org_ids?:     [...int & >0] | null
+	expires_at?:  string | null
+}
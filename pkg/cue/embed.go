@@ -13,4 +13,28 @@ var (
 
 	//go:embed definitions/project.cue
 	CueDefinitionForProject string
+
+	//go:embed definitions/token.cue
+	CueDefinitionForToken string
+
+	//go:embed definitions/service_account.cue
+	CueDefinitionForServiceAccount string
+
+	//go:embed definitions/local_auth.cue
+	CueDefinitionForLocalAuth string
+
+	//go:embed definitions/impersonation.cue
+	CueDefinitionForImpersonation string
+
+	//go:embed definitions/platform_role.cue
+	CueDefinitionForPlatformRole string
+
+	//go:embed definitions/org_role.cue
+	CueDefinitionForOrgRole string
+
+	//go:embed definitions/authz.cue
+	CueDefinitionForAuthz string
+
+	//go:embed definitions/resource_grant.cue
+	CueDefinitionForResourceGrant string
 )
@@ -0,0 +1,45 @@
+package subscribers
+
+import (
+	"context"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/hook"
+	"github.com/dzungtran/echo-rest-api/pkg/middlewares"
+)
+
+// PrincipalCacheSubscriber drops the cached principal of a user when the user, its org memberships or its platform roles change
+type PrincipalCacheSubscriber struct {
+	cache *middlewares.PrincipalCache
+}
+
+func NewPrincipalCacheSubscriber(cache *middlewares.PrincipalCache) *PrincipalCacheSubscriber {
+	return &PrincipalCacheSubscriber{
+		cache: cache,
+	}
+}
+
+func (s PrincipalCacheSubscriber) Created(payload hook.EventPayload) {
+	s.invalidate(payload)
+}
+
+func (s PrincipalCacheSubscriber) Deleted(payload hook.EventPayload) {
+	s.invalidate(payload)
+}
+
+func (s PrincipalCacheSubscriber) Updated(payload hook.EventPayload) {
+	s.invalidate(payload)
+}
+
+func (s PrincipalCacheSubscriber) invalidate(payload hook.EventPayload) {
+	for _, p := range []interface{}{payload.PayloadOld, payload.Payload} {
+		switch v := p.(type) {
+		case *domains.User:
+			s.cache.InvalidateUser(context.Background(), v.Id)
+		case *domains.UserOrg:
+			s.cache.InvalidateUser(context.Background(), v.UserId)
+		case *domains.UserPlatformRole:
+			s.cache.InvalidateUser(context.Background(), v.UserId)
+		}
+	}
+}
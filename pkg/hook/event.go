@@ -9,10 +9,16 @@ const (
 	Deleted Event = "deleted"
 
 	// Define scopes
-	UserScope Scope = "user"
+	UserScope    Scope = "user"
+	UserOrgScope Scope = "user_org"
+	// PlatformRoleScope is triggered with a *domains.UserPlatformRole
+	PlatformRoleScope Scope = "platform_role"
 
 	// Event source
 	SourceUserAPI = "user_api"
+	SourceOrgAPI  = "org_api"
+	// SourcePlatformRoleAPI is also used by the PLATFORM_BOOTSTRAP_SUPER_ADMIN seed
+	SourcePlatformRoleAPI = "platform_role_api"
 )
 
 type Scope string
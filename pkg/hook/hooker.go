@@ -5,8 +5,9 @@ import (
 )
 
 type HookerInterface interface {
-	Trigger(event Event, payload interface{})
-	AddSubscriber(scope Scope, subscriber Subscriber)
+	Trigger(payload EventPayload)
+	AddSubscriber(subscriber Subscriber)
+	AddScopedSubscriber(scope Scope, subscriber Subscriber)
 }
 
 type Hooker struct {
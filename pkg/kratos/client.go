@@ -0,0 +1,127 @@
+package kratos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+)
+
+const (
+	whoamiPath = "/sessions/whoami"
+
+	// SessionCookieName is the cookie Kratos sets on browser logins
+	SessionCookieName = "ory_kratos_session"
+)
+
+var (
+	ErrNoSession       = errors.New("kratos: no active session")
+	ErrMissingEndpoint = errors.New("kratos: missing api endpoint")
+)
+
+type (
+	// Client calls the Kratos public API
+	Client struct {
+		endpoint   string
+		httpClient *http.Client
+	}
+
+	Session struct {
+		Id        string    `json:"id"`
+		Active    bool      `json:"active"`
+		ExpiresAt time.Time `json:"expires_at"`
+		Identity  Identity  `json:"identity"`
+	}
+
+	Identity struct {
+		Id     string         `json:"id"`
+		State  string         `json:"state"`
+		Traits IdentityTraits `json:"traits"`
+	}
+
+	// IdentityTraits is the subset of the identity schema we map onto domains.User
+	IdentityTraits struct {
+		Email string `json:"email"`
+		Name  struct {
+			First string `json:"first"`
+			Last  string `json:"last"`
+		} `json:"name"`
+	}
+)
+
+// NewClient will create new a Kratos client for the given public API endpoint
+func NewClient(endpoint string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &Client{
+		endpoint:   strings.TrimRight(endpoint, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// Whoami validates the session cookie or session token against the Kratos whoami endpoint
+func (cli *Client) Whoami(ctx context.Context, cookie, sessionToken string) (*Session, error) {
+	if cli.endpoint == "" {
+		return nil, ErrMissingEndpoint
+	}
+
+	if cookie == "" && sessionToken == "" {
+		return nil, ErrNoSession
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cli.endpoint+whoamiPath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if cookie != "" {
+		req.Header.Set("Cookie", cookie)
+	}
+	if sessionToken != "" {
+		req.Header.Set(constants.HeaderXSessionToken, sessionToken)
+	}
+
+	resp, err := cli.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("kratos: call whoami: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, ErrNoSession
+	default:
+		return nil, fmt.Errorf("kratos: unexpected whoami status %d", resp.StatusCode)
+	}
+
+	session := &Session{}
+	if err = json.NewDecoder(resp.Body).Decode(session); err != nil {
+		return nil, fmt.Errorf("kratos: decode whoami response: %w", err)
+	}
+
+	if !session.Active || session.Identity.Id == "" {
+		return nil, ErrNoSession
+	}
+
+	return session, nil
+}
+
+// ToUser maps the identity traits onto domains.User
+func (i Identity) ToUser() *domains.User {
+	return &domains.User{
+		Code:      i.Id,
+		Email:     i.Traits.Email,
+		FirstName: i.Traits.Name.First,
+		LastName:  i.Traits.Name.Last,
+	}
+}
@@ -0,0 +1,107 @@
+package kratos
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+const testWhoamiBody = `{
+	"id": "b1d5b7c2-session",
+	"active": true,
+	"expires_at": "2030-01-01T00:00:00Z",
+	"identity": {
+		"id": "6f1c1e3a-identity",
+		"state": "active",
+		"traits": {
+			"email": "jane@api.com",
+			"name": {"first": "Jane", "last": "Doe"}
+		}
+	}
+}`
+
+func newTestKratosServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != whoamiPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		cookie, _ := r.Cookie("ory_kratos_session")
+		switch {
+		case cookie != nil && cookie.Value == "valid-cookie",
+			r.Header.Get(constants.HeaderXSessionToken) == "valid-token":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(testWhoamiBody))
+		case r.Header.Get(constants.HeaderXSessionToken) == "inactive-token":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id": "s", "active": false, "identity": {"id": "i"}}`))
+		case r.Header.Get(constants.HeaderXSessionToken) == "broken-token":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+}
+
+func TestClientWhoami(t *testing.T) {
+	srv := newTestKratosServer(t)
+	defer srv.Close()
+
+	cli := NewClient(srv.URL+"/", srv.Client())
+
+	tcs := []struct {
+		name         string
+		cookie       string
+		sessionToken string
+		expectedErr  error
+		hasError     bool
+	}{
+		{"should accept a valid session cookie", "ory_kratos_session=valid-cookie", "", nil, false},
+		{"should accept a valid session token", "", "valid-token", nil, false},
+		{"should reject when no credential is sent", "", "", ErrNoSession, true},
+		{"should reject an unknown session", "", "unknown-token", ErrNoSession, true},
+		{"should reject an inactive session", "", "inactive-token", ErrNoSession, true},
+		{"should return error on kratos failure", "", "broken-token", nil, true},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			session, err := cli.Whoami(context.Background(), tc.cookie, tc.sessionToken)
+			if !tc.hasError {
+				assert.Nil(t, err)
+				assert.Equal(t, "6f1c1e3a-identity", session.Identity.Id)
+				return
+			}
+
+			assert.NotNil(t, err)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestClientWhoamiMissingEndpoint(t *testing.T) {
+	_, err := NewClient("", nil).Whoami(context.Background(), "", "valid-token")
+	assert.ErrorIs(t, err, ErrMissingEndpoint)
+}
+
+func TestIdentityToUser(t *testing.T) {
+	identity := Identity{Id: "6f1c1e3a-identity"}
+	identity.Traits.Email = "jane@api.com"
+	identity.Traits.Name.First = "Jane"
+	identity.Traits.Name.Last = "Doe"
+
+	u := identity.ToUser()
+	assert.Equal(t, "6f1c1e3a-identity", u.Code)
+	assert.Equal(t, "jane@api.com", u.Email)
+	assert.Equal(t, "Jane", u.FirstName)
+	assert.Equal(t, "Doe", u.LastName)
+}
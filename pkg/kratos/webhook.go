@@ -0,0 +1,51 @@
+package kratos
+
+const (
+	// MessageIdValidationGeneric is the Kratos message id for generic validation errors
+	MessageIdValidationGeneric = 4000001
+	// MessageIdUnauthorized is used for rejected webhook calls
+	MessageIdUnauthorized = 4010001
+)
+
+type (
+	// WebhookPayload is the body our Kratos web hooks are configured to send, e.g. `{identity: ctx.identity}`
+	WebhookPayload struct {
+		Identity Identity `json:"identity"`
+	}
+
+	// WebhookErrorResponse follows the format Kratos parses from failed web hook responses
+	WebhookErrorResponse struct {
+		Messages []WebhookMessages `json:"messages"`
+	}
+
+	WebhookMessages struct {
+		InstancePtr string           `json:"instance_ptr"`
+		Messages    []WebhookMessage `json:"messages"`
+	}
+
+	WebhookMessage struct {
+		Id      int                    `json:"id"`
+		Text    string                 `json:"text"`
+		Type    string                 `json:"type"`
+		Context map[string]interface{} `json:"context"`
+	}
+)
+
+// NewWebhookError builds a single error message response for the given json pointer, e.g. `#/traits/email`
+func NewWebhookError(instancePtr string, id int, text string) WebhookErrorResponse {
+	return WebhookErrorResponse{
+		Messages: []WebhookMessages{
+			{
+				InstancePtr: instancePtr,
+				Messages: []WebhookMessage{
+					{
+						Id:      id,
+						Text:    text,
+						Type:    "error",
+						Context: map[string]interface{}{},
+					},
+				},
+			},
+		},
+	}
+}
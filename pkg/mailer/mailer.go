@@ -0,0 +1,58 @@
+package mailer
+
+import (
+	"context"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends transactional emails, e.g. email verification and password reset links
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewMailer sends the emails through SMTP_HOST, they are only logged when it is not set
+func NewMailer(appConf *config.AppConfig) (Mailer, error) {
+	if appConf.SmtpHost == "" {
+		return NewLogMailer(), nil
+	}
+
+	return NewSMTPMailer(SMTPOptions{
+		Host:     appConf.SmtpHost,
+		Port:     appConf.SmtpPort,
+		Username: appConf.SmtpUsername,
+		Password: appConf.SmtpPassword,
+		From:     appConf.SmtpFrom,
+		Timeout:  appConf.SmtpTimeout,
+	})
+}
+
+type logMailer struct{}
+
+// NewLogMailer writes the recipient and the subject of the emails to the log instead of sending them,
+// for local development only. The body carries secret links and is never logged
+func NewLogMailer() Mailer {
+	return &logMailer{}
+}
+
+// IsLogMailer tells whether the emails are only logged
+func IsLogMailer(m Mailer) bool {
+	_, ok := m.(*logMailer)
+	return ok
+}
+
+func (m *logMailer) Send(ctx context.Context, msg Message) error {
+	logger.Log().Infow("email is not sent, no mailer is configured",
+		"to", msg.To,
+		"subject", msg.Subject,
+	)
+	return nil
+}
@@ -0,0 +1,167 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultSMTPTimeout = 10 * time.Second
+
+// ErrInvalidHeader is returned when the recipient or the subject would inject headers
+var ErrInvalidHeader = errors.New("email header must not contain line breaks")
+
+// SMTPOptions configure a SMTP mailer, see SMTP_HOST
+type SMTPOptions struct {
+	Host string
+	// Port is 587 when zero
+	Port     int
+	Username string
+	Password string
+	// From is the sender address, e.g. "Echo <no-reply@example.com>"
+	From string
+	// Timeout of the delivery of an email, 10s when zero
+	Timeout time.Duration
+	// TLSConfig of STARTTLS, the server name is Host when nil
+	TLSConfig *tls.Config
+}
+
+type smtpMailer struct {
+	addr      string
+	host      string
+	auth      smtp.Auth
+	from      *mail.Address
+	timeout   time.Duration
+	tlsConfig *tls.Config
+}
+
+// NewSMTPMailer sends the emails to a SMTP server. The connection is upgraded with STARTTLS when the server
+// offers it, the credentials are only sent over TLS
+func NewSMTPMailer(opts SMTPOptions) (Mailer, error) {
+	if opts.Host == "" {
+		return nil, errors.New("smtp host is required")
+	}
+
+	from, err := mail.ParseAddress(opts.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp sender %q: %w", opts.From, err)
+	}
+
+	m := &smtpMailer{
+		addr:      net.JoinHostPort(opts.Host, strconv.Itoa(opts.Port)),
+		host:      opts.Host,
+		from:      from,
+		timeout:   opts.Timeout,
+		tlsConfig: opts.TLSConfig,
+	}
+	if opts.Port == 0 {
+		m.addr = net.JoinHostPort(opts.Host, "587")
+	}
+	if m.timeout <= 0 {
+		m.timeout = defaultSMTPTimeout
+	}
+	if m.tlsConfig == nil {
+		m.tlsConfig = &tls.Config{ServerName: opts.Host}
+	}
+	if opts.Username != "" {
+		// PlainAuth refuses to send the credentials without TLS, except to localhost
+		m.auth = smtp.PlainAuth("", opts.Username, opts.Password, opts.Host)
+	}
+	return m, nil
+}
+
+func (m *smtpMailer) Send(ctx context.Context, msg Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", msg.To, err)
+	}
+	data, err := m.buildMessage(to, msg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	if err = conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(m.tlsConfig); err != nil {
+			return err
+		}
+	}
+	if m.auth != nil {
+		if err = c.Auth(m.auth); err != nil {
+			return err
+		}
+	}
+	if err = c.Mail(m.from.Address); err != nil {
+		return err
+	}
+	if err = c.Rcpt(to.Address); err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(data); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// buildMessage writes the headers and the plain text body with CRLF line endings
+func (m *smtpMailer) buildMessage(to *mail.Address, msg Message) ([]byte, error) {
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, ErrInvalidHeader
+	}
+
+	var buf bytes.Buffer
+	headers := [][2]string{
+		{"From", m.from.String()},
+		{"To", to.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=utf-8"},
+		{"Content-Transfer-Encoding", "8bit"},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", h[0], h[1])
+	}
+	buf.WriteString("\r\n")
+
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	buf.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return buf.Bytes(), nil
+}
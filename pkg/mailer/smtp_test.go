@@ -0,0 +1,119 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// newSMTPServer accepts one email without TLS nor authentication, the commands and the data are sent on the channel
+func newSMTPServer(t *testing.T) (host string, port int, received chan []string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	received = make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		lines := make([]string, 0)
+		r := bufio.NewReader(conn)
+		reply := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				received <- lines
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+
+			switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+			case "EHLO":
+				reply("250-localhost")
+				reply("250 8BITMIME")
+			case "DATA":
+				reply("354 end data with <CR><LF>.<CR><LF>")
+				for {
+					line, err = r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					lines = append(lines, strings.TrimRight(line, "\r\n"))
+				}
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				received <- lines
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, received
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	host, port, received := newSMTPServer(t)
+	m, err := NewSMTPMailer(SMTPOptions{Host: host, Port: port, From: "Echo <no-reply@example.com>"})
+	assert.Nil(t, err)
+
+	err = m.Send(context.Background(), Message{
+		To:      "ann@example.com",
+		Subject: "Verify your email",
+		Body:    "Open https://example.com/verify?token=secret\nto verify your email",
+	})
+	assert.Nil(t, err)
+
+	lines := <-received
+	assert.Contains(t, lines, "MAIL FROM:<no-reply@example.com> BODY=8BITMIME")
+	assert.Contains(t, lines, "RCPT TO:<ann@example.com>")
+	assert.Contains(t, lines, `From: "Echo" <no-reply@example.com>`)
+	assert.Contains(t, lines, "To: <ann@example.com>")
+	assert.Contains(t, lines, "Subject: Verify your email")
+	assert.Contains(t, lines, "Open https://example.com/verify?token=secret")
+	assert.Contains(t, lines, "to verify your email")
+}
+
+func TestSMTPMailerRejectsHeaderInjection(t *testing.T) {
+	m, err := NewSMTPMailer(SMTPOptions{Host: "127.0.0.1", Port: 1, From: "no-reply@example.com"})
+	assert.Nil(t, err)
+
+	err = m.Send(context.Background(), Message{To: "ann@example.com", Subject: "Hi\r\nBcc: eve@example.com"})
+	assert.ErrorIs(t, err, ErrInvalidHeader)
+
+	err = m.Send(context.Background(), Message{To: "ann@example.com\r\nBcc: eve@example.com", Subject: "Hi"})
+	assert.NotNil(t, err)
+}
+
+func TestNewMailer(t *testing.T) {
+	m, err := NewMailer(&config.AppConfig{})
+	assert.Nil(t, err)
+	assert.True(t, IsLogMailer(m))
+
+	m, err = NewMailer(&config.AppConfig{SmtpHost: "smtp.example.com", SmtpPort: 587, SmtpFrom: "no-reply@example.com"})
+	assert.Nil(t, err)
+	assert.False(t, IsLogMailer(m))
+
+	_, err = NewMailer(&config.AppConfig{SmtpHost: "smtp.example.com", SmtpFrom: "not an address"})
+	assert.NotNil(t, err)
+}
+
+func TestNewSMTPMailerDefaultPort(t *testing.T) {
+	m, err := NewSMTPMailer(SMTPOptions{Host: "smtp.example.com", From: "no-reply@example.com"})
+	assert.Nil(t, err)
+	assert.Equal(t, "smtp.example.com:587", m.(*smtpMailer).addr)
+}
@@ -0,0 +1,134 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/labstack/echo/v4"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+	"go.uber.org/dig"
+)
+
+const (
+	authenticatorsGroup = "authenticators"
+
+	// firebaseIssuerPrefix is followed by the project id in the iss of Firebase ID tokens
+	firebaseIssuerPrefix = "https://securetoken.google.com/"
+)
+
+var (
+	// ErrNoCredentials is returned by an Authenticator when the request does not carry
+	// any credential it understands, the next authenticator of the chain is tried
+	ErrNoCredentials = errors.New("no credentials")
+)
+
+type (
+	// Authenticator resolves the caller of a request.
+	// Modules register their own through dig with ProvideAuthenticator,
+	// they are enabled and ordered by AUTH_PROVIDER, e.g. `AUTH_PROVIDER=oidc,kratos`
+	Authenticator interface {
+		// Name is the value used in AUTH_PROVIDER
+		Name() string
+		// Authenticate returns ErrNoCredentials to skip the request,
+		// errors wrapping constants.ErrUnauthorized are reported as 401
+		Authenticate(c echo.Context) (*domains.Principal, error)
+	}
+
+	// RegisteredAuthenticators collects every Authenticator provided to the container
+	RegisteredAuthenticators struct {
+		dig.In
+		Authenticators []Authenticator `group:"authenticators"`
+	}
+)
+
+// ProvideAuthenticator adds an Authenticator constructor to the container
+func ProvideAuthenticator(container *dig.Container, constructor interface{}) error {
+	return container.Provide(constructor, dig.Group(authenticatorsGroup))
+}
+
+// RegisterAuthenticators provides the built-in authenticators enabled by the app config
+func RegisterAuthenticators(container *dig.Container, appConf *config.AppConfig) error {
+	err := container.Provide(NewPrincipalCache)
+	if err != nil {
+		return err
+	}
+
+	err = container.Provide(NewUserResolver)
+	if err != nil {
+		return err
+	}
+
+	builtIns := map[string]interface{}{
+		constants.AuthProviderFirebase:  NewFirebaseAuthenticator,
+		constants.AuthProviderKratos:    NewKratosAuthenticator,
+		constants.AuthProviderOidc:      NewOidcAuthenticator,
+		constants.AuthProviderDevHeader: NewDevHeaderAuthenticator,
+		constants.AuthProviderPat:       NewPatAuthenticator,
+		constants.AuthProviderLocal:     NewLocalAuthenticator,
+
+		constants.AuthProviderServiceAccount: NewServiceAccountAuthenticator,
+	}
+
+	for _, name := range appConf.AuthProviders {
+		constructor, ok := builtIns[name]
+		if !ok {
+			continue
+		}
+
+		if err = ProvideAuthenticator(container, constructor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildAuthenticatorChain orders the registered authenticators by the configured provider names
+func buildAuthenticatorChain(names []string, authenticators []Authenticator) ([]Authenticator, error) {
+	byName := make(map[string]Authenticator, len(authenticators))
+	for _, a := range authenticators {
+		byName[a.Name()] = a
+	}
+
+	chain := make([]Authenticator, 0, len(names))
+	for _, name := range names {
+		a, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown auth provider %q", name)
+		}
+		chain = append(chain, a)
+	}
+	return chain, nil
+}
+
+func getBearerToken(c echo.Context) string {
+	authHeader := c.Request().Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
+}
+
+// getBearerJWT returns the bearer token when it is shaped like a JWT,
+// so the JWT authenticators skip other bearer tokens such as personal access tokens
+func getBearerJWT(c echo.Context) string {
+	token := getBearerToken(c)
+	if strings.Count(token, ".") != 2 {
+		return ""
+	}
+	return token
+}
+
+// getUnverifiedIssuer reads the iss of a JWT without verifying it, so a JWT authenticator can leave the tokens of
+// other issuers to the next authenticator of the chain
+func getUnverifiedIssuer(rawToken string) string {
+	tkn, err := jwt.ParseInsecure([]byte(rawToken))
+	if err != nil {
+		return ""
+	}
+	iss, _ := tkn.Issuer()
+	return iss
+}
@@ -0,0 +1,149 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/authz"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeAuthenticator struct {
+	name   string
+	header string
+	status domains.UserStatus
+}
+
+func (a fakeAuthenticator) Name() string {
+	return a.name
+}
+
+func (a fakeAuthenticator) Authenticate(c echo.Context) (*domains.Principal, error) {
+	val := c.Request().Header.Get(a.header)
+	switch val {
+	case "":
+		return nil, ErrNoCredentials
+	case "invalid":
+		return nil, fmt.Errorf("%w: invalid %s", constants.ErrUnauthorized, a.name)
+	case "broken":
+		return nil, fmt.Errorf("%s is down", a.name)
+	case "duplicated":
+		return nil, fmt.Errorf("%w: email of %s", constants.ErrDuplicated, a.name)
+	}
+
+	status := a.status
+	if status == "" {
+		status = domains.UserStatusActive
+	}
+
+	return &domains.Principal{
+		Kind:       domains.PrincipalKindUser,
+		Subject:    val,
+		AuthMethod: a.name,
+		User:       &domains.UserWithRoles{User: domains.User{Code: val, Status: status}},
+	}, nil
+}
+
+func TestAuthenticatorChain(t *testing.T) {
+	m, err := NewMiddlewareManager(
+		&config.AppConfig{AuthProviders: []string{"bearer", "apikey"}},
+		nil, nil, nil, nil, nil, nil, nil, nil,
+		RegisteredAuthenticators{Authenticators: []Authenticator{
+			fakeAuthenticator{name: "apikey", header: "X-Api-Key"},
+			fakeAuthenticator{name: "bearer", header: "Authorization"},
+			fakeAuthenticator{name: "unused", header: "X-Unused"},
+		}},
+		authz.AllowAll(),
+	)
+	assert.Nil(t, err)
+
+	tcs := []struct {
+		name           string
+		headers        map[string]string
+		expectedStatus int
+		expectedMethod string
+	}{
+		{"should use the first authenticator", map[string]string{"Authorization": "u1", "X-Api-Key": "u2"}, http.StatusOK, "bearer"},
+		{"should fall through to the next authenticator", map[string]string{"X-Api-Key": "u2"}, http.StatusOK, "apikey"},
+		{"should not try the next authenticator on invalid credentials", map[string]string{"Authorization": "invalid", "X-Api-Key": "u2"}, http.StatusUnauthorized, ""},
+		{"should reject without credentials", map[string]string{}, http.StatusUnauthorized, ""},
+		{"should ignore authenticators not enabled", map[string]string{"X-Unused": "u3"}, http.StatusUnauthorized, ""},
+		{"should return error when authenticator fails", map[string]string{"Authorization": "broken"}, http.StatusInternalServerError, ""},
+		{"should return conflict when the identity collides with another user", map[string]string{"Authorization": "duplicated"}, http.StatusConflict, ""},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			var principal *domains.Principal
+			err := m.Auth()(func(c echo.Context) error {
+				principal, _ = contexts.GetPrincipalFromContext(c)
+				return c.NoContent(http.StatusOK)
+			})(c)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.NotContains(t, rec.Body.String(), "is down")
+			if tc.expectedMethod != "" {
+				assert.Equal(t, tc.expectedMethod, principal.AuthMethod)
+			}
+		})
+	}
+}
+
+func TestAuthenticatorChainRejectsInactiveUser(t *testing.T) {
+	m, err := NewMiddlewareManager(
+		&config.AppConfig{AuthProviders: []string{"bearer"}},
+		nil, nil, nil, nil, nil, nil, nil, nil,
+		RegisteredAuthenticators{Authenticators: []Authenticator{
+			fakeAuthenticator{name: "bearer", header: "Authorization", status: domains.UserStatusBanned},
+		}},
+		authz.AllowAll(),
+	)
+	assert.Nil(t, err)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "u1")
+	rec := httptest.NewRecorder()
+
+	err = m.Auth()(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})(e.NewContext(req, rec))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestBuildAuthenticatorChainUnknownProvider(t *testing.T) {
+	_, err := buildAuthenticatorChain([]string{"missing"}, []Authenticator{
+		fakeAuthenticator{name: "bearer"},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestNewDevHeaderAuthenticatorRefusesProduction(t *testing.T) {
+	_, err := NewDevHeaderAuthenticator(&config.AppConfig{Environment: constants.EnvironmentProduction}, nil)
+	assert.ErrorIs(t, err, ErrDevHeaderAuthInProduction)
+
+	a, err := NewDevHeaderAuthenticator(&config.AppConfig{Environment: constants.EnvironmentDevelopment}, nil)
+	assert.Nil(t, err)
+
+	e := echo.New()
+	_, err = a.Authenticate(e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder()))
+	assert.ErrorIs(t, err, ErrNoCredentials)
+}
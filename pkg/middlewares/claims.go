@@ -0,0 +1,75 @@
+package middlewares
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+)
+
+// ClaimMapping tells which token claims become user fields, nested claims use dots, e.g. `name.first`
+type ClaimMapping struct {
+	Code      string
+	Email     string
+	FirstName string
+	LastName  string
+}
+
+var firebaseClaimMapping = ClaimMapping{
+	Code:      "user_id",
+	Email:     "email",
+	FirstName: "name",
+}
+
+func parseTokenClaimsToUser(tknClaims map[string]interface{}, mapping ClaimMapping) (u *domains.User, err error) {
+	u = &domains.User{}
+	if u.Code, err = getStringClaim(tknClaims, mapping.Code, true); err != nil {
+		return nil, err
+	}
+
+	if u.Email, err = getStringClaim(tknClaims, mapping.Email, true); err != nil {
+		return nil, err
+	}
+
+	if u.FirstName, err = getStringClaim(tknClaims, mapping.FirstName, false); err != nil {
+		return nil, err
+	}
+
+	if u.LastName, err = getStringClaim(tknClaims, mapping.LastName, false); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func getStringClaim(tknClaims map[string]interface{}, name string, required bool) (string, error) {
+	if name == "" {
+		if required {
+			return "", fmt.Errorf("%w: claim mapping is missing", constants.ErrUnauthorized)
+		}
+		return "", nil
+	}
+
+	var val interface{} = tknClaims
+	for _, key := range strings.Split(name, ".") {
+		obj, ok := val.(map[string]interface{})
+		if !ok {
+			val = nil
+			break
+		}
+		val = obj[key]
+	}
+
+	if val == nil {
+		if required {
+			return "", fmt.Errorf("%w: missing claim %s", constants.ErrUnauthorized, name)
+		}
+		return "", nil
+	}
+
+	str, ok := val.(string)
+	if !ok || (required && str == "") {
+		return "", fmt.Errorf("%w: invalid claim %s", constants.ErrUnauthorized, name)
+	}
+	return str, nil
+}
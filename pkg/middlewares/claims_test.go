@@ -0,0 +1,75 @@
+package middlewares
+
+import (
+	"testing"
+
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTokenClaimsToUser(t *testing.T) {
+	mapping := ClaimMapping{
+		Code:      "sub",
+		Email:     "email",
+		FirstName: "name.first",
+		LastName:  "name.last",
+	}
+
+	tcs := []struct {
+		name      string
+		claims    map[string]interface{}
+		hasError  bool
+		firstName string
+	}{
+		{
+			"should map all claims",
+			map[string]interface{}{
+				"sub":   "user-123",
+				"email": "jane@api.com",
+				"name":  map[string]interface{}{"first": "Jane", "last": "Doe"},
+			},
+			false,
+			"Jane",
+		},
+		{
+			"should allow missing optional claims",
+			map[string]interface{}{"sub": "user-123", "email": "jane@api.com"},
+			false,
+			"",
+		},
+		{
+			"should reject missing code claim",
+			map[string]interface{}{"email": "jane@api.com"},
+			true,
+			"",
+		},
+		{
+			"should reject ill-typed email claim",
+			map[string]interface{}{"sub": "user-123", "email": 42},
+			true,
+			"",
+		},
+		{
+			"should reject ill-typed optional claim",
+			map[string]interface{}{"sub": "user-123", "email": "jane@api.com", "name": map[string]interface{}{"first": true}},
+			true,
+			"",
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := parseTokenClaimsToUser(tc.claims, mapping)
+			if tc.hasError {
+				assert.ErrorIs(t, err, constants.ErrUnauthorized)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, "user-123", u.Code)
+			assert.Equal(t, "jane@api.com", u.Email)
+			assert.Equal(t, tc.firstName, u.FirstName)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	ErrDevHeaderAuthInProduction = errors.New("dev-header auth provider must not be enabled in production")
+)
+
+type devHeaderAuthenticator struct {
+	autoCreate bool
+	resolver   *UserResolver
+}
+
+// NewDevHeaderAuthenticator trusts the X-User-Email header, it is meant for the local stack and integration tests only
+func NewDevHeaderAuthenticator(appConf *config.AppConfig, resolver *UserResolver) (Authenticator, error) {
+	if appConf.Environment == constants.EnvironmentProduction {
+		return nil, ErrDevHeaderAuthInProduction
+	}
+
+	return &devHeaderAuthenticator{
+		autoCreate: appConf.DevHeaderAutoCreate,
+		resolver:   resolver,
+	}, nil
+}
+
+func (a *devHeaderAuthenticator) Name() string {
+	return constants.AuthProviderDevHeader
+}
+
+func (a *devHeaderAuthenticator) Authenticate(c echo.Context) (*domains.Principal, error) {
+	email := strings.TrimSpace(c.Request().Header.Get(constants.HeaderXUserEmail))
+	if email == "" {
+		return nil, ErrNoCredentials
+	}
+
+	logger.Log().Warnw("request authenticated with development header",
+		"email", strings.ReplaceAll(email, "\n", ""),
+		"method", c.Request().Method,
+		"path", c.Path(),
+	)
+
+	ctx := c.Request().Context()
+	u, err := a.resolver.FetchUser(ctx, "", email)
+	if errors.Is(err, constants.ErrNotFound) {
+		if !a.autoCreate {
+			return nil, fmt.Errorf("%w: cannot fetch user", constants.ErrUnauthorized)
+		}
+
+		u, err = a.resolver.FetchOrRegisterUser(ctx, &domains.User{
+			Code:      utils.GenerateLongUUID(),
+			Email:     email,
+			FirstName: strings.Split(email, "@")[0],
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &domains.Principal{
+		Kind:       domains.PrincipalKindUser,
+		Subject:    email,
+		AuthMethod: a.Name(),
+		User:       u,
+	}, nil
+}
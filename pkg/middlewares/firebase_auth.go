@@ -2,83 +2,68 @@ package middlewares
 
 import (
 	"errors"
-	"net/http"
+	"fmt"
 	"strings"
 
+	"github.com/dzungtran/echo-rest-api/config"
 	"github.com/dzungtran/echo-rest-api/modules/core/domains"
-	"github.com/dzungtran/echo-rest-api/modules/core/dto"
 	"github.com/dzungtran/echo-rest-api/pkg/constants"
 	"github.com/labstack/echo/v4"
 )
 
-func (m *MiddlewareManager) FireBaseAuth(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		cli, err := m.appConf.FirebaseApp.Auth(c.Request().Context())
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-				"error": err.Error(),
-			})
-		}
-
-		idToken := strings.Replace(c.Request().Header.Get("Authorization"), "Bearer ", "", 1)
-		tkn, err := cli.VerifyIDToken(c.Request().Context(), idToken)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-				"error": err.Error(),
-			})
-		}
+type firebaseAuthenticator struct {
+	appConf  *config.AppConfig
+	resolver *UserResolver
+}
 
-		tknUser := parseTokenClaimsToUser(tkn.Claims)
-		var u *domains.UserWithRoles
+// NewFirebaseAuthenticator verifies Firebase ID tokens sent as bearer tokens
+func NewFirebaseAuthenticator(appConf *config.AppConfig, resolver *UserResolver) Authenticator {
+	return &firebaseAuthenticator{
+		appConf:  appConf,
+		resolver: resolver,
+	}
+}
 
-		// Get user info
-		u, err = m.fetchUserFromAuth(c.Request().Context(), tknUser.Code, tknUser.Email)
-		if err != nil {
-			if errors.Is(err, constants.ErrNotFound) {
-				_, err = m.userUC.Register(c.Request().Context(), dto.CreateUserReq{
-					FirstName: tknUser.FirstName,
-					Email:     tknUser.Email,
-					Code:      tknUser.Code,
-				})
+func (a *firebaseAuthenticator) Name() string {
+	return constants.AuthProviderFirebase
+}
 
-				if err != nil {
-					return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-						"error": err.Error(),
-					})
-				}
+func (a *firebaseAuthenticator) Authenticate(c echo.Context) (*domains.Principal, error) {
+	idToken := getBearerJWT(c)
+	if idToken == "" || !strings.HasPrefix(getUnverifiedIssuer(idToken), firebaseIssuerPrefix) {
+		return nil, ErrNoCredentials
+	}
 
-				u, err = m.fetchUserFromAuth(c.Request().Context(), tknUser.Code, tknUser.Email)
-				if err != nil {
-					return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-						"error": err.Error(),
-					})
-				}
-			} else {
-				return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-					"error": err.Error(),
-				})
-			}
-		}
+	if a.appConf.FirebaseApp == nil {
+		return nil, errors.New("firebase is not configured")
+	}
 
-		if u.Status != domains.UserStatusActive {
-			return c.JSON(http.StatusUnauthorized, map[string]interface{}{
-				"error": "user is not active",
-			})
-		}
+	ctx := c.Request().Context()
+	cli, err := a.appConf.FirebaseApp.Auth(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-		c.Set(constants.ContextKeyUser, u)
-		return next(c)
+	tkn, err := cli.VerifyIDToken(ctx, idToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", constants.ErrUnauthorized, err)
 	}
-}
 
-func parseTokenClaimsToUser(tknClaims map[string]interface{}) *domains.User {
-	userExtId := tknClaims["user_id"].(string)
-	userEmail := tknClaims["email"].(string)
-	name := tknClaims["name"].(string)
+	tknUser, err := parseTokenClaimsToUser(tkn.Claims, firebaseClaimMapping)
+	if err != nil {
+		return nil, err
+	}
 
-	return &domains.User{
-		Code:      userExtId,
-		Email:     userEmail,
-		FirstName: name,
+	// Get user info
+	u, err := a.resolver.FetchOrRegisterUser(ctx, tknUser)
+	if err != nil {
+		return nil, err
 	}
+
+	return &domains.Principal{
+		Kind:       domains.PrincipalKindUser,
+		Subject:    tknUser.Code,
+		AuthMethod: a.Name(),
+		User:       u,
+	}, nil
 }
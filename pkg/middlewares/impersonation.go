@@ -0,0 +1,188 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	impersonationAuditUriMaxSize = 2048
+)
+
+// impersonate swaps the principal for the user impersonated by a super admin, from the X-Impersonate-User header
+// or the running impersonation of the actor. The principal is returned unchanged when not impersonating
+func (m MiddlewareManager) impersonate(c echo.Context, principal *domains.Principal) (*domains.Principal, *domains.Impersonation, error) {
+	header := strings.TrimSpace(c.Request().Header.Get(constants.HeaderXImpersonateUser))
+
+	// the impersonation endpoints always act as the super admin, so an impersonation can be stopped
+	if isImpersonationEndpoint(c.Path()) {
+		return principal, nil, nil
+	}
+
+	if principal.Kind != domains.PrincipalKindUser || !m.authorizer.IsSuperAdmin(c.Request().Context(), principal.User) {
+		if header != "" {
+			return nil, nil, usecases.ErrImpersonationNotAllowed
+		}
+		return principal, nil, nil
+	}
+
+	var (
+		ctx           = c.Request().Context()
+		impersonation *domains.Impersonation
+		target        *domains.UserWithRoles
+		err           error
+	)
+
+	if header != "" {
+		target, err = m.fetchImpersonationTarget(ctx, header)
+	} else {
+		impersonation, err = m.impersonationUC.GetActive(ctx, principal.User.Id)
+		if errors.Is(err, constants.ErrNotFound) {
+			return principal, nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		target, err = m.resolver.FetchUserByID(ctx, impersonation.TargetUserId)
+	}
+
+	if errors.Is(err, constants.ErrNotFound) {
+		return nil, nil, fmt.Errorf("%w: unknown user", usecases.ErrImpersonationNotAllowed)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err = m.impersonationUC.ValidateTarget(ctx, principal.User.Id, target); err != nil {
+		return nil, nil, err
+	}
+
+	impersonated := impersonatedPrincipal(principal, target)
+	if impersonation != nil {
+		impersonated.ImpersonationId = impersonation.Id
+	}
+	return impersonated, impersonation, nil
+}
+
+// impersonatedPrincipal serves the request as the target. The session belongs to the actor, so the impersonated
+// principal has none and calls on the current session (e.g. POST /me/mfa/verify) are not made on the actor's one.
+// The second factor stays the one verified by the actor, orgs requiring one are reached once the super admin stepped up
+func impersonatedPrincipal(actor *domains.Principal, target *domains.UserWithRoles) *domains.Principal {
+	impersonated := *actor
+	impersonated.User = target
+	impersonated.Actor = actor.User
+	impersonated.SessionId = 0
+	impersonated.MfaVerifiedAt = actor.MfaVerifiedAt
+	return &impersonated
+}
+
+// serveImpersonated flags the response, blocks mutating calls unless allowed and writes the audit trail
+func (m MiddlewareManager) serveImpersonated(c echo.Context, principal *domains.Principal, impersonation *domains.Impersonation, next echo.HandlerFunc) error {
+	c.Response().Header().Set(constants.HeaderXImpersonatedBy, strconv.FormatInt(principal.Actor.Id, 10))
+
+	var err error
+	if isReadOnlyMethod(c.Request().Method) || m.impersonationAllowsWrites(impersonation) {
+		err = next(c)
+	} else {
+		err = c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error": "mutating calls are blocked while impersonating",
+			"code":  constants.ErrorCodeImpersonationReadOnly,
+		})
+	}
+
+	m.auditImpersonation(c, principal, err)
+	return err
+}
+
+func (m MiddlewareManager) impersonationAllowsWrites(impersonation *domains.Impersonation) bool {
+	if !m.appConf.ImpersonationAllowWrites {
+		return false
+	}
+	return impersonation == nil || impersonation.AllowWrites
+}
+
+func (m MiddlewareManager) auditImpersonation(c echo.Context, principal *domains.Principal, handlerErr error) {
+	status := c.Response().Status
+	if handlerErr != nil {
+		status = http.StatusInternalServerError
+		var httpErr *echo.HTTPError
+		if errors.As(handlerErr, &httpErr) {
+			status = httpErr.Code
+		}
+	}
+
+	log := &domains.ImpersonationAuditLog{
+		ActorUserId:  principal.Actor.Id,
+		TargetUserId: principal.User.Id,
+		Method:       c.Request().Method,
+		Endpoint:     c.Path(),
+		Uri:          truncateString(c.Request().RequestURI, impersonationAuditUriMaxSize),
+		Status:       status,
+		IpAddress:    c.RealIP(),
+		RequestId:    c.Response().Header().Get(echo.HeaderXRequestID),
+	}
+	if principal.ImpersonationId > 0 {
+		log.ImpersonationId = &principal.ImpersonationId
+	}
+
+	// the trail is written even when the client went away
+	ctx := context.WithoutCancel(c.Request().Context())
+	if err := m.impersonationUC.Audit(ctx, log); err != nil {
+		logger.Log().Errorw("cannot write impersonation audit log", "actor_id", log.ActorUserId,
+			"target_id", log.TargetUserId, "endpoint", log.Endpoint, "error", err)
+	}
+}
+
+// fetchImpersonationTarget resolves the X-Impersonate-User header, a user id or an email
+func (m MiddlewareManager) fetchImpersonationTarget(ctx context.Context, header string) (*domains.UserWithRoles, error) {
+	id, email := parseImpersonationTarget(header)
+	if id > 0 {
+		return m.resolver.FetchUserByID(ctx, id)
+	}
+	if email != "" {
+		return m.resolver.FetchUser(ctx, "", email)
+	}
+	return nil, constants.ErrNotFound
+}
+
+func parseImpersonationTarget(header string) (id int64, email string) {
+	if strings.Contains(header, "@") {
+		return 0, header
+	}
+
+	id, err := strconv.ParseInt(header, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ""
+	}
+	return id, ""
+}
+
+func isImpersonationEndpoint(path string) bool {
+	return strings.HasPrefix(path, "/admin/impersonation") ||
+		path == "/admin/users/:userId/impersonation"
+}
+
+func isReadOnlyMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func truncateString(s string, size int) string {
+	if len(s) <= size {
+		return s
+	}
+	return s[:size]
+}
@@ -0,0 +1,63 @@
+package middlewares
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseImpersonationTarget(t *testing.T) {
+	tcs := []struct {
+		name          string
+		header        string
+		expectedId    int64
+		expectedEmail string
+	}{
+		{"should parse user id", "42", 42, ""},
+		{"should parse email", "user@email.com", 0, "user@email.com"},
+		{"should ignore negative id", "-1", 0, ""},
+		{"should ignore garbage", "abc", 0, ""},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			id, email := parseImpersonationTarget(tc.header)
+			assert.Equal(t, tc.expectedId, id)
+			assert.Equal(t, tc.expectedEmail, email)
+		})
+	}
+}
+
+func TestIsImpersonationEndpoint(t *testing.T) {
+	assert.True(t, isImpersonationEndpoint("/admin/impersonation"))
+	assert.True(t, isImpersonationEndpoint("/admin/impersonation/logs"))
+	assert.True(t, isImpersonationEndpoint("/admin/users/:userId/impersonation"))
+	assert.False(t, isImpersonationEndpoint("/admin/users/:userId"))
+	assert.False(t, isImpersonationEndpoint("/me"))
+}
+
+func TestIsReadOnlyMethod(t *testing.T) {
+	assert.True(t, isReadOnlyMethod(http.MethodGet))
+	assert.True(t, isReadOnlyMethod(http.MethodHead))
+	assert.True(t, isReadOnlyMethod(http.MethodOptions))
+	assert.False(t, isReadOnlyMethod(http.MethodPost))
+	assert.False(t, isReadOnlyMethod(http.MethodDelete))
+}
+
+func TestImpersonatedPrincipal(t *testing.T) {
+	verifiedAt := time.Now()
+	admin := &domains.UserWithRoles{User: domains.User{Id: 1}}
+	target := &domains.UserWithRoles{User: domains.User{Id: 2}}
+	actor := &domains.Principal{Kind: domains.PrincipalKindUser, User: admin, SessionId: 7, MfaVerifiedAt: &verifiedAt}
+
+	impersonated := impersonatedPrincipal(actor, target)
+	assert.Equal(t, target, impersonated.User)
+	assert.Equal(t, admin, impersonated.Actor)
+	assert.Zero(t, impersonated.SessionId)
+	assert.Equal(t, &verifiedAt, impersonated.MfaVerifiedAt)
+	assert.Equal(t, int64(7), actor.SessionId)
+}
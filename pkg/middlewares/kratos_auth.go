@@ -0,0 +1,79 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/kratos"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+type kratosAuthenticator struct {
+	kratosCli *kratos.Client
+	resolver  *UserResolver
+}
+
+// NewKratosAuthenticator validates the ory_kratos_session cookie or X-Session-Token against Ory Kratos,
+// other cookies are left to the next authenticators
+func NewKratosAuthenticator(appConf *config.AppConfig, resolver *UserResolver) Authenticator {
+	return &kratosAuthenticator{
+		kratosCli: kratos.NewClient(appConf.KratosApiEndpoint, nil),
+		resolver:  resolver,
+	}
+}
+
+func (a *kratosAuthenticator) Name() string {
+	return constants.AuthProviderKratos
+}
+
+func (a *kratosAuthenticator) Authenticate(c echo.Context) (*domains.Principal, error) {
+	// only the Kratos session cookie is forwarded, other cookies of the domain are not Kratos credentials
+	cookie := ""
+	if sessionCookie, err := c.Cookie(kratos.SessionCookieName); err == nil && sessionCookie.Value != "" {
+		cookie = (&http.Cookie{Name: sessionCookie.Name, Value: sessionCookie.Value}).String()
+	}
+	sessionToken := c.Request().Header.Get(constants.HeaderXSessionToken)
+	if cookie == "" && sessionToken == "" {
+		return nil, ErrNoCredentials
+	}
+
+	ctx := c.Request().Context()
+	session, err := a.kratosCli.Whoami(ctx, cookie, sessionToken)
+	if err != nil {
+		if errors.Is(err, kratos.ErrNoSession) {
+			return nil, fmt.Errorf("%w: invalid session", constants.ErrUnauthorized)
+		}
+		return nil, err
+	}
+
+	// Get user info
+	u, err := a.resolver.FetchOrRegisterUser(ctx, session.Identity.ToUser())
+	if err != nil {
+		return nil, err
+	}
+
+	return &domains.Principal{
+		Kind:       domains.PrincipalKindUser,
+		Subject:    session.Identity.Id,
+		AuthMethod: a.Name(),
+		User:       u,
+	}, nil
+}
+
+// KratosWebhookAuth verifies the X-Api-Key header sent by Kratos web hooks
+func (m *MiddlewareManager) KratosWebhookAuth() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			apiKey := c.Request().Header.Get(constants.HeaderXApiKey)
+			if m.appConf.KratosWebhookApiKey == "" || !utils.IsSecureEqual(apiKey, m.appConf.KratosWebhookApiKey) {
+				return c.JSON(http.StatusUnauthorized, kratos.NewWebhookError("#", kratos.MessageIdUnauthorized, "invalid api key"))
+			}
+			return next(c)
+		}
+	}
+}
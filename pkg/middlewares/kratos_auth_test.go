@@ -0,0 +1,91 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/kratos"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKratosWebhookAuth(t *testing.T) {
+	tcs := []struct {
+		name           string
+		configuredKey  string
+		apiKey         string
+		expectedStatus int
+	}{
+		{"should pass with the configured key", "secret-key", "secret-key", http.StatusOK},
+		{"should reject another key", "secret-key", "other-key", http.StatusUnauthorized},
+		{"should reject without key", "secret-key", "", http.StatusUnauthorized},
+		{"should reject every call when no key is configured", "", "", http.StatusUnauthorized},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			m := &MiddlewareManager{appConf: &config.AppConfig{KratosWebhookApiKey: tc.configuredKey}}
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/hooks/kratos/identity", nil)
+			if tc.apiKey != "" {
+				req.Header.Set(constants.HeaderXApiKey, tc.apiKey)
+			}
+			rec := httptest.NewRecorder()
+
+			err := m.KratosWebhookAuth()(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})(e.NewContext(req, rec))
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestKratosAuthenticatorCredentials(t *testing.T) {
+	tcs := []struct {
+		name           string
+		cookie         string
+		sessionToken   string
+		expectedErr    error
+		expectedCookie string
+	}{
+		{"should skip without credentials", "", "", ErrNoCredentials, ""},
+		{"should skip unrelated cookies", "_ga=GA1.1; theme=dark", "", ErrNoCredentials, ""},
+		{"should forward only the session cookie", "_ga=GA1.1; ory_kratos_session=abc", "", constants.ErrUnauthorized, "ory_kratos_session=abc"},
+		{"should forward the session token", "theme=dark", "token", constants.ErrUnauthorized, ""},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			called := false
+			receivedCookie := ""
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				receivedCookie = r.Header.Get("Cookie")
+				w.WriteHeader(http.StatusUnauthorized)
+			}))
+			defer srv.Close()
+
+			a := &kratosAuthenticator{kratosCli: kratos.NewClient(srv.URL, nil)}
+
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if tc.cookie != "" {
+				req.Header.Set("Cookie", tc.cookie)
+			}
+			if tc.sessionToken != "" {
+				req.Header.Set(constants.HeaderXSessionToken, tc.sessionToken)
+			}
+
+			_, err := a.Authenticate(echo.New().NewContext(req, httptest.NewRecorder()))
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Equal(t, tc.expectedErr != ErrNoCredentials, called)
+			assert.Equal(t, tc.expectedCookie, receivedCookie)
+		})
+	}
+}
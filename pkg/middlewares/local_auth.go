@@ -0,0 +1,64 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/labstack/echo/v4"
+)
+
+type localAuthenticator struct {
+	localAuthUC usecases.LocalAuthUsecase
+	resolver    *UserResolver
+}
+
+// NewLocalAuthenticator accepts the access tokens issued by the built-in identity provider on `POST /auth/login`
+func NewLocalAuthenticator(localAuthUC usecases.LocalAuthUsecase, resolver *UserResolver) Authenticator {
+	return &localAuthenticator{
+		localAuthUC: localAuthUC,
+		resolver:    resolver,
+	}
+}
+
+func (a *localAuthenticator) Name() string {
+	return constants.AuthProviderLocal
+}
+
+func (a *localAuthenticator) Authenticate(c echo.Context) (*domains.Principal, error) {
+	rawToken := getBearerJWT(c)
+	if rawToken == "" {
+		return nil, ErrNoCredentials
+	}
+
+	ctx := c.Request().Context()
+	tkn, err := a.localAuthUC.VerifyAccessToken(ctx, rawToken)
+	if err != nil {
+		if errors.Is(err, usecases.ErrNotLocalToken) {
+			return nil, ErrNoCredentials
+		}
+		if errors.Is(err, constants.ErrUnauthorized) {
+			return nil, fmt.Errorf("%w: invalid token", constants.ErrUnauthorized)
+		}
+		return nil, err
+	}
+
+	u, err := a.resolver.FetchUser(ctx, tkn.UserCode, "")
+	if errors.Is(err, constants.ErrNotFound) {
+		return nil, fmt.Errorf("%w: cannot fetch user", constants.ErrUnauthorized)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &domains.Principal{
+		Kind:          domains.PrincipalKindUser,
+		Subject:       tkn.UserCode,
+		AuthMethod:    a.Name(),
+		User:          u,
+		SessionId:     tkn.SessionId,
+		MfaVerifiedAt: tkn.MfaVerifiedAt,
+	}, nil
+}
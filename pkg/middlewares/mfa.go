@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/labstack/echo/v4"
+)
+
+// isMfaRequired tells whether the caller has to verify a second factor before accessing the org,
+// see domains.Principal.IsMfaVerified
+func isMfaRequired(org *domains.Org, principal *domains.Principal, ttl time.Duration, now time.Time) bool {
+	return org != nil && org.RequireMfa && !principal.IsMfaVerified(ttl, now)
+}
+
+// mfaRequired answers 403 with ErrorCodeMfaRequired, the frontend prompts for a code and retries
+func mfaRequired(c echo.Context) error {
+	return c.JSON(http.StatusForbidden, map[string]interface{}{
+		"error": constants.MessageMfaRequired,
+		"code":  constants.ErrorCodeMfaRequired,
+	})
+}
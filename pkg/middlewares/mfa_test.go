@@ -0,0 +1,46 @@
+package middlewares
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsMfaRequired(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	ttl := time.Hour
+	verified := now.Add(-time.Minute)
+	stale := now.Add(-2 * time.Hour)
+
+	requiring := &domains.Org{Id: 1, RequireMfa: true}
+	notRequiring := &domains.Org{Id: 1}
+
+	tcs := []struct {
+		name      string
+		org       *domains.Org
+		principal *domains.Principal
+		expected  bool
+	}{
+		{"should not require mfa when org does not", notRequiring,
+			&domains.Principal{Kind: domains.PrincipalKindUser}, false},
+		{"should require mfa for unverified session", requiring,
+			&domains.Principal{Kind: domains.PrincipalKindUser, SessionId: 1}, true},
+		{"should accept recently verified session", requiring,
+			&domains.Principal{Kind: domains.PrincipalKindUser, SessionId: 1, MfaVerifiedAt: &verified}, false},
+		{"should require mfa again after step-up ttl", requiring,
+			&domains.Principal{Kind: domains.PrincipalKindUser, SessionId: 1, MfaVerifiedAt: &stale}, true},
+		{"should require mfa for personal access token", requiring,
+			&domains.Principal{Kind: domains.PrincipalKindApiKey}, true},
+		{"should not require mfa for service account", requiring,
+			&domains.Principal{Kind: domains.PrincipalKindServiceAccount}, false},
+		{"should require mfa without principal", requiring, nil, true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, isMfaRequired(tc.org, tc.principal, ttl, now))
+		})
+	}
+}
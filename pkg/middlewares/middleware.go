@@ -1,9 +1,10 @@
 package middlewares
 
 import (
-	"context"
+	"errors"
 	"net/http"
 	"reflect"
+	"time"
 
 	"github.com/dzungtran/echo-rest-api/config"
 	"github.com/dzungtran/echo-rest-api/modules/core/domains"
@@ -13,6 +14,7 @@ import (
 	"github.com/dzungtran/echo-rest-api/pkg/authz"
 	"github.com/dzungtran/echo-rest-api/pkg/constants"
 	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
 	"github.com/dzungtran/echo-rest-api/pkg/utils"
 	"github.com/labstack/echo/v4"
 )
@@ -26,8 +28,14 @@ type MiddlewareManager struct {
 	userOrgRepo coreRepo.UserOrgRepository
 	orgRepo     coreRepo.OrgRepository
 	projectRepo projectRepo.ProjectRepository
+	grantRepo   coreRepo.ResourceGrantRepository
 
-	userUC usecases.UserUsecase
+	userUC          usecases.UserUsecase
+	impersonationUC usecases.ImpersonationUsecase
+
+	resolver       *UserResolver
+	authenticators []Authenticator
+	authorizer     authz.Authorizer
 }
 
 // NewMiddlewareManager will create new an MiddlewareManager object
@@ -38,66 +46,114 @@ func NewMiddlewareManager(
 	userOrgRepo coreRepo.UserOrgRepository,
 	orgRepo coreRepo.OrgRepository,
 	projectRepo projectRepo.ProjectRepository,
+	grantRepo coreRepo.ResourceGrantRepository,
 
 	userUC usecases.UserUsecase,
-) *MiddlewareManager {
-	return &MiddlewareManager{
-		appConf:     appConf,
-		userRepo:    userRepo,
-		userOrgRepo: userOrgRepo,
-		orgRepo:     orgRepo,
-		userUC:      userUC,
+	impersonationUC usecases.ImpersonationUsecase,
+	resolver *UserResolver,
+	registered RegisteredAuthenticators,
+	authorizer authz.Authorizer,
+) (*MiddlewareManager, error) {
+	chain, err := buildAuthenticatorChain(appConf.AuthProviders, registered.Authenticators)
+	if err != nil {
+		return nil, err
 	}
+
+	return &MiddlewareManager{
+		appConf:         appConf,
+		userRepo:        userRepo,
+		userOrgRepo:     userOrgRepo,
+		orgRepo:         orgRepo,
+		projectRepo:     projectRepo,
+		grantRepo:       grantRepo,
+		userUC:          userUC,
+		impersonationUC: impersonationUC,
+		resolver:        resolver,
+		authenticators:  chain,
+		authorizer:      authorizer,
+	}, nil
 }
 
+// Auth tries the configured authenticators in order and stores the resolved principal
 func (m MiddlewareManager) Auth() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal, err := m.authenticate(c)
+			if err != nil {
+				if errors.Is(err, constants.ErrUnauthorized) || errors.Is(err, ErrNoCredentials) {
+					return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+						"error": err.Error(),
+					})
+				}
+
+				// e.g. the email of a new identity already belongs to another user
+				if errors.Is(err, constants.ErrDuplicated) {
+					logger.Log().Warnw("identity conflicts with an existing user", "error", err)
+					return c.JSON(http.StatusConflict, map[string]interface{}{
+						"error": "the identity conflicts with an existing user",
+					})
+				}
+
+				// the error may come from an identity provider or the database, it is not shown to the caller
+				logger.Log().Errorw("error while authenticate request", "error", err)
+				return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"error": "failed to authenticate the request",
+				})
+			}
+
+			if principal.User == nil || principal.User.Status != domains.UserStatusActive {
+				return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+					"error": "user is not active",
+				})
+			}
+
+			principal, impersonation, err := m.impersonate(c, principal)
+			if err != nil {
+				if errors.Is(err, usecases.ErrImpersonationNotAllowed) {
+					return c.JSON(http.StatusForbidden, map[string]interface{}{
+						"error": err.Error(),
+					})
+				}
+
+				logger.Log().Errorw("error while impersonate user", "error", err)
+				return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"error": "failed to impersonate the user",
+				})
+			}
+
+			c.Set(constants.ContextKeyPrincipal, principal)
+			c.Set(constants.ContextKeyUser, principal.User)
+			if principal.IsImpersonated() {
+				c.Set(constants.ContextKeyActor, principal.Actor)
+				return m.serveImpersonated(c, principal, impersonation, next)
+			}
+			return next(c)
+		}
+	}
+}
+
+func (m MiddlewareManager) authenticate(c echo.Context) (*domains.Principal, error) {
+	for _, a := range m.authenticators {
+		principal, err := a.Authenticate(c)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
 
-		switch m.appConf.AuthProvider {
-		default:
-			// Default auth method
-			return m.FireBaseAuth(next)
+		if err != nil {
+			return nil, err
 		}
 
-		// Default auth here for local debug and development
-		// Bypass auth
-
-		// return func(c echo.Context) error {
-		// 	ctx := c.Request().Context()
-		// 	email := c.Request().Header.Get(constants.HeaderXUserEmail)
-
-		// 	u, err := m.fetchUserFromAuth(ctx, "", email)
-		// 	if err != nil {
-		// 		logger.Log().Errorw("error while fetch user for auth", "email", strings.ReplaceAll(email, "\n", ""), "error", err)
-		// 		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
-		// 			"error": "cannot fetch user",
-		// 		})
-		// 	}
-
-		// 	if u.Status != domains.UserStatusActive {
-		// 		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
-		// 			"error": "user is not active",
-		// 		})
-		// 	}
-
-		// 	c.Set(constants.ContextKeyUser, u)
-		// 	return next(c)
-		// }
+		return principal, nil
 	}
+	return nil, ErrNoCredentials
 }
 
 func (m MiddlewareManager) CheckPolicies() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			denyMsg, err := authz.CheckPoliciesContext(c)
+			denyMsg, err := authz.CheckContext(m.authorizer, c)
 			if err != nil {
-				msg := ""
-				if len(denyMsg) > 0 {
-					msg = denyMsg[0]
-				}
-				return c.JSON(http.StatusForbidden, map[string]interface{}{
-					"error": msg,
-				})
+				return policyDenied(c, denyMsg, err, "")
 			}
 			return next(c)
 		}
@@ -120,17 +176,23 @@ func (m MiddlewareManager) CheckPoliciesWithOrg() echo.MiddlewareFunc {
 				})
 			}
 
-			denyMsg, err := authz.CheckPoliciesContext(c, authz.WithInputOrg(org))
+			grantOpts, err := m.resourceGrantOptions(c, domains.ResourceTypeOrg, org.Id)
 			if err != nil {
-				msg := ""
-				if len(denyMsg) > 0 {
-					msg = denyMsg[0]
-				}
-				return c.JSON(http.StatusForbidden, map[string]interface{}{
-					"error": msg,
+				return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"error": err.Error(),
 				})
 			}
 
+			denyMsg, err := authz.CheckContext(m.authorizer, c, append(grantOpts, authz.WithInputOrg(org))...)
+			if err != nil {
+				return policyDenied(c, denyMsg, err, "")
+			}
+
+			principal, _ := contexts.GetPrincipalFromContext(c)
+			if isMfaRequired(org, principal, m.appConf.MfaStepUpTTL, time.Now()) {
+				return mfaRequired(c)
+			}
+
 			c.Set(constants.ContextKeyOrg, org)
 			return next(c)
 		}
@@ -153,16 +215,33 @@ func (m MiddlewareManager) CheckPoliciesWithProject() echo.MiddlewareFunc {
 				})
 			}
 
-			denyMsg, err := authz.CheckPoliciesContext(c, authz.WithInputExtraData("project", project))
+			grantOpts, err := m.resourceGrantOptions(c, domains.ResourceTypeProject, project.Id)
 			if err != nil {
-				msg := ""
-				if len(denyMsg) > 0 {
-					msg = denyMsg[0]
-				}
-				return c.JSON(http.StatusForbidden, map[string]interface{}{
-					"error": msg,
+				return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+
+			// the access settings of the org apply to its projects
+			org, err := m.orgRepo.GetByID(c.Request().Context(), project.OrgId)
+			if err != nil && err != constants.ErrNotFound {
+				return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+					"error": err.Error(),
 				})
 			}
+			if org != nil {
+				grantOpts = append(grantOpts, authz.WithInputOrg(org))
+			}
+
+			denyMsg, err := authz.CheckContext(m.authorizer, c, append(grantOpts, authz.WithInputExtraData("project", project))...)
+			if err != nil {
+				return policyDenied(c, denyMsg, err, "")
+			}
+
+			principal, _ := contexts.GetPrincipalFromContext(c)
+			if isMfaRequired(org, principal, m.appConf.MfaStepUpTTL, time.Now()) {
+				return mfaRequired(c)
+			}
 
 			c.Set(constants.ContextKeyProject, project)
 			return next(c)
@@ -170,6 +249,46 @@ func (m MiddlewareManager) CheckPoliciesWithProject() echo.MiddlewareFunc {
 	}
 }
 
+// policyDenied answers 403 with the first deny message of the policies, msg without any. A request denied because
+// the remote policy decision point is unavailable is told apart with ErrorCodePolicyUnavailable
+func policyDenied(c echo.Context, denyMsg []string, err error, msg string) error {
+	if errors.Is(err, authz.ErrRemotePolicyUnavailable) {
+		return c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error": authz.RemotePolicyUnavailableMessage,
+			"code":  constants.ErrorCodePolicyUnavailable,
+		})
+	}
+
+	if len(denyMsg) > 0 {
+		msg = denyMsg[0]
+	}
+	return c.JSON(http.StatusForbidden, map[string]interface{}{
+		"error": msg,
+	})
+}
+
+// resourceGrantOptions passes the permissions granted to the current user on the resource to the policies
+func (m MiddlewareManager) resourceGrantOptions(c echo.Context, resourceType domains.ResourceType, id int64) ([]authz.CallOPAInputOption, error) {
+	u, err := contexts.GetUserFromContext(c)
+	if err != nil || m.grantRepo == nil {
+		return nil, nil
+	}
+
+	grants, err := m.grantRepo.Fetch(c.Request().Context(), coreRepo.ParamsForFetchResourceGrants{
+		UserId:       u.Id,
+		ResourceType: resourceType,
+		ResourceId:   id,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return []authz.CallOPAInputOption{
+		authz.WithInputResource(resourceType, id),
+		authz.WithInputResourcePermissions(authz.ResourceGrantPermissions(grants)),
+	}, nil
+}
+
 // NOTES: everywhen we call this function, we should pass a new instance of payloadInst to avoid race condition
 func (m MiddlewareManager) CheckPoliciesWithRequestPayload(payloadInst interface{}) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -192,59 +311,12 @@ func (m MiddlewareManager) CheckPoliciesWithRequestPayload(payloadInst interface
 				payloadInst = currPayload
 			}
 
-			denyMsg, err := authz.CheckPoliciesContext(c, authz.WithInputExtraData("payload", payloadInst))
+			denyMsg, err := authz.CheckContext(m.authorizer, c, authz.WithInputExtraData("payload", payloadInst))
 			if err != nil {
-				msg := err.Error()
-				if len(denyMsg) > 0 {
-					msg = denyMsg[0]
-				}
-				return c.JSON(http.StatusForbidden, map[string]interface{}{
-					"error": msg,
-				})
+				return policyDenied(c, denyMsg, err, err.Error())
 			}
 
 			return next(c)
 		}
 	}
 }
-
-func (m *MiddlewareManager) fetchUserFromAuth(ctx context.Context, code, email string) (u *domains.UserWithRoles, err error) {
-	var user *domains.User
-	if code != "" {
-		user, err = m.userRepo.GetByCode(ctx, code)
-		if err != nil {
-			return
-		}
-	} else if email != "" {
-		user, err = m.userRepo.GetByEmail(ctx, email)
-		if err != nil {
-			return
-		}
-	} else {
-		return nil, constants.ErrUnauthorized
-	}
-
-	if user == nil {
-		return nil, constants.ErrUnauthorized
-	}
-
-	u = &domains.UserWithRoles{
-		User:    *user,
-		OrgRole: map[int64]string{},
-	}
-
-	userOrgs, _, err := m.userOrgRepo.Fetch(ctx, coreRepo.ParamsForFetchUserOrgs{
-		CommonParamsForFetch: contexts.CommonParamsForFetch{
-			NoLimit: true,
-		},
-		UserIds: []int64{user.Id},
-	})
-	if err != nil {
-		return
-	}
-
-	for _, uo := range userOrgs {
-		u.OrgRole[uo.OrgId] = string(uo.Role)
-	}
-	return
-}
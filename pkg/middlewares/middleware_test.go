@@ -0,0 +1,141 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	coreRepo "github.com/dzungtran/echo-rest-api/modules/core/repositories"
+	projectDomains "github.com/dzungtran/echo-rest-api/modules/projects/domains"
+	projectRepo "github.com/dzungtran/echo-rest-api/modules/projects/repositories"
+	"github.com/dzungtran/echo-rest-api/pkg/authz"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckPoliciesAuthorizer(t *testing.T) {
+	// the policy decision point is down, the remote authorizer fails closed
+	pdp := httptest.NewServer(http.NotFoundHandler())
+	pdp.Close()
+	unavailable, err := authz.NewRemoteAuthorizer(authz.RemoteAuthorizerOptions{Url: pdp.URL})
+	assert.Nil(t, err)
+
+	tcs := []struct {
+		name           string
+		authorizer     authz.Authorizer
+		withUser       bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"should pass when authorizer allows", authz.AllowAll(), true, http.StatusOK, ""},
+		{"should forbid when authorizer denies", authz.DenyAll(), true, http.StatusForbidden, `{"error":""}`},
+		{"should forbid without user", authz.AllowAll(), false, http.StatusForbidden, `{"error":""}`},
+		{"should tell the policy decision point is unavailable", unavailable, true, http.StatusForbidden,
+			`{"error":"` + authz.RemotePolicyUnavailableMessage + `","code":"` + constants.ErrorCodePolicyUnavailable + `"}`},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := NewMiddlewareManager(
+				&config.AppConfig{},
+				nil, nil, nil, nil, nil, nil, nil, nil,
+				RegisteredAuthenticators{},
+				tc.authorizer,
+			)
+			assert.Nil(t, err)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			if tc.withUser {
+				c.Set(constants.ContextKeyUser, &domains.UserWithRoles{User: domains.User{Id: 1}})
+			}
+
+			err = m.CheckPolicies()(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})(c)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+type fakeOrgRepository struct {
+	coreRepo.OrgRepository
+	org *domains.Org
+}
+
+func (r fakeOrgRepository) GetByID(ctx context.Context, id int64) (*domains.Org, error) {
+	return r.org, nil
+}
+
+type fakeProjectRepository struct {
+	projectRepo.ProjectRepository
+	project *projectDomains.Project
+}
+
+func (r fakeProjectRepository) GetByID(ctx context.Context, id int64) (*projectDomains.Project, error) {
+	return r.project, nil
+}
+
+func TestCheckPoliciesWithProjectMfa(t *testing.T) {
+	verifiedAt := time.Now()
+	tcs := []struct {
+		name           string
+		org            *domains.Org
+		principal      *domains.Principal
+		expectedStatus int
+	}{
+		{"should pass when the org does not require mfa", &domains.Org{Id: 9},
+			&domains.Principal{Kind: domains.PrincipalKindUser}, http.StatusOK},
+		{"should require mfa for an unverified session", &domains.Org{Id: 9, RequireMfa: true},
+			&domains.Principal{Kind: domains.PrincipalKindUser}, http.StatusForbidden},
+		{"should pass a verified session", &domains.Org{Id: 9, RequireMfa: true},
+			&domains.Principal{Kind: domains.PrincipalKindUser, MfaVerifiedAt: &verifiedAt}, http.StatusOK},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := NewMiddlewareManager(
+				&config.AppConfig{MfaStepUpTTL: time.Hour},
+				nil, nil,
+				fakeOrgRepository{org: tc.org},
+				fakeProjectRepository{project: &projectDomains.Project{Id: 3, OrgId: tc.org.Id}},
+				nil, nil, nil, nil,
+				RegisteredAuthenticators{},
+				authz.AllowAll(),
+			)
+			assert.Nil(t, err)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/admin/projects/3", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("projectId")
+			c.SetParamValues("3")
+			tc.principal.User = &domains.UserWithRoles{User: domains.User{Id: 1}}
+			c.Set(constants.ContextKeyPrincipal, tc.principal)
+			c.Set(constants.ContextKeyUser, tc.principal.User)
+
+			err = m.CheckPoliciesWithProject()(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})(c)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			if tc.expectedStatus == http.StatusForbidden {
+				assert.Contains(t, rec.Body.String(), constants.ErrorCodeMfaRequired)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/dzungtran/echo-rest-api/pkg/oidc"
+	"github.com/labstack/echo/v4"
+)
+
+// ErrOidcNotConfigured is returned when the oidc provider is enabled without OIDC_ISSUER or OIDC_AUDIENCE
+var ErrOidcNotConfigured = errors.New("OIDC_ISSUER and OIDC_AUDIENCE are required by the oidc auth provider")
+
+type oidcAuthenticator struct {
+	issuer       string
+	verifier     *oidc.Verifier
+	claimMapping ClaimMapping
+	resolver     *UserResolver
+}
+
+// NewOidcAuthenticator verifies RS256/ES256 bearer tokens against the configured JWKS
+func NewOidcAuthenticator(appConf *config.AppConfig, resolver *UserResolver) (Authenticator, error) {
+	if appConf.OidcIssuer == "" || appConf.OidcAudience == "" {
+		return nil, ErrOidcNotConfigured
+	}
+
+	verifier, err := oidc.NewVerifier(oidc.Config{
+		Issuer:   appConf.OidcIssuer,
+		Audience: appConf.OidcAudience,
+		JwksURL:  appConf.OidcJwksURL,
+		JwksFile: appConf.OidcJwksFile,
+		CacheTTL: appConf.OidcJwksCacheTTL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &oidcAuthenticator{
+		issuer:   appConf.OidcIssuer,
+		verifier: verifier,
+		claimMapping: ClaimMapping{
+			Code:      appConf.OidcClaimCode,
+			Email:     appConf.OidcClaimEmail,
+			FirstName: appConf.OidcClaimFirstName,
+			LastName:  appConf.OidcClaimLastName,
+		},
+		resolver: resolver,
+	}, nil
+}
+
+func (a *oidcAuthenticator) Name() string {
+	return constants.AuthProviderOidc
+}
+
+func (a *oidcAuthenticator) Authenticate(c echo.Context) (*domains.Principal, error) {
+	rawToken := getBearerJWT(c)
+	if rawToken == "" || getUnverifiedIssuer(rawToken) != a.issuer {
+		return nil, ErrNoCredentials
+	}
+
+	ctx := c.Request().Context()
+	claims, err := a.verifier.Verify(ctx, rawToken)
+	if err != nil {
+		if errors.Is(err, oidc.ErrInvalidToken) {
+			logger.Log().Debugw("invalid oidc token", "error", err)
+			return nil, fmt.Errorf("%w: invalid token", constants.ErrUnauthorized)
+		}
+		return nil, err
+	}
+
+	tknUser, err := parseTokenClaimsToUser(claims, a.claimMapping)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get user info
+	u, err := a.resolver.FetchOrRegisterUser(ctx, tknUser)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domains.Principal{
+		Kind:       domains.PrincipalKindUser,
+		Subject:    tknUser.Code,
+		AuthMethod: a.Name(),
+		User:       u,
+	}, nil
+}
@@ -0,0 +1,154 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/authz"
+	"github.com/dzungtran/echo-rest-api/pkg/cache"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+	"github.com/dzungtran/echo-rest-api/pkg/oidc"
+	"github.com/labstack/echo/v4"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOidcAuthenticatorRequiresIssuerAndAudience(t *testing.T) {
+	tcs := []struct {
+		name     string
+		issuer   string
+		audience string
+	}{
+		{"should refuse without issuer", "", "echo-rest-api"},
+		{"should refuse without audience", "https://idp.example.com/", ""},
+		{"should refuse without both", "", ""},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewOidcAuthenticator(&config.AppConfig{
+				OidcIssuer:   tc.issuer,
+				OidcAudience: tc.audience,
+				OidcJwksURL:  "https://idp.example.com/jwks",
+			}, nil)
+			assert.ErrorIs(t, err, ErrOidcNotConfigured)
+		})
+	}
+}
+
+// fakeLocalAuthUsecase verifies the access tokens of its own issuer like the built-in identity provider
+type fakeLocalAuthUsecase struct {
+	usecases.LocalAuthUsecase
+	issuer   string
+	verifier *oidc.Verifier
+}
+
+func (u *fakeLocalAuthUsecase) VerifyAccessToken(ctx context.Context, rawToken string) (*usecases.LocalAccessToken, error) {
+	if getUnverifiedIssuer(rawToken) != u.issuer {
+		return nil, usecases.ErrNotLocalToken
+	}
+	claims, err := u.verifier.Verify(ctx, rawToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", constants.ErrUnauthorized, err)
+	}
+	sub, _ := claims["sub"].(string)
+	return &usecases.LocalAccessToken{UserCode: sub, SessionId: 1}, nil
+}
+
+func signChainTestToken(t *testing.T, signer *oidc.Signer, issuer, subject string) string {
+	tkn, err := jwt.NewBuilder().
+		Issuer(issuer).
+		Subject(subject).
+		Claim("email", subject+"@example.com").
+		Audience([]string{"echo-rest-api"}).
+		Expiration(time.Now().Add(time.Minute)).
+		Build()
+	assert.Nil(t, err)
+	signed, err := signer.Sign(tkn)
+	assert.Nil(t, err)
+	return string(signed)
+}
+
+func TestAuthenticatorChainWithJWTProviders(t *testing.T) {
+	const (
+		oidcIssuer  = "https://idp.example.com/"
+		localIssuer = "https://api.example.com/"
+	)
+
+	oidcSigner, err := oidc.NewEphemeralSigner()
+	assert.Nil(t, err)
+	localSigner, err := oidc.NewEphemeralSigner()
+	assert.Nil(t, err)
+	otherSigner, err := oidc.NewEphemeralSigner()
+	assert.Nil(t, err)
+
+	oidcVerifier, err := oidc.NewVerifier(oidc.Config{Issuer: oidcIssuer, Audience: "echo-rest-api", KeySet: oidcSigner.PublicKeySet()})
+	assert.Nil(t, err)
+	localVerifier, err := oidc.NewVerifier(oidc.Config{Issuer: localIssuer, KeySet: localSigner.PublicKeySet()})
+	assert.Nil(t, err)
+
+	// the users are resolved from the cache, no repository is needed
+	conf := &config.AppConfig{AuthProviders: []string{"oidc", "local"}, PrincipalCacheTTL: time.Minute}
+	principalCache := NewPrincipalCache(PrincipalCacheParams{AppConf: conf, Store: cache.NewLRUStore(10)})
+	for i, code := range []string{"oidc-user", "local-user"} {
+		gen, _ := principalCache.Generation(context.Background(), int64(i+1))
+		principalCache.Set(context.Background(), "code:"+code, gen, &domains.UserWithRoles{
+			User: domains.User{Id: int64(i + 1), Code: code, Status: domains.UserStatusActive},
+			Kind: domains.PrincipalKindUser,
+		})
+	}
+	resolver := NewUserResolver(nil, nil, nil, nil, principalCache)
+
+	m, err := NewMiddlewareManager(conf, nil, nil, nil, nil, nil, nil, nil, resolver,
+		RegisteredAuthenticators{Authenticators: []Authenticator{
+			&oidcAuthenticator{issuer: oidcIssuer, verifier: oidcVerifier, claimMapping: ClaimMapping{Code: "sub", Email: "email"}, resolver: resolver},
+			NewLocalAuthenticator(&fakeLocalAuthUsecase{issuer: localIssuer, verifier: localVerifier}, resolver),
+		}},
+		authz.AllowAll(),
+	)
+	assert.Nil(t, err)
+
+	tcs := []struct {
+		name           string
+		token          string
+		expectedStatus int
+		expectedMethod string
+	}{
+		{"should accept token of the first provider", signChainTestToken(t, oidcSigner, oidcIssuer, "oidc-user"), http.StatusOK, "oidc"},
+		{"should leave token of another issuer to the next provider", signChainTestToken(t, localSigner, localIssuer, "local-user"), http.StatusOK, "local"},
+		{"should reject forged token of the first provider", signChainTestToken(t, otherSigner, oidcIssuer, "oidc-user"), http.StatusUnauthorized, ""},
+		{"should reject forged token of the next provider", signChainTestToken(t, otherSigner, localIssuer, "local-user"), http.StatusUnauthorized, ""},
+		{"should reject token of unknown issuer", signChainTestToken(t, otherSigner, "https://evil.example.com/", "oidc-user"), http.StatusUnauthorized, ""},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			rec := httptest.NewRecorder()
+
+			var principal *domains.Principal
+			err := m.Auth()(func(c echo.Context) error {
+				principal, _ = contexts.GetPrincipalFromContext(c)
+				return c.NoContent(http.StatusOK)
+			})(e.NewContext(req, rec))
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			if tc.expectedMethod != "" && assert.NotNil(t, principal) {
+				assert.Equal(t, tc.expectedMethod, principal.AuthMethod)
+			}
+		})
+	}
+}
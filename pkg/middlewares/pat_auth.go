@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/labstack/echo/v4"
+)
+
+type patAuthenticator struct {
+	tokenUC  usecases.PersonalAccessTokenUsecase
+	resolver *UserResolver
+}
+
+// NewPatAuthenticator accepts personal access tokens sent as `Authorization: Bearer pat_...` or X-Api-Key
+func NewPatAuthenticator(
+	tokenUC usecases.PersonalAccessTokenUsecase,
+	resolver *UserResolver,
+) Authenticator {
+	return &patAuthenticator{
+		tokenUC:  tokenUC,
+		resolver: resolver,
+	}
+}
+
+func (a *patAuthenticator) Name() string {
+	return constants.AuthProviderPat
+}
+
+func (a *patAuthenticator) Authenticate(c echo.Context) (*domains.Principal, error) {
+	rawToken := getBearerToken(c)
+	if !strings.HasPrefix(rawToken, domains.PersonalAccessTokenPrefix) {
+		rawToken = c.Request().Header.Get(constants.HeaderXApiKey)
+	}
+
+	if !strings.HasPrefix(rawToken, domains.PersonalAccessTokenPrefix) {
+		return nil, ErrNoCredentials
+	}
+
+	ctx := c.Request().Context()
+	token, err := a.tokenUC.Authenticate(ctx, rawToken)
+	if err != nil {
+		if errors.Is(err, constants.ErrUnauthorized) {
+			return nil, fmt.Errorf("%w: invalid token", constants.ErrUnauthorized)
+		}
+		return nil, err
+	}
+
+	u, err := a.resolver.FetchUserByID(ctx, token.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	principal := &domains.Principal{
+		Kind:       domains.PrincipalKindApiKey,
+		Subject:    strconv.FormatInt(token.Id, 10),
+		AuthMethod: a.Name(),
+		User:       token.Restrict(u),
+	}
+
+	if len(token.Permissions) > 0 {
+		principal.Permissions = token.Permissions
+	}
+	return principal, nil
+}
@@ -0,0 +1,142 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/pkg/cache"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+	"github.com/dzungtran/echo-rest-api/pkg/utils"
+	"go.uber.org/dig"
+)
+
+const (
+	principalCacheKeyPrefix = "principal:"
+	principalCacheGenPrefix = "principal:gen:"
+)
+
+type (
+	// PrincipalCache keeps the resolved UserWithRoles between requests, keyed by auth subject.
+	// Every user has a generation key, entries of an older generation are ignored,
+	// so InvalidateUser drops all the entries of a user whatever the subject they were cached under.
+	PrincipalCache struct {
+		store cache.Store
+		conf  *config.AppConfig
+	}
+
+	// PrincipalCacheParams lets a module provide a shared cache.Store, the in-process LRU is used otherwise
+	PrincipalCacheParams struct {
+		dig.In
+		AppConf *config.AppConfig
+		Store   cache.Store `optional:"true"`
+	}
+
+	principalCacheEntry struct {
+		Gen  string                 `json:"gen"`
+		User *domains.UserWithRoles `json:"user"`
+	}
+)
+
+// NewPrincipalCache will create new a PrincipalCache, a zero PRINCIPAL_CACHE_TTL disables it
+func NewPrincipalCache(params PrincipalCacheParams) *PrincipalCache {
+	store := params.Store
+	if store == nil {
+		store = cache.NewLRUStore(params.AppConf.PrincipalCacheSize)
+	}
+
+	return &PrincipalCache{
+		store: store,
+		conf:  params.AppConf,
+	}
+}
+
+func (pc *PrincipalCache) enabled() bool {
+	return pc != nil && pc.conf.PrincipalCacheTTL > 0
+}
+
+// Get returns the cached user of the subject, cache errors are reported as misses
+func (pc *PrincipalCache) Get(ctx context.Context, subject string) (*domains.UserWithRoles, bool) {
+	if !pc.enabled() {
+		return nil, false
+	}
+
+	raw, ok, err := pc.store.Get(ctx, principalCacheKeyPrefix+subject)
+	if err != nil || !ok {
+		return nil, false
+	}
+
+	entry := principalCacheEntry{}
+	if err = json.Unmarshal(raw, &entry); err != nil || entry.User == nil {
+		return nil, false
+	}
+
+	gen, ok, err := pc.store.Get(ctx, generationKey(entry.User.Id))
+	if err != nil || !ok || string(gen) != entry.Gen {
+		return nil, false
+	}
+	return entry.User, true
+}
+
+// Generation returns the current generation of the user, starting one when there is none.
+// Read it before loading the user and pass it to Set, so that a load racing with InvalidateUser is not cached
+func (pc *PrincipalCache) Generation(ctx context.Context, userId int64) (string, bool) {
+	if !pc.enabled() {
+		return "", false
+	}
+
+	genKey := generationKey(userId)
+	gen, ok, err := pc.store.Get(ctx, genKey)
+	if err != nil {
+		return "", false
+	}
+	if ok {
+		return string(gen), true
+	}
+
+	gen = []byte(utils.GenerateUUID())
+	if err = pc.store.Set(ctx, genKey, gen, pc.conf.PrincipalCacheTTL); err != nil {
+		logger.Log().Warnw("cannot cache principal generation", "user_id", userId, "error", err)
+		return "", false
+	}
+	return string(gen), true
+}
+
+// Set caches the user under the subject, unless the generation read before loading it
+// changed or disappeared in between, i.e. the user was invalidated while it was loaded
+func (pc *PrincipalCache) Set(ctx context.Context, subject, gen string, u *domains.UserWithRoles) {
+	if !pc.enabled() || u == nil || gen == "" {
+		return
+	}
+
+	current, ok, err := pc.store.Get(ctx, generationKey(u.Id))
+	if err != nil || !ok || string(current) != gen {
+		return
+	}
+
+	raw, err := json.Marshal(principalCacheEntry{Gen: gen, User: u})
+	if err != nil {
+		return
+	}
+
+	if err = pc.store.Set(ctx, principalCacheKeyPrefix+subject, raw, pc.conf.PrincipalCacheTTL); err != nil {
+		logger.Log().Warnw("cannot cache principal", "user_id", u.Id, "error", err)
+	}
+}
+
+// InvalidateUser drops every cached entry of the user, e.g. when its status or org membership changes
+func (pc *PrincipalCache) InvalidateUser(ctx context.Context, userId int64) {
+	if !pc.enabled() {
+		return
+	}
+
+	if err := pc.store.Delete(ctx, generationKey(userId)); err != nil {
+		logger.Log().Errorw("cannot invalidate cached principal", "user_id", userId, "error", err)
+	}
+}
+
+func generationKey(userId int64) string {
+	return principalCacheGenPrefix + strconv.FormatInt(userId, 10)
+}
@@ -0,0 +1,84 @@
+package middlewares
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrincipalCache(t *testing.T) {
+	ctx := context.Background()
+	jane := &domains.UserWithRoles{
+		User:    domains.User{Id: 1, Code: "jane", Status: domains.UserStatusActive},
+		Kind:    domains.PrincipalKindUser,
+		OrgRole: map[int64]string{9: "owner"},
+	}
+	john := &domains.UserWithRoles{
+		User: domains.User{Id: 2, Code: "john"},
+	}
+	set := func(pc *PrincipalCache, subject string, u *domains.UserWithRoles) {
+		gen, _ := pc.Generation(ctx, u.Id)
+		pc.Set(ctx, subject, gen, u)
+	}
+
+	tcs := []struct {
+		name     string
+		ttl      time.Duration
+		run      func(pc *PrincipalCache)
+		subject  string
+		expected *domains.UserWithRoles
+	}{
+		{"should return cached user", time.Minute, func(pc *PrincipalCache) {
+			set(pc, "code:jane", jane)
+		}, "code:jane", jane},
+		{"should miss unknown subject", time.Minute, func(pc *PrincipalCache) {
+			set(pc, "code:jane", jane)
+		}, "code:john", nil},
+		{"should miss when disabled", 0, func(pc *PrincipalCache) {
+			set(pc, "code:jane", jane)
+		}, "code:jane", nil},
+		{"should drop every subject of an invalidated user", time.Minute, func(pc *PrincipalCache) {
+			set(pc, "code:jane", jane)
+			set(pc, "id:1", jane)
+			pc.InvalidateUser(ctx, 1)
+		}, "id:1", nil},
+		{"should keep other users on invalidation", time.Minute, func(pc *PrincipalCache) {
+			set(pc, "code:jane", jane)
+			set(pc, "code:john", john)
+			pc.InvalidateUser(ctx, 1)
+		}, "code:john", john},
+		{"should cache again after invalidation", time.Minute, func(pc *PrincipalCache) {
+			set(pc, "code:jane", jane)
+			pc.InvalidateUser(ctx, 1)
+			set(pc, "code:jane", jane)
+		}, "code:jane", jane},
+		{"should skip a load that raced with an invalidation", time.Minute, func(pc *PrincipalCache) {
+			gen, _ := pc.Generation(ctx, 1)
+			pc.InvalidateUser(ctx, 1)
+			pc.Set(ctx, "code:jane", gen, jane)
+		}, "code:jane", nil},
+		{"should skip a load of an older generation", time.Minute, func(pc *PrincipalCache) {
+			gen, _ := pc.Generation(ctx, 1)
+			pc.InvalidateUser(ctx, 1)
+			pc.Generation(ctx, 1)
+			pc.Set(ctx, "code:jane", gen, jane)
+		}, "code:jane", nil},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			pc := NewPrincipalCache(PrincipalCacheParams{
+				AppConf: &config.AppConfig{PrincipalCacheTTL: tc.ttl, PrincipalCacheSize: 10},
+			})
+			tc.run(pc)
+
+			u, ok := pc.Get(ctx, tc.subject)
+			assert.Equal(t, tc.expected != nil, ok)
+			assert.Equal(t, tc.expected, u)
+		})
+	}
+}
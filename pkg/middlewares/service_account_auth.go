@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dzungtran/echo-rest-api/config"
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/labstack/echo/v4"
+)
+
+type serviceAccountAuthenticator struct {
+	saUC usecases.ServiceAccountUsecase
+}
+
+// NewServiceAccountAuthenticator accepts the access tokens issued on `POST /auth/service-accounts/token`
+func NewServiceAccountAuthenticator(appConf *config.AppConfig, saUC usecases.ServiceAccountUsecase) (Authenticator, error) {
+	if len(appConf.ServiceAccountTokenSecret) < usecases.ServiceAccountTokenSecretMinSize {
+		return nil, usecases.ErrServiceAccountTokenNotConfigured
+	}
+
+	return &serviceAccountAuthenticator{
+		saUC: saUC,
+	}, nil
+}
+
+func (a *serviceAccountAuthenticator) Name() string {
+	return constants.AuthProviderServiceAccount
+}
+
+func (a *serviceAccountAuthenticator) Authenticate(c echo.Context) (*domains.Principal, error) {
+	rawToken := getBearerJWT(c)
+	if rawToken == "" {
+		return nil, ErrNoCredentials
+	}
+
+	sa, err := a.saUC.VerifyToken(c.Request().Context(), rawToken)
+	if err != nil {
+		if errors.Is(err, usecases.ErrNotServiceAccountToken) {
+			return nil, ErrNoCredentials
+		}
+		if errors.Is(err, constants.ErrUnauthorized) {
+			return nil, fmt.Errorf("%w: invalid token", constants.ErrUnauthorized)
+		}
+		return nil, err
+	}
+
+	return &domains.Principal{
+		Kind:       domains.PrincipalKindServiceAccount,
+		Subject:    sa.ClientId,
+		AuthMethod: a.Name(),
+		User:       sa.ToUserWithRoles(),
+	}, nil
+}
@@ -0,0 +1,158 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/dzungtran/echo-rest-api/modules/core/domains"
+	"github.com/dzungtran/echo-rest-api/modules/core/dto"
+	coreRepo "github.com/dzungtran/echo-rest-api/modules/core/repositories"
+	"github.com/dzungtran/echo-rest-api/modules/core/usecases"
+	"github.com/dzungtran/echo-rest-api/pkg/constants"
+	"github.com/dzungtran/echo-rest-api/pkg/contexts"
+)
+
+// UserResolver loads the local user with its org and platform roles for an authenticated identity,
+// resolved users are kept in the PrincipalCache
+type UserResolver struct {
+	userRepo         coreRepo.UserRepository
+	userOrgRepo      coreRepo.UserOrgRepository
+	platformRoleRepo coreRepo.PlatformRoleRepository
+	userUC           usecases.UserUsecase
+	cache            *PrincipalCache
+}
+
+// NewUserResolver will create new an UserResolver object
+func NewUserResolver(
+	userRepo coreRepo.UserRepository,
+	userOrgRepo coreRepo.UserOrgRepository,
+	platformRoleRepo coreRepo.PlatformRoleRepository,
+	userUC usecases.UserUsecase,
+	cache *PrincipalCache,
+) *UserResolver {
+	return &UserResolver{
+		userRepo:         userRepo,
+		userOrgRepo:      userOrgRepo,
+		platformRoleRepo: platformRoleRepo,
+		userUC:           userUC,
+		cache:            cache,
+	}
+}
+
+// FetchOrRegisterUser loads the user behind an external identity, registering it on first sight
+func (r *UserResolver) FetchOrRegisterUser(ctx context.Context, tknUser *domains.User) (u *domains.UserWithRoles, err error) {
+	u, err = r.FetchUser(ctx, tknUser.Code, tknUser.Email)
+	if err == nil || !errors.Is(err, constants.ErrNotFound) {
+		return
+	}
+
+	_, err = r.userUC.Register(ctx, dto.CreateUserReq{
+		FirstName: tknUser.FirstName,
+		LastName:  tknUser.LastName,
+		Email:     tknUser.Email,
+		Code:      tknUser.Code,
+	})
+	if err != nil {
+		return
+	}
+
+	return r.FetchUser(ctx, tknUser.Code, tknUser.Email)
+}
+
+// FetchUser loads the user by code, or by email when code is empty
+func (r *UserResolver) FetchUser(ctx context.Context, code, email string) (u *domains.UserWithRoles, err error) {
+	var subject string
+	if code != "" {
+		subject = "code:" + code
+	} else if email != "" {
+		subject = "email:" + email
+	} else {
+		return nil, constants.ErrUnauthorized
+	}
+
+	return r.cached(ctx, subject, func() (*domains.User, error) {
+		if code != "" {
+			return r.userRepo.GetByCode(ctx, code)
+		}
+		return r.userRepo.GetByEmail(ctx, email)
+	})
+}
+
+// FetchUserByID loads the user by id, e.g. the owner of a personal access token
+func (r *UserResolver) FetchUserByID(ctx context.Context, id int64) (u *domains.UserWithRoles, err error) {
+	return r.cached(ctx, "id:"+strconv.FormatInt(id, 10), func() (*domains.User, error) {
+		return r.userRepo.GetByID(ctx, id)
+	})
+}
+
+func (r *UserResolver) cached(ctx context.Context, subject string, load func() (*domains.User, error)) (u *domains.UserWithRoles, err error) {
+	if u, ok := r.cache.Get(ctx, subject); ok {
+		return u, nil
+	}
+
+	user, err := load()
+	if err != nil {
+		return
+	}
+
+	if user == nil {
+		return nil, constants.ErrUnauthorized
+	}
+
+	// the generation is read before the user and its roles are loaded again by id,
+	// so that an invalidation racing with the load keeps the stale user out of the cache
+	gen, cacheable := r.cache.Generation(ctx, user.Id)
+	if cacheable {
+		user, err = r.userRepo.GetByID(ctx, user.Id)
+		if err != nil {
+			return
+		}
+		if user == nil {
+			return nil, constants.ErrUnauthorized
+		}
+	}
+
+	u, err = r.WithRoles(ctx, user)
+	if err != nil {
+		return
+	}
+
+	if cacheable {
+		r.cache.Set(ctx, subject, gen, u)
+	}
+	return
+}
+
+// WithRoles loads the org and platform roles of the given user
+func (r *UserResolver) WithRoles(ctx context.Context, user *domains.User) (u *domains.UserWithRoles, err error) {
+	u = &domains.UserWithRoles{
+		User:    *user,
+		Kind:    domains.PrincipalKindUser,
+		OrgRole: map[int64]string{},
+	}
+
+	userOrgs, _, err := r.userOrgRepo.Fetch(ctx, coreRepo.ParamsForFetchUserOrgs{
+		CommonParamsForFetch: contexts.CommonParamsForFetch{
+			NoLimit: true,
+		},
+		UserIds: []int64{user.Id},
+	})
+	if err != nil {
+		return
+	}
+
+	for _, uo := range userOrgs {
+		u.OrgRole[uo.OrgId] = string(uo.Role)
+	}
+
+	platformRoles, err := r.platformRoleRepo.FetchByUserId(ctx, user.Id)
+	if err != nil {
+		return
+	}
+
+	for _, pr := range platformRoles {
+		u.PlatformRoles = append(u.PlatformRoles, pr.Role)
+	}
+	return
+}
@@ -0,0 +1,169 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+)
+
+const (
+	defaultCacheTTL        = time.Hour
+	defaultMinRefreshDelay = 30 * time.Second
+)
+
+var (
+	ErrMissingKeySource = errors.New("oidc: missing jwks url or jwks file")
+)
+
+// keySetCache keeps the last fetched JWKS and refreshes it when it expires
+// or when a token is signed by a key we don't know yet (key rotation)
+type keySetCache struct {
+	mu sync.Mutex
+
+	jwksURL    string
+	jwksFile   string
+	httpClient *http.Client
+	ttl        time.Duration
+	minDelay   time.Duration
+	now        func() time.Time
+
+	set       jwk.Set
+	fetchedAt time.Time
+	fileMTime time.Time
+	// static key sets are never refreshed
+	static bool
+}
+
+func newKeySetCache(jwksURL, jwksFile string, ttl time.Duration, httpClient *http.Client) (*keySetCache, error) {
+	if jwksURL == "" && jwksFile == "" {
+		return nil, ErrMissingKeySource
+	}
+
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &keySetCache{
+		jwksURL:    jwksURL,
+		jwksFile:   jwksFile,
+		httpClient: httpClient,
+		ttl:        ttl,
+		minDelay:   defaultMinRefreshDelay,
+		now:        time.Now,
+	}, nil
+}
+
+func newStaticKeySetCache(set jwk.Set) *keySetCache {
+	return &keySetCache{
+		set:    set,
+		static: true,
+	}
+}
+
+// Get returns the cached key set, fetching it again if it has expired
+func (k *keySetCache) Get(ctx context.Context) (jwk.Set, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.static || (k.set != nil && !k.isStale()) {
+		return k.set, nil
+	}
+	return k.refresh(ctx)
+}
+
+// Refresh forces a new fetch unless the key set was fetched very recently,
+// so tokens with unknown key ids cannot be used to hammer the JWKS endpoint
+func (k *keySetCache) Refresh(ctx context.Context) (jwk.Set, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.static || (k.set != nil && k.now().Sub(k.fetchedAt) < k.minDelay) {
+		return k.set, nil
+	}
+	return k.refresh(ctx)
+}
+
+func (k *keySetCache) isStale() bool {
+	if k.now().Sub(k.fetchedAt) >= k.ttl {
+		return true
+	}
+
+	if k.jwksFile != "" && k.now().Sub(k.fetchedAt) >= k.minDelay {
+		fi, err := os.Stat(k.jwksFile)
+		return err == nil && !fi.ModTime().Equal(k.fileMTime)
+	}
+	return false
+}
+
+func (k *keySetCache) refresh(ctx context.Context) (set jwk.Set, err error) {
+	if k.jwksFile != "" {
+		set, err = k.readFile()
+	} else {
+		set, err = k.fetch(ctx)
+	}
+
+	if err != nil {
+		// Keep serving the previous keys if the source is temporarily unavailable
+		if k.set != nil {
+			return k.set, nil
+		}
+		return nil, err
+	}
+
+	k.set = set
+	k.fetchedAt = k.now()
+	return set, nil
+}
+
+func (k *keySetCache) readFile() (jwk.Set, error) {
+	fi, err := os.Stat(k.jwksFile)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: read jwks file: %w", err)
+	}
+
+	data, err := os.ReadFile(k.jwksFile)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: read jwks file: %w", err)
+	}
+
+	set, err := jwk.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: parse jwks file: %w", err)
+	}
+
+	k.fileMTime = fi.ModTime()
+	return set, nil
+}
+
+func (k *keySetCache) fetch(ctx context.Context) (jwk.Set, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: unexpected jwks status %d", resp.StatusCode)
+	}
+
+	set, err := jwk.ParseReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: parse jwks: %w", err)
+	}
+	return set, nil
+}
@@ -0,0 +1,126 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+)
+
+const (
+	ephemeralKeySize = 2048
+)
+
+var (
+	ErrNoSigningKey = errors.New("oidc: no private RS256/ES256 signing key")
+)
+
+// Signer signs the tokens issued by this service, every public key of its key set
+// is published so tokens signed by a previous key stay valid until they expire
+type Signer struct {
+	key       jwk.Key
+	alg       jwa.SignatureAlgorithm
+	publicSet jwk.Set
+}
+
+// NewSignerFromFile loads a JWKS of private keys, keyId selects the signing key, default is the first one
+func NewSignerFromFile(path, keyId string) (*Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: read signing keys: %w", err)
+	}
+
+	set, err := jwk.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: parse signing keys: %w", err)
+	}
+	return newSigner(set, keyId)
+}
+
+// NewEphemeralSigner generates a RSA key which only lives as long as the process, for local development
+func NewEphemeralSigner() (*Signer, error) {
+	raw, err := rsa.GenerateKey(rand.Reader, ephemeralKeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := jwk.Import(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	set := jwk.NewSet()
+	if err = set.AddKey(key); err != nil {
+		return nil, err
+	}
+	return newSigner(set, "")
+}
+
+func newSigner(set jwk.Set, keyId string) (*Signer, error) {
+	publicSet := jwk.NewSet()
+	signer := &Signer{}
+
+	for i := 0; i < set.Len(); i++ {
+		key, _ := set.Key(i)
+		alg, err := signatureAlgorithmOf(key)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := key.KeyID(); !ok {
+			if err = jwk.AssignKeyID(key); err != nil {
+				return nil, err
+			}
+		}
+		if err = key.Set(jwk.AlgorithmKey, alg); err != nil {
+			return nil, err
+		}
+
+		kid, _ := key.KeyID()
+		isPrivate, _ := jwk.IsPrivateKey(key)
+		if isPrivate && signer.key == nil && (keyId == "" || keyId == kid) {
+			signer.key = key
+			signer.alg = alg
+		}
+
+		pub, err := key.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		if err = publicSet.AddKey(pub); err != nil {
+			return nil, err
+		}
+	}
+
+	if signer.key == nil {
+		return nil, ErrNoSigningKey
+	}
+
+	signer.publicSet = publicSet
+	return signer, nil
+}
+
+// Sign signs the token with the current signing key
+func (s *Signer) Sign(tkn jwt.Token) ([]byte, error) {
+	return jwt.Sign(tkn, jwt.WithKey(s.alg, s.key))
+}
+
+// PublicKeySet returns the keys to publish on the JWKS endpoint
+func (s *Signer) PublicKeySet() jwk.Set {
+	return s.publicSet
+}
+
+func signatureAlgorithmOf(key jwk.Key) (jwa.SignatureAlgorithm, error) {
+	switch key.KeyType() {
+	case jwa.RSA():
+		return jwa.RS256(), nil
+	case jwa.EC():
+		return jwa.ES256(), nil
+	}
+	return jwa.EmptySignatureAlgorithm(), fmt.Errorf("%w: unsupported key type %s", ErrNoSigningKey, key.KeyType())
+}
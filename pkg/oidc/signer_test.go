@@ -0,0 +1,100 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKeySetFile(t *testing.T, keys ...jwk.Key) string {
+	t.Helper()
+	set := jwk.NewSet()
+	for _, k := range keys {
+		require.NoError(t, set.AddKey(k))
+	}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+
+	fileName := filepath.Join(t.TempDir(), "signing-keys.json")
+	require.NoError(t, os.WriteFile(fileName, data, 0600))
+	return fileName
+}
+
+func signWith(t *testing.T, signer *Signer) string {
+	t.Helper()
+	tkn, err := jwt.NewBuilder().
+		Issuer(testIssuer).
+		Subject("user-123").
+		Expiration(time.Now().Add(time.Hour)).
+		Build()
+	require.NoError(t, err)
+
+	signed, err := signer.Sign(tkn)
+	require.NoError(t, err)
+	return string(signed)
+}
+
+func TestSignerFromFile(t *testing.T) {
+	fileName := writeKeySetFile(t, newRSAKey(t, "rsa-old"), newECKey(t, "ec-current"))
+
+	tcs := []struct {
+		name     string
+		keyId    string
+		hasError bool
+	}{
+		{"should sign with the first key by default", "", false},
+		{"should sign with the selected key", "ec-current", false},
+		{"should fail with unknown key id", "unknown", true},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			signer, err := NewSignerFromFile(fileName, tc.keyId)
+			if tc.hasError {
+				assert.ErrorIs(t, err, ErrNoSigningKey)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 2, signer.PublicKeySet().Len())
+
+			verifier, err := NewVerifier(Config{Issuer: testIssuer, KeySet: signer.PublicKeySet()})
+			require.NoError(t, err)
+
+			claims, err := verifier.Verify(context.Background(), signWith(t, signer))
+			assert.Nil(t, err)
+			assert.Equal(t, "user-123", claims["sub"])
+		})
+	}
+}
+
+func TestSignerPublishesPublicKeysOnly(t *testing.T) {
+	signer, err := NewEphemeralSigner()
+	require.NoError(t, err)
+
+	for i := 0; i < signer.PublicKeySet().Len(); i++ {
+		key, _ := signer.PublicKeySet().Key(i)
+		isPrivate, err := jwk.IsPrivateKey(key)
+		require.NoError(t, err)
+		assert.False(t, isPrivate)
+
+		_, ok := key.KeyID()
+		assert.True(t, ok)
+	}
+}
+
+func TestSignerWithoutPrivateKey(t *testing.T) {
+	pub, err := newRSAKey(t, "rsa-public").PublicKey()
+	require.NoError(t, err)
+
+	_, err = NewSignerFromFile(writeKeySetFile(t, pub), "")
+	assert.ErrorIs(t, err, ErrNoSigningKey)
+}
@@ -0,0 +1,128 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jws"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+)
+
+const (
+	acceptableClockSkew = 30 * time.Second
+)
+
+var (
+	ErrInvalidToken = errors.New("oidc: invalid token")
+
+	allowedAlgorithms = []jwa.SignatureAlgorithm{
+		jwa.RS256(),
+		jwa.ES256(),
+	}
+)
+
+type (
+	// Config is used to verify bearer tokens issued by an OIDC provider
+	Config struct {
+		Issuer   string
+		Audience string
+		JwksURL  string
+		JwksFile string
+		// KeySet is used as is instead of loading JwksURL or JwksFile, e.g. the keys of a Signer
+		KeySet jwk.Set
+		// CacheTTL controls how long fetched keys are kept, default is 1 hour
+		CacheTTL   time.Duration
+		HTTPClient *http.Client
+	}
+
+	// Verifier checks signature, issuer, audience and expiry of JWTs against a JWKS
+	Verifier struct {
+		conf Config
+		keys *keySetCache
+	}
+)
+
+// NewVerifier will create new a Verifier, keys are loaded on the first verification
+func NewVerifier(conf Config) (*Verifier, error) {
+	if conf.KeySet != nil {
+		return &Verifier{
+			conf: conf,
+			keys: newStaticKeySetCache(conf.KeySet),
+		}, nil
+	}
+
+	keys, err := newKeySetCache(conf.JwksURL, conf.JwksFile, conf.CacheTTL, conf.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Verifier{
+		conf: conf,
+		keys: keys,
+	}, nil
+}
+
+// Verify validates the raw token and returns its claims
+func (v *Verifier) Verify(ctx context.Context, rawToken string) (map[string]interface{}, error) {
+	msg, err := jws.Parse([]byte(rawToken))
+	if err != nil || len(msg.Signatures()) != 1 {
+		return nil, ErrInvalidToken
+	}
+
+	headers := msg.Signatures()[0].ProtectedHeaders()
+	alg, ok := headers.Algorithm()
+	if !ok || !isAllowedAlgorithm(alg) {
+		return nil, fmt.Errorf("%w: unsupported algorithm", ErrInvalidToken)
+	}
+
+	set, err := v.keys.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if kid, ok := headers.KeyID(); ok {
+		if _, found := set.LookupKeyID(kid); !found {
+			set, err = v.keys.Refresh(ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	parseOpts := []jwt.ParseOption{
+		jwt.WithKeySet(set, jws.WithInferAlgorithmFromKey(true)),
+		jwt.WithAcceptableSkew(acceptableClockSkew),
+		jwt.WithRequiredClaim("exp"),
+	}
+	if v.conf.Issuer != "" {
+		parseOpts = append(parseOpts, jwt.WithIssuer(v.conf.Issuer))
+	}
+	if v.conf.Audience != "" {
+		parseOpts = append(parseOpts, jwt.WithAudience(v.conf.Audience))
+	}
+
+	if _, err = jwt.Parse([]byte(rawToken), parseOpts...); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	claims := map[string]interface{}{}
+	if err = json.Unmarshal(msg.Payload(), &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func isAllowedAlgorithm(alg jwa.SignatureAlgorithm) bool {
+	for _, a := range allowedAlgorithms {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v3/jwa"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lestrrat-go/jwx/v3/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIssuer   = "https://issuer.api.com/"
+	testAudience = "echo-rest-api"
+)
+
+type testJwksServer struct {
+	*httptest.Server
+	mu   sync.Mutex
+	set  jwk.Set
+	hits int
+}
+
+func newTestJwksServer(t *testing.T, keys ...jwk.Key) *testJwksServer {
+	t.Helper()
+	s := &testJwksServer{}
+	s.setKeys(t, keys...)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.hits++
+		json.NewEncoder(w).Encode(s.set)
+	}))
+	return s
+}
+
+func (s *testJwksServer) setKeys(t *testing.T, keys ...jwk.Key) {
+	t.Helper()
+	set := jwk.NewSet()
+	for _, k := range keys {
+		pub, err := k.PublicKey()
+		require.NoError(t, err)
+		require.NoError(t, set.AddKey(pub))
+	}
+
+	s.mu.Lock()
+	s.set = set
+	s.mu.Unlock()
+}
+
+func newRSAKey(t *testing.T, kid string) jwk.Key {
+	t.Helper()
+	raw, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	key, err := jwk.Import(raw)
+	require.NoError(t, err)
+	require.NoError(t, key.Set(jwk.KeyIDKey, kid))
+	require.NoError(t, key.Set(jwk.AlgorithmKey, jwa.RS256()))
+	return key
+}
+
+func newECKey(t *testing.T, kid string) jwk.Key {
+	t.Helper()
+	raw, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	key, err := jwk.Import(raw)
+	require.NoError(t, err)
+	require.NoError(t, key.Set(jwk.KeyIDKey, kid))
+	require.NoError(t, key.Set(jwk.AlgorithmKey, jwa.ES256()))
+	return key
+}
+
+func signTestToken(t *testing.T, alg jwa.SignatureAlgorithm, key interface{}, edit func(tkn jwt.Token)) string {
+	t.Helper()
+	tkn, err := jwt.NewBuilder().
+		Issuer(testIssuer).
+		Audience([]string{testAudience}).
+		Subject("user-123").
+		Expiration(time.Now().Add(time.Hour)).
+		Claim("email", "jane@api.com").
+		Build()
+	require.NoError(t, err)
+
+	if edit != nil {
+		edit(tkn)
+	}
+
+	signed, err := jwt.Sign(tkn, jwt.WithKey(alg, key))
+	require.NoError(t, err)
+	return string(signed)
+}
+
+func TestVerifierVerify(t *testing.T) {
+	rsaKey := newRSAKey(t, "rsa-1")
+	ecKey := newECKey(t, "ec-1")
+	unknownKey := newRSAKey(t, "rsa-unknown")
+
+	srv := newTestJwksServer(t, rsaKey, ecKey)
+	defer srv.Close()
+
+	verifier, err := NewVerifier(Config{
+		Issuer:   testIssuer,
+		Audience: testAudience,
+		JwksURL:  srv.URL,
+	})
+	require.NoError(t, err)
+
+	tcs := []struct {
+		name     string
+		token    string
+		hasError bool
+	}{
+		{"should accept RS256 token", signTestToken(t, jwa.RS256(), rsaKey, nil), false},
+		{"should accept ES256 token", signTestToken(t, jwa.ES256(), ecKey, nil), false},
+		{"should reject HS256 token", signTestToken(t, jwa.HS256(), []byte("a-shared-secret-with-32-bytes!!!"), nil), true},
+		{"should reject token signed by unknown key", signTestToken(t, jwa.RS256(), unknownKey, nil), true},
+		{"should reject token with other issuer", signTestToken(t, jwa.RS256(), rsaKey, func(tkn jwt.Token) {
+			tkn.Set(jwt.IssuerKey, "https://other.api.com/")
+		}), true},
+		{"should reject token with other audience", signTestToken(t, jwa.RS256(), rsaKey, func(tkn jwt.Token) {
+			tkn.Set(jwt.AudienceKey, []string{"other"})
+		}), true},
+		{"should reject expired token", signTestToken(t, jwa.RS256(), rsaKey, func(tkn jwt.Token) {
+			tkn.Set(jwt.ExpirationKey, time.Now().Add(-time.Hour))
+		}), true},
+		{"should reject token without expiry", signTestToken(t, jwa.RS256(), rsaKey, func(tkn jwt.Token) {
+			tkn.Remove(jwt.ExpirationKey)
+		}), true},
+		{"should reject malformed token", "not-a-jwt", true},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := verifier.Verify(context.Background(), tc.token)
+			if tc.hasError {
+				assert.ErrorIs(t, err, ErrInvalidToken)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, "user-123", claims["sub"])
+			assert.Equal(t, "jane@api.com", claims["email"])
+		})
+	}
+}
+
+func TestVerifierKeyRotation(t *testing.T) {
+	oldKey := newRSAKey(t, "rsa-old")
+	newKey := newRSAKey(t, "rsa-new")
+
+	srv := newTestJwksServer(t, oldKey)
+	defer srv.Close()
+
+	verifier, err := NewVerifier(Config{JwksURL: srv.URL})
+	require.NoError(t, err)
+	verifier.keys.minDelay = 0
+
+	_, err = verifier.Verify(context.Background(), signTestToken(t, jwa.RS256(), oldKey, nil))
+	assert.Nil(t, err)
+
+	// cached keys are reused
+	_, err = verifier.Verify(context.Background(), signTestToken(t, jwa.RS256(), oldKey, nil))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, srv.hits)
+
+	srv.setKeys(t, oldKey, newKey)
+	_, err = verifier.Verify(context.Background(), signTestToken(t, jwa.RS256(), newKey, nil))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, srv.hits)
+}
+
+func TestVerifierLocalJwksFile(t *testing.T) {
+	key := newRSAKey(t, "rsa-file")
+	pub, err := key.PublicKey()
+	require.NoError(t, err)
+
+	set := jwk.NewSet()
+	require.NoError(t, set.AddKey(pub))
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+
+	fileName := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(fileName, data, 0600))
+
+	verifier, err := NewVerifier(Config{JwksFile: fileName})
+	require.NoError(t, err)
+
+	_, err = verifier.Verify(context.Background(), signTestToken(t, jwa.RS256(), key, nil))
+	assert.Nil(t, err)
+}
+
+func TestNewVerifierMissingKeySource(t *testing.T) {
+	_, err := NewVerifier(Config{Issuer: testIssuer})
+	assert.ErrorIs(t, err, ErrMissingKeySource)
+}
@@ -0,0 +1,71 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHOTPWithRFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	tcs := []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tc := range tcs {
+		step := Step(time.Unix(tc.unix, 0))
+		assert.Equal(t, tc.expected, hotp(key, uint64(step), 8))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111111, 0)
+	code, err := Code(secret, now)
+	assert.Nil(t, err)
+
+	tcs := []struct {
+		name     string
+		secret   string
+		code     string
+		at       time.Time
+		expected bool
+	}{
+		{"should accept code of current period", secret, code, now, true},
+		{"should accept code of previous period", secret, code, now.Add(Period), true},
+		{"should accept code of next period", secret, code, now.Add(-Period), true},
+		{"should reject code older than one period", secret, code, now.Add(2 * Period), false},
+		{"should reject wrong code", secret, "000000", now, false},
+		{"should reject code with wrong length", secret, code[:5], now, false},
+		{"should reject invalid secret", "not base32!", code, now, false},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, ok := Validate(tc.secret, tc.code, tc.at)
+			assert.Equal(t, tc.expected, ok)
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.Nil(t, err)
+
+	code, err := Code(secret, time.Now())
+	assert.Nil(t, err)
+
+	_, ok := Validate(secret, code, time.Now())
+	assert.True(t, ok)
+}
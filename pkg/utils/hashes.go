@@ -2,8 +2,11 @@ package utils
 
 import (
 	"crypto/md5"
+	"crypto/rand"
 	"crypto/sha256"
 	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
 )
 
@@ -27,3 +30,17 @@ func GetSHA512Hash(text string) string {
 	shaHash.Write([]byte(text))
 	return hex.EncodeToString(shaHash.Sum(nil))
 }
+
+// IsSecureEqual -- compare two secrets in constant time
+func IsSecureEqual(given, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(given), []byte(expected)) == 1
+}
+
+// GenerateSecureToken -- get an url safe random token from size bytes of crypto/rand
+func GenerateSecureToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dzungtran/echo-rest-api/pkg/authz"
+	"github.com/dzungtran/echo-rest-api/pkg/logger"
+)
+
+const (
+	formatMarkdown = "markdown"
+	formatCSV      = "csv"
+	formatJSON     = "json"
+)
+
+func main() {
+	format := flag.String("format", formatMarkdown, "output format: markdown, csv or json")
+	out := flag.String("out", "", "file to write the matrix to, stdout when empty")
+	baseline := flag.String("baseline", "", "JSON matrix to diff against, exits 1 on any change")
+	policies := flag.String("policies", "", "policy directory or bundle to evaluate instead of the embedded policies")
+	flag.Parse()
+
+	authorizer := authz.NewOPAAuthorizer()
+	if *policies != "" {
+		if err := authorizer.ReloadPolicies(*policies); err != nil {
+			logger.Log().Fatalf("error while load policies: %v", err)
+		}
+	}
+
+	matrix, err := authorizer.BuildPermissionMatrix(context.Background())
+	if err != nil {
+		logger.Log().Fatalf("error while build permission matrix: %v", err)
+	}
+
+	if *baseline != "" {
+		os.Exit(diffBaseline(matrix, *baseline))
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			logger.Log().Fatalf("error while create %s: %v", *out, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err = writeMatrix(w, matrix, *format); err != nil {
+		logger.Log().Fatalf("error while write permission matrix: %v", err)
+	}
+}
+
+// diffBaseline prints the changes from the baseline and returns the exit code
+func diffBaseline(matrix *authz.PermissionMatrix, path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Log().Fatalf("error while read baseline: %v", err)
+	}
+
+	var base authz.PermissionMatrix
+	if err = json.Unmarshal(data, &base); err != nil {
+		logger.Log().Fatalf("error while parse baseline, it must be a json matrix: %v", err)
+	}
+
+	changes := matrix.Diff(&base)
+	if len(changes) == 0 {
+		logger.Log().Info("permission matrix matches ", path)
+		return 0
+	}
+
+	fmt.Printf("permission matrix changed from %s:\n", path)
+	for _, c := range changes {
+		fmt.Println("  " + c)
+	}
+	return 1
+}
+
+func writeMatrix(w io.Writer, matrix *authz.PermissionMatrix, format string) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(matrix)
+	case formatCSV:
+		cw := csv.NewWriter(w)
+		_ = cw.Write(append([]string{"method", "endpoint", "permission"}, matrix.Roles...))
+		for _, r := range matrix.Routes {
+			_ = cw.Write(append([]string{r.Method, r.Endpoint, r.Permission}, cells(r, matrix.Roles, "allow", "deny")...))
+		}
+		cw.Flush()
+		return cw.Error()
+	case formatMarkdown:
+		header := append([]string{"Method", "Endpoint", "Permission"}, matrix.Roles...)
+		fmt.Fprintf(w, "| %s |\n", strings.Join(header, " | "))
+		fmt.Fprintf(w, "|%s\n", strings.Repeat("---|", len(header)))
+		for _, r := range matrix.Routes {
+			row := append([]string{r.Method, "`" + r.Endpoint + "`", r.Permission}, cells(r, matrix.Roles, "✅", "")...)
+			fmt.Fprintf(w, "| %s |\n", strings.Join(row, " | "))
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown format: %s", format)
+}
+
+func cells(r authz.MatrixRoute, roles []string, allow, deny string) []string {
+	rs := make([]string, 0, len(roles))
+	for _, role := range roles {
+		if r.Allowed[role] {
+			rs = append(rs, allow)
+		} else {
+			rs = append(rs, deny)
+		}
+	}
+	return rs
+}
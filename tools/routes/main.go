@@ -3,11 +3,11 @@ package main
 import (
 	"encoding/json"
 	"io/ioutil"
-	"strings"
 
 	"github.com/dzungtran/echo-rest-api/cmd/api/di"
 	"github.com/dzungtran/echo-rest-api/config"
 	"github.com/dzungtran/echo-rest-api/infrastructure/datastore"
+	"github.com/dzungtran/echo-rest-api/pkg/authz"
 	"github.com/dzungtran/echo-rest-api/pkg/logger"
 	"github.com/labstack/echo/v4"
 )
@@ -25,19 +25,13 @@ func generateRoutes() {
 	)
 	di.RegisterModules(e, container)
 
-	mapRoutes := map[string]map[string]string{}
+	mapRoutes, unnamed := authz.BuildEndpointsACL(e.Routes())
 	count := 0
-	for _, r := range e.Routes() {
-		if strings.HasPrefix(r.Name, "github.com") {
-			continue
-		}
-		count++
-		acl := mapRoutes[r.Path]
-		if len(acl) == 0 {
-			acl = map[string]string{}
-		}
-		acl[r.Method] = r.Name
-		mapRoutes[r.Path] = acl
+	for _, acl := range mapRoutes {
+		count += len(acl)
+	}
+	if len(unnamed) > 0 {
+		logger.Log().Warnw("routes without a permission name", "routes", unnamed)
 	}
 
 	logger.Log().Info("Generated routes: ", count)